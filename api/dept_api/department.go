@@ -1,25 +1,78 @@
 package dept_api
 
 import (
+	"errors"
+	"strconv"
+
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
+	"rbac_admin_server/utils/depttree"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// departmentListSpec 部门列表允许的筛选和排序字段
+var departmentListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"name":      {Column: "name", Op: search.OpLike},
+		"status":    {Column: "status", Op: search.OpIn},
+		"parent_id": {Column: "parent_id", Op: search.OpEq},
+		"level":     {Column: "level", Op: search.OpEq},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"sort":       "sort",
+		"created_at": "created_at",
+	},
+	DefaultSort: "sort ASC, id ASC",
+}
+
+// departmentUserSpec 部门用户列表允许的筛选和排序字段
+var departmentUserSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"username": {Column: "username", Op: search.OpLike},
+		"nickname": {Column: "nickname", Op: search.OpLike},
+		"status":   {Column: "status", Op: search.OpIn},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"username":   "username",
+		"created_at": "created_at",
+	},
+	DefaultSort: "id ASC",
+}
+
 // GetDepartmentList 获取部门列表
 // @Summary 获取部门列表接口
-// @Description 查询系统中的部门列表
+// @Description 分页查询系统中的部门列表，支持筛选和排序
 // @Tags 部门管理
 // @Accept json
 // @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Department}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param name query string false "部门名称(模糊)"
+// @Param parent_id query int false "上级部门ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/dept/list [get]
 func (d *DepartmentApi) GetDepartmentList(c *gin.Context) {
+	q, err := search.Parse(c, departmentListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	var departments []models.Department
-	if err := global.DB.Find(&departments).Error; err != nil {
-		global.Logger.Error("获取部门列表失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Department{}), &departments)
+	if err != nil {
+		reqlog.Entry(c).Error("获取部门列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取部门列表失败"})
 		return
 	}
@@ -27,7 +80,7 @@ func (d *DepartmentApi) GetDepartmentList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": departments,
+		"data": page,
 	})
 }
 
@@ -45,27 +98,38 @@ func (d *DepartmentApi) GetDepartmentList(c *gin.Context) {
 func (d *DepartmentApi) CreateDepartment(c *gin.Context) {
 	var department models.Department
 	if err := c.ShouldBindJSON(&department); err != nil {
-		global.Logger.Error("创建部门参数错误: " + err.Error())
+		reqlog.Entry(c).Error("创建部门参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 检查部门名称是否已存在
 	var count int64
-	global.DB.Model(&models.Department{}).Where("name = ?", department.Name).Count(&count)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Department{}).Where("name = ?", department.Name).Count(&count)
 	if count > 0 {
-		global.Logger.Error("部门名称已存在: " + department.Name)
+		reqlog.Entry(c).Error("部门名称已存在: " + department.Name)
 		c.JSON(400, gin.H{"code": 400, "msg": "部门名称已存在"})
 		return
 	}
 
-	if err := global.DB.Create(&department).Error; err != nil {
-		global.Logger.Error("创建部门失败: " + err.Error())
+	// 路径和层级由上级部门生成
+	if department.ParentID != 0 {
+		if _, err := depttree.Get(global.DB.WithContext(c.Request.Context()), department.ParentID); err != nil {
+			if errors.Is(err, depttree.ErrNotFound) {
+				err = depttree.ErrParentNotFound
+			}
+			treeError(c, "创建部门", err)
+			return
+		}
+	}
+
+	if err := global.DB.WithContext(c.Request.Context()).Create(&department).Error; err != nil {
+		reqlog.Entry(c).Error("创建部门失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员创建部门成功: %s", department.Name)
+	reqlog.Entry(c).Infof("管理员创建部门成功: %s", department.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "创建成功",
@@ -74,7 +138,7 @@ func (d *DepartmentApi) CreateDepartment(c *gin.Context) {
 
 // UpdateDepartment 更新部门
 // @Summary 更新部门接口
-// @Description 管理员更新部门信息
+// @Description 管理员更新部门信息，上级部门变化时同时移动下级部门
 // @Tags 部门管理
 // @Accept json
 // @Produce json
@@ -86,24 +150,37 @@ func (d *DepartmentApi) CreateDepartment(c *gin.Context) {
 func (d *DepartmentApi) UpdateDepartment(c *gin.Context) {
 	var department models.Department
 	if err := c.ShouldBindJSON(&department); err != nil {
-		global.Logger.Error("更新部门参数错误: " + err.Error())
+		reqlog.Entry(c).Error("更新部门参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	if department.ID == 0 {
-		global.Logger.Error("更新部门参数错误: ID为空")
+		reqlog.Entry(c).Error("更新部门参数错误: ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	if err := global.DB.Save(&department).Error; err != nil {
-		global.Logger.Error("更新部门失败: " + err.Error())
+	db := global.DB.WithContext(c.Request.Context())
+	current, err := depttree.Get(db, department.ID)
+	if err != nil {
+		treeError(c, "更新部门", err)
+		return
+	}
+	if current.ParentID != department.ParentID {
+		if err := depttree.Move(db, department.ID, department.ParentID); err != nil {
+			treeError(c, "更新部门", err)
+			return
+		}
+	}
+
+	if err := db.Omit("parent_id", "path", "level", "created_at").Save(&department).Error; err != nil {
+		reqlog.Entry(c).Error("更新部门失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员更新部门成功: %s", department.Name)
+	reqlog.Entry(c).Infof("管理员更新部门成功: %s", department.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "更新成功",
@@ -122,38 +199,39 @@ func (d *DepartmentApi) UpdateDepartment(c *gin.Context) {
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/dept/delete [delete]
 func (d *DepartmentApi) DeleteDepartment(c *gin.Context) {
-	id := c.Query("id")
-	if id == "" {
-		global.Logger.Error("删除部门参数错误: ID为空")
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除部门参数错误: ID无效")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 检查部门是否有子部门
 	var childDepartments []models.Department
-	global.DB.Where("parent_id = ?", id).Find(&childDepartments)
+	global.DB.WithContext(c.Request.Context()).Where("parent_id = ?", id).Find(&childDepartments)
 	if len(childDepartments) > 0 {
-		global.Logger.Error("删除部门失败: 部门有子部门")
+		reqlog.Entry(c).Error("删除部门失败: 部门有子部门")
 		c.JSON(400, gin.H{"code": 400, "msg": "部门有子部门，无法删除"})
 		return
 	}
 
-	// 检查部门是否有用户
-	var users []models.User
-	global.DB.Where("department_id = ?", id).Find(&users)
-	if len(users) > 0 {
-		global.Logger.Error("删除部门失败: 部门有用户")
+	// 检查部门是否有用户，包括以该部门为兼职部门的用户
+	var users int64
+	global.DB.WithContext(c.Request.Context()).Model(&models.User{}).
+		Where("department_id = ? OR id IN (?)", id, departmentMembers([]uint{uint(id)})).Count(&users)
+	if users > 0 {
+		reqlog.Entry(c).Error("删除部门失败: 部门有用户")
 		c.JSON(400, gin.H{"code": 400, "msg": "部门有用户，无法删除"})
 		return
 	}
 
-	if err := global.DB.Delete(&models.Department{}, id).Error; err != nil {
-		global.Logger.Error("删除部门失败: " + err.Error())
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "department", uint(id)); err != nil {
+		reqlog.Entry(c).Error("删除部门失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员删除部门成功: ID=%s", id)
+	reqlog.Entry(c).Infof("管理员删除部门成功，已移入回收站: ID=%d", id)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
@@ -166,19 +244,19 @@ func (d *DepartmentApi) DeleteDepartment(c *gin.Context) {
 // @Tags 部门管理
 // @Accept json
 // @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]gin.H{"id":int, "name":string, "children":[]gin.H}}
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]gin.H{"id":int, "name":string, "level":int, "children":[]gin.H}}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/dept/tree [get]
 func (d *DepartmentApi) GetDepartmentTree(c *gin.Context) {
 	var departments []models.Department
-	if err := global.DB.Order("sort").Find(&departments).Error; err != nil {
-		global.Logger.Error("获取部门树失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Order("level, sort, id").Find(&departments).Error; err != nil {
+		reqlog.Entry(c).Error("获取部门树失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
 
 	// 构建部门树
-	tree := buildDepartmentTree(departments, 0)
+	tree := buildDepartmentTree(departments)
 
 	c.JSON(200, gin.H{
 		"code": 200,
@@ -188,19 +266,24 @@ func (d *DepartmentApi) GetDepartmentTree(c *gin.Context) {
 }
 
 // buildDepartmentTree 构建部门树结构
-func buildDepartmentTree(departments []models.Department, parentID uint) []gin.H {
+// 部门已按层级排序，上级节点总是先于下级节点创建；上级不存在的部门作为顶级节点
+func buildDepartmentTree(departments []models.Department) []gin.H {
 	tree := make([]gin.H, 0)
+	nodes := make(map[uint]gin.H, len(departments))
 
 	for _, dept := range departments {
-		if dept.ParentID == parentID {
-			children := buildDepartmentTree(departments, dept.ID)
-			node := gin.H{
-				"id":       dept.ID,
-				"name":     dept.Name,
-				"parent_id": dept.ParentID,
-				"sort":     dept.Sort,
-				"children": children,
-			}
+		node := gin.H{
+			"id":        dept.ID,
+			"name":      dept.Name,
+			"parent_id": dept.ParentID,
+			"sort":      dept.Sort,
+			"level":     dept.Level,
+			"children":  make([]gin.H, 0),
+		}
+		nodes[dept.ID] = node
+		if parent, ok := nodes[dept.ParentID]; ok {
+			parent["children"] = append(parent["children"].([]gin.H), node)
+		} else {
 			tree = append(tree, node)
 		}
 	}
@@ -210,26 +293,51 @@ func buildDepartmentTree(departments []models.Department, parentID uint) []gin.H
 
 // GetDepartmentUsers 获取部门用户
 // @Summary 获取部门用户接口
-// @Description 查询指定部门下的用户列表
+// @Description 分页查询指定部门下的用户列表，包括以该部门为兼职部门的用户
 // @Tags 部门管理
 // @Accept json
 // @Produce json
 // @Param dept_id query int true "部门ID"
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.User}
+// @Param include_children query bool false "是否包含下级部门的用户"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/dept/users [get]
 func (d *DepartmentApi) GetDepartmentUsers(c *gin.Context) {
 	deptID := c.Query("dept_id")
 	if deptID == "" {
-		global.Logger.Error("获取部门用户参数错误: 部门ID为空")
+		reqlog.Entry(c).Error("获取部门用户参数错误: 部门ID为空")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	q, err := search.Parse(c, departmentUserSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	id, err := strconv.ParseUint(deptID, 10, 64)
+	if err != nil {
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
+	ids := []uint{uint(id)}
+	if c.Query("include_children") == "true" {
+		if ids, err = depttree.DescendantIDs(db, uint(id)); err != nil {
+			treeError(c, "获取部门用户", err)
+			return
+		}
+	}
 
 	var users []models.User
-	if err := global.DB.Where("department_id = ?", deptID).Find(&users).Error; err != nil {
-		global.Logger.Error("获取部门用户失败: " + err.Error())
+	page, err := q.Find(db.Model(&models.User{}).Where("id IN (?)", departmentMembers(ids)), &users)
+	if err != nil {
+		reqlog.Entry(c).Error("获取部门用户失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
@@ -237,6 +345,10 @@ func (d *DepartmentApi) GetDepartmentUsers(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": users,
+		"data": page,
 	})
-}
\ No newline at end of file
+}
+// departmentMembers 部门成员用户ID子查询
+func departmentMembers(deptIDs []uint) *gorm.DB {
+	return global.DB.Model(&models.UserDepartment{}).Select("user_id").Where("department_id IN ?", deptIDs)
+}
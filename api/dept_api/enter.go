@@ -20,5 +20,12 @@ func (d *DepartmentApi) RegisterRoutes(router *gin.RouterGroup) {
 		deptRouter.DELETE("/delete", d.DeleteDepartment)
 		deptRouter.GET("/tree", d.GetDepartmentTree)
 		deptRouter.GET("/users", d.GetDepartmentUsers)
+		deptRouter.PUT("/move", d.MoveDepartment)
+		deptRouter.PUT("/reorder", d.ReorderDepartments)
+		deptRouter.GET("/descendants", d.GetDepartmentDescendants)
+		deptRouter.GET("/ancestors", d.GetDepartmentAncestors)
+		deptRouter.GET("/leaders", d.GetDepartmentLeaders)
+		deptRouter.PUT("/leaders", d.SetDepartmentLeaders)
+		deptRouter.GET("/chart", d.GetOrgChart)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,126 @@
+package dept_api
+
+import (
+	"errors"
+	"strconv"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/reqlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetLeadersRequest 设置部门负责人请求参数
+type SetLeadersRequest struct {
+	// 部门ID
+	DeptID uint `json:"dept_id" binding:"required"`
+	// 负责人用户ID，为空表示清除负责人
+	UserIDs []uint `json:"user_ids" binding:"max=50"`
+}
+
+// GetDepartmentLeaders 获取部门负责人
+// @Summary 获取部门负责人接口
+// @Description 查询指定部门的负责人，已归档的用户除外
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param dept_id query int true "部门ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]org.Leader}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/leaders [get]
+func (d *DepartmentApi) GetDepartmentLeaders(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("dept_id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	leaders, err := org.Leaders(global.DB.WithContext(c.Request.Context()), []uint{uint(id)})
+	if err != nil {
+		reqlog.Entry(c).Error("获取部门负责人失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+	list := leaders[uint(id)]
+	if list == nil {
+		list = []org.Leader{}
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+	})
+}
+
+// SetDepartmentLeaders 设置部门负责人
+// @Summary 设置部门负责人接口
+// @Description 替换部门的全部负责人，负责人可以有多个，不在该部门的用户会加入为部门成员
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param data body SetLeadersRequest true "部门ID和负责人用户ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/leaders [put]
+func (d *DepartmentApi) SetDepartmentLeaders(c *gin.Context) {
+	var req SetLeadersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("设置部门负责人参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var before []uint
+	if leaders, err := org.Leaders(db, []uint{req.DeptID}); err == nil {
+		for _, l := range leaders[req.DeptID] {
+			before = append(before, l.ID)
+		}
+	}
+
+	if err := org.SetLeaders(db, req.DeptID, req.UserIDs); err != nil {
+		if errors.Is(err, org.ErrDepartmentNotFound) || errors.Is(err, org.ErrUserNotFound) {
+			c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		reqlog.Entry(c).Error("设置部门负责人失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "设置失败"})
+		return
+	}
+	audit.RecordChange(c, "department", "set-leaders", req.DeptID, before, req.UserIDs)
+
+	reqlog.Entry(c).Infof("管理员设置部门负责人成功: 部门ID=%d", req.DeptID)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "设置成功",
+	})
+}
+
+// GetOrgChart 获取组织架构图
+// @Summary 获取组织架构图接口
+// @Description 返回部门树，每个节点包含负责人、直属成员数和包括下级部门的总人数
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]org.Node}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/chart [get]
+func (d *DepartmentApi) GetOrgChart(c *gin.Context) {
+	chart, err := org.Chart(global.DB.WithContext(c.Request.Context()))
+	if err != nil {
+		reqlog.Entry(c).Error("获取组织架构图失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": chart,
+	})
+}
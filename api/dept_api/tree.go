@@ -0,0 +1,172 @@
+package dept_api
+
+import (
+	"errors"
+	"strconv"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/depttree"
+	"rbac_admin_server/utils/reqlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MoveRequest 移动部门请求参数
+type MoveRequest struct {
+	// 部门ID
+	ID uint `json:"id" binding:"required"`
+	// 新的上级部门ID，0表示移动为顶级部门
+	ParentID uint `json:"parent_id"`
+}
+
+// ReorderRequest 同级部门排序请求参数
+type ReorderRequest struct {
+	// 上级部门ID，0表示顶级部门
+	ParentID uint `json:"parent_id"`
+	// 按新顺序排列的同级部门ID
+	IDs []uint `json:"ids" binding:"required,min=1,max=500,unique"`
+}
+
+// MoveDepartment 移动部门
+// @Summary 移动部门接口
+// @Description 将部门及其全部下级部门移动到新的上级部门下，不能移动到自身或下级部门下
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param data body MoveRequest true "部门ID和新的上级部门ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/move [put]
+func (d *DepartmentApi) MoveDepartment(c *gin.Context) {
+	var req MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("移动部门参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	if err := depttree.Move(global.DB.WithContext(c.Request.Context()), req.ID, req.ParentID); err != nil {
+		treeError(c, "移动部门", err)
+		return
+	}
+
+	reqlog.Entry(c).Infof("管理员移动部门成功: ID=%d, 上级部门ID=%d", req.ID, req.ParentID)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "移动成功",
+	})
+}
+
+// ReorderDepartments 同级部门排序
+// @Summary 同级部门排序接口
+// @Description 按提交的顺序重新设置同一上级下部门的排序值
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param data body ReorderRequest true "上级部门ID和排序后的部门ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/reorder [put]
+func (d *DepartmentApi) ReorderDepartments(c *gin.Context) {
+	var req ReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("部门排序参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	if err := depttree.Reorder(global.DB.WithContext(c.Request.Context()), req.ParentID, req.IDs); err != nil {
+		treeError(c, "部门排序", err)
+		return
+	}
+
+	reqlog.Entry(c).Infof("管理员调整部门排序成功: 上级部门ID=%d, 共%d个", req.ParentID, len(req.IDs))
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "排序成功",
+	})
+}
+
+// GetDepartmentDescendants 获取下级部门
+// @Summary 获取全部下级部门接口
+// @Description 按部门路径查询全部层级的下级部门，按层级和排序排列
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id query int true "部门ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Department}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/descendants [get]
+func (d *DepartmentApi) GetDepartmentDescendants(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	list, err := depttree.Descendants(global.DB.WithContext(c.Request.Context()), uint(id))
+	if err != nil {
+		treeError(c, "获取下级部门", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+	})
+}
+
+// GetDepartmentAncestors 获取上级部门
+// @Summary 获取全部上级部门接口
+// @Description 按部门路径查询从顶级部门开始的全部上级部门，可用于面包屑导航
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id query int true "部门ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Department}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/dept/ancestors [get]
+func (d *DepartmentApi) GetDepartmentAncestors(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	list, err := depttree.Ancestors(global.DB.WithContext(c.Request.Context()), uint(id))
+	if err != nil {
+		treeError(c, "获取上级部门", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+	})
+}
+
+// treeError 按部门树操作的错误类型返回响应
+func treeError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, depttree.ErrNotFound):
+		c.JSON(404, gin.H{"code": 404, "msg": err.Error()})
+	case errors.Is(err, depttree.ErrParentNotFound), errors.Is(err, depttree.ErrCycle),
+		errors.Is(err, depttree.ErrNotSibling):
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+	case errors.Is(err, depttree.ErrNoPath):
+		reqlog.Entry(c).Error(action + "失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": err.Error()})
+	default:
+		reqlog.Entry(c).Error(action + "失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": action + "失败"})
+	}
+}
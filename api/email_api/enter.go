@@ -9,6 +9,7 @@ import (
 	"rbac_admin_server/utils"
 	"rbac_admin_server/utils/captcha"
 	"rbac_admin_server/utils/email"
+	"rbac_admin_server/utils/reqlog"
 )
 
 // EmailApi 邮件API控制器
@@ -36,7 +37,7 @@ func (e *EmailApi) SendEmailView(c *gin.Context) {
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, gin.H{
 			"code": utils.ERROR_INVALID_PARAM,
-			"msg":  utils.GetErrMsg(utils.ERROR_INVALID_PARAM) + ": " + err.Error(),
+			"msg":  utils.Message(c, utils.ERROR_INVALID_PARAM) + ": " + err.Error(),
 		})
 		return
 	}
@@ -45,7 +46,7 @@ func (e *EmailApi) SendEmailView(c *gin.Context) {
 	if !global.Config.Email.Verify() {
 		c.JSON(400, gin.H{
 			"code": utils.ERROR_EMAIL_CONFIG,
-			"msg":  utils.GetErrMsg(utils.ERROR_EMAIL_CONFIG),
+			"msg":  utils.Message(c, utils.ERROR_EMAIL_CONFIG),
 		})
 		return
 	}
@@ -64,7 +65,7 @@ func (e *EmailApi) SendEmailView(c *gin.Context) {
 		if !captcha.CaptchaStore.Verify(req.CaptchaID, req.CaptchaCode, true) {
 			c.JSON(400, gin.H{
 				"code": utils.ERROR_CAPTCHA_WRONG,
-				"msg":  utils.GetErrMsg(utils.ERROR_CAPTCHA_WRONG),
+				"msg":  utils.Message(c, utils.ERROR_CAPTCHA_WRONG),
 			})
 			return
 		}
@@ -85,16 +86,16 @@ func (e *EmailApi) SendEmailView(c *gin.Context) {
 	// 发送邮件
 	err := email.SendEmail(req.Email, "用户注册", content)
 	if err != nil {
-		global.Logger.Error("发送验证码邮件失败: " + err.Error())
+		reqlog.Entry(c).Error("发送验证码邮件失败: " + err.Error())
 		c.JSON(500, gin.H{
 			"code": utils.ERROR_EMAIL_SEND,
-			"msg":  utils.GetErrMsg(utils.ERROR_EMAIL_SEND),
+			"msg":  utils.Message(c, utils.ERROR_EMAIL_SEND),
 		})
 		return
 	}
 
 	// 记录日志
-	global.Logger.Info("验证码邮件发送成功: " + req.Email)
+	reqlog.Entry(c).Info("验证码邮件发送成功: " + req.Email)
 
 	// 返回成功响应
 	c.JSON(200, gin.H{
@@ -3,25 +3,37 @@ package api
 import (
 	"rbac_admin_server/api/dept_api"
 	"rbac_admin_server/api/file_api"
+	"rbac_admin_server/api/group_api"
+	"rbac_admin_server/api/i18n_api"
 	"rbac_admin_server/api/log_api"
 	"rbac_admin_server/api/menu_api"
 	"rbac_admin_server/api/permission_api"
+	"rbac_admin_server/api/position_api"
 	"rbac_admin_server/api/profile_api"
+	"rbac_admin_server/api/rbac_api"
+	"rbac_admin_server/api/recycle_api"
+	"rbac_admin_server/api/registration_api"
 	"rbac_admin_server/api/role_api"
 	"rbac_admin_server/api/user_api"
 )
 
 // Api 全局API实例，提供所有API接口的访问入口
 type Api struct {
-	UserApi       *user_api.UserApi
-	RoleApi       *role_api.RoleApi
-	PermissionApi *permission_api.PermissionApi
-	DeptApi       *dept_api.DepartmentApi
-	MenuApi       *menu_api.MenuApi
-	FileApi       *file_api.FileApi
-	LogApi        *log_api.LogApi
-	ProfileApi    *profile_api.ProfileApi
-	HealthApi     *HealthApi
+	UserApi         *user_api.UserApi
+	RoleApi         *role_api.RoleApi
+	PermissionApi   *permission_api.PermissionApi
+	DeptApi         *dept_api.DepartmentApi
+	PositionApi     *position_api.PositionApi
+	GroupApi        *group_api.GroupApi
+	MenuApi         *menu_api.MenuApi
+	FileApi         *file_api.FileApi
+	LogApi          *log_api.LogApi
+	ProfileApi      *profile_api.ProfileApi
+	RbacApi         *rbac_api.RbacApi
+	RecycleApi      *recycle_api.RecycleApi
+	RegistrationApi *registration_api.RegistrationApi
+	I18nApi         *i18n_api.I18nApi
+	HealthApi       *HealthApi
 }
 
 // App 全局API实例，供外部调用
@@ -33,9 +45,15 @@ func InitApi() {
 	App.RoleApi = role_api.NewRoleApi()
 	App.PermissionApi = permission_api.NewPermissionApi()
 	App.DeptApi = dept_api.NewDepartmentApi()
+	App.PositionApi = position_api.NewPositionApi()
+	App.GroupApi = group_api.NewGroupApi()
 	App.MenuApi = menu_api.NewMenuApi()
 	App.FileApi = file_api.NewFileApi()
 	App.LogApi = log_api.NewLogApi()
 	App.ProfileApi = profile_api.NewProfileApi()
+	App.RbacApi = rbac_api.NewRbacApi()
+	App.RecycleApi = recycle_api.NewRecycleApi()
+	App.RegistrationApi = registration_api.NewRegistrationApi()
+	App.I18nApi = i18n_api.NewI18nApi()
 	App.HealthApi = NewHealthApi()
-}
\ No newline at end of file
+}
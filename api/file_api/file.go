@@ -12,6 +12,9 @@ import (
 
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
 )
 
 // UploadFile 上传单个文件
@@ -30,7 +33,7 @@ func (f *FileApi) UploadFile(c *gin.Context) {
 	// 单个文件
 	file, _ := c.FormFile("file")
 	if file == nil {
-		global.Logger.Error("上传文件失败: 文件为空")
+		reqlog.Entry(c).Error("上传文件失败: 文件为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "文件不能为空"})
 		return
 	}
@@ -39,7 +42,7 @@ func (f *FileApi) UploadFile(c *gin.Context) {
 	uploadDir := "uploads/"
 	dir := uploadDir + time.Now().Format("2006-01-02") + "/"
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		global.Logger.Error("创建上传目录失败: " + err.Error())
+		reqlog.Entry(c).Error("创建上传目录失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建目录失败"})
 		return
 	}
@@ -51,7 +54,7 @@ func (f *FileApi) UploadFile(c *gin.Context) {
 
 	// 保存文件
 	if err := c.SaveUploadedFile(file, dst); err != nil {
-		global.Logger.Error("保存文件失败: " + err.Error())
+		reqlog.Entry(c).Error("保存文件失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "保存文件失败"})
 		return
 	}
@@ -65,13 +68,13 @@ func (f *FileApi) UploadFile(c *gin.Context) {
 		Extension: ext,
 	}
 
-	if err := global.DB.Create(&fileModel).Error; err != nil {
-		global.Logger.Error("保存文件信息失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Create(&fileModel).Error; err != nil {
+		reqlog.Entry(c).Error("保存文件信息失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "保存文件信息失败"})
 		return
 	}
 
-	global.Logger.Infof("文件上传成功: %s", file.Filename)
+	reqlog.Entry(c).Infof("文件上传成功: %s", file.Filename)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "上传成功",
@@ -96,7 +99,7 @@ func (f *FileApi) UploadMultipleFiles(c *gin.Context) {
 	form, _ := c.MultipartForm()
 	files := form.File["files"]
 	if len(files) == 0 {
-		global.Logger.Error("上传文件失败: 文件为空")
+		reqlog.Entry(c).Error("上传文件失败: 文件为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "文件不能为空"})
 		return
 	}
@@ -105,7 +108,7 @@ func (f *FileApi) UploadMultipleFiles(c *gin.Context) {
 	uploadDir := "uploads/"
 	dir := uploadDir + time.Now().Format("2006-01-02") + "/"
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		global.Logger.Error("创建上传目录失败: " + err.Error())
+		reqlog.Entry(c).Error("创建上传目录失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建目录失败"})
 		return
 	}
@@ -120,7 +123,7 @@ func (f *FileApi) UploadMultipleFiles(c *gin.Context) {
 
 		// 保存文件
 		if err := c.SaveUploadedFile(file, dst); err != nil {
-			global.Logger.Error("保存文件失败: " + err.Error())
+			reqlog.Entry(c).Error("保存文件失败: " + err.Error())
 			c.JSON(500, gin.H{"code": 500, "msg": "保存文件失败"})
 			return
 		}
@@ -137,13 +140,13 @@ func (f *FileApi) UploadMultipleFiles(c *gin.Context) {
 	}
 
 	// 批量保存文件信息
-	if err := global.DB.CreateInBatches(fileModels, len(fileModels)).Error; err != nil {
-		global.Logger.Error("保存文件信息失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).CreateInBatches(fileModels, len(fileModels)).Error; err != nil {
+		reqlog.Entry(c).Error("保存文件信息失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "保存文件信息失败"})
 		return
 	}
 
-	global.Logger.Infof("多文件上传成功: 共%d个文件", len(files))
+	reqlog.Entry(c).Infof("多文件上传成功: 共%d个文件", len(files))
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  fmt.Sprintf("上传成功，共%d个文件", len(files)),
@@ -167,68 +170,77 @@ func (f *FileApi) DownloadFile(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		global.Logger.Error("下载文件失败: ID格式错误")
+		reqlog.Entry(c).Error("下载文件失败: ID格式错误")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 查询文件信息
 	var fileModel models.File
-	if err := global.DB.First(&fileModel, id).Error; err != nil {
-		global.Logger.Error("下载文件失败: 文件不存在")
+	if err := global.DB.WithContext(c.Request.Context()).First(&fileModel, id).Error; err != nil {
+		reqlog.Entry(c).Error("下载文件失败: 文件不存在")
 		c.JSON(404, gin.H{"code": 404, "msg": "文件不存在"})
 		return
 	}
 
 	// 检查文件是否存在
 	if _, err := os.Stat(fileModel.Path); os.IsNotExist(err) {
-		global.Logger.Error("下载文件失败: 文件不存在")
+		reqlog.Entry(c).Error("下载文件失败: 文件不存在")
 		c.JSON(404, gin.H{"code": 404, "msg": "文件不存在"})
 		return
 	}
 
-	global.Logger.Infof("文件下载成功: %s", fileModel.Name)
+	reqlog.Entry(c).Infof("文件下载成功: %s", fileModel.Name)
 	c.File(fileModel.Path)
 }
 
+// fileListSpec 文件列表允许的筛选和排序字段
+var fileListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"name":        {Column: "name", Op: search.OpLike},
+		"type":        {Column: "type", Op: search.OpEq},
+		"mime_type":   {Column: "mime_type", Op: search.OpIn},
+		"extension":   {Column: "extension", Op: search.OpIn},
+		"category":    {Column: "category", Op: search.OpEq},
+		"uploaded_by": {Column: "uploaded_by", Op: search.OpEq},
+		"size":        {Column: "size", Op: search.OpRange},
+		"created_at":  {Column: "created_at", Op: search.OpDateRange},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"size":       "size",
+		"created_at": "created_at",
+	},
+	DefaultSort: "created_at DESC",
+}
+
 // GetFileList 获取文件列表
 // @Summary 获取文件列表接口
-// @Description 查询系统中的文件列表
+// @Description 分页查询系统中的文件列表，支持筛选和排序
 // @Tags 文件管理
 // @Accept json
 // @Produce json
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
 // @Param type query string false "文件类型"
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"list":[]models.File, "total":int}}
+// @Param created_at query string false "上传日期区间，如 2024-01-01,2024-01-31"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/file/list [get]
 func (f *FileApi) GetFileList(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-	fileType := c.Query("type")
-
-	pageInt, _ := strconv.Atoi(page)
-	pageSizeInt, _ := strconv.Atoi(pageSize)
-
-	// 构建查询条件
-	query := global.DB.Model(&models.File{})
-	if fileType != "" {
-		query = query.Where("type = ?", fileType)
+	q, err := search.Parse(c, fileListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
 	}
 
-	// 查询总数
-	var total int64
-	query.Count(&total)
-
-	// 查询列表
 	var files []models.File
-	if err := query.
-		Order("created_at DESC").
-		Offset((pageInt - 1) * pageSizeInt).
-		Limit(pageSizeInt).
-		Find(&files).Error; err != nil {
-		global.Logger.Error("获取文件列表失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.File{}), &files)
+	if err != nil {
+		reqlog.Entry(c).Error("获取文件列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取文件列表失败"})
 		return
 	}
@@ -236,16 +248,13 @@ func (f *FileApi) GetFileList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": gin.H{
-			"list":  files,
-			"total": total,
-		},
+		"data": page,
 	})
 }
 
 // DeleteFile 删除文件
 // @Summary 删除文件接口
-// @Description 根据文件ID删除文件
+// @Description 根据文件ID删除文件，文件移入回收站，彻底删除时才移除物理文件
 // @Tags 文件管理
 // @Accept json
 // @Produce json
@@ -259,33 +268,27 @@ func (f *FileApi) DeleteFile(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		global.Logger.Error("删除文件失败: ID格式错误")
+		reqlog.Entry(c).Error("删除文件失败: ID格式错误")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 查询文件信息
 	var fileModel models.File
-	if err := global.DB.First(&fileModel, id).Error; err != nil {
-		global.Logger.Error("删除文件失败: 文件不存在")
+	if err := global.DB.WithContext(c.Request.Context()).First(&fileModel, id).Error; err != nil {
+		reqlog.Entry(c).Error("删除文件失败: 文件不存在")
 		c.JSON(404, gin.H{"code": 404, "msg": "文件不存在"})
 		return
 	}
 
-	// 删除物理文件
-	if err := os.Remove(fileModel.Path); err != nil {
-		global.Logger.Error("删除物理文件失败: " + err.Error())
-		// 继续执行，删除数据库记录
-	}
-
-	// 删除数据库记录
-	if err := global.DB.Delete(&fileModel).Error; err != nil {
-		global.Logger.Error("删除文件记录失败: " + err.Error())
+	// 移入回收站，物理文件在彻底删除时才移除
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "file", fileModel.ID); err != nil {
+		reqlog.Entry(c).Error("删除文件记录失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
 		return
 	}
 
-	global.Logger.Infof("文件删除成功: %s", fileModel.Name)
+	reqlog.Entry(c).Infof("文件已移入回收站: %s", fileModel.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
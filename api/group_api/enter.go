@@ -0,0 +1,29 @@
+package group_api
+
+import "github.com/gin-gonic/gin"
+
+// GroupApi 用户组API结构体
+type GroupApi struct{}
+
+// NewGroupApi 创建用户组API实例
+func NewGroupApi() *GroupApi {
+	return &GroupApi{}
+}
+
+// RegisterRoutes 注册用户组API路由
+func (g *GroupApi) RegisterRoutes(router *gin.RouterGroup) {
+	groupRouter := router.Group("/group")
+	{
+		groupRouter.GET("/list", g.GetGroupList)
+		groupRouter.GET("/tree", g.GetGroupTree)
+		groupRouter.POST("/create", g.CreateGroup)
+		groupRouter.PUT("/update", g.UpdateGroup)
+		groupRouter.DELETE("/delete", g.DeleteGroup)
+		groupRouter.GET("/members", g.GetGroupMembers)
+		groupRouter.POST("/add-members", g.AddGroupMembers)
+		groupRouter.POST("/remove-members", g.RemoveGroupMembers)
+		groupRouter.GET("/roles", g.GetGroupRoles)
+		groupRouter.POST("/set-roles", g.SetGroupRoles)
+		groupRouter.GET("/effective-roles", g.GetEffectiveRoles)
+	}
+}
@@ -0,0 +1,70 @@
+package i18n_api
+
+import (
+	"errors"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/i18n"
+	"rbac_admin_server/utils/reqlog"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DictItem 翻译后的字典项
+type DictItem struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// GetDictItems 获取翻译后的字典项
+// @Summary 获取翻译后的字典项接口
+// @Description 返回启用字典中启用的字典项，标签按当前用户的语言翻译，供前端下拉框和状态显示使用
+// @Tags 翻译管理
+// @Accept json
+// @Produce json
+// @Param key query string true "字典标识"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"name":string, "items":[]DictItem}}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/i18n/dict-items [get]
+func (i *I18nApi) GetDictItems(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var dict models.Dict
+	err := db.Where("dicts.key = ? AND status = 1", key).First(&dict).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"code": 404, "msg": "字典不存在"})
+		return
+	}
+	var items []models.DictItem
+	if err == nil {
+		err = db.Where("dict_id = ? AND status = 1", dict.ID).Order("sort, id").Find(&items).Error
+	}
+	if err != nil {
+		reqlog.Entry(c).Error("获取字典项失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	locale := i18n.Locale(c)
+	list := make([]DictItem, len(items))
+	for n, item := range items {
+		list[n] = DictItem{Value: item.Value, Label: i18n.DictLabel(locale, dict.Key, item.Value, item.Label)}
+	}
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"name":  i18n.T(locale, i18n.NamespaceDict, dict.Key, dict.Name),
+			"items": list,
+		},
+	})
+}
@@ -0,0 +1,24 @@
+package i18n_api
+
+import "github.com/gin-gonic/gin"
+
+// I18nApi 翻译管理API结构体
+type I18nApi struct{}
+
+// NewI18nApi 创建翻译管理API实例
+func NewI18nApi() *I18nApi {
+	return &I18nApi{}
+}
+
+// RegisterRoutes 注册翻译管理API路由
+func (i *I18nApi) RegisterRoutes(router *gin.RouterGroup) {
+	i18nRouter := router.Group("/i18n")
+	{
+		i18nRouter.GET("/locales", i.GetLocales)
+		i18nRouter.GET("/list", i.GetTranslationList)
+		i18nRouter.POST("/create", i.CreateTranslation)
+		i18nRouter.PUT("/update", i.UpdateTranslation)
+		i18nRouter.DELETE("/delete", i.DeleteTranslation)
+		i18nRouter.GET("/dict-items", i.GetDictItems)
+	}
+}
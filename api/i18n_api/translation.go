@@ -0,0 +1,264 @@
+package i18n_api
+
+import (
+	"strconv"
+	"strings"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/i18n"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
+	"rbac_admin_server/utils/usersettings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// translationListSpec 翻译列表允许的筛选和排序字段
+var translationListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"locale":    {Column: "locale", Op: search.OpEq},
+		"namespace": {Column: "namespace", Op: search.OpIn},
+		"key":       {Column: "translations.key", Op: search.OpLike},
+		"value":     {Column: "value", Op: search.OpLike},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"key":        "translations.key",
+		"updated_at": "updated_at",
+	},
+	DefaultSort: "namespace ASC, translations.key ASC, locale ASC",
+}
+
+// GetLocales 获取支持的语言和命名空间
+// @Summary 获取支持的语言和命名空间接口
+// @Description 返回可以添加翻译的语言、命名空间和当前请求使用的语言
+// @Tags 翻译管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H}
+// @Router /admin/i18n/locales [get]
+func (i *I18nApi) GetLocales(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"locales":    usersettings.Languages,
+			"namespaces": i18n.Namespaces,
+			"current":    i18n.Locale(c),
+		},
+	})
+}
+
+// GetTranslationList 获取翻译列表
+// @Summary 获取翻译列表接口
+// @Description 分页查询翻译，只包含添加到翻译表的翻译，不包含内置翻译
+// @Tags 翻译管理
+// @Accept json
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param locale query string false "语言"
+// @Param namespace query string false "命名空间，多个用逗号分隔"
+// @Param key query string false "键(模糊)"
+// @Param value query string false "译文(模糊)"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/i18n/list [get]
+func (i *I18nApi) GetTranslationList(c *gin.Context) {
+	q, err := search.Parse(c, translationListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	var translations []models.Translation
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Translation{}), &translations)
+	if err != nil {
+		reqlog.Entry(c).Error("获取翻译列表失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取翻译列表失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": page,
+	})
+}
+
+// CreateTranslation 创建翻译
+// @Summary 创建翻译接口
+// @Description 添加翻译，同一语言、命名空间和键只能有一条，保存后立即生效
+// @Tags 翻译管理
+// @Accept json
+// @Produce json
+// @Param translation body models.Translation true "翻译信息"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/i18n/create [post]
+func (i *I18nApi) CreateTranslation(c *gin.Context) {
+	var translation models.Translation
+	if err := c.ShouldBindJSON(&translation); err != nil {
+		reqlog.Entry(c).Error("创建翻译参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	translation.ID = 0
+	db := global.DB.WithContext(c.Request.Context())
+	if msg := checkTranslation(db, &translation); msg != "" {
+		c.JSON(400, gin.H{"code": 400, "msg": msg})
+		return
+	}
+
+	if err := db.Create(&translation).Error; err != nil {
+		reqlog.Entry(c).Error("创建翻译失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
+		return
+	}
+	reload(c)
+
+	reqlog.Entry(c).Infof("管理员创建翻译成功: %s/%s/%s", translation.Locale, translation.Namespace, translation.Key)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": translation,
+	})
+}
+
+// UpdateTranslation 更新翻译
+// @Summary 更新翻译接口
+// @Description 修改翻译，保存后立即生效
+// @Tags 翻译管理
+// @Accept json
+// @Produce json
+// @Param translation body models.Translation true "更新的翻译信息"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/i18n/update [put]
+func (i *I18nApi) UpdateTranslation(c *gin.Context) {
+	var translation models.Translation
+	if err := c.ShouldBindJSON(&translation); err != nil {
+		reqlog.Entry(c).Error("更新翻译参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	if translation.ID == 0 {
+		reqlog.Entry(c).Error("更新翻译参数错误: ID为空")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var before models.Translation
+	if err := db.First(&before, translation.ID).Error; err != nil {
+		c.JSON(404, gin.H{"code": 404, "msg": "翻译不存在"})
+		return
+	}
+	if msg := checkTranslation(db, &translation); msg != "" {
+		c.JSON(400, gin.H{"code": 400, "msg": msg})
+		return
+	}
+
+	if err := db.Omit("created_at").Save(&translation).Error; err != nil {
+		reqlog.Entry(c).Error("更新翻译失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
+		return
+	}
+	reload(c)
+	audit.RecordChange(c, "i18n", "update", translation.ID, before, translation)
+
+	reqlog.Entry(c).Infof("管理员更新翻译成功: %s/%s/%s", translation.Locale, translation.Namespace, translation.Key)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+	})
+}
+
+// DeleteTranslation 删除翻译
+// @Summary 删除翻译接口
+// @Description 删除翻译，之后恢复使用内置翻译或源文本
+// @Tags 翻译管理
+// @Accept json
+// @Produce json
+// @Param id query int true "翻译ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/i18n/delete [delete]
+func (i *I18nApi) DeleteTranslation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除翻译参数错误: ID无效")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var before models.Translation
+	if err := db.First(&before, id).Error; err != nil {
+		c.JSON(404, gin.H{"code": 404, "msg": "翻译不存在"})
+		return
+	}
+	if err := db.Delete(&models.Translation{}, id).Error; err != nil {
+		reqlog.Entry(c).Error("删除翻译失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
+		return
+	}
+	reload(c)
+	audit.RecordChange(c, "i18n", "delete", before.ID, before, nil)
+
+	reqlog.Entry(c).Infof("管理员删除翻译成功: ID=%d", id)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// checkTranslation 校验语言、命名空间和键，去掉首尾空白，通过时返回空字符串
+func checkTranslation(db *gorm.DB, t *models.Translation) string {
+	t.Locale = strings.TrimSpace(t.Locale)
+	t.Namespace = strings.TrimSpace(t.Namespace)
+	t.Key = strings.TrimSpace(t.Key)
+	if !i18n.Supported(t.Locale) {
+		return "不支持的语言: " + t.Locale
+	}
+	valid := false
+	for _, ns := range i18n.Namespaces {
+		valid = valid || ns == t.Namespace
+	}
+	if !valid {
+		return "命名空间只能是 " + strings.Join(i18n.Namespaces, ", ")
+	}
+	if t.Key == "" || len(t.Key) > 128 {
+		return "键不能为空且不能超过128个字符"
+	}
+	if t.Value == "" || len(t.Value) > 512 {
+		return "译文不能为空且不能超过512个字符"
+	}
+
+	var count int64
+	db.Model(&models.Translation{}).Where("locale = ? AND namespace = ? AND translations.key = ? AND id <> ?",
+		t.Locale, t.Namespace, t.Key, t.ID).Count(&count)
+	if count > 0 {
+		return "该翻译已存在"
+	}
+	return ""
+}
+
+// reload 翻译修改后重新加载翻译表，失败时等待定时重新加载
+func reload(c *gin.Context) {
+	if err := i18n.Reload(global.DB.WithContext(c.Request.Context())); err != nil {
+		reqlog.Entry(c).Warn("重新加载翻译失败: " + err.Error())
+	}
+}
@@ -11,13 +11,15 @@ func NewLogApi() *LogApi {
 }
 
 // RegisterRoutes 注册日志API路由
+// 审计日志只追加，不提供删除接口，过期日志由保留策略清理
 func (l *LogApi) RegisterRoutes(router *gin.RouterGroup) {
 	logRouter := router.Group("/log")
 	{
 		logRouter.GET("/list", l.GetLogList)
 		logRouter.GET("/user-logs", l.GetUserLogs)
-		logRouter.DELETE("/delete", l.DeleteLog)
-		logRouter.DELETE("/delete-multiple", l.DeleteMultipleLogs)
 		logRouter.GET("/dashboard", l.GetLogDashboard)
+		logRouter.GET("/archive-list", l.GetArchiveLogList)
+		logRouter.GET("/export", l.ExportLogs)
+		logRouter.GET("/login-export", l.ExportLoginLogs)
 	}
 }
\ No newline at end of file
@@ -1,60 +1,53 @@
 package log_api
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
+	"rbac_admin_server/utils/logstat"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
 )
 
 // GetLogList 获取日志列表
 // @Summary 获取日志列表接口
-// @Description 查询系统中的日志列表
+// @Description 分页查询系统中的日志列表，支持筛选和排序
 // @Tags 日志管理
 // @Accept json
 // @Produce json
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
-// @Param level query string false "日志级别"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param type query string false "日志类型"
 // @Param start_time query string false "开始时间"
 // @Param end_time query string false "结束时间"
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"list":[]models.Log, "total":int}}
+// @Param user_id query int false "用户ID"
+// @Param username query string false "用户名"
+// @Param module query string false "模块"
+// @Param status_code query string false "状态码，多个用逗号分隔"
+// @Param ip query string false "IP地址"
+// @Param request_id query string false "请求ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/log/list [get]
 func (l *LogApi) GetLogList(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-	level := c.Query("level")
-	startTime := c.Query("start_time")
-	endTime := c.Query("end_time")
-
-	pageInt, _ := strconv.Atoi(page)
-	pageSizeInt, _ := strconv.Atoi(pageSize)
-
-	// 构建查询条件
-	query := global.DB.Model(&models.Log{})
-	if level != "" {
-		query = query.Where("level = ?", level)
-	}
-	if startTime != "" && endTime != "" {
-		query = query.Where("created_at BETWEEN ? AND ?", startTime, endTime)
+	q, err := search.Parse(c, logListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
 	}
 
-	// 查询总数
-	var total int64
-	query.Count(&total)
-
-	// 查询列表
 	var logs []models.Log
-	if err := query.
-		Order("created_at DESC").
-		Offset((pageInt - 1) * pageSizeInt).
-		Limit(pageSizeInt).
-		Find(&logs).Error; err != nil {
-		global.Logger.Error("获取日志列表失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Log{}), &logs)
+	if err != nil {
+		reqlog.Entry(c).Error("获取日志列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取日志列表失败"})
 		return
 	}
@@ -62,55 +55,88 @@ func (l *LogApi) GetLogList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": gin.H{
-			"list":  logs,
-			"total": total,
-		},
+		"data": page,
 	})
 }
 
+// logListSpec 日志列表和导出共用的筛选和排序字段
+var logListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"type":         {Column: "type", Op: search.OpEq},
+		"start_time":   {Column: "created_at", Op: search.OpGte},
+		"end_time":     {Column: "created_at", Op: search.OpLte},
+		"created_at":   {Column: "created_at", Op: search.OpDateRange},
+		"user_id":      {Column: "user_id", Op: search.OpEq},
+		"on_behalf_of": {Column: "on_behalf_of", Op: search.OpEq},
+		"username":     {Column: "username", Op: search.OpLike},
+		"module":       {Column: "module", Op: search.OpEq},
+		"action":       {Column: "action", Op: search.OpEq},
+		"method":       {Column: "method", Op: search.OpIn},
+		"status_code":  {Column: "status_code", Op: search.OpIn},
+		"latency":      {Column: "latency", Op: search.OpRange},
+		"ip":           {Column: "ip", Op: search.OpEq},
+		"request_id":   {Column: "request_id", Op: search.OpEq},
+	},
+	Sorts: map[string]string{
+		"id":          "id",
+		"created_at":  "created_at",
+		"latency":     "latency",
+		"status_code": "status_code",
+	},
+	DefaultSort: "created_at DESC, id DESC",
+}
+
+// archiveListSpec 归档日志列表允许的筛选和排序字段
+var archiveListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"type":         {Column: "type", Op: search.OpEq},
+		"archive_file": {Column: "archive_file", Op: search.OpEq},
+		"start_time":   {Column: "created_at", Op: search.OpGte},
+		"end_time":     {Column: "created_at", Op: search.OpLte},
+		"user_id":      {Column: "user_id", Op: search.OpEq},
+		"username":     {Column: "username", Op: search.OpLike},
+		"module":       {Column: "module", Op: search.OpEq},
+		"ip":           {Column: "ip", Op: search.OpEq},
+	},
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"seq":        "seq",
+	},
+	DefaultSort: "created_at DESC",
+}
+
 // GetUserLogs 获取用户日志
 // @Summary 获取用户日志接口
-// @Description 查询指定用户的操作日志
+// @Description 分页查询指定用户的操作日志
 // @Tags 日志管理
 // @Accept json
 // @Produce json
 // @Param user_id query int true "用户ID"
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"list":[]models.Log, "total":int}}
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/log/user-logs [get]
 func (l *LogApi) GetUserLogs(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
-		global.Logger.Error("获取用户日志失败: 用户ID为空")
+		reqlog.Entry(c).Error("获取用户日志失败: 用户ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
-
-	pageInt, _ := strconv.Atoi(page)
-	pageSizeInt, _ := strconv.Atoi(pageSize)
-
-	// 构建查询条件
-	query := global.DB.Model(&models.Log{}).Where("user_id = ?", userID)
-
-	// 查询总数
-	var total int64
-	query.Count(&total)
+	q, err := search.Parse(c, logListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
 
-	// 查询列表
 	var logs []models.Log
-	if err := query.
-		Order("created_at DESC").
-		Offset((pageInt - 1) * pageSizeInt).
-		Limit(pageSizeInt).
-		Find(&logs).Error; err != nil {
-		global.Logger.Error("获取用户日志失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Log{}).Where("user_id = ?", userID), &logs)
+	if err != nil {
+		reqlog.Entry(c).Error("获取用户日志失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取用户日志失败"})
 		return
 	}
@@ -118,137 +144,95 @@ func (l *LogApi) GetUserLogs(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": gin.H{
-			"list":  logs,
-			"total": total,
-		},
+		"data": page,
 	})
 }
 
-// DeleteLog 删除日志
-// @Summary 删除日志接口
-// @Description 根据日志ID删除日志
+// GetArchiveLogList 获取归档日志列表
+// @Summary 获取归档日志列表接口
+// @Description 查询通过命令行从归档文件恢复的日志
 // @Tags 日志管理
 // @Accept json
 // @Produce json
-// @Param id query int true "日志ID"
-// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param type query string false "日志类型"
+// @Param archive_file query string false "归档文件名"
+// @Param start_time query string false "开始时间"
+// @Param end_time query string false "结束时间"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
-// @Router /admin/log/delete [delete]
-func (l *LogApi) DeleteLog(c *gin.Context) {
-	id := c.Query("id")
-	if id == "" {
-		global.Logger.Error("删除日志失败: ID为空")
-		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+// @Router /admin/log/archive-list [get]
+func (l *LogApi) GetArchiveLogList(c *gin.Context) {
+	q, err := search.Parse(c, archiveListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
 		return
 	}
 
-	if err := global.DB.Delete(&models.Log{}, id).Error; err != nil {
-		global.Logger.Error("删除日志失败: " + err.Error())
-		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
+	var logs []models.LogArchive
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.LogArchive{}), &logs)
+	if err != nil {
+		reqlog.Entry(c).Error("获取归档日志列表失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取归档日志列表失败"})
 		return
 	}
 
-	global.Logger.Infof("删除日志成功: ID=%s", id)
 	c.JSON(200, gin.H{
 		"code": 200,
-		"msg":  "删除成功",
+		"msg":  "获取成功",
+		"data": page,
 	})
 }
 
-// DeleteMultipleLogs 批量删除日志
-// @Summary 批量删除日志接口
-// @Description 批量删除指定的日志
+// GetLogDashboard 获取日志仪表盘数据
+// @Summary 获取日志仪表盘数据接口
+// @Description 统计指定时间窗口内的请求量、错误率、耗时分位数、排行榜和登录失败情况
 // @Tags 日志管理
 // @Accept json
 // @Produce json
-// @Param ids body []int true "日志ID列表"
-// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Param window query string false "统计窗口，如24h、7d，默认7d，最长90d"
+// @Param interval query string false "时间粒度: hour, day"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":logstat.Dashboard}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
-// @Router /admin/log/delete-multiple [delete]
-func (l *LogApi) DeleteMultipleLogs(c *gin.Context) {
-	var req struct {
-		Ids []int `json:"ids" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		global.Logger.Error("批量删除日志参数错误: " + err.Error())
-		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+// @Router /admin/log/dashboard [get]
+func (l *LogApi) GetLogDashboard(c *gin.Context) {
+	window, err := parseWindow(c.DefaultQuery("window", "7d"))
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
 		return
 	}
 
-	if err := global.DB.Delete(&models.Log{}, req.Ids).Error; err != nil {
-		global.Logger.Error("批量删除日志失败: " + err.Error())
-		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
+	data, err := logstat.Get(window, c.Query("interval"))
+	if err != nil {
+		reqlog.Entry(c).Error("获取日志统计失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取日志统计失败"})
 		return
 	}
 
-	global.Logger.Infof("批量删除日志成功: 共%d条", len(req.Ids))
 	c.JSON(200, gin.H{
 		"code": 200,
-		"msg":  "删除成功",
+		"msg":  "获取成功",
+		"data": data,
 	})
 }
 
-// GetLogDashboard 获取日志仪表盘数据
-// @Summary 获取日志仪表盘数据接口
-// @Description 获取日志统计数据
-// @Tags 日志管理
-// @Accept json
-// @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"total":int, "levels":map[string]int, "recent":[]gin.H}}
-// @Failure 500 {object} gin.H{"code":int, "msg":string}
-// @Router /admin/log/dashboard [get]
-func (l *LogApi) GetLogDashboard(c *gin.Context) {
-	// 获取总日志数
-	var total int64
-	global.DB.Model(&models.Log{}).Count(&total)
-
-	// 获取各级别日志数量
-	var levelStats []struct {
-		Level string `json:"level"`
-		Count int64  `json:"count"`
-	}
-	global.DB.Model(&models.Log{}).
-		Select("level, count(*) as count").
-		Group("level").
-		Scan(&levelStats)
-
-	levelMap := make(map[string]int64)
-	for _, stat := range levelStats {
-		levelMap[stat.Level] = stat.Count
-	}
+// maxDashboardWindow 仪表盘允许的最大统计窗口
+const maxDashboardWindow = 90 * 24 * time.Hour
 
-	// 获取最近7天的日志数量
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	var dailyStats []struct {
-		Date  string `json:"date"`
-		Count int64  `json:"count"`
+// parseWindow 解析统计窗口，支持以d结尾的天数和Go时长格式
+func parseWindow(value string) (time.Duration, error) {
+	var window time.Duration
+	if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && strings.HasSuffix(value, "d") {
+		window = time.Duration(days) * 24 * time.Hour
+	} else if window, err = time.ParseDuration(value); err != nil {
+		return 0, errors.New("统计窗口格式错误")
 	}
-	global.DB.Model(&models.Log{}).
-		Select("date(created_at) as date, count(*) as count").
-		Where("created_at >= ?", sevenDaysAgo).
-		Group("date").
-		Order("date").
-		Scan(&dailyStats)
-
-	// 获取最近的10条日志
-	var recentLogs []models.Log
-	global.DB.Model(&models.Log{}).
-		Order("created_at DESC").
-		Limit(10).
-		Find(&recentLogs)
-
-	c.JSON(200, gin.H{
-		"code": 200,
-		"msg":  "获取成功",
-		"data": gin.H{
-			"total":  total,
-			"levels": levelMap,
-			"daily":  dailyStats,
-			"recent": recentLogs,
-		},
-	})
-}
\ No newline at end of file
+	if window <= 0 || window > maxDashboardWindow {
+		return 0, errors.New("统计窗口超出范围")
+	}
+	return window, nil
+}
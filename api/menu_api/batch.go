@@ -0,0 +1,76 @@
+package menu_api
+
+import (
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/treeedit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// menuTree 菜单树，按钮不能有下级且只能属于菜单
+var menuTree = &treeedit.Tree{
+	Label:  "菜单",
+	Entity: "menu",
+	Model:  func() interface{} { return &models.Menu{} },
+	Load: func(db *gorm.DB) ([]treeedit.Node, error) {
+		var menus []models.Menu
+		if err := db.Select("id", "parent_id", "type").Find(&menus).Error; err != nil {
+			return nil, err
+		}
+		nodes := make([]treeedit.Node, len(menus))
+		for i, menu := range menus {
+			nodes[i] = treeedit.Node{ID: menu.ID, ParentID: menu.ParentID, Type: menu.Type}
+			if menu.Type == models.MenuTypeButton {
+				nodes[i].Leaf, nodes[i].NeedParent = true, true
+				nodes[i].ParentTypes = []string{models.MenuTypeMenu}
+			}
+		}
+		return nodes, nil
+	},
+}
+
+// SortMenus 批量调整菜单位置
+// @Summary 批量调整菜单位置接口
+// @Description 提交拖拽后的菜单树或移动操作列表，校验无循环且按钮位于菜单下后在一个事务中更新上级和排序
+// @Tags 菜单管理
+// @Accept json
+// @Produce json
+// @Param data body treeedit.SortRequest true "菜单树或移动操作"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/menu/sort [put]
+func (m *MenuApi) SortMenus(c *gin.Context) {
+	menuTree.HandleSort(c)
+}
+
+// BatchMenuStatus 批量启用禁用菜单
+// @Summary 批量启用禁用菜单接口
+// @Description 设置菜单及其全部下级菜单和按钮的状态
+// @Tags 菜单管理
+// @Accept json
+// @Produce json
+// @Param data body treeedit.StatusRequest true "菜单ID和状态"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]int}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/menu/batch-status [put]
+func (m *MenuApi) BatchMenuStatus(c *gin.Context) {
+	menuTree.HandleStatus(c)
+}
+
+// BatchDeleteMenus 批量删除菜单
+// @Summary 批量删除菜单接口
+// @Description 将菜单及其全部下级菜单和按钮移入回收站，恢复时需要先恢复上级
+// @Tags 菜单管理
+// @Accept json
+// @Produce json
+// @Param data body treeedit.DeleteRequest true "菜单ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]int}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/menu/batch-delete [post]
+func (m *MenuApi) BatchDeleteMenus(c *gin.Context) {
+	menuTree.HandleDelete(c)
+}
@@ -19,6 +19,9 @@ func (m *MenuApi) RegisterRoutes(router *gin.RouterGroup) {
 		menuRouter.PUT("/update", m.UpdateMenu)
 		menuRouter.DELETE("/delete", m.DeleteMenu)
 		menuRouter.GET("/tree", m.GetMenuTree)
+		menuRouter.PUT("/sort", m.SortMenus)
+		menuRouter.PUT("/batch-status", m.BatchMenuStatus)
+		menuRouter.POST("/batch-delete", m.BatchDeleteMenus)
 		menuRouter.GET("/user-menus", m.GetUserMenus)
 	}
 }
\ No newline at end of file
@@ -1,25 +1,63 @@
 package menu_api
 
 import (
+	"strconv"
+
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// menuListSpec 菜单列表允许的筛选和排序字段
+var menuListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"name":      {Column: "name", Op: search.OpLike},
+		"path":      {Column: "path", Op: search.OpLike},
+		"type":      {Column: "type", Op: search.OpIn},
+		"status":    {Column: "status", Op: search.OpIn},
+		"parent_id": {Column: "parent_id", Op: search.OpEq},
+	},
+	Sorts: map[string]string{
+		"id":   "id",
+		"name": "name",
+		"sort": "sort",
+	},
+	DefaultSort: "sort ASC, id ASC",
+}
+
 // GetMenuList 获取菜单列表
 // @Summary 获取菜单列表接口
-// @Description 查询系统中的菜单列表
+// @Description 分页查询系统中的菜单列表，支持筛选和排序
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Permission}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param name query string false "菜单名称(模糊)"
+// @Param type query string false "菜单类型(dir,menu,button)，多个用逗号分隔"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/menu/list [get]
 func (m *MenuApi) GetMenuList(c *gin.Context) {
-	var menus []models.Permission
-	if err := global.DB.Where("type in (1, 2)").Order("sort").Find(&menus).Error; err != nil {
-		global.Logger.Error("获取菜单列表失败: " + err.Error())
+	q, err := search.Parse(c, menuListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	var menus []models.Menu
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Menu{}), &menus)
+	if err != nil {
+		reqlog.Entry(c).Error("获取菜单列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取菜单列表失败"})
 		return
 	}
@@ -27,55 +65,48 @@ func (m *MenuApi) GetMenuList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": menus,
+		"data": page,
 	})
 }
 
 // CreateMenu 创建菜单
 // @Summary 创建菜单接口
-// @Description 管理员创建新菜单
+// @Description 管理员创建目录、菜单或按钮，按钮必须有权限标识且上级为菜单
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
-// @Param menu body models.Permission true "菜单信息"
+// @Param menu body models.Menu true "菜单信息"
 // @Success 200 {object} gin.H{"code":int, "msg":string}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/menu/create [post]
 func (m *MenuApi) CreateMenu(c *gin.Context) {
-	var menu models.Permission
+	var menu models.Menu
 	if err := c.ShouldBindJSON(&menu); err != nil {
-		global.Logger.Error("创建菜单参数错误: " + err.Error())
+		reqlog.Entry(c).Error("创建菜单参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	// 菜单类型只能是dir(目录)或menu(菜单)
-	if menu.Type != "dir" && menu.Type != "menu" {
-		global.Logger.Error("创建菜单参数错误: 菜单类型错误")
-		c.JSON(400, gin.H{"code": 400, "msg": "菜单类型只能是目录或菜单"})
-		return
-	}
-
-	// 检查菜单名称是否已存在
-	var count int64
-	global.DB.Model(&models.Permission{}).Where("name = ?", menu.Name).Count(&count)
-	if count > 0 {
-		global.Logger.Error("菜单名称已存在: " + menu.Name)
-		c.JSON(400, gin.H{"code": 400, "msg": "菜单名称已存在"})
+	menu.ID = 0
+	if msg := checkMenu(global.DB.WithContext(c.Request.Context()), &menu); msg != "" {
+		reqlog.Entry(c).Error("创建菜单参数错误: " + msg)
+		c.JSON(400, gin.H{"code": 400, "msg": msg})
 		return
 	}
 
-	if err := global.DB.Create(&menu).Error; err != nil {
-		global.Logger.Error("创建菜单失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Create(&menu).Error; err != nil {
+		reqlog.Entry(c).Error("创建菜单失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
 		return
 	}
+	permcache.InvalidateAll(c.Request.Context())
 
-	global.Logger.Infof("管理员创建菜单成功: %s", menu.Name)
+	reqlog.Entry(c).Infof("管理员创建菜单成功: %s", menu.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "创建成功",
+		"data": menu,
 	})
 }
 
@@ -85,39 +116,47 @@ func (m *MenuApi) CreateMenu(c *gin.Context) {
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
-// @Param menu body models.Permission true "更新的菜单信息"
+// @Param menu body models.Menu true "更新的菜单信息"
 // @Success 200 {object} gin.H{"code":int, "msg":string}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/menu/update [put]
 func (m *MenuApi) UpdateMenu(c *gin.Context) {
-	var menu models.Permission
+	var menu models.Menu
 	if err := c.ShouldBindJSON(&menu); err != nil {
-		global.Logger.Error("更新菜单参数错误: " + err.Error())
+		reqlog.Entry(c).Error("更新菜单参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	if menu.ID == 0 {
-		global.Logger.Error("更新菜单参数错误: ID为空")
+		reqlog.Entry(c).Error("更新菜单参数错误: ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	// 菜单类型只能是dir(目录)或menu(菜单)
-	if menu.Type != "dir" && menu.Type != "menu" {
-		global.Logger.Error("更新菜单参数错误: 菜单类型错误")
-		c.JSON(400, gin.H{"code": 400, "msg": "菜单类型只能是目录或菜单"})
+	db := global.DB.WithContext(c.Request.Context())
+	var before models.Menu
+	if err := db.First(&before, menu.ID).Error; err != nil {
+		c.JSON(404, gin.H{"code": 404, "msg": "菜单不存在"})
+		return
+	}
+	if msg := checkMenu(db, &menu); msg != "" {
+		reqlog.Entry(c).Error("更新菜单参数错误: " + msg)
+		c.JSON(400, gin.H{"code": 400, "msg": msg})
 		return
 	}
 
-	if err := global.DB.Save(&menu).Error; err != nil {
-		global.Logger.Error("更新菜单失败: " + err.Error())
+	if err := db.Omit("created_at").Save(&menu).Error; err != nil {
+		reqlog.Entry(c).Error("更新菜单失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
 		return
 	}
+	permcache.InvalidateAll(c.Request.Context())
+	audit.RecordChange(c, "menu", "update", menu.ID, before, menu)
 
-	global.Logger.Infof("管理员更新菜单成功: %s", menu.Name)
+	reqlog.Entry(c).Infof("管理员更新菜单成功: %s", menu.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "更新成功",
@@ -136,37 +175,29 @@ func (m *MenuApi) UpdateMenu(c *gin.Context) {
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/menu/delete [delete]
 func (m *MenuApi) DeleteMenu(c *gin.Context) {
-	id := c.Query("id")
-	if id == "" {
-		global.Logger.Error("删除菜单参数错误: ID为空")
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除菜单参数错误: ID无效")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 检查菜单是否有子菜单
-	var childMenus []models.Permission
-	global.DB.Where("parent_id = ?", id).Find(&childMenus)
+	var childMenus []models.Menu
+	global.DB.WithContext(c.Request.Context()).Where("parent_id = ?", id).Find(&childMenus)
 	if len(childMenus) > 0 {
-		global.Logger.Error("删除菜单失败: 菜单有子菜单")
+		reqlog.Entry(c).Error("删除菜单失败: 菜单有子菜单")
 		c.JSON(400, gin.H{"code": 400, "msg": "菜单有子菜单，无法删除"})
 		return
 	}
 
-	// 检查菜单是否有权限关联
-	var rolePermissions []models.RolePermission
-	global.DB.Where("permission_id = ?", id).Find(&rolePermissions)
-	if len(rolePermissions) > 0 {
-		// 先删除角色关联
-		global.DB.Delete(&models.RolePermission{}, "permission_id = ?", id)
-	}
-
-	if err := global.DB.Delete(&models.Permission{}, id).Error; err != nil {
-		global.Logger.Error("删除菜单失败: " + err.Error())
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "menu", uint(id)); err != nil {
+		reqlog.Entry(c).Error("删除菜单失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员删除菜单成功: ID=%s", id)
+	reqlog.Entry(c).Infof("管理员删除菜单成功，已移入回收站: ID=%d", id)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
@@ -175,7 +206,7 @@ func (m *MenuApi) DeleteMenu(c *gin.Context) {
 
 // GetMenuTree 获取菜单树结构
 // @Summary 获取菜单树结构接口
-// @Description 查询系统中的菜单树结构
+// @Description 查询系统中的菜单树结构，包括目录、菜单和按钮
 // @Tags 菜单管理
 // @Accept json
 // @Produce json
@@ -183,9 +214,9 @@ func (m *MenuApi) DeleteMenu(c *gin.Context) {
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/menu/tree [get]
 func (m *MenuApi) GetMenuTree(c *gin.Context) {
-	var menus []models.Permission
-	if err := global.DB.Where("type in (1, 2)").Order("sort").Find(&menus).Error; err != nil {
-		global.Logger.Error("获取菜单树失败: " + err.Error())
+	var menus []models.Menu
+	if err := global.DB.WithContext(c.Request.Context()).Order("sort, id").Find(&menus).Error; err != nil {
+		reqlog.Entry(c).Error("获取菜单树失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
@@ -201,21 +232,24 @@ func (m *MenuApi) GetMenuTree(c *gin.Context) {
 }
 
 // buildMenuTree 构建菜单树结构
-func buildMenuTree(menus []models.Permission, parentID uint) []gin.H {
+func buildMenuTree(menus []models.Menu, parentID uint) []gin.H {
 	tree := make([]gin.H, 0)
 
 	for _, menu := range menus {
 		if menu.ParentID == parentID {
 			children := buildMenuTree(menus, menu.ID)
 			node := gin.H{
-				"id":       menu.ID,
-				"name":     menu.Name,
-				"path":     menu.Path,
-				"component": menu.Component,
-				"icon":     menu.Icon,
-				"type":     menu.Type,
-				"sort":     menu.Sort,
-				"children": children,
+				"id":         menu.ID,
+				"name":       menu.Name,
+				"title":      menu.Meta.Title,
+				"path":       menu.Path,
+				"component":  menu.Component,
+				"icon":       menu.Icon,
+				"type":       menu.Type,
+				"permission": menu.Permission,
+				"sort":       menu.Sort,
+				"status":     menu.Status,
+				"children":   children,
 			}
 			tree = append(tree, node)
 		}
@@ -224,65 +258,73 @@ func buildMenuTree(menus []models.Permission, parentID uint) []gin.H {
 	return tree
 }
 
-// GetUserMenus 获取用户菜单
-// @Summary 获取用户菜单接口
-// @Description 查询指定用户的菜单列表
-// @Tags 菜单管理
-// @Accept json
-// @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]gin.H{"id":int, "name":string, "children":[]gin.H}}
-// @Failure 500 {object} gin.H{"code":int, "msg":string}
-// @Router /admin/menu/user-menus [get]
-func (m *MenuApi) GetUserMenus(c *gin.Context) {
-	// 从token中获取用户信息
-	userID, exists := c.Get("user_id")
-	if !exists {
-		global.Logger.Error("获取用户菜单失败: 用户未登录")
-		c.JSON(401, gin.H{"code": 401, "msg": "用户未登录"})
-		return
+// checkMenu 校验菜单类型、上级和按钮的权限标识，通过时返回空字符串
+// 上级必须是目录或菜单，按钮的上级只能是菜单，且不能是自身或其下级
+func checkMenu(db *gorm.DB, menu *models.Menu) string {
+	switch menu.Type {
+	case "":
+		menu.Type = models.MenuTypeMenu
+	case models.MenuTypeDir, models.MenuTypeMenu, models.MenuTypeButton:
+	default:
+		return "菜单类型只能是目录、菜单或按钮"
 	}
-
-	// 如果是超级管理员，返回所有菜单
-	var user models.User
-	global.DB.First(&user, userID)
-	if user.IsAdmin {
-		var menus []models.Permission
-		if err := global.DB.Where("type in (1, 2)").Order("sort").Find(&menus).Error; err != nil {
-			global.Logger.Error("获取用户菜单失败: " + err.Error())
-			c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
-			return
+	if menu.Name == "" {
+		return "菜单名称不能为空"
+	}
+	if menu.Type == models.MenuTypeButton {
+		if menu.Permission == "" {
+			return "按钮必须有权限标识"
+		}
+		if menu.ParentID == 0 {
+			return "按钮必须属于菜单"
+		}
+	} else {
+		// 路由名称在前端必须唯一
+		var count int64
+		db.Model(&models.Menu{}).Where("name = ? AND type <> ? AND id <> ?", menu.Name, models.MenuTypeButton, menu.ID).Count(&count)
+		if count > 0 {
+			return "菜单名称已存在"
 		}
-
-		tree := buildMenuTree(menus, 0)
-		c.JSON(200, gin.H{
-			"code": 200,
-			"msg":  "获取成功",
-			"data": tree,
-		})
-		return
 	}
-
-	// 普通用户，根据权限获取菜单
-	var permissions []models.Permission
-	if err := global.DB.Table("permissions").
-		Select("permissions.*").
-		Joins("join role_permissions on permissions.id = role_permissions.permission_id").
-		Joins("join user_roles on role_permissions.role_id = user_roles.role_id").
-		Where("user_roles.user_id = ? and permissions.type in (1, 2)", userID).
-		Order("permissions.sort").
-		Distinct().
-		Find(&permissions).Error; err != nil {
-		global.Logger.Error("获取用户菜单失败: " + err.Error())
-		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
-		return
+	// 修改类型时，已有的下级必须仍然满足约束
+	if menu.ID != 0 && menu.Type != models.MenuTypeMenu {
+		var children []models.Menu
+		db.Select("type").Where("parent_id = ?", menu.ID).Find(&children)
+		for _, child := range children {
+			if menu.Type == models.MenuTypeButton {
+				return "按钮不能有下级菜单"
+			}
+			if child.Type == models.MenuTypeButton {
+				return "目录下不能有按钮，请先移动其下的按钮"
+			}
+		}
+	}
+	if menu.ParentID == 0 {
+		return ""
 	}
 
-	// 构建菜单树
-	tree := buildMenuTree(permissions, 0)
-
-	c.JSON(200, gin.H{
-		"code": 200,
-		"msg":  "获取成功",
-		"data": tree,
-	})
-}
\ No newline at end of file
+	var menus []models.Menu
+	db.Select("id", "parent_id", "type").Find(&menus)
+	index := make(map[uint]models.Menu, len(menus))
+	for _, m := range menus {
+		index[m.ID] = m
+	}
+	parent, ok := index[menu.ParentID]
+	if !ok {
+		return "上级菜单不存在"
+	}
+	if parent.Type == models.MenuTypeButton {
+		return "上级菜单不能是按钮"
+	}
+	if menu.Type == models.MenuTypeButton && parent.Type != models.MenuTypeMenu {
+		return "按钮只能属于菜单，不能属于目录"
+	}
+	seen := make(map[uint]bool)
+	for id := menu.ParentID; id != 0 && !seen[id]; id = index[id].ParentID {
+		if id == menu.ID {
+			return "上级菜单不能是自身或其下级菜单"
+		}
+		seen[id] = true
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package menu_api
+
+import (
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/i18n"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/reqlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route 前端路由，字段与vue-router/react-router的路由配置一致
+type Route struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Component string    `json:"component,omitempty"`
+	Redirect  string    `json:"redirect,omitempty"`
+	Meta      RouteMeta `json:"meta"`
+	Children  []*Route  `json:"children,omitempty"`
+}
+
+// RouteMeta 路由元数据，沿用前端路由meta的驼峰命名
+type RouteMeta struct {
+	Title      string `json:"title"`
+	Icon       string `json:"icon,omitempty"`
+	Hidden     bool   `json:"hidden"`
+	KeepAlive  bool   `json:"keepAlive"`
+	AlwaysShow bool   `json:"alwaysShow"`
+	Breadcrumb bool   `json:"breadcrumb"`
+	Affix      bool   `json:"affix"`
+	ActiveMenu string `json:"activeMenu,omitempty"`
+	Permission string `json:"permission,omitempty"`
+}
+
+// UserMenus 当前用户的前端路由和按钮权限标识，按钮权限标识与个人权限接口相同
+type UserMenus struct {
+	Routes  []*Route `json:"routes"`
+	Buttons []string `json:"buttons"`
+}
+
+// GetUserMenus 获取用户菜单
+// @Summary 获取用户菜单接口
+// @Description 返回当前用户可访问的前端路由树和按钮权限标识，菜单来自直接分配和通过用户组获得的角色，超级管理员返回全部菜单；标题按用户语言翻译；按钮权限标识包括角色的权限和菜单按钮，与 /admin/profile/permissions 相同
+// @Tags 菜单管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":UserMenus}
+// @Failure 401 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/menu/user-menus [get]
+func (m *MenuApi) GetUserMenus(c *gin.Context) {
+	userID := c.GetUint("userID")
+	db := global.DB.WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		reqlog.Entry(c).Error("获取用户菜单失败: 用户不存在")
+		c.JSON(401, gin.H{"code": 401, "msg": "用户不存在"})
+		return
+	}
+
+	var menus []models.Menu
+	if err := db.Where("status = ?", 1).Order("sort, id").Find(&menus).Error; err != nil {
+		reqlog.Entry(c).Error("获取用户菜单失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	// 普通用户只保留启用角色分配的菜单及其上级
+	if !user.IsAdmin {
+		roleIDs, err := global.RoleIDs(db, userID)
+		if err != nil {
+			reqlog.Entry(c).Error("获取用户角色失败: " + err.Error())
+			c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+			return
+		}
+		var granted []uint
+		if len(roleIDs) > 0 {
+			if err := db.Model(&models.RoleMenu{}).
+				Joins("JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+				Where("role_menus.role_id IN ?", roleIDs).
+				Pluck("role_menus.menu_id", &granted).Error; err != nil {
+				reqlog.Entry(c).Error("获取用户菜单失败: " + err.Error())
+				c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+				return
+			}
+		}
+		menus = grantedMenus(menus, granted)
+	}
+
+	buttons, err := permcache.Keys(db, userID)
+	if err != nil {
+		reqlog.Entry(c).Error("获取用户权限标识失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": UserMenus{Routes: buildRoutes(menus, i18n.Locale(c)), Buttons: buttons},
+	})
+}
+
+// grantedMenus 筛选出分配的菜单，并补上它们的上级目录和菜单
+func grantedMenus(menus []models.Menu, granted []uint) []models.Menu {
+	parents := make(map[uint]uint, len(menus))
+	for _, menu := range menus {
+		parents[menu.ID] = menu.ParentID
+	}
+	keep := make(map[uint]bool, len(granted))
+	for _, id := range granted {
+		for ; id != 0 && !keep[id]; id = parents[id] {
+			if _, ok := parents[id]; !ok {
+				break
+			}
+			keep[id] = true
+		}
+	}
+
+	list := make([]models.Menu, 0, len(keep))
+	for _, menu := range menus {
+		if keep[menu.ID] {
+			list = append(list, menu)
+		}
+	}
+	return list
+}
+
+// buildRoutes 将已按排序的菜单构建为路由树，标题翻译为locale
+// 按钮不生成路由，上级不在列表中(已禁用或未分配)的菜单一并丢弃
+func buildRoutes(menus []models.Menu, locale string) []*Route {
+	index := make(map[uint]models.Menu, len(menus))
+	for _, menu := range menus {
+		index[menu.ID] = menu
+	}
+	reachable := make(map[uint]bool, len(menus))
+	var reach func(id uint, depth int) bool
+	reach = func(id uint, depth int) bool {
+		if id == 0 {
+			return true
+		}
+		if ok, done := reachable[id]; done {
+			return ok
+		}
+		menu, ok := index[id]
+		ok = ok && depth < len(menus) && menu.Type != models.MenuTypeButton && reach(menu.ParentID, depth+1)
+		reachable[id] = ok
+		return ok
+	}
+
+	result := []*Route{}
+	routes := make(map[uint]*Route, len(menus))
+	for _, menu := range menus {
+		if menu.Type == models.MenuTypeButton || !reach(menu.ParentID, 0) {
+			continue
+		}
+		routes[menu.ID] = newRoute(menu, locale)
+	}
+	for _, menu := range menus {
+		route, ok := routes[menu.ID]
+		if !ok {
+			continue
+		}
+		if parent, ok := routes[menu.ParentID]; ok {
+			parent.Children = append(parent.Children, route)
+		} else if menu.ParentID == 0 {
+			result = append(result, route)
+		}
+	}
+	return result
+}
+
+// newRoute 菜单转为前端路由，标题和图标优先使用元数据，标题按菜单名称翻译
+func newRoute(menu models.Menu, locale string) *Route {
+	title := menu.Meta.Title
+	if title == "" {
+		title = menu.Name
+	}
+	title = i18n.Menu(locale, menu.Name, title)
+	icon := menu.Meta.Icon
+	if icon == "" {
+		icon = menu.Icon
+	}
+	return &Route{
+		Name:      menu.Name,
+		Path:      menu.Path,
+		Component: menu.Component,
+		Redirect:  menu.Redirect,
+		Meta: RouteMeta{
+			Title:      title,
+			Icon:       icon,
+			Hidden:     menu.Hidden == 1,
+			KeepAlive:  menu.KeepAlive == 1 && menu.NoCache != 1 && !menu.Meta.NoCache,
+			AlwaysShow: menu.AlwaysShow == 1,
+			Breadcrumb: menu.Breadcrumb != 2,
+			Affix:      menu.Affix == 1 || menu.Meta.Affix,
+			ActiveMenu: menu.Meta.ActiveMenu,
+			Permission: menu.Permission,
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package permission_api
+
+import (
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/treeedit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// permissionTree 权限树，按钮权限不能有下级
+var permissionTree = &treeedit.Tree{
+	Label:  "权限",
+	Entity: "permission",
+	Model:  func() interface{} { return &models.Permission{} },
+	Load: func(db *gorm.DB) ([]treeedit.Node, error) {
+		var permissions []models.Permission
+		if err := db.Select("id", "parent_id", "type").Find(&permissions).Error; err != nil {
+			return nil, err
+		}
+		nodes := make([]treeedit.Node, len(permissions))
+		for i, permission := range permissions {
+			nodes[i] = treeedit.Node{ID: permission.ID, ParentID: permission.ParentID, Type: permission.Type,
+				Leaf: permission.Type == "button"}
+		}
+		return nodes, nil
+	},
+}
+
+// SortPermissions 批量调整权限位置
+// @Summary 批量调整权限位置接口
+// @Description 提交拖拽后的权限树或移动操作列表，校验无循环且按钮权限没有下级后在一个事务中更新上级和排序
+// @Tags 权限管理
+// @Accept json
+// @Produce json
+// @Param data body treeedit.SortRequest true "权限树或移动操作"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/permission/sort [put]
+func (p *PermissionApi) SortPermissions(c *gin.Context) {
+	permissionTree.HandleSort(c)
+}
+
+// BatchPermissionStatus 批量启用禁用权限
+// @Summary 批量启用禁用权限接口
+// @Description 设置权限及其全部下级权限的状态，用户的权限标识缓存同时失效
+// @Tags 权限管理
+// @Accept json
+// @Produce json
+// @Param data body treeedit.StatusRequest true "权限ID和状态"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]int}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/permission/batch-status [put]
+func (p *PermissionApi) BatchPermissionStatus(c *gin.Context) {
+	permissionTree.HandleStatus(c)
+}
+
+// BatchDeletePermissions 批量删除权限
+// @Summary 批量删除权限接口
+// @Description 将权限及其全部下级权限移入回收站，恢复时需要先恢复上级
+// @Tags 权限管理
+// @Accept json
+// @Produce json
+// @Param data body treeedit.DeleteRequest true "权限ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]int}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/permission/batch-delete [post]
+func (p *PermissionApi) BatchDeletePermissions(c *gin.Context) {
+	permissionTree.HandleDelete(c)
+}
@@ -19,6 +19,9 @@ func (p *PermissionApi) RegisterRoutes(router *gin.RouterGroup) {
 		permissionRouter.PUT("/update", p.UpdatePermission)
 		permissionRouter.DELETE("/delete", p.DeletePermission)
 		permissionRouter.GET("/tree", p.GetPermissionTree)
+		permissionRouter.PUT("/sort", p.SortPermissions)
+		permissionRouter.PUT("/batch-status", p.BatchPermissionStatus)
+		permissionRouter.POST("/batch-delete", p.BatchDeletePermissions)
 		permissionRouter.GET("/role-permissions", p.GetRolePermissions)
 	}
 }
\ No newline at end of file
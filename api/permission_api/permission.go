@@ -1,25 +1,64 @@
 package permission_api
 
 import (
+	"strconv"
+
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
 
 	"github.com/gin-gonic/gin"
 )
 
+// permissionListSpec 权限列表允许的筛选和排序字段
+var permissionListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"name":      {Column: "name", Op: search.OpLike},
+		"key":       {Column: "key", Op: search.OpLike},
+		"type":      {Column: "type", Op: search.OpIn},
+		"method":    {Column: "method", Op: search.OpEq},
+		"path":      {Column: "path", Op: search.OpLike},
+		"status":    {Column: "status", Op: search.OpIn},
+		"parent_id": {Column: "parent_id", Op: search.OpEq},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"sort":       "sort",
+		"created_at": "created_at",
+	},
+	DefaultSort: "sort ASC, id ASC",
+}
+
 // GetPermissionList 获取权限列表
 // @Summary 获取权限列表接口
-// @Description 查询系统中的权限列表
+// @Description 分页查询系统中的权限列表，支持筛选和排序
 // @Tags 权限管理
 // @Accept json
 // @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Permission}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param name query string false "权限名称(模糊)"
+// @Param type query string false "权限类型，多个用逗号分隔"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/permission/list [get]
 func (p *PermissionApi) GetPermissionList(c *gin.Context) {
+	q, err := search.Parse(c, permissionListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	var permissions []models.Permission
-	if err := global.DB.Find(&permissions).Error; err != nil {
-		global.Logger.Error("获取权限列表失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Permission{}), &permissions)
+	if err != nil {
+		reqlog.Entry(c).Error("获取权限列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取权限列表失败"})
 		return
 	}
@@ -27,7 +66,7 @@ func (p *PermissionApi) GetPermissionList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": permissions,
+		"data": page,
 	})
 }
 
@@ -45,27 +84,34 @@ func (p *PermissionApi) GetPermissionList(c *gin.Context) {
 func (p *PermissionApi) CreatePermission(c *gin.Context) {
 	var permission models.Permission
 	if err := c.ShouldBindJSON(&permission); err != nil {
-		global.Logger.Error("创建权限参数错误: " + err.Error())
+		reqlog.Entry(c).Error("创建权限参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
+	if isMenuType(permission.Type) {
+		c.JSON(400, gin.H{"code": 400, "msg": "目录和菜单请在菜单管理中维护"})
+		return
+	}
+
 	// 检查权限名是否已存在
 	var count int64
-	global.DB.Model(&models.Permission{}).Where("name = ?", permission.Name).Count(&count)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Permission{}).Where("name = ?", permission.Name).Count(&count)
 	if count > 0 {
-		global.Logger.Error("权限名已存在: " + permission.Name)
+		reqlog.Entry(c).Error("权限名已存在: " + permission.Name)
 		c.JSON(400, gin.H{"code": 400, "msg": "权限名已存在"})
 		return
 	}
 
-	if err := global.DB.Create(&permission).Error; err != nil {
-		global.Logger.Error("创建权限失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Create(&permission).Error; err != nil {
+		reqlog.Entry(c).Error("创建权限失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员创建权限成功: %s", permission.Name)
+	permcache.InvalidateAll(c.Request.Context())
+
+	reqlog.Entry(c).Infof("管理员创建权限成功: %s", permission.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "创建成功",
@@ -86,24 +132,30 @@ func (p *PermissionApi) CreatePermission(c *gin.Context) {
 func (p *PermissionApi) UpdatePermission(c *gin.Context) {
 	var permission models.Permission
 	if err := c.ShouldBindJSON(&permission); err != nil {
-		global.Logger.Error("更新权限参数错误: " + err.Error())
+		reqlog.Entry(c).Error("更新权限参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	if permission.ID == 0 {
-		global.Logger.Error("更新权限参数错误: ID为空")
+		reqlog.Entry(c).Error("更新权限参数错误: ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
+	if isMenuType(permission.Type) {
+		c.JSON(400, gin.H{"code": 400, "msg": "目录和菜单请在菜单管理中维护"})
+		return
+	}
 
-	if err := global.DB.Save(&permission).Error; err != nil {
-		global.Logger.Error("更新权限失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Save(&permission).Error; err != nil {
+		reqlog.Entry(c).Error("更新权限失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员更新权限成功: %s", permission.Name)
+	permcache.InvalidateAll(c.Request.Context())
+
+	reqlog.Entry(c).Infof("管理员更新权限成功: %s", permission.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "更新成功",
@@ -122,37 +174,29 @@ func (p *PermissionApi) UpdatePermission(c *gin.Context) {
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/permission/delete [delete]
 func (p *PermissionApi) DeletePermission(c *gin.Context) {
-	id := c.Query("id")
-	if id == "" {
-		global.Logger.Error("删除权限参数错误: ID为空")
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除权限参数错误: ID无效")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 检查权限是否有子权限
 	var childPermissions []models.Permission
-	global.DB.Where("parent_id = ?", id).Find(&childPermissions)
+	global.DB.WithContext(c.Request.Context()).Where("parent_id = ?", id).Find(&childPermissions)
 	if len(childPermissions) > 0 {
-		global.Logger.Error("删除权限失败: 权限有子权限")
+		reqlog.Entry(c).Error("删除权限失败: 权限有子权限")
 		c.JSON(400, gin.H{"code": 400, "msg": "权限有子权限，无法删除"})
 		return
 	}
 
-	// 检查权限是否有角色关联
-	var rolePermissions []models.RolePermission
-	global.DB.Where("permission_id = ?", id).Find(&rolePermissions)
-	if len(rolePermissions) > 0 {
-		// 先删除角色关联
-		global.DB.Delete(&models.RolePermission{}, "permission_id = ?", id)
-	}
-
-	if err := global.DB.Delete(&models.Permission{}, id).Error; err != nil {
-		global.Logger.Error("删除权限失败: " + err.Error())
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "permission", uint(id)); err != nil {
+		reqlog.Entry(c).Error("删除权限失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员删除权限成功: ID=%s", id)
+	reqlog.Entry(c).Infof("管理员删除权限成功，已移入回收站: ID=%d", id)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
@@ -170,8 +214,8 @@ func (p *PermissionApi) DeletePermission(c *gin.Context) {
 // @Router /admin/permission/tree [get]
 func (p *PermissionApi) GetPermissionTree(c *gin.Context) {
 	var permissions []models.Permission
-	if err := global.DB.Order("sort").Find(&permissions).Error; err != nil {
-		global.Logger.Error("获取权限树失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Order("sort").Find(&permissions).Error; err != nil {
+		reqlog.Entry(c).Error("获取权限树失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
@@ -186,6 +230,11 @@ func (p *PermissionApi) GetPermissionTree(c *gin.Context) {
 	})
 }
 
+// isMenuType 目录和菜单已由菜单表维护，不再作为权限类型
+func isMenuType(t string) bool {
+	return t == models.MenuTypeDir || t == models.MenuTypeMenu
+}
+
 // buildPermissionTree 构建权限树结构
 func buildPermissionTree(permissions []models.Permission, parentID uint) []gin.H {
 	tree := make([]gin.H, 0)
@@ -224,14 +273,14 @@ func buildPermissionTree(permissions []models.Permission, parentID uint) []gin.H
 func (p *PermissionApi) GetRolePermissions(c *gin.Context) {
 	roleID := c.Query("role_id")
 	if roleID == "" {
-		global.Logger.Error("获取角色权限参数错误: 角色ID为空")
+		reqlog.Entry(c).Error("获取角色权限参数错误: 角色ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	var rolePermissions []models.RolePermission
-	if err := global.DB.Where("role_id = ?", roleID).Find(&rolePermissions).Error; err != nil {
-		global.Logger.Error("获取角色权限失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Where("role_id = ?", roleID).Find(&rolePermissions).Error; err != nil {
+		reqlog.Entry(c).Error("获取角色权限失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
@@ -0,0 +1,23 @@
+package position_api
+
+import "github.com/gin-gonic/gin"
+
+// PositionApi 岗位API结构体
+type PositionApi struct{}
+
+// NewPositionApi 创建岗位API实例
+func NewPositionApi() *PositionApi {
+	return &PositionApi{}
+}
+
+// RegisterRoutes 注册岗位API路由
+func (p *PositionApi) RegisterRoutes(router *gin.RouterGroup) {
+	positionRouter := router.Group("/position")
+	{
+		positionRouter.GET("/list", p.GetPositionList)
+		positionRouter.POST("/create", p.CreatePosition)
+		positionRouter.PUT("/update", p.UpdatePosition)
+		positionRouter.DELETE("/delete", p.DeletePosition)
+		positionRouter.GET("/users", p.GetPositionUsers)
+	}
+}
@@ -0,0 +1,271 @@
+package position_api
+
+import (
+	"strconv"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// positionListSpec 岗位列表允许的筛选和排序字段
+var positionListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"name":       {Column: "name", Op: search.OpLike},
+		"code":       {Column: "code", Op: search.OpLike},
+		"status":     {Column: "status", Op: search.OpIn},
+		"created_at": {Column: "created_at", Op: search.OpDateRange},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"code":       "code",
+		"sort":       "sort",
+		"created_at": "created_at",
+	},
+	DefaultSort: "sort ASC, id ASC",
+}
+
+// positionUserSpec 岗位用户列表允许的筛选和排序字段
+var positionUserSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"username": {Column: "users.username", Op: search.OpLike},
+		"nickname": {Column: "users.nickname", Op: search.OpLike},
+		"status":   {Column: "users.status", Op: search.OpIn},
+	},
+	Sorts: map[string]string{
+		"id":         "users.id",
+		"username":   "users.username",
+		"created_at": "users.created_at",
+	},
+	DefaultSort: "users.id ASC",
+}
+
+// GetPositionList 获取岗位列表
+// @Summary 获取岗位列表接口
+// @Description 分页查询系统中的岗位列表，支持筛选和排序
+// @Tags 岗位管理
+// @Accept json
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param name query string false "岗位名称(模糊)"
+// @Param code query string false "岗位编码(模糊)"
+// @Param status query string false "状态，多个用逗号分隔"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/position/list [get]
+func (p *PositionApi) GetPositionList(c *gin.Context) {
+	q, err := search.Parse(c, positionListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	var positions []models.Position
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Position{}), &positions)
+	if err != nil {
+		reqlog.Entry(c).Error("获取岗位列表失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取岗位列表失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": page,
+	})
+}
+
+// CreatePosition 创建岗位
+// @Summary 创建岗位接口
+// @Description 管理员创建新岗位
+// @Tags 岗位管理
+// @Accept json
+// @Produce json
+// @Param position body models.Position true "岗位信息"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/position/create [post]
+func (p *PositionApi) CreatePosition(c *gin.Context) {
+	var position models.Position
+	if err := c.ShouldBindJSON(&position); err != nil {
+		reqlog.Entry(c).Error("创建岗位参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+	if position.Name == "" || position.Code == "" {
+		c.JSON(400, gin.H{"code": 400, "msg": "岗位名称和编码不能为空"})
+		return
+	}
+
+	// 检查岗位编码是否已存在
+	var count int64
+	global.DB.WithContext(c.Request.Context()).Model(&models.Position{}).Where("code = ?", position.Code).Count(&count)
+	if count > 0 {
+		reqlog.Entry(c).Error("岗位编码已存在: " + position.Code)
+		c.JSON(400, gin.H{"code": 400, "msg": "岗位编码已存在"})
+		return
+	}
+
+	if err := global.DB.WithContext(c.Request.Context()).Create(&position).Error; err != nil {
+		reqlog.Entry(c).Error("创建岗位失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
+		return
+	}
+
+	reqlog.Entry(c).Infof("管理员创建岗位成功: %s", position.Name)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "创建成功",
+		"data": position,
+	})
+}
+
+// UpdatePosition 更新岗位
+// @Summary 更新岗位接口
+// @Description 管理员更新岗位信息
+// @Tags 岗位管理
+// @Accept json
+// @Produce json
+// @Param position body models.Position true "更新的岗位信息"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/position/update [put]
+func (p *PositionApi) UpdatePosition(c *gin.Context) {
+	var position models.Position
+	if err := c.ShouldBindJSON(&position); err != nil {
+		reqlog.Entry(c).Error("更新岗位参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	if position.ID == 0 {
+		reqlog.Entry(c).Error("更新岗位参数错误: ID为空")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var count int64
+	db.Model(&models.Position{}).Where("code = ? AND id <> ?", position.Code, position.ID).Count(&count)
+	if count > 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "岗位编码已存在"})
+		return
+	}
+
+	// 记录变更前的数据
+	var before models.Position
+	if err := db.First(&before, position.ID).Error; err != nil {
+		c.JSON(404, gin.H{"code": 404, "msg": "岗位不存在"})
+		return
+	}
+
+	if err := db.Omit("created_at").Save(&position).Error; err != nil {
+		reqlog.Entry(c).Error("更新岗位失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
+		return
+	}
+	audit.RecordChange(c, "position", "update", position.ID, before, position)
+
+	reqlog.Entry(c).Infof("管理员更新岗位成功: %s", position.Name)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "更新成功",
+	})
+}
+
+// DeletePosition 删除岗位
+// @Summary 删除岗位接口
+// @Description 管理员删除岗位，岗位下仍有用户时不能删除
+// @Tags 岗位管理
+// @Accept json
+// @Produce json
+// @Param id query int true "岗位ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/position/delete [delete]
+func (p *PositionApi) DeletePosition(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除岗位参数错误: ID无效")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	// 检查岗位是否有用户
+	var count int64
+	global.DB.WithContext(c.Request.Context()).Model(&models.UserPosition{}).Where("position_id = ?", id).Count(&count)
+	if count > 0 {
+		reqlog.Entry(c).Error("删除岗位失败: 岗位有用户")
+		c.JSON(400, gin.H{"code": 400, "msg": "岗位有用户，无法删除"})
+		return
+	}
+
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "position", uint(id)); err != nil {
+		reqlog.Entry(c).Error("删除岗位失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
+		return
+	}
+
+	reqlog.Entry(c).Infof("管理员删除岗位成功，已移入回收站: ID=%d", id)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+	})
+}
+
+// GetPositionUsers 获取岗位用户
+// @Summary 获取岗位用户接口
+// @Description 分页查询指定岗位下的用户列表
+// @Tags 岗位管理
+// @Accept json
+// @Produce json
+// @Param position_id query int true "岗位ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/position/users [get]
+func (p *PositionApi) GetPositionUsers(c *gin.Context) {
+	positionID := c.Query("position_id")
+	if positionID == "" {
+		reqlog.Entry(c).Error("获取岗位用户参数错误: 岗位ID为空")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	q, err := search.Parse(c, positionUserSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	var users []models.User
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.User{}).
+		Joins("join user_positions on users.id = user_positions.user_id").
+		Where("user_positions.position_id = ?", positionID), &users)
+	if err != nil {
+		reqlog.Entry(c).Error("获取岗位用户失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": page,
+	})
+}
@@ -0,0 +1,167 @@
+package profile_api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/contact"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// ChangeEmailRequest 申请变更邮箱请求参数
+type ChangeEmailRequest struct {
+	// 新邮箱
+	Email string `json:"email" binding:"required"`
+}
+
+// ConfirmEmailRequest 确认变更邮箱请求参数
+type ConfirmEmailRequest struct {
+	// 新邮箱，须与申请时一致
+	Email string `json:"email" binding:"required"`
+	// 发送到新邮箱的验证码
+	Code string `json:"code" binding:"required"`
+}
+
+// ChangePhoneRequest 申请变更手机号请求参数
+type ChangePhoneRequest struct {
+	// 新手机号
+	Phone string `json:"phone" binding:"required"`
+}
+
+// ConfirmPhoneRequest 确认变更手机号请求参数
+type ConfirmPhoneRequest struct {
+	// 新手机号，须与申请时一致
+	Phone string `json:"phone" binding:"required"`
+	// 发送到新手机号的验证码
+	Code string `json:"code" binding:"required"`
+}
+
+// RequestEmailChange 申请变更邮箱
+// @Summary 申请变更邮箱
+// @Description 向新邮箱发送验证码并通知原邮箱，确认前原邮箱继续有效
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body ChangeEmailRequest true "新邮箱"
+// @Success 200 {object} utils.Response{data=string}
+// @Router /profile/email/change [post]
+func (p *ProfileApi) RequestEmailChange(c *gin.Context) {
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+	requestChange(c, contact.Email, req.Email)
+}
+
+// ConfirmEmailChange 确认变更邮箱
+// @Summary 确认变更邮箱
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body ConfirmEmailRequest true "新邮箱和验证码"
+// @Success 200 {object} utils.Response{data=string}
+// @Router /profile/email/confirm [post]
+func (p *ProfileApi) ConfirmEmailChange(c *gin.Context) {
+	audit.MaskFields(c, "code")
+	var req ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+	confirmChange(c, contact.Email, req.Email, req.Code)
+}
+
+// RequestPhoneChange 申请变更手机号
+// @Summary 申请变更手机号
+// @Description 向新手机号发送短信验证码并通知原手机号，确认前原手机号继续有效
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body ChangePhoneRequest true "新手机号"
+// @Success 200 {object} utils.Response{data=string}
+// @Router /profile/phone/change [post]
+func (p *ProfileApi) RequestPhoneChange(c *gin.Context) {
+	var req ChangePhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+	requestChange(c, contact.Phone, req.Phone)
+}
+
+// ConfirmPhoneChange 确认变更手机号
+// @Summary 确认变更手机号
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body ConfirmPhoneRequest true "新手机号和验证码"
+// @Success 200 {object} utils.Response{data=string}
+// @Router /profile/phone/confirm [post]
+func (p *ProfileApi) ConfirmPhoneChange(c *gin.Context) {
+	audit.MaskFields(c, "code")
+	var req ConfirmPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+	confirmChange(c, contact.Phone, req.Phone, req.Code)
+}
+
+// requestChange 发送变更验证码
+func requestChange(c *gin.Context, kind, target string) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
+		return
+	}
+	db := global.DB.WithContext(c.Request.Context())
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, utils.ERROR_USER_NOT_EXIST, nil)
+		return
+	}
+
+	if err := contact.Request(db, &user, kind, target); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	utils.Success(c, "验证码已发送")
+}
+
+// confirmChange 校验验证码并完成变更
+func confirmChange(c *gin.Context, kind, target, code string) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
+		return
+	}
+
+	old, err := contact.Confirm(global.DB.WithContext(c.Request.Context()), userID.(uint), kind, target, code)
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	audit.RecordChange(c, "user", kind, userID.(uint), gin.H{kind: old}, gin.H{kind: target})
+	reqlog.Entry(c).Infof("用户%s变更成功", kind)
+	utils.Success(c, "变更成功")
+}
+
+func respondContactError(c *gin.Context, err error) {
+	status, code := contact.ErrorCode(err)
+	if status == http.StatusInternalServerError {
+		reqlog.Entry(c).Errorf("变更联系方式失败: %v", err)
+		utils.Error(c, status, code, nil)
+		return
+	}
+	utils.Error(c, status, code, err.Error())
+}
@@ -22,10 +22,22 @@ func (p *ProfileApi) RegisterRoutes(router *gin.RouterGroup) {
 		{
 			profileRouter.GET("/info", p.GetUserInfo)           // 获取用户个人信息
 			profileRouter.PUT("/info", p.UpdateUserInfo)         // 更新用户个人信息
-			profileRouter.PUT("/password", p.UpdatePassword)      // 修改密码
+			profileRouter.PUT("/password", middleware.NoImpersonation(), p.UpdatePassword)      // 修改密码
+			profileRouter.POST("/avatar", p.UploadAvatar)         // 上传头像
+			profileRouter.POST("/email/change", middleware.NoImpersonation(), p.RequestEmailChange)  // 申请变更邮箱
+			profileRouter.POST("/email/confirm", middleware.NoImpersonation(), p.ConfirmEmailChange) // 确认变更邮箱
+			profileRouter.POST("/phone/change", middleware.NoImpersonation(), p.RequestPhoneChange)  // 申请变更手机号
+			profileRouter.POST("/phone/confirm", middleware.NoImpersonation(), p.ConfirmPhoneChange) // 确认变更手机号
 			profileRouter.GET("/dashboard", p.GetDashboardData)   // 获取仪表盘数据
+			profileRouter.GET("/permissions", p.GetUserPermissions) // 获取权限标识
 			profileRouter.GET("/settings", p.GetUserSettings)     // 获取用户设置
-			profileRouter.PUT("/settings", p.UpdateUserSettings)  // 更新用户设置
+			profileRouter.PATCH("/settings", p.UpdateUserSettings) // 更新用户设置
+			profileRouter.PUT("/settings", p.UpdateUserSettings)  // 更新用户设置，兼容旧版前端
+			profileRouter.DELETE("/settings", p.ResetUserSettings) // 重置用户设置
+			profileRouter.GET("/export", middleware.NoImpersonation(), p.ExportPersonalData)    // 导出个人数据
+			profileRouter.GET("/deletion", p.GetDeletionRequest)  // 查询注销申请
+			profileRouter.POST("/deletion", middleware.NoImpersonation(), p.RequestDeletion)    // 申请注销账号
+			profileRouter.DELETE("/deletion", middleware.NoImpersonation(), p.CancelDeletion)   // 撤销注销申请
 		}
 	}
 }
\ No newline at end of file
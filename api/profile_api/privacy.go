@@ -0,0 +1,132 @@
+package profile_api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/privacy"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// DeletionRequestBody 申请注销账号请求参数
+type DeletionRequestBody struct {
+	// 当前密码，用于确认本人操作
+	Password string `json:"password" binding:"required"`
+	// 注销原因
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// ExportPersonalData 导出个人数据
+// @Summary 导出个人数据
+// @Description 下载包含个人资料、角色、会话、登录历史、上传文件元数据和审计日志的ZIP文件
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Produce application/zip
+// @Success 200 {file} file "ZIP文件"
+// @Router /profile/export [get]
+func (p *ProfileApi) ExportPersonalData(c *gin.Context) {
+	userID := c.GetUint("userID")
+	var buf bytes.Buffer
+	err := privacy.Export(global.DB.WithContext(c.Request.Context()), userID, &buf)
+	audit.RecordOperation(c, "profile", "export", "导出个人数据", err)
+	if err != nil {
+		reqlog.Entry(c).Errorf("导出个人数据失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+		return
+	}
+
+	filename := fmt.Sprintf("personal_data_%d_%s.zip", userID, time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// GetDeletionRequest 查询注销申请
+// @Summary 查询注销申请
+// @Description 返回冷静期中的注销申请，没有时data为null
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} utils.Response{data=models.DeletionRequest}
+// @Router /profile/deletion [get]
+func (p *ProfileApi) GetDeletionRequest(c *gin.Context) {
+	req, err := privacy.Pending(global.DB.WithContext(c.Request.Context()), c.GetUint("userID"))
+	if err != nil {
+		reqlog.Entry(c).Errorf("查询注销申请失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+		return
+	}
+	utils.Success(c, req)
+}
+
+// RequestDeletion 申请注销账号
+// @Summary 申请注销账号
+// @Description 验证密码后提交注销申请，冷静期结束后匿名化个人信息，冷静期内可撤销
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body DeletionRequestBody true "当前密码和注销原因"
+// @Success 200 {object} utils.Response{data=models.DeletionRequest}
+// @Router /profile/deletion [post]
+func (p *ProfileApi) RequestDeletion(c *gin.Context) {
+	var req DeletionRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var user models.User
+	if err := db.First(&user, c.GetUint("userID")).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR_GET_USER, nil)
+		return
+	}
+	if !utils.ComparePassword(user.Password, req.Password) {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_PASSWORD_WRONG, nil)
+		return
+	}
+
+	deletion, err := privacy.Request(db, &user, req.Reason)
+	audit.Describe(c, "申请注销账号", err)
+	switch {
+	case errors.Is(err, privacy.ErrAdmin):
+		utils.Error(c, http.StatusForbidden, utils.ERROR_DELETION_ADMIN, nil)
+	case errors.Is(err, privacy.ErrPending):
+		utils.Error(c, http.StatusConflict, utils.ERROR_DELETION_PENDING, nil)
+	case err != nil:
+		reqlog.Entry(c).Errorf("申请注销账号失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+	default:
+		utils.Success(c, deletion)
+	}
+}
+
+// CancelDeletion 撤销注销申请
+// @Summary 撤销注销申请
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} utils.Response{data=string}
+// @Router /profile/deletion [delete]
+func (p *ProfileApi) CancelDeletion(c *gin.Context) {
+	err := privacy.Cancel(global.DB.WithContext(c.Request.Context()), c.GetUint("userID"))
+	audit.Describe(c, "撤销注销申请", err)
+	switch {
+	case errors.Is(err, privacy.ErrNoRequest):
+		utils.Error(c, http.StatusNotFound, utils.ERROR_DELETION_NONE, nil)
+	case err != nil:
+		reqlog.Entry(c).Errorf("撤销注销申请失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+	default:
+		utils.Success(c, "已撤销注销申请")
+	}
+}
@@ -1,6 +1,9 @@
 package profile_api
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -9,6 +12,11 @@ import (
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
 	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/avatar"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/usersettings"
 )
 
 // GetUserInfoRequest 获取用户信息请求参数
@@ -38,11 +46,31 @@ type UserInfoResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 	// 角色列表
 	Roles []string `json:"roles"`
-	// 部门信息
+	// 主部门信息
 	Department *struct {
 		ID   uint   `json:"id"`
 		Name string `json:"name"`
 	} `json:"department,omitempty"`
+	// 所属部门，主部门在前
+	Departments []org.Membership `json:"departments"`
+	// 岗位名称列表
+	Positions []string `json:"positions"`
+	// 权限标识，包括通过用户组获得的权限，供前端控制按钮显示
+	Permissions []string `json:"permissions"`
+	// 是否为管理员模拟登录，前端应显示醒目提示
+	Impersonating bool `json:"impersonating"`
+	// 模拟登录信息，仅模拟登录时返回
+	Impersonator *ImpersonatorInfo `json:"impersonator,omitempty"`
+}
+
+// ImpersonatorInfo 发起模拟登录的管理员
+type ImpersonatorInfo struct {
+	// 管理员ID
+	ID uint `json:"id"`
+	// 管理员用户名
+	Username string `json:"username"`
+	// 模拟登录令牌过期时间
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // GetUserInfo 获取用户个人信息
@@ -61,7 +89,7 @@ func (p *ProfileApi) GetUserInfo(c *gin.Context) {
 	}
 
 	var user models.User
-	result := global.DB.Preload("Roles").First(&user, userID)
+	result := global.DB.WithContext(c.Request.Context()).Preload("Roles").First(&user, userID)
 	if result.Error != nil {
 		utils.Error(c, http.StatusInternalServerError, utils.ERROR_GET_USER, nil)
 		return
@@ -72,8 +100,8 @@ func (p *ProfileApi) GetUserInfo(c *gin.Context) {
 		ID:        user.ID,
 		Username:  user.Username,
 		Nickname:  user.Nickname,
-		Email:     user.Email,
-		Phone:     user.Phone,
+		Email:     string(user.Email),
+		Phone:     string(user.Phone),
 		Avatar:    user.Avatar,
 		Gender:    user.Gender,
 		Status:    user.Status,
@@ -86,15 +114,44 @@ func (p *ProfileApi) GetUserInfo(c *gin.Context) {
 	}
 
 	// 填充部门信息
-	if user.DeptID > 0 {
+	db := global.DB.WithContext(c.Request.Context())
+	if user.DepartmentID > 0 {
 		var dept models.Department
-		if err := global.DB.First(&dept, user.DeptID).Error; err == nil {
+		if err := db.First(&dept, user.DepartmentID).Error; err == nil {
 			resp.Department = &struct {
 				ID   uint   `json:"id"`
 				Name string `json:"name"`
 			}{dept.ID, dept.Name}
 		}
 	}
+	memberships, err := org.Memberships(db, user.ID)
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取所属部门失败: %v", err)
+	}
+	resp.Departments = memberships
+
+	// 填充岗位信息
+	positions, err := org.Positions(db, user.ID)
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取岗位失败: %v", err)
+	}
+	resp.Positions = make([]string, 0, len(positions))
+	for _, position := range positions {
+		resp.Positions = append(resp.Positions, position.Name)
+	}
+
+	// 填充权限标识
+	resp.Permissions, err = permcache.Keys(db, user.ID)
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取权限标识失败: %v", err)
+		resp.Permissions = []string{}
+	}
+
+	// 填充模拟登录信息
+	if id := c.GetUint("impersonatorID"); id != 0 {
+		resp.Impersonating = true
+		resp.Impersonator = &ImpersonatorInfo{ID: id, Username: c.GetString("impersonatorName"), ExpiresAt: c.GetTime("tokenExpiresAt")}
+	}
 
 	utils.Success(c, resp)
 }
@@ -104,9 +161,9 @@ func (p *ProfileApi) GetUserInfo(c *gin.Context) {
 type UpdateUserInfoRequest struct {
 	// 昵称
 	Nickname string `json:"nickname" validate:"max=50"`
-	// 邮箱
+	// 邮箱，只能通过 /profile/email/change 验证后修改，提交不同的值会被拒绝
 	Email string `json:"email" validate:"email,max=100"`
-	// 手机号
+	// 手机号，只能通过 /profile/phone/change 验证后修改，提交不同的值会被拒绝
 	Phone string `json:"phone" validate:"max=20"`
 	// 头像
 	Avatar string `json:"avatar" validate:"max=255"`
@@ -143,11 +200,24 @@ func (p *ProfileApi) UpdateUserInfo(c *gin.Context) {
 		return
 	}
 
+	// 邮箱和手机号是登录和找回密码的凭据，需验证新地址后才能修改
+	var user models.User
+	if err := global.DB.WithContext(c.Request.Context()).First(&user, userID).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR_GET_USER, nil)
+		return
+	}
+	if req.Email != "" && req.Email != string(user.Email) {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, "修改邮箱请通过 /profile/email/change 验证新邮箱")
+		return
+	}
+	if req.Phone != "" && req.Phone != string(user.Phone) {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, "修改手机号请通过 /profile/phone/change 验证新手机号")
+		return
+	}
+
 	// 更新用户信息
-	result := global.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+	result := global.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
 		"nickname": req.Nickname,
-		"email":    req.Email,
-		"phone":    req.Phone,
 		"avatar":   req.Avatar,
 		"gender":   req.Gender,
 	})
@@ -202,7 +272,7 @@ func (p *ProfileApi) UpdatePassword(c *gin.Context) {
 
 	// 查询用户
 	var user models.User
-	result := global.DB.First(&user, userID)
+	result := global.DB.WithContext(c.Request.Context()).First(&user, userID)
 	if result.Error != nil {
 		utils.Error(c, http.StatusInternalServerError, utils.ERROR_GET_USER, nil)
 		return
@@ -222,7 +292,7 @@ func (p *ProfileApi) UpdatePassword(c *gin.Context) {
 	}
 
 	// 更新密码
-	result = global.DB.Model(&user).Update("password", passwordHash)
+	result = global.DB.WithContext(c.Request.Context()).Model(&user).Update("password", passwordHash)
 	if result.Error != nil {
 		utils.Error(c, http.StatusInternalServerError, utils.ERROR_UPDATE_USER, nil)
 		return
@@ -271,39 +341,39 @@ func (p *ProfileApi) GetDashboardData(c *gin.Context) {
 
 	// 获取用户总数
 	var totalUsers int64
-	global.DB.Model(&models.User{}).Count(&totalUsers)
+	global.DB.WithContext(c.Request.Context()).Model(&models.User{}).Count(&totalUsers)
 	dashboardData.TotalUsers = int(totalUsers)
 
 	// 获取角色总数
 	var totalRoles int64
-	global.DB.Model(&models.Role{}).Count(&totalRoles)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Role{}).Count(&totalRoles)
 	dashboardData.TotalRoles = int(totalRoles)
 
 	// 获取部门总数
 	var totalDepartments int64
-	global.DB.Model(&models.Department{}).Count(&totalDepartments)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Department{}).Count(&totalDepartments)
 	dashboardData.TotalDepartments = int(totalDepartments)
 
 	// 获取菜单总数
 	var totalMenus int64
-	global.DB.Model(&models.Menu{}).Count(&totalMenus)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Menu{}).Count(&totalMenus)
 	dashboardData.TotalMenus = int(totalMenus)
 
 	// 获取权限总数
 	var totalPermissions int64
-	global.DB.Model(&models.Permission{}).Count(&totalPermissions)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Permission{}).Count(&totalPermissions)
 	dashboardData.TotalPermissions = int(totalPermissions)
 
 	// 获取文件总数
 	var totalFiles int64
-	global.DB.Model(&models.File{}).Count(&totalFiles)
+	global.DB.WithContext(c.Request.Context()).Model(&models.File{}).Count(&totalFiles)
 	dashboardData.TotalFiles = int(totalFiles)
 
 	// 获取今日登录次数
 	var todayLogins int64
 	now := time.Now()
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	global.DB.Model(&models.Log{}).Where("created_at >= ? AND action = ?", todayStart, "login").Count(&todayLogins)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Log{}).Where("created_at >= ? AND action = ?", todayStart, "login").Count(&todayLogins)
 	dashboardData.TodayLogins = int(todayLogins)
 
 	// 计算系统运行时间（简化处理，返回0）
@@ -312,116 +382,189 @@ func (p *ProfileApi) GetDashboardData(c *gin.Context) {
 	utils.Success(c, dashboardData)
 }
 
-// UserSettingsResponse 用户设置响应
-// swagger:model UserSettingsResponse
-type UserSettingsResponse struct {
-	// 主题设置 (light, dark, auto)
-	Theme string `json:"theme"`
-	// 语言设置 (zh-CN, en-US)
-	Language string `json:"language"`
-	// 布局设置
-	Layout string `json:"layout"`
-	// 侧边栏折叠状态
-	SidebarCollapsed bool `json:"sidebar_collapsed"`
-	// 通知设置
-	Notifications map[string]bool `json:"notifications"`
+// GetUserPermissions 获取当前用户的权限标识
+// @Summary 获取当前用户权限标识
+// @Description 返回当前用户通过角色和用户组(含上级用户组)拥有的启用权限标识和菜单按钮标识，如system:user:delete，前端据此控制按钮显示，与用户菜单接口的buttons相同
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} utils.Response{data=[]string}
+// @Router /profile/permissions [get]
+func (p *ProfileApi) GetUserPermissions(c *gin.Context) {
+	// 从上下文获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
+		return
+	}
+
+	keys, err := permcache.Keys(global.DB.WithContext(c.Request.Context()), userID.(uint))
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取权限标识失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+		return
+	}
+
+	utils.Success(c, keys)
 }
 
 // GetUserSettings 获取用户设置
 // @Summary 获取用户设置
+// @Description 返回系统默认设置与用户修改合并后的生效设置
 // @Tags 个人信息管理
 // @Security ApiKeyAuth
 // @Produce json
-// @Success 200 {object} utils.Response{data=UserSettingsResponse}
+// @Success 200 {object} utils.Response{data=usersettings.Settings}
 // @Router /profile/settings [get]
 func (p *ProfileApi) GetUserSettings(c *gin.Context) {
 	// 从上下文获取用户ID
-	_, exists := c.Get("userID")
+	userID, exists := c.Get("userID")
 	if !exists {
 		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
 		return
 	}
 
-	// 这里简化处理，实际应该从数据库或缓存获取用户设置
-	// 为了演示，返回默认设置
-	settings := UserSettingsResponse{
-		Theme:            "light",
-		Language:         "zh-CN",
-		Layout:           "default",
-		SidebarCollapsed: false,
-		Notifications: map[string]bool{
-			"email":  true,
-			"sms":    false,
-			"push":   true,
-			"system": true,
-		},
-	}
-
-	// 实际项目中，应该查询用户设置表
-	// var userSettings models.UserSettings
-	// err := global.DB.Where("user_id = ?", userID).First(&userSettings).Error
-	// if err == nil {
-	//     // 填充设置数据
-	// }
+	settings, err := usersettings.Get(global.DB.WithContext(c.Request.Context()), userID.(uint))
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取用户设置失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+		return
+	}
 
 	utils.Success(c, settings)
 }
 
 // UpdateUserSettingsRequest 更新用户设置请求参数
+// 只需提交要修改的项，值为null表示恢复系统默认值
 // swagger:model UpdateUserSettingsRequest
 type UpdateUserSettingsRequest struct {
 	// 主题设置 (light, dark, auto)
-	Theme string `json:"theme" validate:"oneof=light dark auto"`
+	Theme *string `json:"theme"`
 	// 语言设置 (zh-CN, en-US)
-	Language string `json:"language" validate:"oneof=zh-CN en-US"`
+	Language *string `json:"language"`
 	// 布局设置
-	Layout string `json:"layout"`
+	Layout *string `json:"layout"`
 	// 侧边栏折叠状态
-	SidebarCollapsed bool `json:"sidebar_collapsed"`
-	// 通知设置
-	Notifications map[string]bool `json:"notifications"`
+	SidebarCollapsed *bool `json:"sidebar_collapsed"`
+	// 通知设置，按类型合并 (email, sms, push, system)
+	Notifications map[string]*bool `json:"notifications"`
 }
 
 // UpdateUserSettings 更新用户设置
 // @Summary 更新用户设置
+// @Description 按JSON Merge Patch合并修改，未提交的项保持不变
 // @Tags 个人信息管理
 // @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param data body UpdateUserSettingsRequest true "设置信息"
-// @Success 200 {object} utils.Response{data=string}
-// @Router /profile/settings [put]
+// @Success 200 {object} utils.Response{data=usersettings.Settings}
+// @Router /profile/settings [patch]
 func (p *ProfileApi) UpdateUserSettings(c *gin.Context) {
 	// 从上下文获取用户ID
-	_, exists := c.Get("userID")
+	userID, exists := c.Get("userID")
 	if !exists {
 		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
 		return
 	}
 
-	// 绑定请求参数
-	var req UpdateUserSettingsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// 参数验证
-		if errs, ok := err.(validator.ValidationErrors); ok {
-			utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, utils.GetValidationError(errs))
+	patch, err := c.GetRawData()
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+
+	settings, err := usersettings.Update(global.DB.WithContext(c.Request.Context()), userID.(uint), patch)
+	if err != nil {
+		if errors.Is(err, usersettings.ErrInvalid) {
+			utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, err.Error())
 			return
 		}
-		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		reqlog.Entry(c).Errorf("更新用户设置失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+		return
+	}
+
+	utils.Success(c, settings)
+}
+
+// ResetUserSettings 重置用户设置
+// @Summary 重置用户设置
+// @Description 清除用户的全部修改，恢复系统默认设置
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} utils.Response{data=usersettings.Settings}
+// @Router /profile/settings [delete]
+func (p *ProfileApi) ResetUserSettings(c *gin.Context) {
+	// 从上下文获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
 		return
 	}
 
-	// 这里简化处理，实际应该更新数据库或缓存中的用户设置
-	// 为了演示，直接返回成功
+	settings, err := usersettings.Reset(global.DB.WithContext(c.Request.Context()), userID.(uint))
+	if err != nil {
+		reqlog.Entry(c).Errorf("重置用户设置失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR, nil)
+		return
+	}
 
-	// 实际项目中，应该更新用户设置表
-	// var userSettings models.UserSettings
-	// err := global.DB.Where("user_id = ?", userID).First(&userSettings).Error
-	// if err == gorm.ErrRecordNotFound {
-	//     // 创建新的设置记录
-	// } else if err == nil {
-	//     // 更新现有设置
-	// }
+	utils.Success(c, settings)
+}
 
-	utils.Success(c, "设置更新成功")
-}
\ No newline at end of file
+// UploadAvatar 上传头像
+// @Summary 上传头像
+// @Description 按文件头校验图片类型(jpeg, png, gif, webp)，去除EXIF后居中裁剪为正方形并生成多种尺寸的PNG，替换原头像
+// @Tags 个人信息管理
+// @Security ApiKeyAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "头像图片"
+// @Success 200 {object} utils.Response{data=avatar.Result}
+// @Router /profile/avatar [post]
+func (p *ProfileApi) UploadAvatar(c *gin.Context) {
+	// 从上下文获取用户ID
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.Error(c, http.StatusUnauthorized, utils.ERROR_UNAUTHORIZED, nil)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, "头像文件不能为空")
+		return
+	}
+	maxSize := int64(global.Config.Avatar.MaxSize) << 20
+	if maxSize > 0 && file.Size > maxSize {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, fmt.Sprintf("头像不能超过 %dMB", global.Config.Avatar.MaxSize))
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, nil)
+		return
+	}
+
+	result, err := avatar.Save(global.DB.WithContext(c.Request.Context()), userID.(uint), data)
+	if err != nil {
+		if errors.Is(err, avatar.ErrInvalidImage) {
+			utils.Error(c, http.StatusBadRequest, utils.ERROR_INVALID_PARAM, err.Error())
+			return
+		}
+		reqlog.Entry(c).Errorf("保存头像失败: %v", err)
+		utils.Error(c, http.StatusInternalServerError, utils.ERROR_UPDATE_USER, nil)
+		return
+	}
+
+	reqlog.Entry(c).Infof("头像更新成功: %s", result.Avatar)
+	utils.Success(c, result)
+}
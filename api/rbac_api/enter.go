@@ -0,0 +1,21 @@
+package rbac_api
+
+import "github.com/gin-gonic/gin"
+
+// RbacApi RBAC配置快照API结构体
+type RbacApi struct{}
+
+// NewRbacApi 创建RBAC配置快照API实例
+func NewRbacApi() *RbacApi {
+	return &RbacApi{}
+}
+
+// RegisterRoutes 注册RBAC配置快照API路由
+func (r *RbacApi) RegisterRoutes(router *gin.RouterGroup) {
+	rbacRouter := router.Group("/rbac")
+	{
+		rbacRouter.GET("/export", r.ExportSnapshot)
+		rbacRouter.POST("/diff", r.DiffSnapshot)
+		rbacRouter.POST("/import", r.ImportSnapshot)
+	}
+}
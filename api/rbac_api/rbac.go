@@ -0,0 +1,154 @@
+package rbac_api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/rbacsync"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// maxSnapshotSize 快照文件大小上限
+const maxSnapshotSize = 20 << 20
+
+// ExportSnapshot 导出RBAC配置快照
+// @Summary 导出RBAC配置快照接口
+// @Description 导出用户(不含密码)、角色、权限、角色权限、菜单、部门和字典为一个带版本号的文件
+// @Tags RBAC配置
+// @Produce octet-stream
+// @Param format query string false "导出格式: yaml, json"
+// @Success 200 {file} file "快照文件"
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/rbac/export [get]
+func (r *RbacApi) ExportSnapshot(c *gin.Context) {
+	format := c.DefaultQuery("format", rbacsync.FormatYAML)
+	if format != rbacsync.FormatYAML && format != rbacsync.FormatJSON {
+		c.JSON(400, gin.H{"code": 400, "msg": "不支持的快照格式"})
+		return
+	}
+
+	snap, err := rbacsync.Export(global.DB.WithContext(c.Request.Context()))
+	if err == nil {
+		var data []byte
+		if data, err = rbacsync.Encode(snap, format); err == nil {
+			filename := fmt.Sprintf("rbac_%s.%s", time.Now().Format("20060102150405"), format)
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			c.Data(200, "application/"+format+"; charset=utf-8", data)
+		}
+	}
+	if err != nil {
+		reqlog.Entry(c).Errorf("导出RBAC配置快照失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "导出失败: " + err.Error()})
+	}
+	audit.RecordOperation(c, "rbac", "export", "导出RBAC配置快照，格式 "+format, err)
+}
+
+// DiffSnapshot 预览RBAC配置快照导入计划
+// @Summary 预览快照导入计划接口
+// @Description 比较快照和当前数据库，按标识匹配，返回将要新增和更新的内容，不修改数据
+// @Tags RBAC配置
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file false "快照文件，也可以直接作为请求体提交"
+// @Param format query string false "快照格式: yaml, json，默认按文件名判断"
+// @Param prune query bool false "删除快照分组中未列出的实体"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":rbacsync.Plan}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/rbac/diff [post]
+func (r *RbacApi) DiffSnapshot(c *gin.Context) {
+	snap, err := readSnapshot(c)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	plan, err := rbacsync.Diff(global.DB.WithContext(c.Request.Context()), snap, snapshotOptions(c))
+	if err != nil {
+		reqlog.Entry(c).Errorf("生成导入计划失败: %v", err)
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": plan.String(), "data": plan})
+}
+
+// ImportSnapshot 导入RBAC配置快照
+// @Summary 导入RBAC配置快照接口
+// @Description 在一个事务中按导入计划写入快照，任一项失败时全部回滚；新建用户返回随机初始密码
+// @Tags RBAC配置
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file false "快照文件，也可以直接作为请求体提交"
+// @Param format query string false "快照格式: yaml, json，默认按文件名判断"
+// @Param prune query bool false "删除快照分组中未列出的实体"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":rbacsync.Result}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/rbac/import [post]
+func (r *RbacApi) ImportSnapshot(c *gin.Context) {
+	snap, err := readSnapshot(c)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	result, err := rbacsync.Apply(global.DB.WithContext(c.Request.Context()), snap, snapshotOptions(c))
+	if err != nil {
+		reqlog.Entry(c).Errorf("导入RBAC配置快照失败: %v", err)
+		audit.Describe(c, "导入RBAC配置快照", err)
+		c.JSON(400, gin.H{"code": 400, "msg": "导入失败，已回滚: " + err.Error()})
+		return
+	}
+	audit.Describe(c, "导入RBAC配置快照: "+result.Plan.String(), nil)
+	c.JSON(200, gin.H{"code": 200, "msg": "导入成功: " + result.Plan.String(), "data": result})
+}
+
+// snapshotOptions 解析导入选项
+func snapshotOptions(c *gin.Context) rbacsync.Options {
+	prune, _ := strconv.ParseBool(c.Query("prune"))
+	return rbacsync.Options{Prune: prune}
+}
+
+// readSnapshot 从上传文件或请求体读取快照
+func readSnapshot(c *gin.Context) (*rbacsync.Snapshot, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSnapshotSize)
+
+	var (
+		data   []byte
+		err    error
+		format = c.Query("format")
+	)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _ := c.FormFile("file")
+		if file == nil {
+			return nil, fmt.Errorf("文件不能为空")
+		}
+		if format == "" {
+			format = rbacsync.FormatOf(file.Filename)
+		}
+		src, openErr := file.Open()
+		if openErr != nil {
+			return nil, fmt.Errorf("读取文件失败")
+		}
+		defer src.Close()
+		data, err = io.ReadAll(src)
+	} else {
+		if format == "" && strings.Contains(c.ContentType(), "json") {
+			format = rbacsync.FormatJSON
+		}
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取快照失败: %v", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("快照内容不能为空")
+	}
+	return rbacsync.Decode(data, format)
+}
@@ -0,0 +1,22 @@
+package recycle_api
+
+import "github.com/gin-gonic/gin"
+
+// RecycleApi 回收站API结构体
+type RecycleApi struct{}
+
+// NewRecycleApi 创建回收站API实例
+func NewRecycleApi() *RecycleApi {
+	return &RecycleApi{}
+}
+
+// RegisterRoutes 注册回收站API路由
+func (r *RecycleApi) RegisterRoutes(router *gin.RouterGroup) {
+	recycleRouter := router.Group("/recycle")
+	{
+		recycleRouter.GET("/entities", r.GetEntities)
+		recycleRouter.GET("/:entity", r.GetDeletedList)
+		recycleRouter.POST("/:entity/restore", r.Restore)
+		recycleRouter.POST("/:entity/purge", r.Purge)
+	}
+}
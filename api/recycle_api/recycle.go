@@ -0,0 +1,181 @@
+package recycle_api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
+)
+
+// maxBatchIDs 单次恢复或彻底删除的最大记录数
+const maxBatchIDs = 100
+
+// GetEntities 获取支持回收站的实体
+// @Summary 获取回收站实体接口
+// @Description 返回支持查看、恢复和彻底删除的实体类型
+// @Tags 回收站
+// @Produce json
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]recycle.Entity}
+// @Router /admin/recycle/entities [get]
+func (r *RecycleApi) GetEntities(c *gin.Context) {
+	c.JSON(200, gin.H{"code": 200, "msg": "获取成功", "data": recycle.Entities()})
+}
+
+// GetDeletedList 获取已删除记录列表
+// @Summary 获取回收站列表接口
+// @Description 分页查询指定实体已删除的记录
+// @Tags 回收站
+// @Produce json
+// @Param entity path string true "实体类型: user, role, permission, menu, department, dict, file"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段: id, deleted_at，前缀-表示降序"
+// @Param keyword query string false "名称关键字"
+// @Param deleted_at query string false "删除日期区间，如 2024-01-01,2024-01-31"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/recycle/{entity} [get]
+func (r *RecycleApi) GetDeletedList(c *gin.Context) {
+	entity, ok := lookupEntity(c)
+	if !ok {
+		return
+	}
+	q, err := search.Parse(c, entity.Spec())
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	page, err := entity.List(global.DB.WithContext(c.Request.Context()), q)
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取回收站%s列表失败: %v", entity.Label, err)
+		c.JSON(500, gin.H{"code": 500, "msg": "获取回收站列表失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "获取成功", "data": page})
+}
+
+// Restore 恢复已删除记录
+// @Summary 恢复回收站记录接口
+// @Description 逐条恢复记录并重建删除时解除的关联；唯一值已被占用或上级已删除的记录不会恢复，在failed中返回原因
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Param entity path string true "实体类型"
+// @Param restore body struct{IDs []uint} true "记录ID列表"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"restored":[]recycle.Restored, "failed":map[uint]string}}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 409 {object} gin.H{"code":int, "msg":string, "data":gin.H}
+// @Router /admin/recycle/{entity}/restore [post]
+func (r *RecycleApi) Restore(c *gin.Context) {
+	entity, ok := lookupEntity(c)
+	if !ok {
+		return
+	}
+	ids, ok := bindIDs(c)
+	if !ok {
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	restored := []*recycle.Restored{}
+	failed := make(map[uint]string)
+	for _, id := range ids {
+		result, err := entity.Restore(db, id)
+		if err != nil {
+			var conflict *recycle.ConflictError
+			if !errors.Is(err, recycle.ErrNotDeleted) && !errors.As(err, &conflict) {
+				reqlog.Entry(c).Errorf("恢复%s(ID %d)失败: %v", entity.Label, id, err)
+			}
+			failed[id] = err.Error()
+			continue
+		}
+		restored = append(restored, result)
+	}
+
+	desc := fmt.Sprintf("恢复%s %d 条，失败 %d 条", entity.Label, len(restored), len(failed))
+	audit.Describe(c, desc+": "+joinIDs(ids), nil)
+
+	data := gin.H{"restored": restored, "failed": failed}
+	if len(restored) == 0 {
+		c.JSON(409, gin.H{"code": 409, "msg": "恢复失败", "data": data})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": desc, "data": data})
+}
+
+// Purge 彻底删除记录
+// @Summary 彻底删除回收站记录接口
+// @Description 彻底删除回收站中的记录，不可恢复；文件会同时删除物理文件
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Param entity path string true "实体类型"
+// @Param purge body struct{IDs []uint} true "记录ID列表"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"purged":int}}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/recycle/{entity}/purge [post]
+func (r *RecycleApi) Purge(c *gin.Context) {
+	entity, ok := lookupEntity(c)
+	if !ok {
+		return
+	}
+	ids, ok := bindIDs(c)
+	if !ok {
+		return
+	}
+
+	purged, err := entity.Purge(global.DB.WithContext(c.Request.Context()), ids)
+	audit.Describe(c, fmt.Sprintf("彻底删除%s %d 条: %s", entity.Label, purged, joinIDs(ids)), err)
+	if err != nil {
+		reqlog.Entry(c).Errorf("彻底删除%s失败: %v", entity.Label, err)
+		c.JSON(500, gin.H{"code": 500, "msg": "彻底删除失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": fmt.Sprintf("已彻底删除 %d 条", purged), "data": gin.H{"purged": purged}})
+}
+
+// lookupEntity 解析路径中的实体类型
+func lookupEntity(c *gin.Context) (*recycle.Entity, bool) {
+	entity, ok := recycle.Lookup(c.Param("entity"))
+	if !ok {
+		c.JSON(404, gin.H{"code": 404, "msg": "不支持的实体类型"})
+	}
+	return entity, ok
+}
+
+// bindIDs 绑定记录ID列表
+func bindIDs(c *gin.Context) ([]uint, bool) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return nil, false
+	}
+	if len(req.IDs) > maxBatchIDs {
+		c.JSON(400, gin.H{"code": 400, "msg": fmt.Sprintf("单次最多处理 %d 条", maxBatchIDs)})
+		return nil, false
+	}
+	return req.IDs, true
+}
+
+func joinIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,170 @@
+package registration_api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/email"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
+	"rbac_admin_server/utils/userstate"
+)
+
+// maxBatchIDs 单次审核的最大用户数
+const maxBatchIDs = 100
+
+// pendingListSpec 待审核列表允许的筛选和排序字段
+var pendingListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"username":      {Column: "username", Op: search.OpLike},
+		"email":         {Column: "email", Op: search.OpLike},
+		"invitation_id": {Column: "invitation_id", Op: search.OpEq},
+		"created_at":    {Column: "created_at", Op: search.OpDateRange},
+	},
+	Sorts:       map[string]string{"id": "id", "created_at": "created_at"},
+	DefaultSort: "created_at ASC",
+}
+
+// GetPendingList 获取待审核的注册申请
+// @Summary 获取待审核注册列表接口
+// @Description 分页查询待激活的用户，审核模式下自助注册的用户在此等待审核
+// @Tags 注册管理
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param username query string false "用户名"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/registration/pending [get]
+func (r *RegistrationApi) GetPendingList(c *gin.Context) {
+	q, err := search.Parse(c, pendingListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	var users []models.User
+	db := global.DB.WithContext(c.Request.Context()).Model(&models.User{}).
+		Preload("Roles").Where("status = ?", models.UserStatusPending)
+	page, err := q.Find(db, &users)
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取待审核列表失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "获取待审核列表失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "获取成功", "data": page})
+}
+
+// Approve 审核通过注册申请
+// @Summary 审核通过接口
+// @Description 将待激活用户启用，邮箱已配置时通知用户
+// @Tags 注册管理
+// @Accept json
+// @Produce json
+// @Param approve body struct{IDs []uint} true "用户ID列表"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"approved":[]uint, "failed":map[uint]string}}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/registration/approve [post]
+func (r *RegistrationApi) Approve(c *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) > maxBatchIDs {
+		c.JSON(400, gin.H{"code": 400, "msg": fmt.Sprintf("参数错误，单次最多处理 %d 条", maxBatchIDs)})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	approved := []uint{}
+	failed := make(map[uint]string)
+	for _, id := range req.IDs {
+		user, err := pendingUser(db, id)
+		if err == nil {
+			err = userstate.Transition(db, user, models.UserStatusActive, "", nil)
+		}
+		if err != nil {
+			failed[id] = err.Error()
+			continue
+		}
+		approved = append(approved, id)
+		go notify(user, "注册审核通过", fmt.Sprintf("您的账号 %s 已通过审核，现在可以登录了。", user.Username))
+	}
+
+	desc := fmt.Sprintf("审核通过 %d 个注册申请，失败 %d 个", len(approved), len(failed))
+	audit.Describe(c, fmt.Sprintf("%s: %v", desc, approved), nil)
+	c.JSON(200, gin.H{"code": 200, "msg": desc, "data": gin.H{"approved": approved, "failed": failed}})
+}
+
+// Reject 拒绝注册申请
+// @Summary 拒绝注册申请接口
+// @Description 将待激活用户移入回收站，释放其用户名、邮箱和手机号；邮箱已配置时通知用户
+// @Tags 注册管理
+// @Accept json
+// @Produce json
+// @Param reject body struct{IDs []uint, Reason string} true "用户ID列表和拒绝原因"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"rejected":[]uint, "failed":map[uint]string}}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/registration/reject [post]
+func (r *RegistrationApi) Reject(c *gin.Context) {
+	var req struct {
+		IDs    []uint `json:"ids" binding:"required,min=1"`
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs) > maxBatchIDs {
+		c.JSON(400, gin.H{"code": 400, "msg": fmt.Sprintf("参数错误，单次最多处理 %d 条", maxBatchIDs)})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	rejected := []uint{}
+	failed := make(map[uint]string)
+	for _, id := range req.IDs {
+		user, err := pendingUser(db, id)
+		if err == nil {
+			err = recycle.Delete(db, "user", id)
+		}
+		if err != nil {
+			failed[id] = err.Error()
+			continue
+		}
+		rejected = append(rejected, id)
+		content := fmt.Sprintf("很抱歉，您的账号 %s 未通过注册审核。", user.Username)
+		if req.Reason != "" {
+			content += "原因: " + req.Reason
+		}
+		go notify(user, "注册审核未通过", content)
+	}
+
+	desc := fmt.Sprintf("拒绝 %d 个注册申请，失败 %d 个", len(rejected), len(failed))
+	audit.Describe(c, fmt.Sprintf("%s: %v %s", desc, rejected, req.Reason), nil)
+	c.JSON(200, gin.H{"code": 200, "msg": desc, "data": gin.H{"rejected": rejected, "failed": failed}})
+}
+
+// pendingUser 查询待激活的用户
+func pendingUser(db *gorm.DB, id uint) (*models.User, error) {
+	var user models.User
+	if err := db.First(&user, id).Error; err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+	if user.Status != models.UserStatusPending {
+		return nil, fmt.Errorf("用户状态为%s，不是待审核", userstate.Name(user.Status))
+	}
+	return &user, nil
+}
+
+// notify 向用户邮箱发送审核结果，邮箱未配置时跳过
+func notify(user *models.User, subject, content string) {
+	if user.Email == "" || !global.Config.Email.Verify() {
+		return
+	}
+	if err := email.SendEmail(string(user.Email), subject, content); err != nil {
+		global.Logger.Warnf("发送审核结果通知失败: %v", err)
+	}
+}
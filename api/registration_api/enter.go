@@ -0,0 +1,25 @@
+package registration_api
+
+import "github.com/gin-gonic/gin"
+
+// RegistrationApi 注册管理API结构体
+// 管理注册邀请码和待审核的注册申请
+type RegistrationApi struct{}
+
+// NewRegistrationApi 创建注册管理API实例
+func NewRegistrationApi() *RegistrationApi {
+	return &RegistrationApi{}
+}
+
+// RegisterRoutes 注册注册管理API路由
+func (r *RegistrationApi) RegisterRoutes(router *gin.RouterGroup) {
+	registrationRouter := router.Group("/registration")
+	{
+		registrationRouter.GET("/invitations", r.GetInvitationList)
+		registrationRouter.POST("/invitations", r.CreateInvitation)
+		registrationRouter.PUT("/invitations/:id/revoke", r.RevokeInvitation)
+		registrationRouter.GET("/pending", r.GetPendingList)
+		registrationRouter.POST("/approve", r.Approve)
+		registrationRouter.POST("/reject", r.Reject)
+	}
+}
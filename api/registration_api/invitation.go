@@ -0,0 +1,165 @@
+package registration_api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/registration"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
+)
+
+// invitationListSpec 邀请码列表允许的筛选和排序字段
+var invitationListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"code":       {Column: "code", Op: search.OpEq},
+		"status":     {Column: "status", Op: search.OpEq},
+		"role_id":    {Column: "role_id", Op: search.OpEq},
+		"created_by": {Column: "created_by", Op: search.OpEq},
+		"created_at": {Column: "created_at", Op: search.OpDateRange},
+	},
+	Sorts:       map[string]string{"id": "id", "created_at": "created_at", "expires_at": "expires_at"},
+	DefaultSort: "id DESC",
+}
+
+// GetInvitationList 获取邀请码列表
+// @Summary 获取邀请码列表接口
+// @Description 分页查询注册邀请码
+// @Tags 注册管理
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param status query int false "状态(1:可用,2:已作废)"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/registration/invitations [get]
+func (r *RegistrationApi) GetInvitationList(c *gin.Context) {
+	q, err := search.Parse(c, invitationListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	var list []models.Invitation
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Invitation{}), &list)
+	if err != nil {
+		reqlog.Entry(c).Errorf("获取邀请码列表失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "获取邀请码列表失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "获取成功", "data": page})
+}
+
+// CreateInvitation 创建邀请码
+// @Summary 创建邀请码接口
+// @Description 创建注册邀请码，可限制使用次数和有效期，并预设注册后的角色和部门
+// @Tags 注册管理
+// @Accept json
+// @Produce json
+// @Param invitation body struct{MaxUses int, ExpiresAt string, RoleID uint, DepartmentID uint, Remark string} true "邀请码信息，max_uses为0表示不限次数"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":models.Invitation}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/registration/invitations [post]
+func (r *RegistrationApi) CreateInvitation(c *gin.Context) {
+	var req struct {
+		MaxUses      *int       `json:"max_uses"`
+		ExpiresAt    *time.Time `json:"expires_at"`
+		RoleID       uint       `json:"role_id"`
+		DepartmentID uint       `json:"department_id"`
+		Remark       string     `json:"remark" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+	if req.MaxUses != nil && *req.MaxUses < 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "使用次数不能为负数"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(400, gin.H{"code": 400, "msg": "过期时间必须晚于当前时间"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	if req.RoleID != 0 {
+		if err := db.First(&models.Role{}, req.RoleID).Error; err != nil {
+			c.JSON(400, gin.H{"code": 400, "msg": "角色不存在"})
+			return
+		}
+	}
+	if req.DepartmentID != 0 {
+		if err := db.First(&models.Department{}, req.DepartmentID).Error; err != nil {
+			c.JSON(400, gin.H{"code": 400, "msg": "部门不存在"})
+			return
+		}
+	}
+
+	code, err := registration.NewCode()
+	if err != nil {
+		reqlog.Entry(c).Errorf("生成邀请码失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "生成邀请码失败"})
+		return
+	}
+	inv := models.Invitation{
+		Code:         code,
+		MaxUses:      1,
+		ExpiresAt:    req.ExpiresAt,
+		RoleID:       req.RoleID,
+		DepartmentID: req.DepartmentID,
+		Status:       models.InvitationStatusActive,
+		Remark:       req.Remark,
+		CreatedBy:    c.GetUint("userID"),
+	}
+	if req.MaxUses != nil {
+		inv.MaxUses = *req.MaxUses
+	}
+	err = db.Create(&inv).Error
+	audit.Describe(c, fmt.Sprintf("创建邀请码 %s，可用 %d 次", inv.Code, inv.MaxUses), err)
+	if err != nil {
+		reqlog.Entry(c).Errorf("创建邀请码失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "创建邀请码失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "创建成功", "data": inv})
+}
+
+// RevokeInvitation 作废邀请码
+// @Summary 作废邀请码接口
+// @Description 作废后邀请码不能再用于注册，已注册的用户不受影响
+// @Tags 注册管理
+// @Produce json
+// @Param id path int true "邀请码ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/registration/invitations/{id}/revoke [put]
+func (r *RegistrationApi) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	res := global.DB.WithContext(c.Request.Context()).Model(&models.Invitation{}).
+		Where("id = ?", id).Update("status", models.InvitationStatusRevoked)
+	audit.Describe(c, fmt.Sprintf("作废邀请码 ID %d", id), res.Error)
+	if res.Error != nil {
+		reqlog.Entry(c).Errorf("作废邀请码失败: %v", res.Error)
+		c.JSON(500, gin.H{"code": 500, "msg": "作废邀请码失败"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(404, gin.H{"code": 404, "msg": "邀请码不存在"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "已作废"})
+}
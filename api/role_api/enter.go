@@ -20,6 +20,8 @@ func (r *RoleApi) RegisterRoutes(router *gin.RouterGroup) {
 		roleRouter.DELETE("/delete", r.DeleteRole)
 		roleRouter.GET("/permissions", r.GetRolePermissions)
 		roleRouter.POST("/set-permissions", r.SetRolePermissions)
+		roleRouter.GET("/menus", r.GetRoleMenus)
+		roleRouter.POST("/set-menus", r.SetRoleMenus)
 		roleRouter.GET("/users", r.GetRoleUsers)
 	}
 }
\ No newline at end of file
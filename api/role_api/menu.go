@@ -0,0 +1,136 @@
+package role_api
+
+import (
+	"errors"
+	"strconv"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/reqlog"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errMenuNotFound 分配的菜单不存在
+var errMenuNotFound = errors.New("菜单不存在")
+
+// SetMenusRequest 设置角色菜单请求参数
+type SetMenusRequest struct {
+	// 角色ID
+	RoleID uint `json:"role_id" binding:"required"`
+	// 菜单ID，包括目录、菜单和按钮，为空表示清除菜单
+	MenuIDs []uint `json:"menu_ids" binding:"max=1000"`
+}
+
+// GetRoleMenus 获取角色菜单
+// @Summary 获取角色菜单接口
+// @Description 查询指定角色分配的菜单ID列表，包括目录、菜单和按钮
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param role_id query int true "角色ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]int}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/role/menus [get]
+func (r *RoleApi) GetRoleMenus(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Query("role_id"), 10, 64)
+	if err != nil || roleID == 0 {
+		reqlog.Entry(c).Error("获取角色菜单参数错误: 角色ID无效")
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	menuIDs := []uint{}
+	if err := global.DB.WithContext(c.Request.Context()).Model(&models.RoleMenu{}).
+		Joins("JOIN menus ON menus.id = role_menus.menu_id AND menus.deleted_at IS NULL").
+		Where("role_menus.role_id = ?", roleID).Order("role_menus.menu_id").
+		Pluck("role_menus.menu_id", &menuIDs).Error; err != nil {
+		reqlog.Entry(c).Error("获取角色菜单失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": menuIDs,
+	})
+}
+
+// SetRoleMenus 设置角色菜单
+// @Summary 设置角色菜单接口
+// @Description 替换角色分配的菜单，用户登录后按角色生成前端路由和按钮权限
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param data body SetMenusRequest true "角色ID和菜单ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/role/set-menus [post]
+func (r *RoleApi) SetRoleMenus(c *gin.Context) {
+	var req SetMenusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("设置角色菜单参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	if err := db.First(&models.Role{}, req.RoleID).Error; err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": "角色不存在"})
+		return
+	}
+	var before []uint
+	db.Model(&models.RoleMenu{}).Where("role_id = ?", req.RoleID).Order("menu_id").Pluck("menu_id", &before)
+
+	menuIDs := make([]uint, 0, len(req.MenuIDs))
+	seen := make(map[uint]bool, len(req.MenuIDs))
+	for _, id := range req.MenuIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			menuIDs = append(menuIDs, id)
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(menuIDs) > 0 {
+			var count int64
+			if err := tx.Model(&models.Menu{}).Where("id IN ?", menuIDs).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(menuIDs) {
+				return errMenuNotFound
+			}
+		}
+		if err := tx.Where("role_id = ?", req.RoleID).Delete(&models.RoleMenu{}).Error; err != nil {
+			return err
+		}
+		for _, id := range menuIDs {
+			if err := tx.Create(&models.RoleMenu{RoleID: req.RoleID, MenuID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errMenuNotFound) {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if err != nil {
+		reqlog.Entry(c).Error("设置角色菜单失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "设置失败"})
+		return
+	}
+	permcache.InvalidateAll(c.Request.Context())
+	audit.RecordChange(c, "role", "set-menus", req.RoleID, before, menuIDs)
+
+	reqlog.Entry(c).Infof("管理员设置角色菜单成功: 角色ID=%d", req.RoleID)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "设置成功",
+	})
+}
@@ -1,25 +1,77 @@
 package role_api
 
 import (
+	"strconv"
+
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
 
 	"github.com/gin-gonic/gin"
 )
 
+// roleListSpec 角色列表允许的筛选和排序字段
+var roleListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"name":       {Column: "name", Op: search.OpLike},
+		"key":        {Column: "key", Op: search.OpLike},
+		"status":     {Column: "status", Op: search.OpIn},
+		"created_at": {Column: "created_at", Op: search.OpDateRange},
+	},
+	Sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"sort":       "sort",
+		"created_at": "created_at",
+	},
+	DefaultSort: "sort ASC, id ASC",
+}
+
+// roleUserSpec 角色用户列表允许的筛选和排序字段
+var roleUserSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"username": {Column: "users.username", Op: search.OpLike},
+		"nickname": {Column: "users.nickname", Op: search.OpLike},
+		"status":   {Column: "users.status", Op: search.OpIn},
+	},
+	Sorts: map[string]string{
+		"id":         "users.id",
+		"username":   "users.username",
+		"created_at": "users.created_at",
+	},
+	DefaultSort: "users.id ASC",
+}
+
 // GetRoleList 获取角色列表
 // @Summary 获取角色列表接口
-// @Description 查询系统中的角色列表
+// @Description 分页查询系统中的角色列表，支持筛选和排序
 // @Tags 角色管理
 // @Accept json
 // @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Role}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Param name query string false "角色名称(模糊)"
+// @Param status query string false "状态，多个用逗号分隔"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/role/list [get]
 func (r *RoleApi) GetRoleList(c *gin.Context) {
+	q, err := search.Parse(c, roleListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	var roles []models.Role
-	if err := global.DB.Find(&roles).Error; err != nil {
-		global.Logger.Error("获取角色列表失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.Role{}), &roles)
+	if err != nil {
+		reqlog.Entry(c).Error("获取角色列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取角色列表失败"})
 		return
 	}
@@ -27,7 +79,7 @@ func (r *RoleApi) GetRoleList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": roles,
+		"data": page,
 	})
 }
 
@@ -45,27 +97,27 @@ func (r *RoleApi) GetRoleList(c *gin.Context) {
 func (r *RoleApi) CreateRole(c *gin.Context) {
 	var role models.Role
 	if err := c.ShouldBindJSON(&role); err != nil {
-		global.Logger.Error("创建角色参数错误: " + err.Error())
+		reqlog.Entry(c).Error("创建角色参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 检查角色名是否已存在
 	var count int64
-	global.DB.Model(&models.Role{}).Where("name = ?", role.Name).Count(&count)
+	global.DB.WithContext(c.Request.Context()).Model(&models.Role{}).Where("name = ?", role.Name).Count(&count)
 	if count > 0 {
-		global.Logger.Error("角色名已存在: " + role.Name)
+		reqlog.Entry(c).Error("角色名已存在: " + role.Name)
 		c.JSON(400, gin.H{"code": 400, "msg": "角色名已存在"})
 		return
 	}
 
-	if err := global.DB.Create(&role).Error; err != nil {
-		global.Logger.Error("创建角色失败: " + err.Error())
+	if err := global.DB.WithContext(c.Request.Context()).Create(&role).Error; err != nil {
+		reqlog.Entry(c).Error("创建角色失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员创建角色成功: %s", role.Name)
+	reqlog.Entry(c).Infof("管理员创建角色成功: %s", role.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "创建成功",
@@ -86,24 +138,30 @@ func (r *RoleApi) CreateRole(c *gin.Context) {
 func (r *RoleApi) UpdateRole(c *gin.Context) {
 	var role models.Role
 	if err := c.ShouldBindJSON(&role); err != nil {
-		global.Logger.Error("更新角色参数错误: " + err.Error())
+		reqlog.Entry(c).Error("更新角色参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	if role.ID == 0 {
-		global.Logger.Error("更新角色参数错误: ID为空")
+		reqlog.Entry(c).Error("更新角色参数错误: ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	if err := global.DB.Save(&role).Error; err != nil {
-		global.Logger.Error("更新角色失败: " + err.Error())
+	// 记录变更前的数据
+	var before models.Role
+	global.DB.WithContext(c.Request.Context()).First(&before, role.ID)
+
+	if err := global.DB.WithContext(c.Request.Context()).Save(&role).Error; err != nil {
+		reqlog.Entry(c).Error("更新角色失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
 		return
 	}
+	permcache.InvalidateAll(c.Request.Context())
+	audit.RecordChange(c, "role", "update", role.ID, before, role)
 
-	global.Logger.Infof("管理员更新角色成功: %s", role.Name)
+	reqlog.Entry(c).Infof("管理员更新角色成功: %s", role.Name)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "更新成功",
@@ -122,37 +180,29 @@ func (r *RoleApi) UpdateRole(c *gin.Context) {
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/role/delete [delete]
 func (r *RoleApi) DeleteRole(c *gin.Context) {
-	id := c.Query("id")
-	if id == "" {
-		global.Logger.Error("删除角色参数错误: ID为空")
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除角色参数错误: ID无效")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	// 检查角色是否有用户关联
 	var userRoles []models.UserRole
-	global.DB.Where("role_id = ?", id).Find(&userRoles)
+	global.DB.WithContext(c.Request.Context()).Where("role_id = ?", id).Find(&userRoles)
 	if len(userRoles) > 0 {
-		global.Logger.Error("删除角色失败: 角色有用户关联")
+		reqlog.Entry(c).Error("删除角色失败: 角色有用户关联")
 		c.JSON(400, gin.H{"code": 400, "msg": "角色有用户关联，无法删除"})
 		return
 	}
 
-	// 检查角色是否有权限关联
-	var rolePermissions []models.RolePermission
-	global.DB.Where("role_id = ?", id).Find(&rolePermissions)
-	if len(rolePermissions) > 0 {
-		// 先删除权限关联
-		global.DB.Delete(&models.RolePermission{}, "role_id = ?", id)
-	}
-
-	if err := global.DB.Delete(&models.Role{}, id).Error; err != nil {
-		global.Logger.Error("删除角色失败: " + err.Error())
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "role", uint(id)); err != nil {
+		reqlog.Entry(c).Error("删除角色失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员删除角色成功: ID=%s", id)
+	reqlog.Entry(c).Infof("管理员删除角色成功，已移入回收站: ID=%d", id)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
@@ -173,18 +223,18 @@ func (r *RoleApi) DeleteRole(c *gin.Context) {
 func (r *RoleApi) GetRolePermissions(c *gin.Context) {
 	roleID := c.Query("role_id")
 	if roleID == "" {
-		global.Logger.Error("获取角色权限参数错误: 角色ID为空")
+		reqlog.Entry(c).Error("获取角色权限参数错误: 角色ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
 	var permissions []models.Permission
-	if err := global.DB.Table("permissions").
+	if err := global.DB.WithContext(c.Request.Context()).Table("permissions").
 		Select("permissions.*").
 		Joins("join role_permissions on permissions.id = role_permissions.permission_id").
 		Where("role_permissions.role_id = ?", roleID).
 		Find(&permissions).Error; err != nil {
-		global.Logger.Error("获取角色权限失败: " + err.Error())
+		reqlog.Entry(c).Error("获取角色权限失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
@@ -214,13 +264,17 @@ func (r *RoleApi) SetRolePermissions(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		global.Logger.Error("设置角色权限参数错误: " + err.Error())
+		reqlog.Entry(c).Error("设置角色权限参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
+	// 记录变更前的权限
+	var before []uint
+	global.DB.WithContext(c.Request.Context()).Model(&models.RolePermission{}).Where("role_id = ?", req.RoleID).Pluck("permission_id", &before)
+
 	// 事务处理
-	tx := global.DB.Begin()
+	tx := global.DB.WithContext(c.Request.Context()).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -229,7 +283,7 @@ func (r *RoleApi) SetRolePermissions(c *gin.Context) {
 
 	// 删除原有权限关联
 	if err := tx.Delete(&models.RolePermission{}, "role_id = ?", req.RoleID).Error; err != nil {
-		global.Logger.Error("删除原有角色权限关联失败: " + err.Error())
+		reqlog.Entry(c).Error("删除原有角色权限关联失败: " + err.Error())
 		tx.Rollback()
 		c.JSON(500, gin.H{"code": 500, "msg": "设置失败"})
 		return
@@ -242,7 +296,7 @@ func (r *RoleApi) SetRolePermissions(c *gin.Context) {
 			PermissionID: uint(permissionID),
 		}
 		if err := tx.Create(&rolePermission).Error; err != nil {
-			global.Logger.Error("添加角色权限关联失败: " + err.Error())
+			reqlog.Entry(c).Error("添加角色权限关联失败: " + err.Error())
 			tx.Rollback()
 			c.JSON(500, gin.H{"code": 500, "msg": "设置失败"})
 			return
@@ -251,8 +305,10 @@ func (r *RoleApi) SetRolePermissions(c *gin.Context) {
 
 	// 提交事务
 	tx.Commit()
+	permcache.InvalidateAll(c.Request.Context())
+	audit.RecordChange(c, "role", "set-permissions", uint(req.RoleID), before, req.PermissionIDs)
 
-	global.Logger.Infof("管理员设置角色权限成功: 角色ID=%d", req.RoleID)
+	reqlog.Entry(c).Infof("管理员设置角色权限成功: 角色ID=%d", req.RoleID)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "设置成功",
@@ -261,30 +317,38 @@ func (r *RoleApi) SetRolePermissions(c *gin.Context) {
 
 // GetRoleUsers 获取角色用户
 // @Summary 获取角色用户接口
-// @Description 查询指定角色下的用户列表
+// @Description 分页查询指定角色下的用户列表
 // @Tags 角色管理
 // @Accept json
 // @Produce json
 // @Param role_id query int true "角色ID"
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.User}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/role/users [get]
 func (r *RoleApi) GetRoleUsers(c *gin.Context) {
 	roleID := c.Query("role_id")
 	if roleID == "" {
-		global.Logger.Error("获取角色用户参数错误: 角色ID为空")
+		reqlog.Entry(c).Error("获取角色用户参数错误: 角色ID为空")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
+	q, err := search.Parse(c, roleUserSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	var users []models.User
-	if err := global.DB.Table("users").
-		Select("users.*").
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.User{}).
 		Joins("join user_roles on users.id = user_roles.user_id").
-		Where("user_roles.role_id = ?", roleID).
-		Find(&users).Error; err != nil {
-		global.Logger.Error("获取角色用户失败: " + err.Error())
+		Where("user_roles.role_id = ?", roleID), &users)
+	if err != nil {
+		reqlog.Entry(c).Error("获取角色用户失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
 		return
 	}
@@ -292,6 +356,6 @@ func (r *RoleApi) GetRoleUsers(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": users,
+		"data": page,
 	})
 }
\ No newline at end of file
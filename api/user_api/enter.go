@@ -1,6 +1,11 @@
 package user_api
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/middleware"
+	"rbac_admin_server/utils/impersonation"
+)
 
 // UserApi 用户API结构体
 // 提供用户相关的所有API接口
@@ -23,5 +28,18 @@ func (u *UserApi) RegisterRoutes(router *gin.RouterGroup) {
 		userRouter.POST("/create", u.CreateUser)
 		userRouter.PUT("/update", u.UpdateUser)
 		userRouter.DELETE("/delete", u.DeleteUser)
+		userRouter.PUT("/status", u.SetUserStatus)
+		userRouter.PUT("/expiry", u.SetUserExpiry)
+		userRouter.GET("/export", u.ExportUsers)
+		userRouter.POST("/import", u.ImportUsers)
+		userRouter.GET("/import/:id", u.GetImportJob)
+		userRouter.GET("/departments", u.GetUserDepartments)
+		userRouter.PUT("/departments", u.SetUserDepartments)
+		userRouter.GET("/positions", u.GetUserPositions)
+		userRouter.PUT("/positions", u.SetUserPositions)
+		userRouter.GET("/roles", u.GetUserRoles)
+		userRouter.PUT("/roles", u.SetUserRoles)
+		userRouter.POST("/impersonate/:id", middleware.NoImpersonation(),
+			middleware.RequirePermission(impersonation.PermissionKey), u.Impersonate)
 	}
 }
\ No newline at end of file
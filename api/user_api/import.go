@@ -0,0 +1,92 @@
+package user_api
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/i18n"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/userimport"
+)
+
+// syncImportRows 不超过该行数时同步导入，否则转为后台任务
+const syncImportRows = 200
+
+// ImportUsers 批量导入用户
+// @Summary 批量导入用户接口
+// @Description 从CSV或XLSX文件批量导入用户，返回逐行校验结果；行数较多时转为后台任务
+// @Tags 用户管理
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV或XLSX文件，表头: username,nickname,email,phone,password,department,roles"
+// @Param dry_run query bool false "仅校验不写入"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":userimport.Result}
+// @Success 202 {object} gin.H{"code":int, "msg":string, "data":userimport.Job}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/import [post]
+func (u *UserApi) ImportUsers(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", c.PostForm("dry_run")))
+
+	file, _ := c.FormFile("file")
+	if file == nil {
+		c.JSON(400, gin.H{"code": 400, "msg": "文件不能为空"})
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		reqlog.Entry(c).Errorf("打开导入文件失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "读取文件失败"})
+		return
+	}
+	defer src.Close()
+
+	rows, err := userimport.ReadFile(file.Filename, src)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if len(rows) > userimport.MaxRows {
+		c.JSON(400, gin.H{"code": 400, "msg": "单次导入行数超出限制"})
+		return
+	}
+
+	if len(rows) > syncImportRows {
+		job := userimport.StartJob(global.DB, rows, dryRun, i18n.Locale(c), c.GetUint("userID"))
+		audit.Describe(c, "后台导入用户 "+strconv.Itoa(len(rows))+" 行，任务 "+job.ID, nil)
+		c.JSON(202, gin.H{"code": 202, "msg": "导入任务已创建", "data": job})
+		return
+	}
+
+	result, err := userimport.Import(global.DB.WithContext(c.Request.Context()), rows, dryRun, i18n.Locale(c), nil)
+	if err != nil {
+		reqlog.Entry(c).Errorf("导入用户失败: %v", err)
+		c.JSON(500, gin.H{"code": 500, "msg": "导入用户失败"})
+		return
+	}
+	if !dryRun {
+		audit.Describe(c, "导入用户 "+strconv.Itoa(result.Created)+" 个，失败 "+strconv.Itoa(result.Failed)+" 行", nil)
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "导入完成", "data": result})
+}
+
+// GetImportJob 查询导入任务进度
+// @Summary 查询导入任务接口
+// @Description 查询后台导入任务的进度和结果，任务结束一小时后清理
+// @Tags 用户管理
+// @Produce json
+// @Param id path string true "任务ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":userimport.Job}
+// @Failure 404 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/import/{id} [get]
+func (u *UserApi) GetImportJob(c *gin.Context) {
+	job, ok := userimport.GetJob(c.Param("id"))
+	if !ok || job.CreatedBy != c.GetUint("userID") {
+		c.JSON(404, gin.H{"code": 404, "msg": "导入任务不存在"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 200, "msg": "获取成功", "data": job})
+}
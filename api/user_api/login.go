@@ -1,21 +1,30 @@
 package user_api
 
 import (
+	"errors"
+	"sync"
+
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
 	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
 	"rbac_admin_server/utils/captcha"
+	"rbac_admin_server/utils/i18n"
+	"rbac_admin_server/utils/loginid"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/userstate"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Login 用户登录
 // @Summary 用户登录接口
-// @Description 用户登录系统获取访问令牌
+// @Description 用户登录系统获取访问令牌，账号可以是用户名、邮箱或手机号，允许的类型由配置login.identifiers决定。账号不存在、密码错误和账号不可登录返回相同的错误
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param login body struct{Username string, Password string, Captcha string, CaptchaId string} true "登录信息"
+// @Param login body struct{Identifier string, Username string, Password string, CaptchaID string, CaptchaCode string} true "登录信息，username为兼容旧版的identifier"
 // @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"token":string, "refresh_token":string, "user":models.User, "is_admin":bool}}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 401 {object} gin.H{"code":int, "msg":string}
@@ -23,73 +32,121 @@ import (
 // @Router /public/login [post]
 func (u *UserApi) Login(c *gin.Context) {
 	var req struct {
-		Username    string `json:"username" binding:"required"`
+		Identifier  string `json:"identifier"` // 用户名、邮箱或手机号
+		Username    string `json:"username"`   // 兼容旧版客户端，等同identifier
 		Password    string `json:"password" binding:"required"`
 		CaptchaID   string `json:"captchaID"`
 		CaptchaCode string `json:"captchaCode"`
 	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("登录参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.Message(c, utils.ERROR_INVALID_PARAM)})
+		return
+	}
+	if req.Identifier == "" {
+		req.Identifier = req.Username
+	}
+	if req.Identifier == "" {
+		c.JSON(400, gin.H{"code": utils.ERROR_IDENTIFIER_REQUIRED, "msg": utils.Message(c, utils.ERROR_IDENTIFIER_REQUIRED)})
+		return
+	}
 
 	// 如果启用了验证码，需要验证
 	if global.Config.Captcha.Enable {
-		// 调整绑定规则，增加验证码字段的必填要求
-		type LoginWithCaptchaReq struct {
-			Username    string `json:"username" binding:"required"`
-			Password    string `json:"password" binding:"required"`
-			CaptchaID   string `json:"captchaID" binding:"required"`
-			CaptchaCode string `json:"captchaCode" binding:"required"`
-		}
-		var captchaReq LoginWithCaptchaReq
-		if err := c.ShouldBindJSON(&captchaReq); err != nil {
-			global.Logger.Error("登录参数错误: " + err.Error())
-			c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.GetErrMsg(utils.ERROR_INVALID_PARAM)})
+		if req.CaptchaID == "" || req.CaptchaCode == "" {
+			c.JSON(400, gin.H{"code": utils.ERROR_CAPTCHA_REQUIRED, "msg": utils.Message(c, utils.ERROR_CAPTCHA_REQUIRED)})
 			return
 		}
-		// 验证验证码
-		if !captcha.CaptchaStore.Verify(captchaReq.CaptchaID, captchaReq.CaptchaCode, true) {
-			global.Logger.Error("验证码错误: " + captchaReq.Username)
-			c.JSON(400, gin.H{"code": utils.ERROR_CAPTCHA_WRONG, "msg": utils.GetErrMsg(utils.ERROR_CAPTCHA_WRONG)})
+		if !captcha.CaptchaStore.Verify(req.CaptchaID, req.CaptchaCode, true) {
+			reqlog.Entry(c).Error("验证码错误: " + req.Identifier)
+			c.JSON(400, gin.H{"code": utils.ERROR_CAPTCHA_WRONG, "msg": utils.Message(c, utils.ERROR_CAPTCHA_WRONG)})
 			return
 		}
-		// 复制数据到原始请求结构
-		req.Username = captchaReq.Username
-		req.Password = captchaReq.Password
-	} else {
-		// 不启用验证码时，只绑定基础字段
-		if err := c.ShouldBindJSON(&req); err != nil {
-			global.Logger.Error("登录参数错误: " + err.Error())
-			c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.GetErrMsg(utils.ERROR_INVALID_PARAM)})
+	}
+
+	// 按用户名、邮箱或手机号查询用户
+	db := global.DB.WithContext(c.Request.Context())
+	user, err := loginid.Find(db, req.Identifier)
+	if err != nil {
+		if errors.Is(err, loginid.ErrNotAllowed) {
+			c.JSON(400, gin.H{"code": utils.ERROR_LOGIN_METHOD, "msg": utils.Message(c, utils.ERROR_LOGIN_METHOD)})
 			return
 		}
+		if !errors.Is(err, loginid.ErrNotFound) {
+			reqlog.Entry(c).Error("查询用户失败: " + err.Error())
+		}
+		// 用户不存在和密码错误返回相同的错误并同样计算一次哈希，只在登录日志中区分，避免枚举账号
+		utils.ComparePassword(dummyHash(), req.Password)
+		reqlog.Entry(c).Error("用户不存在: " + req.Identifier)
+		audit.RecordLogin(c, 0, req.Identifier, false, 401, "用户不存在")
+		c.JSON(401, gin.H{"code": utils.ERROR_INVALID_CREDENTIALS, "msg": utils.Message(c, utils.ERROR_INVALID_CREDENTIALS)})
+		return
 	}
 
-	// 查询用户
-	var user models.User
-	err := global.DB.Where("username = ?", req.Username).First(&user).Error
+	// 写回自动解锁和账号到期
+	status, err := userstate.Refresh(db, user)
 	if err != nil {
-		global.Logger.Error("用户不存在: " + req.Username)
-		c.JSON(401, gin.H{"code": utils.ERROR_USER_NOT_EXIST, "msg": utils.GetErrMsg(utils.ERROR_USER_NOT_EXIST)})
-		return
+		reqlog.Entry(c).Error("更新用户状态失败: " + err.Error())
 	}
 
-	// 检查用户状态
-	if user.Status != 1 {
-		global.Logger.Error("用户已被禁用: " + req.Username)
-		c.JSON(401, gin.H{"code": utils.ERROR, "msg": "用户已被禁用"})
+	// 密码错误和账号不可登录返回相同的错误，不论密码是否正确都不返回账号状态，
+	// 避免通过锁定账号的响应差异确认密码；失败原因只记录在登录日志中
+	passwordOK := utils.ComparePassword(user.Password, req.Password)
+	if !passwordOK || status != models.UserStatusActive {
+		reason := "密码错误"
+		if status != models.UserStatusActive {
+			reason = "账号" + userstate.Name(status)
+			if !passwordOK {
+				reason = "密码错误，" + reason
+			}
+		}
+		reqlog.Entry(c).Warnf("登录失败(%s): %s", reason, user.Username)
+		audit.RecordLogin(c, user.ID, user.Username, false, 401, reason)
+		if !passwordOK {
+			locked, err := userstate.LoginFailed(db, user)
+			if err != nil {
+				reqlog.Entry(c).Error("记录登录失败次数失败: " + err.Error())
+			}
+			if locked {
+				reqlog.Entry(c).Warnf("用户连续登录失败被锁定: %s", user.Username)
+			}
+		}
+		c.JSON(401, gin.H{"code": utils.ERROR_INVALID_CREDENTIALS, "msg": utils.Message(c, utils.ERROR_INVALID_CREDENTIALS)})
 		return
 	}
 
-	// 验证密码
-	if !utils.ComparePassword(user.Password, req.Password) {
-		global.Logger.Error("密码验证失败: " + req.Username)
-		c.JSON(401, gin.H{"code": utils.ERROR_PASSWORD_WRONG, "msg": utils.GetErrMsg(utils.ERROR_PASSWORD_WRONG)})
-		return
+	issueTokens(c, db, user)
+}
+
+// dummyHash 用户不存在时用于比较的密码哈希，使响应时间与密码错误时一致
+var dummyHash = sync.OnceValue(func() string {
+	return utils.HashedPassword(utils.RandomPassword())
+})
+
+// checkLoginStatus 检查用户状态，同时写回自动解锁和账号到期；不可登录时写入响应并返回false
+func checkLoginStatus(c *gin.Context, db *gorm.DB, user *models.User) bool {
+	status, err := userstate.Refresh(db, user)
+	if err != nil {
+		reqlog.Entry(c).Error("更新用户状态失败: " + err.Error())
+	}
+	if status != models.UserStatusActive {
+		rejectLogin(c, user, status)
+		return false
+	}
+	return true
+}
+
+// issueTokens 登录成功，重置失败次数并签发访问令牌和刷新令牌
+func issueTokens(c *gin.Context, db *gorm.DB, user *models.User) {
+	if err := userstate.LoginSucceeded(db, user); err != nil {
+		reqlog.Entry(c).Error("重置登录失败次数失败: " + err.Error())
 	}
 
 	// 获取用户角色列表
 	roleList, err := global.GetUserRoles(user.ID)
 	if err != nil {
-		global.Logger.Error("获取用户角色失败: " + err.Error())
-		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.GetErrMsg(utils.ERROR)})
+		reqlog.Entry(c).Error("获取用户角色失败: " + err.Error())
+		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.Message(c, utils.ERROR)})
 		return
 	}
 
@@ -100,23 +157,24 @@ func (u *UserApi) Login(c *gin.Context) {
 		RoleList: roleList,
 	})
 	if err != nil {
-		global.Logger.Error("生成访问令牌失败: ", err)
-		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.GetErrMsg(utils.ERROR)})
+		reqlog.Entry(c).Error("生成访问令牌失败: ", err)
+		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.Message(c, utils.ERROR)})
 		return
 	}
 
 	// 生成刷新令牌
 	refreshToken, err := global.GenerateRefreshToken(user.ID)
 	if err != nil {
-		global.Logger.Error("生成刷新令牌失败: ", err)
-		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.GetErrMsg(utils.ERROR)})
+		reqlog.Entry(c).Error("生成刷新令牌失败: ", err)
+		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.Message(c, utils.ERROR)})
 		return
 	}
 
-	global.Logger.Infof("用户登录成功: %s", user.Username)
+	reqlog.Entry(c).Infof("用户登录成功: %s", user.Username)
+	audit.RecordLogin(c, user.ID, user.Username, true, 200, "")
 	c.JSON(200, gin.H{
 		"code": utils.SUCCESS,
-		"msg":  utils.GetErrMsg(utils.SUCCESS),
+		"msg":  utils.Message(c, utils.SUCCESS),
 		"data": gin.H{
 			"token":         accessToken,
 			"refresh_token": refreshToken,
@@ -136,6 +194,7 @@ func (u *UserApi) Login(c *gin.Context) {
 // @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"token":string, "refresh_token":string}}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 401 {object} gin.H{"code":int, "msg":string}
+// @Failure 403 {object} gin.H{"code":int, "msg":string} "账号待激活、锁定、过期或归档"
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /public/refresh-token [post]
 func (u *UserApi) RefreshToken(c *gin.Context) {
@@ -144,20 +203,38 @@ func (u *UserApi) RefreshToken(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		global.Logger.Error("刷新令牌参数错误: " + err.Error())
+		reqlog.Entry(c).Error("刷新令牌参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": utils.ERROR, "msg": "参数错误"})
 		return
 	}
 
+	// 校验用户状态，锁定、过期等账号不能继续刷新令牌
+	claims, err := global.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		reqlog.Entry(c).Error("刷新令牌失败: " + err.Error())
+		c.JSON(401, gin.H{"code": utils.ERROR_TOKEN_INVALID, "msg": err.Error()})
+		return
+	}
+	code, err := userstate.Verify(global.DB.WithContext(c.Request.Context()), claims.UserID)
+	if err != nil {
+		reqlog.Entry(c).Error("刷新令牌查询用户失败: " + err.Error())
+		c.JSON(401, gin.H{"code": utils.ERROR_USER_NOT_EXIST, "msg": utils.Message(c, utils.ERROR_USER_NOT_EXIST)})
+		return
+	}
+	if code != utils.SUCCESS {
+		c.JSON(403, gin.H{"code": code, "msg": utils.Message(c, code)})
+		return
+	}
+
 	// 刷新令牌
 	newAccessToken, newRefreshToken, err := global.RefreshToken(req.RefreshToken)
 	if err != nil {
-		global.Logger.Error("刷新令牌失败: " + err.Error())
+		reqlog.Entry(c).Error("刷新令牌失败: " + err.Error())
 		c.JSON(401, gin.H{"code": utils.ERROR_TOKEN_INVALID, "msg": err.Error()})
 		return
 	}
 
-	global.Logger.Info("令牌刷新成功")
+	reqlog.Entry(c).Info("令牌刷新成功")
 	c.JSON(200, gin.H{
 		"code": utils.SUCCESS,
 		"msg":  "令牌刷新成功",
@@ -166,4 +243,12 @@ func (u *UserApi) RefreshToken(c *gin.Context) {
 			"refresh_token": newRefreshToken,
 		},
 	})
-}
\ No newline at end of file
+}
+
+// rejectLogin 拒绝不可登录状态的用户
+func rejectLogin(c *gin.Context, user *models.User, status int) {
+	reason := "账号" + userstate.Name(status)
+	reqlog.Entry(c).Warnf("%s，拒绝登录: %s", reason, user.Username)
+	audit.RecordLogin(c, user.ID, user.Username, false, 403, reason)
+	c.JSON(403, gin.H{"code": userstate.ErrorCode(status), "msg": userstate.Message(i18n.Locale(c), user, status)})
+}
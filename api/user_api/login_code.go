@@ -0,0 +1,111 @@
+package user_api
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/captcha"
+	"rbac_admin_server/utils/loginid"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// SendLoginCode 发送登录验证码
+// @Summary 发送邮箱登录验证码接口
+// @Description 需开启login.email_code；邮箱未注册时同样返回成功但不发送邮件
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body struct{Email string, CaptchaID string, CaptchaCode string} true "邮箱"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 429 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /public/login/email-code [post]
+func (u *UserApi) SendLoginCode(c *gin.Context) {
+	var req struct {
+		Email       string `json:"email" binding:"required,email"`
+		CaptchaID   string `json:"captchaID"`
+		CaptchaCode string `json:"captchaCode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.Message(c, utils.ERROR_INVALID_PARAM)})
+		return
+	}
+	if !global.Config.Email.Verify() {
+		c.JSON(400, gin.H{"code": utils.ERROR_EMAIL_CONFIG, "msg": utils.Message(c, utils.ERROR_EMAIL_CONFIG)})
+		return
+	}
+
+	// 如果启用了验证码，验证图片验证码
+	if global.Config.Captcha.Enable {
+		if req.CaptchaID == "" || req.CaptchaCode == "" {
+			c.JSON(400, gin.H{"code": utils.ERROR_CAPTCHA_REQUIRED, "msg": utils.Message(c, utils.ERROR_CAPTCHA_REQUIRED)})
+			return
+		}
+		if !captcha.CaptchaStore.Verify(req.CaptchaID, req.CaptchaCode, true) {
+			c.JSON(400, gin.H{"code": utils.ERROR_CAPTCHA_WRONG, "msg": utils.Message(c, utils.ERROR_CAPTCHA_WRONG)})
+			return
+		}
+	}
+
+	err := loginid.SendCode(global.DB.WithContext(c.Request.Context()), req.Email)
+	switch {
+	case errors.Is(err, loginid.ErrDisabled):
+		c.JSON(400, gin.H{"code": utils.ERROR_LOGIN_METHOD, "msg": utils.Message(c, utils.ERROR_LOGIN_METHOD)})
+	case errors.Is(err, loginid.ErrTooFrequent):
+		c.JSON(429, gin.H{"code": utils.ERROR_SEND_TOO_FREQUENT, "msg": utils.Message(c, utils.ERROR_SEND_TOO_FREQUENT)})
+	case err != nil:
+		reqlog.Entry(c).Error("发送登录验证码失败: " + err.Error())
+		c.JSON(500, gin.H{"code": utils.ERROR_EMAIL_SEND, "msg": utils.Message(c, utils.ERROR_EMAIL_SEND)})
+	default:
+		c.JSON(200, gin.H{"code": utils.SUCCESS, "msg": utils.Message(c, utils.MSG_LOGIN_CODE_SENT)})
+	}
+}
+
+// EmailCodeLogin 邮箱验证码登录
+// @Summary 邮箱验证码免密登录接口
+// @Description 使用发送到邮箱的验证码登录，需开启login.email_code
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body struct{Email string, Code string} true "邮箱和验证码"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":gin.H{"token":string, "refresh_token":string, "user":models.User, "is_admin":bool}}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 401 {object} gin.H{"code":int, "msg":string}
+// @Failure 403 {object} gin.H{"code":int, "msg":string} "账号待激活、锁定、过期或归档"
+// @Router /public/login/email [post]
+func (u *UserApi) EmailCodeLogin(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+		Code  string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.Message(c, utils.ERROR_INVALID_PARAM)})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	user, err := loginid.VerifyCode(db, req.Email, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, loginid.ErrDisabled):
+			c.JSON(400, gin.H{"code": utils.ERROR_LOGIN_METHOD, "msg": utils.Message(c, utils.ERROR_LOGIN_METHOD)})
+		case errors.Is(err, loginid.ErrCode), errors.Is(err, loginid.ErrNotFound):
+			audit.RecordLogin(c, 0, req.Email, false, 401, "邮箱验证码错误")
+			c.JSON(401, gin.H{"code": utils.ERROR_VERIFY_CODE, "msg": utils.Message(c, utils.ERROR_VERIFY_CODE)})
+		default:
+			reqlog.Entry(c).Error("邮箱验证码登录失败: " + err.Error())
+			c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.Message(c, utils.ERROR)})
+		}
+		return
+	}
+
+	if !checkLoginStatus(c, db, user) {
+		return
+	}
+	issueTokens(c, db, user)
+}
@@ -0,0 +1,184 @@
+package user_api
+
+import (
+	"errors"
+	"strconv"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/reqlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetDepartmentsRequest 设置用户所属部门请求参数
+type SetDepartmentsRequest struct {
+	// 用户ID
+	UserID uint `json:"user_id" binding:"required"`
+	// 主部门ID，0表示没有主部门
+	PrimaryID uint `json:"primary_id"`
+	// 全部所属部门ID，主部门不在其中时自动加入
+	DepartmentIDs []uint `json:"department_ids" binding:"max=50"`
+}
+
+// SetPositionsRequest 设置用户岗位请求参数
+type SetPositionsRequest struct {
+	// 用户ID
+	UserID uint `json:"user_id" binding:"required"`
+	// 岗位ID，为空表示清除岗位
+	PositionIDs []uint `json:"position_ids" binding:"max=50"`
+}
+
+// GetUserDepartments 获取用户所属部门
+// @Summary 获取用户所属部门接口
+// @Description 查询用户所属的全部部门，主部门在前，并标记是否为部门负责人
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param user_id query int true "用户ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]org.Membership}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/departments [get]
+func (u *UserApi) GetUserDepartments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	list, err := org.Memberships(global.DB.WithContext(c.Request.Context()), uint(id))
+	if err != nil {
+		reqlog.Entry(c).Error("获取用户所属部门失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+	})
+}
+
+// SetUserDepartments 设置用户所属部门
+// @Summary 设置用户所属部门接口
+// @Description 替换用户所属的全部部门并指定主部门，主部门同步到用户的department_id
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body SetDepartmentsRequest true "用户ID、主部门和所属部门"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/departments [put]
+func (u *UserApi) SetUserDepartments(c *gin.Context) {
+	var req SetDepartmentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("设置用户所属部门参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var before struct {
+		PrimaryID     uint   `json:"primary_id"`
+		DepartmentIDs []uint `json:"department_ids"`
+	}
+	db.Model(&models.User{}).Where("id = ?", req.UserID).Select("department_id").Scan(&before.PrimaryID)
+	db.Model(&models.UserDepartment{}).Where("user_id = ?", req.UserID).Order("department_id").Pluck("department_id", &before.DepartmentIDs)
+
+	if err := org.SetDepartments(db, req.UserID, req.PrimaryID, req.DepartmentIDs); err != nil {
+		orgError(c, "设置用户所属部门", err)
+		return
+	}
+	audit.RecordChange(c, "user", "set-departments", req.UserID, before,
+		gin.H{"primary_id": req.PrimaryID, "department_ids": req.DepartmentIDs})
+
+	reqlog.Entry(c).Infof("管理员设置用户所属部门成功: 用户ID=%d", req.UserID)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "设置成功",
+	})
+}
+
+// GetUserPositions 获取用户岗位
+// @Summary 获取用户岗位接口
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param user_id query int true "用户ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Position}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/positions [get]
+func (u *UserApi) GetUserPositions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	list, err := org.Positions(global.DB.WithContext(c.Request.Context()), uint(id))
+	if err != nil {
+		reqlog.Entry(c).Error("获取用户岗位失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+	})
+}
+
+// SetUserPositions 设置用户岗位
+// @Summary 设置用户岗位接口
+// @Description 替换用户的全部岗位，一个用户可以有多个岗位
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body SetPositionsRequest true "用户ID和岗位ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/positions [put]
+func (u *UserApi) SetUserPositions(c *gin.Context) {
+	var req SetPositionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("设置用户岗位参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var before []uint
+	db.Model(&models.UserPosition{}).Where("user_id = ?", req.UserID).Order("position_id").Pluck("position_id", &before)
+
+	if err := org.SetPositions(db, req.UserID, req.PositionIDs); err != nil {
+		orgError(c, "设置用户岗位", err)
+		return
+	}
+	audit.RecordChange(c, "user", "set-positions", req.UserID, before, req.PositionIDs)
+
+	reqlog.Entry(c).Infof("管理员设置用户岗位成功: 用户ID=%d", req.UserID)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "设置成功",
+	})
+}
+
+// orgError 按组织架构操作的错误类型返回响应
+func orgError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, org.ErrUserNotFound), errors.Is(err, org.ErrDepartmentNotFound),
+		errors.Is(err, org.ErrPositionNotFound):
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+	default:
+		reqlog.Entry(c).Error(action + "失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "设置失败"})
+	}
+}
@@ -1,55 +1,82 @@
 package user_api
 
 import (
+	"errors"
+	"strconv"
+
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
 	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
 	"rbac_admin_server/utils/email"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/registration"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/search"
+	"rbac_admin_server/utils/usergroup"
+	"rbac_admin_server/utils/userstate"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Register 用户注册
 // @Summary 用户注册接口
-// @Description 创建新用户账号
+// @Description 创建新用户账号，是否开放、是否需要邀请码或审核由注册策略决定
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param register body struct{Username string, Password string, Nickname string, Email string, Phone string, EmailID string, EmailCode string} true "注册信息"
+// @Param register body struct{Username string, Password string, Nickname string, Email string, Phone string, EmailID string, EmailCode string, InviteCode string} true "注册信息"
 // @Success 200 {object} gin.H{"code":int, "msg":string, "data":models.User}
 // @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 403 {object} gin.H{"code":int, "msg":string} "不开放注册"
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /public/register [post]
 func (u *UserApi) Register(c *gin.Context) {
 	var req struct {
-		Username  string `json:"username" binding:"required"`
-		Password  string `json:"password" binding:"required"`
-		Nickname  string `json:"nickname" binding:"required"`
-		Email     string `json:"email" binding:"required,email"`
-		Phone     string `json:"phone"`
-		EmailID   string `json:"emailID" binding:"required"`
-		EmailCode string `json:"emailCode" binding:"required"`
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		Nickname   string `json:"nickname" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Phone      string `json:"phone"`
+		EmailID    string `json:"emailID" binding:"required"`
+		EmailCode  string `json:"emailCode" binding:"required"`
+		InviteCode string `json:"inviteCode"`
+	}
+
+	if registration.Mode() == registration.ModeDisabled {
+		c.JSON(403, gin.H{"code": utils.ERROR_REGISTER_CLOSED, "msg": utils.Message(c, utils.ERROR_REGISTER_CLOSED)})
+		return
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		global.Logger.Error("注册参数错误: " + err.Error())
-		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.GetErrMsg(utils.ERROR_INVALID_PARAM)})
+		reqlog.Entry(c).Error("注册参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.Message(c, utils.ERROR_INVALID_PARAM)})
+		return
+	}
+
+	// 按注册策略校验邮箱域名和邀请码
+	plan, err := registration.Check(global.DB.WithContext(c.Request.Context()), req.Email, req.InviteCode)
+	if err != nil {
+		respondRegisterError(c, err)
 		return
 	}
 
 	// 检查用户名是否已存在
 	var count int64
-	global.DB.Model(&models.User{}).Where("username = ?", req.Username).Count(&count)
+	global.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("username = ?", req.Username).Count(&count)
 	if count > 0 {
-		global.Logger.Error("用户名已存在: " + req.Username)
-		c.JSON(400, gin.H{"code": utils.ERROR_USERNAME_USED, "msg": utils.GetErrMsg(utils.ERROR_USERNAME_USED)})
+		reqlog.Entry(c).Error("用户名已存在: " + req.Username)
+		c.JSON(400, gin.H{"code": utils.ERROR_USERNAME_USED, "msg": utils.Message(c, utils.ERROR_USERNAME_USED)})
 		return
 	}
 
 	// 检查邮箱是否已存在
-	global.DB.Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
+	global.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
 	if count > 0 {
-		global.Logger.Error("邮箱已存在: " + req.Email)
+		reqlog.Entry(c).Error("邮箱已存在: " + req.Email)
 		email.Remove(req.EmailID) // 清理验证码记录
 		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": "邮箱已存在"})
 		return
@@ -57,64 +84,152 @@ func (u *UserApi) Register(c *gin.Context) {
 
 	// 验证邮箱验证码
 	if !email.Verify(req.EmailID, req.Email, req.EmailCode) {
-		global.Logger.Error("邮箱验证码错误: " + req.Email)
-		c.JSON(400, gin.H{"code": utils.ERROR_EMAIL_CODE_WRONG, "msg": utils.GetErrMsg(utils.ERROR_EMAIL_CODE_WRONG)})
+		reqlog.Entry(c).Error("邮箱验证码错误: " + req.Email)
+		c.JSON(400, gin.H{"code": utils.ERROR_EMAIL_CODE_WRONG, "msg": utils.Message(c, utils.ERROR_EMAIL_CODE_WRONG)})
 		return
 	}
 
 	// 检查手机号是否已存在
 	if req.Phone != "" {
-		global.DB.Model(&models.User{}).Where("phone = ?", req.Phone).Count(&count)
+		global.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("phone = ?", req.Phone).Count(&count)
 		if count > 0 {
-			global.Logger.Error("手机号已存在: " + req.Phone)
+			reqlog.Entry(c).Error("手机号已存在: " + req.Phone)
 			email.Remove(req.EmailID) // 清理验证码记录
 			c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": "手机号已存在"})
 			return
 		}
 	}
 
-	// 创建用户
+	// 创建用户，注册时已验证邮箱，审核模式下待管理员审核后启用
 	user := models.User{
-		Username: req.Username,
-		Password: utils.MakePassword(req.Password),
-		Nickname: req.Nickname,
-		Email:    req.Email,
-		Phone:    req.Phone,
-		Status:   1, // 默认为启用状态
+		Username:     req.Username,
+		Password:     utils.MakePassword(req.Password),
+		Nickname:     req.Nickname,
+		Email:        models.NullString(req.Email),
+		Phone:        models.NullString(req.Phone),
+		Status:       plan.Status,
+		DepartmentID: plan.DepartmentID,
+	}
+	if plan.Invitation != nil {
+		user.InvitationID = plan.Invitation.ID
 	}
 
-	// 保存用户到数据库
-	if err := global.DB.Create(&user).Error; err != nil {
-		global.Logger.Error("创建用户失败: " + err.Error())
+	// 保存用户到数据库，同时占用邀请码并分配角色
+	err = global.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return registration.Apply(tx, &user, plan)
+	})
+	if err != nil {
 		email.Remove(req.EmailID) // 注册失败，清理验证码记录
-		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.GetErrMsg(utils.ERROR)})
+		if errors.Is(err, registration.ErrInvitation) {
+			respondRegisterError(c, err)
+			return
+		}
+		reqlog.Entry(c).Error("创建用户失败: " + err.Error())
+		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.Message(c, utils.ERROR)})
 		return
 	}
 
 	// 注册成功，清理验证码记录
 	email.Remove(req.EmailID)
+	if plan.RoleID != 0 {
+		if err := usergroup.SyncCasbin(global.DB.WithContext(c.Request.Context())); err != nil {
+			reqlog.Entry(c).Warn("同步Casbin用户组继承关系失败: " + err.Error())
+		}
+	}
 
-	global.Logger.Infof("用户注册成功: %s", req.Username)
+	msg := utils.Message(c, utils.SUCCESS)
+	if user.Status == models.UserStatusPending {
+		msg = "注册成功，请等待管理员审核"
+	}
+	reqlog.Entry(c).Infof("用户注册成功: %s(%s)", req.Username, userstate.Name(user.Status))
 	c.JSON(200, gin.H{
 		"code": utils.SUCCESS,
-		"msg":  utils.GetErrMsg(utils.SUCCESS),
+		"msg":  msg,
 		"data": user,
 	})
 }
 
+// GetRegisterPolicy 获取注册策略
+// @Summary 获取注册策略接口
+// @Description 返回注册模式、是否需要邀请码和审核，供前端显示注册入口
+// @Tags 用户管理
+// @Produce json
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":registration.Policy}
+// @Router /public/register/policy [get]
+func (u *UserApi) GetRegisterPolicy(c *gin.Context) {
+	c.JSON(200, gin.H{"code": utils.SUCCESS, "msg": utils.Message(c, utils.SUCCESS), "data": registration.Current()})
+}
+
+// respondRegisterError 返回注册策略校验失败的响应
+func respondRegisterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, registration.ErrClosed):
+		c.JSON(403, gin.H{"code": utils.ERROR_REGISTER_CLOSED, "msg": utils.Message(c, utils.ERROR_REGISTER_CLOSED)})
+	case errors.Is(err, registration.ErrDomain):
+		c.JSON(403, gin.H{"code": utils.ERROR_REGISTER_CLOSED, "msg": err.Error()})
+	case errors.Is(err, registration.ErrInvitation):
+		c.JSON(400, gin.H{"code": utils.ERROR_INVITATION, "msg": utils.Message(c, utils.ERROR_INVITATION)})
+	default:
+		reqlog.Entry(c).Error("校验注册策略失败: " + err.Error())
+		c.JSON(500, gin.H{"code": utils.ERROR, "msg": utils.Message(c, utils.ERROR)})
+	}
+}
+
+// userListSpec 用户列表允许的筛选和排序字段
+var userListSpec = search.Spec{
+	Filters: map[string]search.Filter{
+		"username":      {Column: "username", Op: search.OpLike},
+		"nickname":      {Column: "nickname", Op: search.OpLike},
+		"email":         {Column: "email", Op: search.OpLike},
+		"phone":         {Column: "phone", Op: search.OpLike},
+		"status":        {Column: "status", Op: search.OpIn},
+		"gender":        {Column: "gender", Op: search.OpEq},
+		"is_admin":      {Column: "is_admin", Op: search.OpEq},
+		"department_id": {Column: "department_id", Op: search.OpEq},
+		"login_count":   {Column: "login_count", Op: search.OpRange},
+		"created_at":    {Column: "created_at", Op: search.OpDateRange},
+		"last_login_at": {Column: "last_login_at", Op: search.OpDateRange},
+	},
+	Sorts: map[string]string{
+		"id":            "id",
+		"username":      "username",
+		"created_at":    "created_at",
+		"last_login_at": "last_login_at",
+		"login_count":   "login_count",
+	},
+	DefaultSort: "id DESC",
+}
+
 // GetUserList 获取用户列表
 // @Summary 获取用户列表接口
-// @Description 查询系统中的用户列表
+// @Description 分页查询系统中的用户列表，支持筛选和排序
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.User}
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param sort query string false "排序字段，前缀-表示降序，如 -created_at"
+// @Param username query string false "用户名(模糊)"
+// @Param status query string false "状态，多个用逗号分隔"
+// @Param created_at query string false "创建日期区间，如 2024-01-01,2024-01-31"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":search.Page}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/user/list [get]
 func (u *UserApi) GetUserList(c *gin.Context) {
+	q, err := search.Parse(c, userListSpec)
+	if err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	var users []models.User
-	if err := global.DB.Find(&users).Error; err != nil {
-		global.Logger.Error("获取用户列表失败: " + err.Error())
+	page, err := q.Find(global.DB.WithContext(c.Request.Context()).Model(&models.User{}), &users)
+	if err != nil {
+		reqlog.Entry(c).Error("获取用户列表失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "获取用户列表失败"})
 		return
 	}
@@ -122,7 +237,7 @@ func (u *UserApi) GetUserList(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "获取成功",
-		"data": users,
+		"data": page,
 	})
 }
 
@@ -140,21 +255,32 @@ func (u *UserApi) GetUserList(c *gin.Context) {
 func (u *UserApi) CreateUser(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		global.Logger.Error("创建用户参数错误: " + err.Error())
+		reqlog.Entry(c).Error("创建用户参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
+	if user.Status != 0 && !userstate.Valid(user.Status) {
+		c.JSON(400, gin.H{"code": 400, "msg": "无效的用户状态"})
+		return
+	}
+
 	// 密码加密
 	user.Password = utils.MakePassword(user.Password)
 
-	if err := global.DB.Create(&user).Error; err != nil {
-		global.Logger.Error("创建用户失败: " + err.Error())
+	err := global.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return org.SetPrimary(tx, user.ID, user.DepartmentID)
+	})
+	if err != nil {
+		reqlog.Entry(c).Error("创建用户失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "创建失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员创建用户成功: %s", user.Username)
+	reqlog.Entry(c).Infof("管理员创建用户成功: %s", user.Username)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "创建成功",
@@ -175,23 +301,61 @@ func (u *UserApi) CreateUser(c *gin.Context) {
 func (u *UserApi) UpdateUser(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		global.Logger.Error("更新用户参数错误: " + err.Error())
+		reqlog.Entry(c).Error("更新用户参数错误: " + err.Error())
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	// 不允许直接更新密码
+	// 生命周期字段只能通过状态接口和登录流程修改
+	omit := []string{"created_at", "status_at", "expires_at", "locked_until", "lock_reason", "failed_logins"}
 	if user.Password != "" {
 		user.Password = utils.MakePassword(user.Password)
+	} else {
+		omit = append(omit, "password")
 	}
 
-	if err := global.DB.Save(&user).Error; err != nil {
-		global.Logger.Error("更新用户失败: " + err.Error())
+	// 记录变更前的数据
+	db := global.DB.WithContext(c.Request.Context())
+	var before models.User
+	db.First(&before, user.ID)
+
+	// 状态变更需符合状态机
+	status := user.Status
+	if status == 0 {
+		status = before.Status
+	}
+	if status != before.Status {
+		if err := userstate.Check(&before, status); err != nil {
+			c.JSON(400, gin.H{"code": utils.ERROR_USER_STATUS_TRANSITION, "msg": err.Error()})
+			return
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(append(omit, "status")...).Save(&user).Error; err != nil {
+			return err
+		}
+		if user.DepartmentID != before.DepartmentID {
+			if err := org.ReplacePrimary(tx, user.ID, user.DepartmentID); err != nil {
+				return err
+			}
+		}
+		if status == before.Status {
+			return nil
+		}
+		state := before
+		return userstate.Transition(tx, &state, status, "", nil)
+	})
+	if err != nil {
+		reqlog.Entry(c).Error("更新用户失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "更新失败"})
 		return
 	}
+	user.Status = status
+	permcache.Invalidate(c.Request.Context(), user.ID)
+	audit.RecordChange(c, "user", "update", user.ID, before, user)
 
-	global.Logger.Infof("管理员更新用户成功: %s", user.Username)
+	reqlog.Entry(c).Infof("管理员更新用户成功: %s", user.Username)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "更新成功",
@@ -210,22 +374,22 @@ func (u *UserApi) UpdateUser(c *gin.Context) {
 // @Failure 500 {object} gin.H{"code":int, "msg":string}
 // @Router /admin/user/delete [delete]
 func (u *UserApi) DeleteUser(c *gin.Context) {
-	id := c.Query("id")
-	if id == "" {
-		global.Logger.Error("删除用户参数错误: ID为空")
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || id == 0 {
+		reqlog.Entry(c).Error("删除用户参数错误: ID无效")
 		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
 		return
 	}
 
-	if err := global.DB.Delete(&models.User{}, id).Error; err != nil {
-		global.Logger.Error("删除用户失败: " + err.Error())
+	if err := recycle.Delete(global.DB.WithContext(c.Request.Context()), "user", uint(id)); err != nil {
+		reqlog.Entry(c).Error("删除用户失败: " + err.Error())
 		c.JSON(500, gin.H{"code": 500, "msg": "删除失败"})
 		return
 	}
 
-	global.Logger.Infof("管理员删除用户成功: ID=%s", id)
+	reqlog.Entry(c).Infof("管理员删除用户成功，已移入回收站: ID=%d", id)
 	c.JSON(200, gin.H{
 		"code": 200,
 		"msg":  "删除成功",
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,138 @@
+package user_api
+
+import (
+	"errors"
+	"strconv"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/usergroup"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errRoleNotFound 分配的角色不存在
+var errRoleNotFound = errors.New("角色不存在")
+
+// SetRolesRequest 设置用户角色请求参数
+type SetRolesRequest struct {
+	// 用户ID
+	UserID uint `json:"user_id" binding:"required"`
+	// 角色ID，为空表示清除直接分配的角色，通过用户组获得的角色不受影响
+	RoleIDs []uint `json:"role_ids" binding:"max=100"`
+}
+
+// GetUserRoles 获取用户角色
+// @Summary 获取用户角色接口
+// @Description 查询直接分配给用户的角色，通过用户组获得的角色见 /admin/group/effective-roles
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param user_id query int true "用户ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":[]models.Role}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/roles [get]
+func (u *UserApi) GetUserRoles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	roles := []models.Role{}
+	if err := global.DB.WithContext(c.Request.Context()).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", id).Order("roles.sort, roles.id").Find(&roles).Error; err != nil {
+		reqlog.Entry(c).Error("获取用户角色失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "获取失败"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": roles,
+	})
+}
+
+// SetUserRoles 设置用户角色
+// @Summary 设置用户角色接口
+// @Description 替换直接分配给用户的角色，用户的权限标识缓存同时失效
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body SetRolesRequest true "用户ID和角色ID"
+// @Success 200 {object} gin.H{"code":int, "msg":string}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/roles [put]
+func (u *UserApi) SetUserRoles(c *gin.Context) {
+	var req SetRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("设置用户角色参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	if err := db.First(&models.User{}, req.UserID).Error; err != nil {
+		c.JSON(400, gin.H{"code": 400, "msg": "用户不存在"})
+		return
+	}
+	var before []uint
+	db.Model(&models.UserRole{}).Where("user_id = ?", req.UserID).Order("role_id").Pluck("role_id", &before)
+
+	roleIDs := make([]uint, 0, len(req.RoleIDs))
+	seen := make(map[uint]bool, len(req.RoleIDs))
+	for _, id := range req.RoleIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			roleIDs = append(roleIDs, id)
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(roleIDs) > 0 {
+			var count int64
+			if err := tx.Model(&models.Role{}).Where("id IN ?", roleIDs).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(roleIDs) {
+				return errRoleNotFound
+			}
+		}
+		if err := tx.Where("user_id = ?", req.UserID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		for _, id := range roleIDs {
+			if err := tx.Create(&models.UserRole{UserID: req.UserID, RoleID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRoleNotFound) {
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if err != nil {
+		reqlog.Entry(c).Error("设置用户角色失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": "设置失败"})
+		return
+	}
+	permcache.Invalidate(c.Request.Context(), req.UserID)
+	if err := usergroup.SyncCasbin(db); err != nil {
+		reqlog.Entry(c).Warn("同步用户角色到Casbin失败: " + err.Error())
+	}
+	audit.RecordChange(c, "user", "set-roles", req.UserID, before, roleIDs)
+
+	reqlog.Entry(c).Infof("管理员设置用户角色成功: 用户ID=%d", req.UserID)
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "设置成功",
+	})
+}
@@ -0,0 +1,109 @@
+package user_api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/userstate"
+)
+
+// SetUserStatus 变更用户状态
+// @Summary 变更用户状态接口
+// @Description 按状态机变更用户状态: 1正常, 2锁定, 3待激活, 4已过期, 5已归档；锁定时可指定原因和截止时间
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param status body struct{UserID uint, Status int, Reason string, LockedUntil string} true "状态信息"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":models.User}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/status [put]
+func (u *UserApi) SetUserStatus(c *gin.Context) {
+	var req struct {
+		UserID      uint       `json:"user_id" binding:"required"`
+		Status      int        `json:"status" binding:"required"`
+		Reason      string     `json:"reason"`
+		LockedUntil *time.Time `json:"locked_until"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.Message(c, utils.ERROR_INVALID_PARAM)})
+		return
+	}
+	if req.LockedUntil != nil && !req.LockedUntil.After(time.Now()) {
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": "锁定截止时间必须晚于当前时间"})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		c.JSON(400, gin.H{"code": utils.ERROR_USER_NOT_EXIST, "msg": utils.Message(c, utils.ERROR_USER_NOT_EXIST)})
+		return
+	}
+
+	before := user
+	if err := userstate.Transition(db, &user, req.Status, req.Reason, req.LockedUntil); err != nil {
+		respondStateError(c, "变更用户状态失败", err)
+		return
+	}
+	audit.RecordChange(c, "user", "status", user.ID, before, user)
+
+	reqlog.Entry(c).Infof("用户 %s 状态变更: %s → %s", user.Username, userstate.Name(before.Status), userstate.Name(user.Status))
+	c.JSON(200, gin.H{"code": 200, "msg": "状态已变更为" + userstate.Name(user.Status), "data": user})
+}
+
+// SetUserExpiry 设置账号到期时间
+// @Summary 设置账号到期时间接口
+// @Description 设置外包等临时账号的到期时间，为空表示永久有效；已过期账号延期后恢复正常
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param expiry body struct{UserID uint, ExpiresAt string} true "到期时间"
+// @Success 200 {object} gin.H{"code":int, "msg":string, "data":models.User}
+// @Failure 400 {object} gin.H{"code":int, "msg":string}
+// @Failure 500 {object} gin.H{"code":int, "msg":string}
+// @Router /admin/user/expiry [put]
+func (u *UserApi) SetUserExpiry(c *gin.Context) {
+	var req struct {
+		UserID    uint       `json:"user_id" binding:"required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": utils.ERROR_INVALID_PARAM, "msg": utils.Message(c, utils.ERROR_INVALID_PARAM)})
+		return
+	}
+
+	db := global.DB.WithContext(c.Request.Context())
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		c.JSON(400, gin.H{"code": utils.ERROR_USER_NOT_EXIST, "msg": utils.Message(c, utils.ERROR_USER_NOT_EXIST)})
+		return
+	}
+
+	before := user
+	if err := userstate.SetExpiry(db, &user, req.ExpiresAt); err != nil {
+		respondStateError(c, "设置账号到期时间失败", err)
+		return
+	}
+	audit.RecordChange(c, "user", "expiry", user.ID, before, user)
+
+	reqlog.Entry(c).Infof("用户 %s 到期时间已设置: %v", user.Username, req.ExpiresAt)
+	c.JSON(200, gin.H{"code": 200, "msg": "设置成功", "data": user})
+}
+
+// respondStateError 响应状态变更错误，不允许的变更返回400
+func respondStateError(c *gin.Context, action string, err error) {
+	if errors.Is(err, userstate.ErrTransition) {
+		c.JSON(400, gin.H{"code": utils.ERROR_USER_STATUS_TRANSITION, "msg": err.Error()})
+		return
+	}
+	reqlog.Entry(c).Errorf("%s: %v", action, err)
+	c.JSON(500, gin.H{"code": utils.ERROR_UPDATE_USER, "msg": utils.Message(c, utils.ERROR_UPDATE_USER)})
+}
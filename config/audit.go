@@ -0,0 +1,20 @@
+package config
+
+// AuditConfig 审计日志配置
+// 审计日志按类型分别维护哈希链，只能通过保留策略删除
+type AuditConfig struct {
+	Enable             bool   `yaml:"enable"`              // 是否记录操作审计日志
+	CheckpointFile     string `yaml:"checkpoint_file"`     // 签名检查点文件路径
+	CheckpointInterval int    `yaml:"checkpoint_interval"` // 检查点写入间隔(分钟)
+	SigningSecret      string `yaml:"signing_secret"`      // 检查点和日志匿名化的签名密钥，必须单独配置，不能与JWT密钥相同
+	MaxBodySize        int    `yaml:"max_body_size"`       // 记录请求体的最大字节数
+
+	Retention         map[string]int `yaml:"retention"`          // 各日志类型保留天数，0或未配置表示永久保留
+	RetentionInterval int            `yaml:"retention_interval"` // 保留策略执行间隔(小时)
+	ArchiveDir        string         `yaml:"archive_dir"`        // 归档文件目录，为空时过期日志直接删除
+}
+
+// RetentionDays 返回指定日志类型的保留天数
+func (a AuditConfig) RetentionDays(logType string) int {
+	return a.Retention[logType]
+}
@@ -0,0 +1,9 @@
+package config
+
+// AvatarConfig 头像配置
+type AvatarConfig struct {
+	MaxSize   int    `yaml:"max_size"`   // 上传图片最大大小(MB)
+	MaxPixels int    `yaml:"max_pixels"` // 上传图片最大像素数(万)，防止解码超大图片
+	Sizes     []int  `yaml:"sizes"`      // 生成的头像边长(px)，最大的作为用户头像
+	SavePath  string `yaml:"save_path"`  // 保存目录，需位于uploads下才能通过/uploads访问
+}
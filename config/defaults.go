@@ -68,6 +68,10 @@ func DefaultConfig() *Config {
 			CSRFProtection:     true,
 			RateLimit:          100,
 			BcryptCost:         12,
+			MaxLoginFailures:   5,
+			LockoutMinutes:     15,
+			ExpiryInterval:     10,
+			ImpersonateTTL:     30,
 		},
 		CORS: CORSConfig{
 			AllowOrigins: []string{"http://localhost:3000", "http://localhost:8080"},
@@ -82,6 +86,8 @@ func DefaultConfig() *Config {
 			HealthCheckPath:   "/health",
 			MetricsPath:       "/metrics",
 			TraceSamplingRate: 0.1,
+			MetricsToken:      "",
+			MetricsAllowIPs:   []string{"127.0.0.1", "::1"},
 		},
 		Swagger: SwaggerConfig{
 			Enabled:        true,
@@ -128,5 +134,45 @@ func DefaultConfig() *Config {
 	Captcha: Captcha{
 		Enable: true,
 	},
+	Audit: AuditConfig{
+		Enable:             true,
+		CheckpointFile:     "./logs/audit_checkpoints.jsonl",
+		CheckpointInterval: 60,
+		SigningSecret:      "",
+		MaxBodySize:        4096,
+		Retention: map[string]int{
+			"operation": 0,
+			"login":     0,
+			"change":    0,
+		},
+		RetentionInterval: 24,
+		ArchiveDir:        "./archives/logs",
+	},
+	Recycle: RecycleConfig{
+		RetentionDays: 30,
+		PurgeInterval: 24,
+	},
+	Avatar: AvatarConfig{
+		MaxSize:   5,
+		MaxPixels: 2500,
+		Sizes:     []int{40, 100, 256},
+		SavePath:  "uploads/avatars",
+	},
+	SMS: SMSConfig{
+		Provider: "log",
+	},
+	Login: LoginConfig{
+		Identifiers: []string{"username", "email", "phone"},
+		EmailCode:   false,
+		CodeTTL:     5,
+	},
+	Register: RegisterConfig{
+		Mode: "open",
+	},
+	Privacy: PrivacyConfig{
+		DeletionDelay:  15,
+		ExportMaxLogs:  10000,
+		DeleteInterval: 60,
+	},
 	}
 }
\ No newline at end of file
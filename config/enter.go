@@ -16,4 +16,11 @@ type Config struct {
 	Upload       UploadConfig     `yaml:"upload"`
 	Email        Email            `yaml:"email"`
 	Captcha      Captcha          `yaml:"captcha"`
+	Audit        AuditConfig      `yaml:"audit"`
+	Recycle      RecycleConfig    `yaml:"recycle"`
+	Avatar       AvatarConfig     `yaml:"avatar"`
+	SMS          SMSConfig        `yaml:"sms"`
+	Login        LoginConfig      `yaml:"login"`
+	Register     RegisterConfig   `yaml:"register"`
+	Privacy      PrivacyConfig    `yaml:"privacy"`
 }
\ No newline at end of file
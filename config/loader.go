@@ -137,6 +137,13 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("JWT密钥不能为空")
 	}
 
+	if cfg.Audit.SigningSecret == "" {
+		return fmt.Errorf("审计签名密钥不能为空")
+	}
+	if cfg.Audit.SigningSecret == cfg.JWT.Secret {
+		return fmt.Errorf("审计签名密钥不能与JWT密钥相同")
+	}
+
 	if cfg.DB.Mode == "" {
 		return fmt.Errorf("数据库类型不能为空")
 	}
@@ -211,6 +218,11 @@ func applyEnvironmentVariables(cfg *Config) {
 		cfg.JWT.Issuer = issuer
 	}
 
+	// 审计配置
+	if secret := os.Getenv("AUDIT_SIGNING_SECRET"); secret != "" {
+		cfg.Audit.SigningSecret = secret
+	}
+
 	// Redis配置
 	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
 		cfg.Redis.Addr = addr
@@ -0,0 +1,8 @@
+package config
+
+// LoginConfig 登录方式配置
+type LoginConfig struct {
+	Identifiers []string `yaml:"identifiers"` // 允许作为登录账号的字段: username, email, phone
+	EmailCode   bool     `yaml:"email_code"`  // 是否允许使用邮箱验证码免密登录
+	CodeTTL     int      `yaml:"code_ttl"`    // 登录验证码有效期(分钟)
+}
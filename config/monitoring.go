@@ -7,4 +7,6 @@ type MonitoringConfig struct {
 	HealthCheckPath    string `yaml:"health_check_path"`
 	MetricsPath        string `yaml:"metrics_path"`
 	TraceSamplingRate  float64 `yaml:"trace_sampling_rate"`
+	MetricsToken       string   `yaml:"metrics_token"`     // 指标接口访问令牌，请求头 Authorization: Bearer <令牌>
+	MetricsAllowIPs    []string `yaml:"metrics_allow_ips"` // 允许不带令牌访问指标接口的IP或网段
 }
\ No newline at end of file
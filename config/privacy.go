@@ -0,0 +1,8 @@
+package config
+
+// PrivacyConfig 个人数据导出和账号注销配置
+type PrivacyConfig struct {
+	DeletionDelay  int `yaml:"deletion_delay"`  // 申请注销后的冷静期(天)，期间可撤销，到期后匿名化账号
+	ExportMaxLogs  int `yaml:"export_max_logs"` // 导出时每类日志最多包含的条数，按时间倒序
+	DeleteInterval int `yaml:"delete_interval"` // 检查到期注销申请的间隔(分钟)
+}
@@ -0,0 +1,7 @@
+package config
+
+// RecycleConfig 回收站配置
+type RecycleConfig struct {
+	RetentionDays int `yaml:"retention_days"` // 已删除记录保留天数，超过后彻底删除，0表示不自动清理
+	PurgeInterval int `yaml:"purge_interval"` // 自动清理执行间隔(小时)
+}
@@ -0,0 +1,8 @@
+package config
+
+// RegisterConfig 自助注册配置
+type RegisterConfig struct {
+	Mode           string   `yaml:"mode"`            // 注册模式: disabled关闭, open开放, domain限定邮箱域名, invite凭邀请码, approval需管理员审核
+	AllowedDomains []string `yaml:"allowed_domains"` // domain模式允许的邮箱域名
+	DefaultRole    string   `yaml:"default_role"`    // 自助注册用户默认分配的角色标识，为空不分配；邀请码指定角色时以邀请码为准
+}
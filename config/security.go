@@ -8,4 +8,9 @@ type SecurityConfig struct {
 	CSRFProtection     bool   `yaml:"csrf_protection"`
 	RateLimit          int    `yaml:"rate_limit"`
 	BcryptCost         int    `yaml:"bcrypt_cost"`
+
+	MaxLoginFailures int `yaml:"max_login_failures"` // 连续登录失败多少次后锁定账号，0表示不锁定
+	LockoutMinutes   int `yaml:"lockout_minutes"`    // 登录失败锁定时长(分钟)，0表示需管理员解锁
+	ExpiryInterval   int `yaml:"expiry_interval"`    // 账号到期检查间隔(分钟)
+	ImpersonateTTL   int `yaml:"impersonate_ttl"`    // 模拟登录令牌有效期(分钟)，不签发刷新令牌
 }
\ No newline at end of file
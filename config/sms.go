@@ -0,0 +1,6 @@
+package config
+
+// SMSConfig 短信配置
+type SMSConfig struct {
+	Provider string `yaml:"provider"` // 短信服务商，需在sms包中注册；log只写日志不实际发送
+}
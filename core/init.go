@@ -10,6 +10,8 @@ import (
 	"rbac_admin_server/core/init_gorm"
 	"rbac_admin_server/core/init_redis"
 	"rbac_admin_server/global"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/usergroup"
 )
 
 // InitSystem 初始化系统核心组件
@@ -46,6 +48,11 @@ func InitSystem() error {
 	}
 	global.Logger.Info("✅ 数据库表结构自动迁移成功")
 
+	// 写入缺少的错误信息翻译，已有的翻译不会被覆盖
+	if err := utils.SeedTranslations(global.DB); err != nil {
+		global.Logger.Warnf("⚠️ %v", err)
+	}
+
 	// 5. 初始化Casbin权限管理
 	casbinEnforcer, err := init_casbin.InitCasbin()
 	if err != nil {
@@ -53,6 +60,9 @@ func InitSystem() error {
 	} else {
 		global.Casbin = casbinEnforcer
 		global.Logger.Info("✅ Casbin权限管理初始化成功")
+		if err := usergroup.SyncCasbin(global.DB); err != nil {
+			global.Logger.Warnf("⚠️ 同步用户组继承关系到Casbin失败: %v", err)
+		}
 	}
 
 	global.Logger.Info("🎉 系统核心组件初始化完成")
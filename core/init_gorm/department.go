@@ -0,0 +1,27 @@
+package init_gorm
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/depttree"
+)
+
+// migrateDeptPaths 升级后旧版部门没有路径时重新生成全部部门的路径和层级
+func migrateDeptPaths(db *gorm.DB) error {
+	var count int64
+	if err := db.Unscoped().Model(&models.Department{}).Where("path = '' OR path IS NULL").Count(&count).Error; err != nil {
+		return fmt.Errorf("检查部门路径失败: %w", err)
+	}
+	if count == 0 {
+		return nil
+	}
+	report, err := depttree.Rebuild(db)
+	if err != nil {
+		return fmt.Errorf("生成部门路径失败: %w", err)
+	}
+	logrus.Infof("已生成部门路径: 共%d个部门, 更新%d个, 改为顶级%d个", report.Total, report.Updated, len(report.Orphans))
+	return nil
+}
@@ -25,15 +25,12 @@ func InitGorm() (*gorm.DB, error) {
 		logLevel = logger.Warn
 	}
 
-	// 创建新的日志器
-	newLogger := logger.New(
-		global.Logger, // 使用全局日志实例
-		logger.Config{
-			SlowThreshold: 200, // 慢SQL阈值
-			LogLevel:      logLevel,
-			Colorful:      true,
-		},
-	)
+	// 创建新的日志器，SQL日志附带请求ID
+	newLogger := newRequestLogger(logger.Config{
+		SlowThreshold:             200 * time.Millisecond, // 慢SQL阈值
+		LogLevel:                  logLevel,
+		IgnoreRecordNotFoundError: true,
+	})
 
 	// 通用配置
 	dbConfig := &gorm.Config{
@@ -67,6 +64,11 @@ func InitGorm() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// 注册软删除回调，维护唯一索引的删除标记
+	if err := registerSoftDelete(db); err != nil {
+		return nil, err
+	}
+
 	// 获取数据库连接池
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -97,6 +99,15 @@ func MigrateTables(db *gorm.DB) error {
 		&models.Permission{},
 		&models.UserRole{},
 		&models.RolePermission{},
+		&models.UserSettings{},
+		&models.Invitation{},
+		&models.DeletionRequest{},
+		&models.Position{},
+		&models.UserPosition{},
+		&models.UserDepartment{},
+		&models.UserGroup{},
+		&models.UserGroupMember{},
+		&models.UserGroupRole{},
 
 		// 菜单模型
 		&models.Menu{},
@@ -107,16 +118,33 @@ func MigrateTables(db *gorm.DB) error {
 		&models.Dict{},
 		&models.DictItem{},
 		&models.Config{},
+		&models.Translation{},
 
 		// 文件和日志模型
 		&models.File{},
 		&models.Log{},
+		&models.LogArchive{},
+
+		// 回收站模型
+		&models.RecycleLink{},
 	}
 
 	// 执行迁移
 	if err := db.AutoMigrate(tables...); err != nil {
 		return fmt.Errorf("迁移模型失败: %v", err)
 	}
+	if err := migrateSoftDelete(db); err != nil {
+		return err
+	}
+	if err := migrateDeptPaths(db); err != nil {
+		return err
+	}
+	if err := migrateUserDepartments(db); err != nil {
+		return err
+	}
+	if err := migrateMenus(db); err != nil {
+		return err
+	}
 
 	logrus.Info("✅ 数据库表迁移成功")
 	return nil
@@ -0,0 +1,92 @@
+package init_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// requestLogger 输出到全局logrus实例的GORM日志器
+// 通过db.WithContext传入的上下文中有请求ID时，SQL日志附带request_id字段
+type requestLogger struct {
+	config logger.Config
+}
+
+// newRequestLogger 创建带请求ID的GORM日志器
+func newRequestLogger(config logger.Config) logger.Interface {
+	return &requestLogger{config: config}
+}
+
+// entry 返回带调用位置和请求ID的日志实例
+// caller须在各日志方法中直接调用utils.FileWithLineNum获取，才能跳过GORM内部的调用栈
+func (l *requestLogger) entry(ctx context.Context, caller string) *logrus.Entry {
+	entry := global.Logger.WithField("caller", caller)
+	if id := reqlog.RequestID(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+	return entry
+}
+
+func (l *requestLogger) LogMode(level logger.LogLevel) logger.Interface {
+	config := l.config
+	config.LogLevel = level
+	return &requestLogger{config: config}
+}
+
+func (l *requestLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Info {
+		l.entry(ctx, utils.FileWithLineNum()).Infof(msg, data...)
+	}
+}
+
+func (l *requestLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Warn {
+		l.entry(ctx, utils.FileWithLineNum()).Warnf(msg, data...)
+	}
+}
+
+func (l *requestLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.config.LogLevel >= logger.Error {
+		l.entry(ctx, utils.FileWithLineNum()).Errorf(msg, data...)
+	}
+}
+
+// Trace 记录SQL执行情况，出错和慢查询分别以Error和Warn级别输出
+func (l *requestLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.config.LogLevel <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	slow := l.config.SlowThreshold != 0 && elapsed > l.config.SlowThreshold
+
+	switch {
+	case err != nil && l.config.LogLevel >= logger.Error &&
+		(!errors.Is(err, gorm.ErrRecordNotFound) || !l.config.IgnoreRecordNotFoundError):
+	case slow && l.config.LogLevel >= logger.Warn:
+	case l.config.LogLevel >= logger.Info:
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	entry := l.entry(ctx, utils.FileWithLineNum()).WithFields(logrus.Fields{
+		"elapsed": fmt.Sprintf("%.3fms", float64(elapsed.Nanoseconds())/1e6),
+		"rows":    rows,
+	})
+	switch {
+	case err != nil && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.config.IgnoreRecordNotFoundError):
+		entry.WithError(err).Error(sql)
+	case slow:
+		entry.Warnf("慢SQL >= %v: %s", l.config.SlowThreshold, sql)
+	default:
+		entry.Info(sql)
+	}
+}
@@ -0,0 +1,117 @@
+package init_gorm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"rbac_admin_server/models"
+)
+
+// legacyMenuTypes 旧版菜单接口以数字保存的类型
+var legacyMenuTypes = [][2]string{
+	{"1", models.MenuTypeDir},
+	{"2", models.MenuTypeMenu},
+	{"3", models.MenuTypeButton},
+}
+
+// migrateMenus 将旧版以权限(类型dir/menu)保存的菜单迁移到菜单表
+// 旧版按数字保存的类型先转换为dir/menu/button
+// 角色的权限关联同时转为菜单关联，迁移后彻底删除原权限记录
+// 旧版菜单下的按钮权限复制为菜单按钮，权限记录保留并改为顶级权限
+func migrateMenus(db *gorm.DB) error {
+	for _, t := range legacyMenuTypes {
+		if err := db.Unscoped().Model(&models.Permission{}).Where("type = ?", t[0]).
+			UpdateColumn("type", t[1]).Error; err != nil {
+			return fmt.Errorf("转换旧版菜单类型失败: %w", err)
+		}
+	}
+
+	var legacy []models.Permission
+	if err := db.Unscoped().Where("type IN ?", []string{models.MenuTypeDir, models.MenuTypeMenu}).
+		Order("id").Find(&legacy).Error; err != nil {
+		return fmt.Errorf("查询旧版菜单失败: %w", err)
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		ids := make(map[uint]uint, len(legacy)) // 权限ID → 菜单ID
+		permissionIDs := make([]uint, 0, len(legacy))
+		for _, p := range legacy {
+			menu := models.Menu{
+				Name: p.Name, Path: p.Path, Component: p.Component, Icon: p.Icon, Type: p.Type,
+				Permission: p.Key, Sort: p.Sort, Status: p.Status,
+				Meta: models.MenuMeta{Title: p.Name, Icon: p.Icon},
+			}
+			menu.CreatedAt = p.CreatedAt
+			menu.DeletedAt = p.DeletedAt
+			if err := tx.Create(&menu).Error; err != nil {
+				return err
+			}
+			ids[p.ID] = menu.ID
+			permissionIDs = append(permissionIDs, p.ID)
+		}
+		// 上级也是旧版菜单的，改为指向迁移后的菜单
+		for _, p := range legacy {
+			if parent, ok := ids[p.ParentID]; ok {
+				if err := tx.Unscoped().Model(&models.Menu{}).Where("id = ?", ids[p.ID]).
+					UpdateColumn("parent_id", parent).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// 旧版菜单下的按钮复制为菜单按钮，角色关联同样复制
+		var buttons []models.Permission
+		if err := tx.Unscoped().Where("type = ? AND parent_id IN ?", models.MenuTypeButton, permissionIDs).
+			Order("id").Find(&buttons).Error; err != nil {
+			return err
+		}
+		menuIDs := make(map[uint]uint, len(ids)+len(buttons)) // 权限ID → 需要复制角色关联的菜单ID
+		for id, menuID := range ids {
+			menuIDs[id] = menuID
+		}
+		buttonIDs := make([]uint, 0, len(buttons))
+		for _, p := range buttons {
+			menu := models.Menu{
+				Name: p.Name, Type: models.MenuTypeButton, Permission: p.Key, Sort: p.Sort, Status: p.Status,
+				ParentID: ids[p.ParentID], Meta: models.MenuMeta{Title: p.Name},
+			}
+			menu.CreatedAt = p.CreatedAt
+			menu.DeletedAt = p.DeletedAt
+			if err := tx.Create(&menu).Error; err != nil {
+				return err
+			}
+			menuIDs[p.ID] = menu.ID
+			buttonIDs = append(buttonIDs, p.ID)
+		}
+		// 按钮权限的上级已迁移，改为顶级权限
+		if len(buttonIDs) > 0 {
+			if err := tx.Unscoped().Model(&models.Permission{}).Where("id IN ?", buttonIDs).
+				UpdateColumn("parent_id", 0).Error; err != nil {
+				return err
+			}
+		}
+
+		var links []models.RolePermission
+		if err := tx.Where("permission_id IN ?", append(permissionIDs, buttonIDs...)).Find(&links).Error; err != nil {
+			return err
+		}
+		for _, l := range links {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.RoleMenu{RoleID: l.RoleID, MenuID: menuIDs[l.PermissionID]}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("permission_id IN ?", permissionIDs).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Permission{}, permissionIDs).Error
+	})
+	if err != nil {
+		return fmt.Errorf("迁移旧版菜单失败: %w", err)
+	}
+	return nil
+}
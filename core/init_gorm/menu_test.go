@@ -0,0 +1,104 @@
+package init_gorm
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"rbac_admin_server/models"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&models.Permission{}, &models.RolePermission{}, &models.Menu{}, &models.RoleMenu{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrateMenusNumericTypes(t *testing.T) {
+	db := openDB(t)
+
+	// 旧版菜单接口按数字保存类型：1目录 2菜单 3按钮
+	legacy := []models.Permission{
+		{Name: "系统管理", Key: "system", Type: "1", Path: "/system"},
+		{Name: "用户管理", Key: "system:user", Type: "2", Path: "/system/user", Component: "system/user/index", ParentID: 1},
+		{Name: "新增用户", Key: "system:user:add", Type: "3", ParentID: 2},
+		{Name: "用户列表", Key: "user:list", Type: "api", Method: "GET", Path: "/admin/user/list"},
+	}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	links := []models.RolePermission{{RoleID: 1, PermissionID: 2}, {RoleID: 1, PermissionID: 3}, {RoleID: 1, PermissionID: 4}}
+	if err := db.Create(&links).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateMenus(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var menus []models.Menu
+	db.Order("id").Find(&menus)
+	// parent为上级在迁移结果中的序号，0表示顶级
+	want := []struct {
+		name, typ string
+		parent    int
+	}{
+		{"系统管理", models.MenuTypeDir, 0},
+		{"用户管理", models.MenuTypeMenu, 1},
+		{"新增用户", models.MenuTypeButton, 2},
+	}
+	if len(menus) != len(want) {
+		t.Fatalf("迁移后菜单 %d 个, want %d", len(menus), len(want))
+	}
+	for i, w := range want {
+		m := menus[i]
+		var parent uint
+		if w.parent > 0 {
+			parent = menus[w.parent-1].ID
+		}
+		if m.Name != w.name || m.Type != w.typ || m.ParentID != parent {
+			t.Errorf("菜单%d = %s/%s/%d, want %s/%s", i+1, m.Name, m.Type, m.ParentID, w.name, w.typ)
+		}
+	}
+
+	var permissions []models.Permission
+	db.Unscoped().Order("id").Find(&permissions)
+	if len(permissions) != 2 {
+		t.Fatalf("应只保留按钮和接口权限，实际 %d 个", len(permissions))
+	}
+	if p := permissions[0]; p.Key != "system:user:add" || p.Type != models.MenuTypeButton || p.ParentID != 0 {
+		t.Errorf("按钮权限 = %s/%s/%d", p.Key, p.Type, p.ParentID)
+	}
+	if p := permissions[1]; p.Key != "user:list" || p.Type != "api" {
+		t.Errorf("接口权限被修改: %s/%s", p.Key, p.Type)
+	}
+
+	var roleMenus int64
+	db.Model(&models.RoleMenu{}).Where("role_id = ?", 1).Count(&roleMenus)
+	if roleMenus != 2 {
+		t.Errorf("角色菜单关联 %d 个, want 2", roleMenus)
+	}
+
+	// 再次执行不应重复迁移
+	if err := migrateMenus(db); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&models.Menu{}).Count(&count)
+	if count != int64(len(want)) {
+		t.Fatalf("重复迁移后菜单 %d 个", count)
+	}
+}
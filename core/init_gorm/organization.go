@@ -0,0 +1,28 @@
+package init_gorm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"rbac_admin_server/models"
+)
+
+// migrateUserDepartments 合并旧版users.dept_id字段，并为主部门补写所属部门记录
+func migrateUserDepartments(db *gorm.DB) error {
+	if db.Migrator().HasColumn(&models.User{}, "dept_id") {
+		if err := db.Exec("UPDATE users SET department_id = dept_id WHERE (department_id IS NULL OR department_id = 0) AND dept_id <> 0").Error; err != nil {
+			return fmt.Errorf("合并dept_id字段失败: %w", err)
+		}
+		if err := db.Migrator().DropColumn(&models.User{}, "dept_id"); err != nil {
+			return fmt.Errorf("删除dept_id字段失败: %w", err)
+		}
+	}
+	err := db.Exec(`INSERT INTO user_departments (user_id, department_id, is_primary, is_leader)
+		SELECT id, department_id, ?, ? FROM users WHERE department_id <> 0 AND NOT EXISTS (
+			SELECT 1 FROM user_departments WHERE user_departments.user_id = users.id AND user_departments.department_id = users.department_id)`,
+		true, false).Error
+	if err != nil {
+		return fmt.Errorf("补写用户所属部门失败: %w", err)
+	}
+	return nil
+}
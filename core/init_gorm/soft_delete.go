@@ -0,0 +1,68 @@
+package init_gorm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"rbac_admin_server/models"
+)
+
+// deletedKeyModels 带DeletedKey字段的模型及其旧版单列唯一索引
+// 旧索引会让已删除记录继续占用唯一值，迁移时删除
+var deletedKeyModels = []struct {
+	model  interface{}
+	legacy []string
+}{
+	{&models.User{}, []string{"idx_users_username", "idx_users_email", "idx_users_phone"}},
+	{&models.Role{}, []string{"idx_roles_name", "idx_roles_key"}},
+	{&models.Permission{}, []string{"idx_permissions_key"}},
+	{&models.Dict{}, []string{"idx_dicts_key"}},
+	{&models.Position{}, nil},
+	{&models.UserGroup{}, nil},
+}
+
+// registerSoftDelete 注册软删除回调
+// 软删除只更新deleted_at，回调在同一事务中为已删除记录写入DeletedKey
+func registerSoftDelete(db *gorm.DB) error {
+	return db.Callback().Delete().After("gorm:delete").Register("app:deleted_key", fillDeletedKey)
+}
+
+// fillDeletedKey 将已删除但DeletedKey仍为0的记录标记为其ID
+func fillDeletedKey(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Unscoped || stmt.Schema == nil || stmt.Schema.LookUpField("DeletedKey") == nil {
+		return
+	}
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(stmt.Table).
+		Where("deleted_at IS NOT NULL AND deleted_key = 0").
+		UpdateColumn("deleted_key", gorm.Expr("id")).Error
+	if err != nil {
+		db.AddError(fmt.Errorf("写入删除标记失败: %w", err))
+	}
+}
+
+// migrateSoftDelete 为已删除记录补写DeletedKey，删除旧版唯一索引，并将用户的空邮箱和手机号改为NULL
+func migrateSoftDelete(db *gorm.DB) error {
+	// 唯一索引不限制NULL，空字符串会让多个未填写邮箱或手机号的用户冲突
+	for _, column := range []string{"email", "phone"} {
+		if err := db.Model(&models.User{}).Unscoped().Where(column+" = ?", "").
+			UpdateColumn(column, nil).Error; err != nil {
+			return fmt.Errorf("清除空的%s失败: %w", column, err)
+		}
+	}
+	for _, m := range deletedKeyModels {
+		if err := db.Model(m.model).Unscoped().Where("deleted_at IS NOT NULL AND deleted_key = 0").
+			UpdateColumn("deleted_key", gorm.Expr("id")).Error; err != nil {
+			return fmt.Errorf("补写删除标记失败: %w", err)
+		}
+		for _, name := range m.legacy {
+			if !db.Migrator().HasIndex(m.model, name) {
+				continue
+			}
+			if err := db.Migrator().DropIndex(m.model, name); err != nil {
+				return fmt.Errorf("删除旧唯一索引 %s 失败: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+
+	"rbac_admin_server/utils/i18n"
 )
 
 var (
@@ -162,37 +164,53 @@ func ValidateVar(value interface{}, tag string) error {
 	return Validate.Var(value, tag)
 }
 
-// FormatValidationError 格式化验证错误
-func FormatValidationError(err error) map[string]string {
+// validationMessages 参数校验信息的内置翻译，{field}和{param}替换为字段名和规则参数
+// 未列出的规则使用default，翻译管理中按规则添加的翻译优先
+var validationMessages = map[string]map[string]string{
+	"zh-CN": {
+		"required":     "{field}不能为空",
+		"email":        "请输入有效的邮箱地址",
+		"phone":        "请输入有效的手机号",
+		"username":     "用户名必须是3-20位的字母、数字或下划线",
+		"password":     "密码必须是8-20位，包含大小写字母、数字、特殊字符中的至少3种",
+		"chinese_name": "请输入2-10个汉字的中文姓名",
+		"id_card":      "请输入有效的身份证号",
+		"min":          "{field}长度不能少于{param}个字符",
+		"max":          "{field}长度不能超过{param}个字符",
+		"len":          "{field}长度必须是{param}个字符",
+		"default":      "{field}格式不正确",
+	},
+	"en-US": {
+		"required":     "{field} is required",
+		"email":        "Please enter a valid email address",
+		"phone":        "Please enter a valid phone number",
+		"username":     "Username must be 3-20 letters, digits or underscores",
+		"password":     "Password must be 8-20 characters and contain at least 3 of uppercase letters, lowercase letters, digits and special characters",
+		"chinese_name": "Please enter a Chinese name of 2-10 characters",
+		"id_card":      "Please enter a valid ID card number",
+		"min":          "{field} must be at least {param} characters",
+		"max":          "{field} must be at most {param} characters",
+		"len":          "{field} must be exactly {param} characters",
+		"default":      "{field} is invalid",
+	},
+}
+
+// FormatValidationError 按指定语言格式化验证错误，键为小写的字段名
+func FormatValidationError(err error, locale string) map[string]string {
 	errors := make(map[string]string)
 
+	messages, ok := validationMessages[locale]
+	if !ok {
+		messages = validationMessages[i18n.DefaultLocale]
+	}
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
 		for _, e := range validationErrors {
-			field := strings.ToLower(e.Field())
-			switch e.Tag() {
-			case "required":
-				errors[field] = fmt.Sprintf("%s不能为空", e.Field())
-			case "email":
-				errors[field] = "请输入有效的邮箱地址"
-			case "phone":
-				errors[field] = "请输入有效的手机号"
-			case "username":
-				errors[field] = "用户名必须是3-20位的字母、数字或下划线"
-			case "password":
-				errors[field] = "密码必须是8-20位，包含大小写字母、数字、特殊字符中的至少3种"
-			case "chinese_name":
-				errors[field] = "请输入2-10个汉字的中文姓名"
-			case "id_card":
-				errors[field] = "请输入有效的身份证号"
-			case "min":
-				errors[field] = fmt.Sprintf("%s长度不能少于%s个字符", e.Field(), e.Param())
-			case "max":
-				errors[field] = fmt.Sprintf("%s长度不能超过%s个字符", e.Field(), e.Param())
-			case "len":
-				errors[field] = fmt.Sprintf("%s长度必须是%s个字符", e.Field(), e.Param())
-			default:
-				errors[field] = fmt.Sprintf("%s格式不正确", e.Field())
+			tag := e.Tag()
+			if _, ok := messages[tag]; !ok {
+				tag = "default"
 			}
+			msg := i18n.T(locale, i18n.NamespaceValidation, tag, messages[tag])
+			errors[strings.ToLower(e.Field())] = strings.NewReplacer("{field}", e.Field(), "{param}", e.Param()).Replace(msg)
 		}
 	}
 
@@ -9,13 +9,16 @@ const (
 	ModeServer   = "server"  // 启动服务器模式
 	ModeDatabase = "db"      // 数据库操作模式
 	ModeUser     = "user"    // 用户管理模式
+	ModeAudit    = "audit"   // 审计日志模式
+	ModeRbac     = "rbac"    // RBAC配置同步模式
 )
 
 // DatabaseType 数据库操作类型枚举
 const (
-	DatabaseMigrate   = "migrate"    // 数据库迁移
-	DatabaseSeed      = "seed"       // 数据库种子数据
-	DatabaseReset     = "reset"      // 重置数据库
+	DatabaseMigrate    = "migrate"     // 数据库迁移
+	DatabaseSeed       = "seed"        // 数据库种子数据
+	DatabaseReset      = "reset"       // 重置数据库
+	DatabaseRepairDept = "repair-dept" // 重新计算部门路径
 )
 
 // UserType 用户操作类型枚举
@@ -23,6 +26,22 @@ const (
 	UserCreateAdmin = "create"      // 创建管理员用户
 	UserList        = "list"        // 列出用户
 	UserReset       = "reset"       // 重置用户密码
+	UserImport      = "import"      // 从CSV/XLSX批量导入用户
+)
+
+// AuditType 审计日志操作类型枚举
+const (
+	AuditVerify     = "verify"     // 校验审计日志哈希链
+	AuditCheckpoint = "checkpoint" // 写入签名检查点
+	AuditPurge      = "purge"      // 按保留策略归档并清理过期日志
+	AuditRestore    = "restore"    // 将归档文件恢复到归档日志表
+)
+
+// RbacType RBAC配置同步操作类型枚举
+const (
+	RbacApply  = "apply"  // 将声明式配置文件应用到数据库
+	RbacDiff   = "diff"   // 检测数据库与配置文件的差异
+	RbacExport = "export" // 将数据库中的配置导出到文件
 )
 
 // CommandLineArgs 命令行参数结构体
@@ -32,16 +51,22 @@ type CommandLineArgs struct {
 	Config    string // 配置文件路径
 	Username  string // 用户名
 	Password  string // 密码
+	File      string // 输入文件路径
+	DryRun    bool   // 仅校验不写入
+	Prune     bool   // 删除配置文件中未列出的实体
 }
 
 // ParseCommandLineArgs 解析命令行参数
 func ParseCommandLineArgs() CommandLineArgs {
 	// 定义命令行参数
-	mode := flag.String("m", ModeServer, "操作模式: server(启动服务器), db(数据库操作), user(用户管理)")
-	typeArg := flag.String("t", "", "操作类型: 对于db模式可以是migrate/seed/reset, 对于user模式可以是create/list/reset")
+	mode := flag.String("m", ModeServer, "操作模式: server(启动服务器), db(数据库操作), user(用户管理), audit(审计日志), rbac(RBAC配置同步)")
+	typeArg := flag.String("t", "", "操作类型: 对于db模式可以是migrate/seed/reset/repair-dept, 对于user模式可以是create/list/reset/import, 对于audit模式可以是verify/checkpoint/purge/restore, 对于rbac模式可以是apply/diff/export")
 	config := flag.String("settings", "settings.yaml", "配置文件路径")
 	username := flag.String("username", "admin", "用户名")
 	password := flag.String("password", "", "密码")
+	file := flag.String("f", "", "输入文件路径，例如audit模式restore操作的归档文件、user模式import操作的CSV/XLSX文件")
+	dryRun := flag.Bool("dry-run", false, "仅校验不写入数据库")
+	prune := flag.Bool("prune", false, "rbac模式下删除配置文件中未列出的实体，只作用于文件中出现的分组")
 
 	// 解析命令行参数
 	flag.Parse()
@@ -52,5 +77,8 @@ func ParseCommandLineArgs() CommandLineArgs {
 		Config:    *config,
 		Username:  *username,
 		Password:  *password,
+		File:      *file,
+		DryRun:    *dryRun,
+		Prune:     *prune,
 	}
 }
\ No newline at end of file
@@ -2,12 +2,23 @@ package flags
 
 import (
 	"fmt"
+	"os"
+	"strings"
+
 	"rbac_admin_server/config"
 	"rbac_admin_server/core"
 	"rbac_admin_server/core/init_gorm"
 	"rbac_admin_server/global"
 	"rbac_admin_server/models"
 	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/depttree"
+	"rbac_admin_server/utils/i18n"
+	"rbac_admin_server/utils/impersonation"
+	"rbac_admin_server/utils/rbacsync"
+	"rbac_admin_server/utils/userimport"
+	"rbac_admin_server/utils/usersettings"
+	"rbac_admin_server/utils/userstate"
 
 	"gorm.io/gorm"
 )
@@ -36,7 +47,13 @@ func HandleCommandLineArgs(args CommandLineArgs) error {
 		return handleDatabaseCommand(args.Type)
 	case ModeUser:
 		// 用户管理模式
-		return handleUserCommand(args.Type, args.Username, args.Password)
+		return handleUserCommand(args.Type, args.Username, args.Password, args.File, args.DryRun)
+	case ModeAudit:
+		// 审计日志模式
+		return handleAuditCommand(args.Type, args.File)
+	case ModeRbac:
+		// RBAC配置同步模式
+		return handleRbacCommand(args.Type, args.File, args.DryRun, args.Prune)
 	default:
 		return fmt.Errorf("不支持的操作模式: %s", args.Mode)
 	}
@@ -79,6 +96,20 @@ func handleDatabaseCommand(typeArg string) error {
 		}
 		global.Logger.Info("✅ 数据库重置成功")
 
+	case DatabaseRepairDept:
+		// 按上级部门重新计算部门路径
+		if err := db.AutoMigrate(&models.Department{}); err != nil {
+			return fmt.Errorf("迁移部门表失败: %v", err)
+		}
+		report, err := depttree.Rebuild(db)
+		if err != nil {
+			return fmt.Errorf("修复部门路径失败: %v", err)
+		}
+		if len(report.Orphans) > 0 {
+			global.Logger.Warnf("部门 %v 的上级不存在或存在循环引用，已改为顶级部门", report.Orphans)
+		}
+		global.Logger.Infof("✅ 部门路径修复完成: 共%d个部门, 修正%d个", report.Total, report.Updated)
+
 	default:
 		return fmt.Errorf("不支持的数据库操作类型: %s", typeArg)
 	}
@@ -87,7 +118,7 @@ func handleDatabaseCommand(typeArg string) error {
 }
 
 // handleUserCommand 处理用户相关命令
-func handleUserCommand(typeArg, username, password string) error {
+func handleUserCommand(typeArg, username, password, file string, dryRun bool) error {
 	// 初始化数据库
 	db, err := init_gorm.InitGorm()
 	if err != nil {
@@ -126,6 +157,15 @@ func handleUserCommand(typeArg, username, password string) error {
 		}
 		global.Logger.Infof("✅ 用户 %s 密码重置成功", username)
 
+	case UserImport:
+		// 批量导入用户
+		if file == "" {
+			return fmt.Errorf("请使用 -f 指定导入文件")
+		}
+		if err := importUsers(db, file, dryRun); err != nil {
+			return fmt.Errorf("导入用户失败: %v", err)
+		}
+
 	default:
 		return fmt.Errorf("不支持的用户操作类型: %s", typeArg)
 	}
@@ -133,6 +173,167 @@ func handleUserCommand(typeArg, username, password string) error {
 	return nil
 }
 
+// handleAuditCommand 处理审计日志相关命令
+func handleAuditCommand(typeArg, file string) error {
+	// 初始化数据库
+	db, err := init_gorm.InitGorm()
+	if err != nil {
+		return fmt.Errorf("数据库初始化失败: %v", err)
+	}
+	global.DB = db
+
+	switch typeArg {
+	case AuditVerify:
+		// 校验哈希链和检查点
+		report, err := audit.Verify()
+		if err != nil {
+			return fmt.Errorf("校验审计日志失败: %v", err)
+		}
+		for _, chain := range report.Chains {
+			global.Logger.Infof("日志链 %s: 共%d条, 序号%d-%d", chain.Type, chain.Count, chain.FirstSeq, chain.LastSeq)
+		}
+		global.Logger.Infof("已加载 %d 个检查点", report.Checkpoints)
+		if !report.OK() {
+			for _, p := range report.Problems {
+				global.Logger.Errorf("[%s] %s#%d: %s", p.Kind, p.Type, p.Seq, p.Message)
+			}
+			return fmt.Errorf("审计日志校验未通过，发现 %d 个问题", len(report.Problems))
+		}
+		global.Logger.Info("✅ 审计日志校验通过")
+
+	case AuditCheckpoint:
+		// 手动写入检查点
+		n, err := audit.WriteCheckpoints(audit.CheckpointManual)
+		if err != nil {
+			return fmt.Errorf("写入检查点失败: %v", err)
+		}
+		global.Logger.Infof("✅ 写入了 %d 个检查点", n)
+
+	case AuditPurge:
+		// 按保留策略归档并清理
+		n, err := audit.ApplyRetention()
+		if err != nil {
+			return fmt.Errorf("清理审计日志失败: %v", err)
+		}
+		global.Logger.Infof("✅ 保留策略清理了 %d 条日志", n)
+
+	case AuditRestore:
+		// 恢复归档文件
+		if file == "" {
+			return fmt.Errorf("请使用 -f 指定归档文件")
+		}
+		if err := global.DB.AutoMigrate(&models.LogArchive{}); err != nil {
+			return fmt.Errorf("迁移归档日志表失败: %v", err)
+		}
+		result, err := audit.Restore(file)
+		if err != nil {
+			return fmt.Errorf("恢复归档文件失败: %v", err)
+		}
+		for _, p := range result.Problems {
+			global.Logger.Warnf("[%s] %s#%d: %s", p.Kind, p.Type, p.Seq, p.Message)
+		}
+		global.Logger.Infof("✅ 恢复了 %d 条归档日志", result.Rows)
+
+	default:
+		return fmt.Errorf("不支持的审计日志操作类型: %s", typeArg)
+	}
+
+	return nil
+}
+
+// handleRbacCommand 处理RBAC配置同步命令
+// 配置文件按标识描述角色、权限、菜单和角色绑定，apply使数据库收敛到文件内容，可重复执行
+func handleRbacCommand(typeArg, file string, dryRun, prune bool) error {
+	if file == "" {
+		return fmt.Errorf("请使用 -f 指定配置文件")
+	}
+
+	// 初始化数据库
+	db, err := init_gorm.InitGorm()
+	if err != nil {
+		return fmt.Errorf("数据库初始化失败: %v", err)
+	}
+	global.DB = db
+
+	if typeArg == RbacExport {
+		snap, err := rbacsync.Export(db)
+		if err != nil {
+			return fmt.Errorf("导出RBAC配置失败: %v", err)
+		}
+		data, err := rbacsync.Encode(snap, rbacsync.FormatOf(file))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
+		}
+		global.Logger.Infof("✅ RBAC配置已导出到 %s", file)
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	snap, err := rbacsync.Decode(data, rbacsync.FormatOf(file))
+	if err != nil {
+		return err
+	}
+	opts := rbacsync.Options{Prune: prune}
+
+	switch typeArg {
+	case RbacDiff:
+		// 漂移检测，存在差异时返回错误，便于在CI中使用
+		plan, err := rbacsync.Diff(db, snap, opts)
+		if err != nil {
+			return fmt.Errorf("生成计划失败: %v", err)
+		}
+		printRbacPlan(plan)
+		if !plan.Empty() {
+			return fmt.Errorf("数据库与配置文件不一致: %s", plan)
+		}
+		global.Logger.Info("✅ 数据库与配置文件一致")
+
+	case RbacApply:
+		if dryRun {
+			plan, err := rbacsync.Diff(db, snap, opts)
+			if err != nil {
+				return fmt.Errorf("生成计划失败: %v", err)
+			}
+			printRbacPlan(plan)
+			global.Logger.Infof("✅ 试运行完成(未写入): %s", plan)
+			return nil
+		}
+		result, err := rbacsync.Apply(db, snap, opts)
+		if err != nil {
+			return fmt.Errorf("应用配置失败，已回滚: %v", err)
+		}
+		printRbacPlan(result.Plan)
+		// 初始密码只输出到终端，不写入日志文件
+		for username, password := range result.Passwords {
+			fmt.Printf("用户 %s 初始密码: %s\n", username, password)
+		}
+		global.Logger.Infof("✅ 配置已应用: %s", result.Plan)
+
+	default:
+		return fmt.Errorf("不支持的RBAC操作类型: %s", typeArg)
+	}
+
+	return nil
+}
+
+// printRbacPlan 输出计划中的每项变更
+func printRbacPlan(plan *rbacsync.Plan) {
+	symbols := map[string]string{rbacsync.ActionCreate: "+", rbacsync.ActionUpdate: "~", rbacsync.ActionDelete: "-"}
+	for _, c := range plan.Changes {
+		line := fmt.Sprintf("%s %s %s", symbols[c.Action], c.Kind, c.Key)
+		if len(c.Fields) > 0 {
+			line += " (" + strings.Join(c.Fields, ", ") + ")"
+		}
+		global.Logger.Info(line)
+	}
+}
+
 // initBaseData 初始化基础数据
 func initBaseData(db *gorm.DB) error {
 	// 这里可以初始化一些基础数据，如默认角色、权限等
@@ -156,6 +357,21 @@ func initBaseData(db *gorm.DB) error {
 		global.Logger.Info("超级管理员角色已存在，跳过创建")
 	}
 
+	// 写入用户设置的系统默认值
+	if err := usersettings.SeedDefaults(db); err != nil {
+		return err
+	}
+
+	// 创建模拟登录权限
+	if err := impersonation.SeedPermission(db); err != nil {
+		return err
+	}
+
+	// 写入错误信息翻译
+	if err := utils.SeedTranslations(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -207,7 +423,7 @@ func createAdminUser(db *gorm.DB, username, password string) error {
 		Avatar:    "/uploads/avatar/default.png",
 		Email:     "admin@example.com",
 		Phone:     "13800138000",
-		Status:    1,
+		Status:    models.UserStatusActive,
 		IsAdmin:   true,
 	}
 
@@ -248,8 +464,8 @@ func listUsers(db *gorm.DB) error {
 	global.Logger.Info("ID\t用户名\t\t昵称\t\t邮箱\t\t\t状态\t是否管理员")
 	global.Logger.Info("--------------------------------------------------------------------------------------------------------")
 	for _, user := range users {
-		global.Logger.Infof("%d\t%s\t\t%s\t\t%s\t\t%d\t\t%t",
-			user.ID, user.Username, user.Nickname, user.Email, user.Status, user.IsAdmin)
+		global.Logger.Infof("%d\t%s\t\t%s\t\t%s\t\t%s\t\t%t",
+			user.ID, user.Username, user.Nickname, user.Email, userstate.Name(user.Status), user.IsAdmin)
 	}
 	global.Logger.Info("--------------------------------------------------------------------------------------------------------")
 	global.Logger.Infof("总计 %d 个用户", len(users))
@@ -257,6 +473,47 @@ func listUsers(db *gorm.DB) error {
 	return nil
 }
 
+// importUsers 从CSV/XLSX文件导入用户并输出逐行结果
+func importUsers(db *gorm.DB, file string, dryRun bool) error {
+	if err := core.InitValidator(); err != nil {
+		return fmt.Errorf("验证器初始化失败: %v", err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := userimport.ReadFile(file, f)
+	if err != nil {
+		return err
+	}
+	result, err := userimport.Import(db, rows, dryRun, i18n.DefaultLocale, func(done, total int) {
+		if done%500 == 0 {
+			global.Logger.Infof("已处理 %d/%d 行", done, total)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows {
+		switch {
+		case len(row.Errors) > 0:
+			global.Logger.Warnf("第%d行 %s: %s", row.Line, row.Username, strings.Join(row.Errors, "; "))
+		case row.Password != "":
+			// 初始密码只输出到终端，不写入日志文件
+			fmt.Printf("第%d行 %s: 已创建，初始密码 %s\n", row.Line, row.Username, row.Password)
+		}
+	}
+	if dryRun {
+		global.Logger.Infof("✅ 校验完成(未写入): 共%d行, 有效%d行, 失败%d行", result.Total, result.Valid, result.Failed)
+		return nil
+	}
+	global.Logger.Infof("✅ 导入完成: 共%d行, 创建%d个, 失败%d行", result.Total, result.Created, result.Failed)
+	return nil
+}
+
 // resetUserPassword 重置用户密码
 func resetUserPassword(db *gorm.DB, username, password string) error {
 	var user models.User
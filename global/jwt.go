@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ClaimsUserInfo 自定义JWT声明结构，包含用户基本信息
@@ -15,6 +17,9 @@ type ClaimsUserInfo struct {
 	UserID   uint   `json:"userID"`
 	Username string `json:"username"`
 	RoleList []uint `json:"roleList"`
+	// 模拟登录时为实际操作的管理员，普通登录为空
+	ImpersonatorID   uint   `json:"impersonatorID,omitempty"`
+	ImpersonatorName string `json:"impersonatorName,omitempty"`
 }
 
 // JWTClaims JWT声明结构
@@ -38,6 +43,14 @@ func GenerateToken(info ClaimsUserInfo) (string, error) {
 	if Config == nil || Config.JWT.Secret == "" {
 		return "", errors.New("JWT配置未初始化")
 	}
+	return GenerateTokenWithTTL(info, time.Duration(Config.JWT.ExpireHours)*time.Hour)
+}
+
+// GenerateTokenWithTTL 生成指定有效期的JWT token，用于模拟登录等短期令牌
+func GenerateTokenWithTTL(info ClaimsUserInfo, ttl time.Duration) (string, error) {
+	if Config == nil || Config.JWT.Secret == "" {
+		return "", errors.New("JWT配置未初始化")
+	}
 
 	// 选择签名方法
 	var signingMethod jwt.SigningMethod
@@ -55,7 +68,7 @@ func GenerateToken(info ClaimsUserInfo) (string, error) {
 	claims := JWTClaims{
 		ClaimsUserInfo: info,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(Config.JWT.ExpireHours) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    Config.JWT.Issuer,
@@ -204,19 +217,88 @@ func RefreshToken(refreshToken string) (string, string, error) {
 	return newAccessToken, newRefreshToken, nil
 }
 
-// GetUserRoles 获取用户角色列表
+// GetUserRoles 获取用户角色列表，包括通过用户组获得的角色
 // userID: 用户ID
 func GetUserRoles(userID uint) ([]uint, error) {
-	var userRoles []models.UserRole
-	result := DB.Where("user_id = ?", userID).Find(&userRoles)
-	if result.Error != nil {
-		return nil, result.Error
+	return RoleIDs(DB, userID)
+}
+
+// RoleIDs 返回用户的有效角色ID：直接分配的角色，以及所在用户组和其上级用户组绑定的角色
+func RoleIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var roleIDs []uint
+	if err := db.Model(&models.UserRole{}).Where("user_id = ?", userID).Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	groupIDs, err := GroupIDs(db, userID)
+	if err != nil || len(groupIDs) == 0 {
+		return roleIDs, err
+	}
+
+	var inherited []uint
+	if err := db.Model(&models.UserGroupRole{}).Where("group_id IN ?", groupIDs).Pluck("role_id", &inherited).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[uint]bool, len(roleIDs)+len(inherited))
+	for _, id := range roleIDs {
+		seen[id] = true
+	}
+	for _, id := range inherited {
+		if !seen[id] {
+			seen[id] = true
+			roleIDs = append(roleIDs, id)
+		}
 	}
+	return roleIDs, nil
+}
+
+// GroupIDs 返回用户所在的用户组及其全部上级用户组
+// 禁用的用户组不再向成员传递角色，也不再继承上级用户组
+func GroupIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var direct []uint
+	if err := db.Model(&models.UserGroupMember{}).Where("user_id = ?", userID).Pluck("group_id", &direct).Error; err != nil {
+		return nil, err
+	}
+	if len(direct) == 0 {
+		return nil, nil
+	}
+
+	var groups []models.UserGroup
+	if err := db.Select("id", "parent_id", "status").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	index := make(map[uint]models.UserGroup, len(groups))
+	for _, g := range groups {
+		index[g.ID] = g
+	}
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, id := range direct {
+		for id != 0 && !seen[id] {
+			g, ok := index[id]
+			if !ok || g.Status != 1 {
+				break
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			id = g.ParentID
+		}
+	}
+	return ids, nil
+}
 
-	roleList := make([]uint, len(userRoles))
-	for i, ur := range userRoles {
-		roleList[i] = ur.RoleID
+// HasPermission 判断用户是否通过启用的角色拥有指定标识的启用权限，角色包括通过用户组获得的角色
+func HasPermission(db *gorm.DB, userID uint, key string) (bool, error) {
+	roleIDs, err := RoleIDs(db, userID)
+	if err != nil || len(roleIDs) == 0 {
+		return false, err
 	}
 
-	return roleList, nil
+	var count int64
+	err = db.Model(&models.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Where("role_permissions.role_id IN ? AND permissions.status = 1", roleIDs).
+		Where(clause.Eq{Column: clause.Column{Table: "permissions", Name: "key"}, Value: key}).
+		Count(&count).Error
+	return count > 0, err
 }
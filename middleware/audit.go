@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// auditResponseWriter 记录响应内容的ResponseWriter
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body  *bytes.Buffer
+	limit int
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if remain := w.limit - w.body.Len(); remain > 0 {
+		if len(b) > remain {
+			w.body.Write(b[:remain])
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Audit 操作审计中间件
+// 记录需要认证的写操作到审计日志，必须注册在Auth之后
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := global.Config.Audit
+		method := c.Request.Method
+		if !cfg.Enable || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		// 读取请求体后放回，供后续处理器使用；处理器可能声明额外的脱敏字段，记录时再脱敏
+		var requestBody string
+		if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+			data, _ := io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(data))
+			if len(data) > cfg.MaxBodySize {
+				data = data[:cfg.MaxBodySize]
+			}
+			requestBody = string(data)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, limit: cfg.MaxBodySize}
+		c.Writer = writer
+
+		c.Next()
+
+		module, action := routeModuleAction(c.Request.URL.Path)
+		userID, username, onBehalfOf := audit.Actor(c)
+		entry := models.Log{
+			Type:        models.LogTypeOperation,
+			UserID:      userID,
+			Username:    username,
+			OnBehalfOf:  onBehalfOf,
+			IP:          c.ClientIP(),
+			UserAgent:   c.Request.UserAgent(),
+			Method:      method,
+			Path:        c.Request.URL.Path,
+			StatusCode:  c.Writer.Status(),
+			RequestBody: audit.MaskRequest(c, requestBody),
+			Response:    audit.MaskResponse(writer.body.String()),
+			Latency:     time.Since(start).Milliseconds(),
+			Error:       c.Errors.String(),
+			Module:      module,
+			Action:      action,
+			Description: audit.Description(c),
+			RequestID:   c.GetString(reqlog.RequestIDKey),
+		}
+		if err := audit.Record(&entry); err != nil {
+			global.Logger.Errorf("记录操作日志失败: %v", err)
+		}
+	}
+}
+
+// routeModuleAction 从请求路径解析模块和操作
+// 例如 /admin/user/create 解析为 user 和 create，/admin/file/delete/1 解析为 file 和 delete
+func routeModuleAction(path string) (string, string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 0 && parts[0] == "admin" {
+		parts = parts[1:]
+	}
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		return parts[0], ""
+	default:
+		return parts[0], parts[1]
+	}
+}
@@ -3,6 +3,9 @@ package middleware
 import (
 	"net/http"
 	"rbac_admin_server/global"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/reqlog"
+	"rbac_admin_server/utils/userstate"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,8 +18,8 @@ func Cors() gin.HandlerFunc {
 		h := c.Writer.Header()
 		h.Set("Access-Control-Allow-Origin", "*")
 		h.Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE")
-		h.Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization")
-		h.Set("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type")
+		h.Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization, X-Request-ID")
+		h.Set("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type, X-Request-ID")
 		h.Set("Access-Control-Allow-Credentials", "true")
 
 		// 处理 OPTIONS 请求
@@ -31,7 +34,7 @@ func Cors() gin.HandlerFunc {
 }
 
 // Auth 认证中间件
-// 验证JWT token的有效性，并将用户信息存入上下文
+// 验证JWT token的有效性和账号状态，并将用户信息存入上下文
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
@@ -48,17 +51,48 @@ func Auth() gin.HandlerFunc {
 
 		claims, err := global.ParseToken(token)
 		if err != nil {
-			global.Logger.Warnf("Token解析失败: %s", err.Error())
+			reqlog.Entry(c).Warnf("Token解析失败: %s", err.Error())
 			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "token 无效"})
 			c.Abort()
 			return
 		}
 
+		// 校验账号状态，令牌有效期内被锁定、过期或归档的账号立即失效
+		code, err := userstate.Verify(global.DB.WithContext(c.Request.Context()), claims.UserID)
+		if err != nil {
+			reqlog.Entry(c).Warnf("认证查询用户失败: %s", err.Error())
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "用户不存在"})
+			c.Abort()
+			return
+		}
+		if code != utils.SUCCESS {
+			reqlog.Entry(c).Warnf("用户状态不可用: %s, 错误码: %d", claims.Username, code)
+			c.JSON(http.StatusForbidden, gin.H{"code": code, "msg": utils.Message(c, code)})
+			c.Abort()
+			return
+		}
+
+		// 模拟登录令牌同时校验实际操作的管理员，管理员被锁定或归档后令牌立即失效
+		if claims.ImpersonatorID != 0 {
+			code, err := userstate.Verify(global.DB.WithContext(c.Request.Context()), claims.ImpersonatorID)
+			if err != nil || code != utils.SUCCESS {
+				reqlog.Entry(c).Warnf("模拟登录的管理员不可用: %s", claims.ImpersonatorName)
+				c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "msg": "模拟登录已失效"})
+				c.Abort()
+				return
+			}
+			c.Set("impersonatorID", claims.ImpersonatorID)
+			c.Set("impersonatorName", claims.ImpersonatorName)
+			c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+			reqlog.WithField(c, "impersonator_id", claims.ImpersonatorID)
+		}
+
 		// 将用户信息存入上下文
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("roleList", claims.RoleList)
-		global.Logger.Debugf("用户认证成功: %s, 角色: %v", claims.Username, claims.RoleList)
+		reqlog.WithField(c, "user_id", claims.UserID)
+		reqlog.Entry(c).Debugf("用户认证成功: %s, 角色: %v", claims.Username, claims.RoleList)
 		c.Next()
 	}
 }
@@ -114,7 +148,7 @@ func Logger() gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
-		global.Logger.Infof("[%s] %s %s %d %v",
+		reqlog.Entry(c).Infof("[%s] %s %s %d %v",
 			clientIP,
 			method,
 			path,
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// RequirePermission 权限中间件
+// 要求当前用户通过角色拥有指定标识的权限，必须注册在Auth之后
+func RequirePermission(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := global.HasPermission(global.DB.WithContext(c.Request.Context()), c.GetUint("userID"), key)
+		if err != nil {
+			reqlog.Entry(c).Errorf("查询用户权限失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询用户权限失败"})
+			c.Abort()
+			return
+		}
+		if !ok {
+			reqlog.Entry(c).Warnf("用户ID: %d 缺少权限 %s", c.GetUint("userID"), key)
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "无权限: " + key})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// NoImpersonation 禁止模拟登录期间访问的敏感操作，如修改密码和联系方式
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetUint("impersonatorID") != 0 {
+			reqlog.Entry(c).Warnf("模拟登录期间尝试敏感操作: %s %s", c.Request.Method, c.Request.URL.Path)
+			c.JSON(http.StatusForbidden, gin.H{"code": utils.ERROR_IMPERSONATING, "msg": utils.Message(c, utils.ERROR_IMPERSONATING)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
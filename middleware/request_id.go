@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// validRequestID 允许透传的外部请求ID格式，防止日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID 请求ID中间件
+// 沿用请求头中合法的X-Request-ID，否则生成新ID，并在响应头中返回
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(reqlog.Header)
+		if !validRequestID.MatchString(id) {
+			id = reqlog.NewID()
+		}
+		reqlog.Attach(c, id)
+		c.Header(reqlog.Header, id)
+		c.Next()
+	}
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
 // API API模型
 type API struct {
 	BaseModel
@@ -21,11 +28,12 @@ func (API) TableName() string {
 type Dict struct {
 	BaseModel
 	Name        string     `gorm:"size:64;not null;comment:字典名称" json:"name" validate:"required"`
-	Key         string     `gorm:"size:64;uniqueIndex;not null;comment:字典标识" json:"key" validate:"required"`
+	Key         string     `gorm:"size:64;uniqueIndex:uk_dicts_key,priority:1;not null;comment:字典标识" json:"key" validate:"required"`
 	Description string     `gorm:"size:255;comment:字典描述" json:"description"`
 	Status      int        `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:禁用)" json:"status"`
 	Sort        int        `gorm:"type:int;default:0;comment:排序" json:"sort"`
 	Items       []DictItem `gorm:"foreignKey:DictID" json:"items,omitempty"`
+	DeletedKey  uint       `gorm:"not null;default:0;uniqueIndex:uk_dicts_key,priority:2;comment:删除标记" json:"-"`
 }
 
 // TableName 设置表名
@@ -88,9 +96,30 @@ func (File) TableName() string {
 	return "files"
 }
 
+// 日志类型，每种类型维护独立的哈希链
+const (
+	LogTypeOperation = "operation" // 操作日志
+	LogTypeLogin     = "login"     // 登录日志
+	LogTypeChange    = "change"    // 数据变更历史
+)
+
+// ErrLogAppendOnly 审计日志只允许追加
+var ErrLogAppendOnly = errors.New("审计日志只允许追加，不能修改或删除")
+
+// LogRetentionKey 保留策略清理时设置的会话标记
+// 只有携带该标记的删除操作才会被放行
+const LogRetentionKey = "audit:retention"
+
+// LogRedactionKey 注销账号匿名化用户名时设置的会话标记
+// 只有携带该标记的修改操作才会被放行，修改后的记录由Redaction签名证明
+const LogRedactionKey = "audit:redaction"
+
 // Log 日志模型
+// 审计日志只追加不修改，每条记录包含同类型上一条记录的哈希
 type Log struct {
-	BaseModel
+	BaseModelNoDelete
+	Type        string `gorm:"size:32;default:'operation';uniqueIndex:idx_log_type_seq,priority:1;comment:日志类型" json:"type"`
+	Seq         uint64 `gorm:"not null;uniqueIndex:idx_log_type_seq,priority:2;comment:链内序号" json:"seq"`
 	UserID      uint   `gorm:"comment:用户ID" json:"user_id"`
 	Username    string `gorm:"size:64;comment:用户名" json:"username"`
 	IP          string `gorm:"size:64;comment:IP地址" json:"ip"`
@@ -105,6 +134,11 @@ type Log struct {
 	Module      string `gorm:"size:64;comment:模块" json:"module"`
 	Action      string `gorm:"size:64;comment:操作" json:"action"`
 	Description string `gorm:"size:255;comment:描述" json:"description"`
+	PrevHash    string `gorm:"size:64;comment:上一条记录哈希" json:"prev_hash"`
+	Hash        string `gorm:"size:64;comment:本条记录哈希" json:"hash"`
+	RequestID   string `gorm:"size:64;index;comment:请求ID" json:"request_id"`
+	Redaction   string `gorm:"size:64;comment:匿名化签名,不参与哈希计算" json:"redaction,omitempty"`
+	OnBehalfOf  uint   `gorm:"default:0;index;comment:模拟登录时被模拟的用户ID,UserID为实际操作的管理员" json:"on_behalf_of,omitempty"`
 	User        User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
@@ -112,3 +146,62 @@ type Log struct {
 func (Log) TableName() string {
 	return "logs"
 }
+
+// BeforeUpdate 只允许注销账号时匿名化审计日志
+func (l *Log) BeforeUpdate(tx *gorm.DB) error {
+	if allowed, ok := tx.Get(LogRedactionKey); ok && allowed == true {
+		return nil
+	}
+	return ErrLogAppendOnly
+}
+
+// BeforeDelete 只允许保留策略删除审计日志
+func (l *Log) BeforeDelete(tx *gorm.DB) error {
+	if allowed, ok := tx.Get(LogRetentionKey); ok && allowed == true {
+		return nil
+	}
+	return ErrLogAppendOnly
+}
+
+// LogArchive 归档日志模型
+// 从归档文件恢复的日志，只用于查询，字段与Log一致
+type LogArchive struct {
+	ID          uint      `gorm:"primarykey" json:"-"`
+	LogID       uint      `gorm:"index;comment:原日志ID" json:"id"`
+	CreatedAt   time.Time `gorm:"column:created_at;type:datetime;index;comment:创建时间" json:"created_at"`
+	Type        string    `gorm:"size:32;uniqueIndex:idx_log_archive_type_seq,priority:1;comment:日志类型" json:"type"`
+	Seq         uint64    `gorm:"not null;uniqueIndex:idx_log_archive_type_seq,priority:2;comment:链内序号" json:"seq"`
+	UserID      uint      `gorm:"comment:用户ID" json:"user_id"`
+	Username    string    `gorm:"size:64;comment:用户名" json:"username"`
+	IP          string    `gorm:"size:64;comment:IP地址" json:"ip"`
+	UserAgent   string    `gorm:"size:255;comment:用户代理" json:"user_agent"`
+	Method      string    `gorm:"size:16;comment:请求方法" json:"method"`
+	Path        string    `gorm:"size:128;comment:请求路径" json:"path"`
+	StatusCode  int       `gorm:"comment:状态码" json:"status_code"`
+	RequestBody string    `gorm:"type:text;comment:请求体" json:"request_body"`
+	Response    string    `gorm:"type:text;comment:响应内容" json:"response"`
+	Latency     int64     `gorm:"comment:响应时间(毫秒)" json:"latency"`
+	Error       string    `gorm:"type:text;comment:错误信息" json:"error"`
+	Module      string    `gorm:"size:64;comment:模块" json:"module"`
+	Action      string    `gorm:"size:64;comment:操作" json:"action"`
+	Description string    `gorm:"size:255;comment:描述" json:"description"`
+	PrevHash    string    `gorm:"size:64;comment:上一条记录哈希" json:"prev_hash"`
+	Hash        string    `gorm:"size:64;comment:本条记录哈希" json:"hash"`
+	RequestID   string    `gorm:"size:64;index;comment:请求ID" json:"request_id,omitempty"`
+	Redaction   string    `gorm:"size:64;comment:匿名化签名,不参与哈希计算" json:"redaction,omitempty"`
+	OnBehalfOf  uint      `gorm:"default:0;index;comment:模拟登录时被模拟的用户ID" json:"on_behalf_of,omitempty"`
+	ArchiveFile string    `gorm:"size:255;index;comment:来源归档文件" json:"archive_file,omitempty"`
+}
+
+// TableName 设置表名
+func (LogArchive) TableName() string {
+	return "log_archives"
+}
+
+// BeforeUpdate 归档日志只读，注销账号匿名化除外
+func (l *LogArchive) BeforeUpdate(tx *gorm.DB) error {
+	if allowed, ok := tx.Get(LogRedactionKey); ok && allowed == true {
+		return nil
+	}
+	return ErrLogAppendOnly
+}
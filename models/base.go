@@ -1,12 +1,16 @@
 package models
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 // BaseModel 基础模型，包含公共字段
+// 带唯一索引的模型另有DeletedKey字段，未删除时为0，软删除后为记录ID；
+// 唯一索引由业务字段和DeletedKey组成，已删除的记录不再占用用户名等唯一值
 type BaseModel struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time      `gorm:"column:created_at;type:datetime;not null;comment:创建时间" json:"created_at"`
@@ -20,3 +24,30 @@ type BaseModelNoDelete struct {
 	CreatedAt time.Time `gorm:"column:created_at;type:datetime;not null;comment:创建时间" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;type:datetime;not null;comment:更新时间" json:"updated_at"`
 }
+
+// NullString 空字符串写入数据库时为NULL，读取NULL时为空字符串
+// 用于邮箱、手机号等可选的唯一字段，唯一索引不限制NULL，多条未填写的记录不会冲突
+type NullString string
+
+// Value 实现driver.Valuer接口
+func (s NullString) Value() (driver.Value, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return string(s), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (s *NullString) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = NullString(v)
+	case []byte:
+		*s = NullString(v)
+	default:
+		return fmt.Errorf("无法将 %T 转换为NullString", value)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&User{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestNullStringBlankContacts(t *testing.T) {
+	db := openDB(t)
+
+	// 多个未填写邮箱和手机号的用户不应在唯一索引上冲突
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if err := db.Create(&User{Username: name, Password: "x"}).Error; err != nil {
+			t.Fatalf("创建%s失败: %v", name, err)
+		}
+	}
+	var blank int64
+	db.Model(&User{}).Where("email IS NULL AND phone IS NULL").Count(&blank)
+	if blank != 3 {
+		t.Fatalf("空邮箱和手机号应存为NULL，实际 %d 条", blank)
+	}
+
+	// 已填写的值仍受唯一索引限制
+	if err := db.Create(&User{Username: "dave", Password: "x", Email: "d@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&User{Username: "erin", Password: "x", Email: "d@example.com"}).Error; err == nil {
+		t.Fatal("重复的邮箱应违反唯一索引")
+	}
+
+	// 清空已填写的值后同样存为NULL，读取为空字符串
+	if err := db.Model(&User{}).Where("username = ?", "dave").Update("email", NullString("")).Error; err != nil {
+		t.Fatal(err)
+	}
+	var dave User
+	if err := db.Where("username = ?", "dave").First(&dave).Error; err != nil {
+		t.Fatal(err)
+	}
+	if dave.Email != "" {
+		t.Fatalf("Email = %q, want empty", dave.Email)
+	}
+	db.Model(&User{}).Where("email IS NULL").Count(&blank)
+	if blank != 4 {
+		t.Fatalf("清空后的邮箱应存为NULL，实际 %d 条为NULL", blank)
+	}
+}
+
+func TestNullStringScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  NullString
+		err   bool
+	}{
+		{nil, "", false},
+		{"a@example.com", "a@example.com", false},
+		{[]byte("13800000000"), "13800000000", false},
+		{42, "", true},
+	}
+	for _, tt := range tests {
+		var s NullString
+		err := s.Scan(tt.value)
+		if (err != nil) != tt.err || s != tt.want {
+			t.Errorf("Scan(%v) = %q, %v", tt.value, s, err)
+		}
+	}
+}
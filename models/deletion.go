@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 注销申请状态
+const (
+	DeletionStatusPending   = 1 // 冷静期中，可撤销
+	DeletionStatusCancelled = 2 // 已撤销
+	DeletionStatusDone      = 3 // 已匿名化
+)
+
+// DeletionRequest 账号注销申请
+// 冷静期结束后匿名化用户信息，被其他记录引用的用户行保留
+type DeletionRequest struct {
+	BaseModelNoDelete
+	UserID      uint       `gorm:"index;not null;comment:用户ID" json:"user_id"`
+	Status      int        `gorm:"type:tinyint;default:1;index;comment:状态(1:冷静期,2:已撤销,3:已匿名化)" json:"status"`
+	Reason      string     `gorm:"size:255;comment:注销原因" json:"reason"`
+	ScheduledAt time.Time  `gorm:"type:datetime;index;comment:计划匿名化时间" json:"scheduled_at"`
+	ProcessedAt *time.Time `gorm:"type:datetime;comment:撤销或匿名化时间" json:"processed_at"`
+}
+
+// TableName 设置表名
+func (DeletionRequest) TableName() string {
+	return "deletion_requests"
+}
@@ -0,0 +1,41 @@
+package models
+
+// UserGroup 用户组
+// 用户组可以嵌套，成员同时获得所在用户组及其全部上级用户组绑定的角色
+type UserGroup struct {
+	BaseModel
+	Name        string `gorm:"size:64;not null;comment:用户组名称" json:"name" validate:"required"`
+	Code        string `gorm:"size:64;uniqueIndex:uk_user_groups_code,priority:1;not null;comment:用户组编码" json:"code" validate:"required"`
+	ParentID    uint   `gorm:"default:0;index;comment:上级用户组ID" json:"parent_id"`
+	Description string `gorm:"size:255;comment:用户组描述" json:"description"`
+	Status      int    `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:禁用)" json:"status"`
+	Sort        int    `gorm:"type:int;default:0;comment:排序" json:"sort"`
+	DeletedKey  uint   `gorm:"not null;default:0;uniqueIndex:uk_user_groups_code,priority:2;comment:删除标记" json:"-"`
+}
+
+// TableName 设置表名
+func (UserGroup) TableName() string {
+	return "user_groups"
+}
+
+// UserGroupMember 用户组成员关联表
+type UserGroupMember struct {
+	GroupID uint `gorm:"primaryKey;comment:用户组ID" json:"group_id"`
+	UserID  uint `gorm:"primaryKey;index;comment:用户ID" json:"user_id"`
+}
+
+// TableName 设置表名
+func (UserGroupMember) TableName() string {
+	return "user_group_members"
+}
+
+// UserGroupRole 用户组角色关联表
+type UserGroupRole struct {
+	GroupID uint `gorm:"primaryKey;comment:用户组ID" json:"group_id"`
+	RoleID  uint `gorm:"primaryKey;index;comment:角色ID" json:"role_id"`
+}
+
+// TableName 设置表名
+func (UserGroupRole) TableName() string {
+	return "user_group_roles"
+}
@@ -0,0 +1,15 @@
+package models
+
+// Translation 翻译文本，按语言、命名空间和键覆盖菜单标题、字典标签、错误信息和参数校验信息
+type Translation struct {
+	BaseModelNoDelete
+	Locale    string `gorm:"size:16;not null;uniqueIndex:uk_translations_key,priority:1;comment:语言" json:"locale"`
+	Namespace string `gorm:"size:32;not null;uniqueIndex:uk_translations_key,priority:2;comment:命名空间(menu,dict,error,validation)" json:"namespace"`
+	Key       string `gorm:"size:128;not null;uniqueIndex:uk_translations_key,priority:3;comment:键" json:"key"`
+	Value     string `gorm:"size:512;not null;comment:译文" json:"value"`
+}
+
+// TableName 设置表名
+func (Translation) TableName() string {
+	return "translations"
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// 邀请码状态
+const (
+	InvitationStatusActive  = 1 // 可用
+	InvitationStatusRevoked = 2 // 已作废
+)
+
+// Invitation 注册邀请码
+type Invitation struct {
+	BaseModelNoDelete
+	Code         string     `gorm:"size:32;uniqueIndex;not null;comment:邀请码" json:"code"`
+	MaxUses      int        `gorm:"type:int;default:0;comment:最多使用次数,0表示不限" json:"max_uses"`
+	UsedCount    int        `gorm:"type:int;default:0;comment:已使用次数" json:"used_count"`
+	ExpiresAt    *time.Time `gorm:"type:datetime;comment:过期时间,为空表示永久有效" json:"expires_at"`
+	RoleID       uint       `gorm:"default:0;comment:注册后分配的角色ID" json:"role_id"`
+	DepartmentID uint       `gorm:"default:0;comment:注册后所属部门ID" json:"department_id"`
+	Status       int        `gorm:"type:tinyint;default:1;comment:状态(1:可用,2:已作废)" json:"status"`
+	Remark       string     `gorm:"size:255;comment:备注" json:"remark"`
+	CreatedBy    uint       `gorm:"comment:创建人ID" json:"created_by"`
+}
+
+// TableName 设置表名
+func (Invitation) TableName() string {
+	return "invitations"
+}
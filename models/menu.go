@@ -1,6 +1,14 @@
 package models
 
+// 菜单类型
+const (
+	MenuTypeDir    = "dir"    // 目录
+	MenuTypeMenu   = "menu"   // 菜单
+	MenuTypeButton = "button" // 按钮，只提供权限标识，不生成路由
+)
+
 // Menu 菜单模型
+// 菜单按角色分配(role_menus)，目录和菜单生成前端路由，按钮的Permission作为前端按钮权限标识
 type Menu struct {
 	BaseModel
 	Name       string   `gorm:"size:64;not null;comment:菜单名称" json:"name" validate:"required"`
@@ -8,7 +16,7 @@ type Menu struct {
 	Component  string   `gorm:"size:128;comment:组件路径" json:"component"`
 	Redirect   string   `gorm:"size:128;comment:重定向路径" json:"redirect"`
 	Icon       string   `gorm:"size:64;comment:菜单图标" json:"icon"`
-	Type       string   `gorm:"size:32;default:'menu';comment:菜单类型(menu,button)" json:"type"`
+	Type       string   `gorm:"size:32;default:'menu';comment:菜单类型(dir,menu,button)" json:"type"`
 	Permission string   `gorm:"size:64;comment:权限标识" json:"permission"`
 	Sort       int      `gorm:"type:int;default:0;comment:排序" json:"sort"`
 	ParentID   uint     `gorm:"default:0;comment:上级菜单ID" json:"parent_id"`
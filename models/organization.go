@@ -0,0 +1,43 @@
+package models
+
+// Position 岗位/职务
+type Position struct {
+	BaseModel
+	Name        string `gorm:"size:64;not null;comment:岗位名称" json:"name" validate:"required"`
+	Code        string `gorm:"size:64;uniqueIndex:uk_positions_code,priority:1;not null;comment:岗位编码" json:"code" validate:"required"`
+	Description string `gorm:"size:255;comment:岗位描述" json:"description"`
+	Status      int    `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:禁用)" json:"status"`
+	Sort        int    `gorm:"type:int;default:0;comment:排序" json:"sort"`
+	DeletedKey  uint   `gorm:"not null;default:0;uniqueIndex:uk_positions_code,priority:2;comment:删除标记" json:"-"`
+}
+
+// TableName 设置表名
+func (Position) TableName() string {
+	return "positions"
+}
+
+// UserPosition 用户岗位关联表
+type UserPosition struct {
+	UserID     uint `gorm:"primaryKey;comment:用户ID" json:"user_id"`
+	PositionID uint `gorm:"primaryKey;comment:岗位ID" json:"position_id"`
+}
+
+// TableName 设置表名
+func (UserPosition) TableName() string {
+	return "user_positions"
+}
+
+// UserDepartment 用户所属部门
+// 用户可以属于多个部门，其中一个为主部门，与users.department_id保持一致
+type UserDepartment struct {
+	UserID       uint       `gorm:"primaryKey;comment:用户ID" json:"user_id"`
+	DepartmentID uint       `gorm:"primaryKey;index;comment:部门ID" json:"department_id"`
+	IsPrimary    bool       `gorm:"default:false;comment:是否主部门" json:"is_primary"`
+	IsLeader     bool       `gorm:"default:false;comment:是否部门负责人" json:"is_leader"`
+	Department   Department `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
+}
+
+// TableName 设置表名
+func (UserDepartment) TableName() string {
+	return "user_departments"
+}
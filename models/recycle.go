@@ -0,0 +1,16 @@
+package models
+
+// RecycleLink 回收站关联记录
+// 删除实体时解除的多对多关联，恢复时据此重建，彻底删除时一并清除
+type RecycleLink struct {
+	BaseModelNoDelete
+	Entity    string `gorm:"size:32;not null;index:idx_recycle_links_record,priority:1;comment:实体类型" json:"entity"`
+	RecordID  uint   `gorm:"not null;index:idx_recycle_links_record,priority:2;comment:记录ID" json:"record_id"`
+	LinkTable string `gorm:"size:64;not null;comment:关联表" json:"link_table"`
+	OtherID   uint   `gorm:"not null;comment:关联记录ID" json:"other_id"`
+}
+
+// TableName 设置表名
+func (RecycleLink) TableName() string {
+	return "recycle_links"
+}
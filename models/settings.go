@@ -0,0 +1,18 @@
+package models
+
+// UserSettings 用户个人设置
+// 只保存用户修改过的项，为空的项使用configs表中的系统默认值
+type UserSettings struct {
+	BaseModelNoDelete
+	UserID           uint    `gorm:"uniqueIndex;not null;comment:用户ID" json:"user_id"`
+	Theme            *string `gorm:"size:16;comment:主题(light,dark,auto)" json:"theme"`
+	Language         *string `gorm:"size:16;comment:语言" json:"language"`
+	Layout           *string `gorm:"size:32;comment:布局" json:"layout"`
+	SidebarCollapsed *bool   `gorm:"comment:侧边栏是否折叠" json:"sidebar_collapsed"`
+	Notifications    string  `gorm:"type:text;comment:通知偏好(JSON)，只包含用户修改过的通知类型" json:"notifications"`
+}
+
+// TableName 设置表名
+func (UserSettings) TableName() string {
+	return "user_settings"
+}
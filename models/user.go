@@ -1,30 +1,46 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// 用户状态
+const (
+	UserStatusActive   = 1 // 正常
+	UserStatusLocked   = 2 // 锁定，管理员锁定或登录失败次数过多
+	UserStatusPending  = 3 // 待激活，邮箱尚未确认或注册待审核
+	UserStatusExpired  = 4 // 已过期，超过账号到期时间
+	UserStatusArchived = 5 // 已归档，离职等不再使用的账号
+)
+
 // User 用户模型
 type User struct {
 	BaseModel
-	Username     string     `gorm:"size:64;uniqueIndex;not null;comment:用户名" json:"username" validate:"required,username"`
+	Username     string     `gorm:"size:64;uniqueIndex:uk_users_username,priority:1;not null;comment:用户名" json:"username" validate:"required,username"`
 	Password     string     `gorm:"size:128;not null;comment:密码" json:"-" validate:"required,password"`
 	Nickname     string     `gorm:"size:64;comment:昵称" json:"nickname"`
-	Email        string     `gorm:"size:128;uniqueIndex;comment:邮箱" json:"email" validate:"omitempty,email"`
-	Phone        string     `gorm:"size:16;uniqueIndex;comment:手机号" json:"phone" validate:"omitempty,phone"`
+	Email        NullString `gorm:"size:128;uniqueIndex:uk_users_email,priority:1;comment:邮箱" json:"email" validate:"omitempty,email"`
+	Phone        NullString `gorm:"size:16;uniqueIndex:uk_users_phone,priority:1;comment:手机号" json:"phone" validate:"omitempty,phone"`
 	Avatar       string     `gorm:"size:255;comment:头像" json:"avatar"`
-	Status       int        `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:禁用)" json:"status"`
+	Status       int        `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:锁定,3:待激活,4:已过期,5:已归档)" json:"status"`
+	StatusAt     *time.Time `gorm:"type:datetime;comment:状态变更时间" json:"status_at"`
+	ExpiresAt    *time.Time `gorm:"type:datetime;comment:账号到期时间,为空表示永久有效" json:"expires_at"`
+	LockedUntil  *time.Time `gorm:"type:datetime;comment:锁定截止时间,为空表示需手动解锁" json:"locked_until"`
+	LockReason   string     `gorm:"size:255;comment:锁定原因" json:"lock_reason"`
+	FailedLogins int        `gorm:"type:int;default:0;comment:连续登录失败次数" json:"-"`
 	LastLoginAt  *time.Time `gorm:"type:datetime;comment:最后登录时间" json:"last_login_at"`
 	LastLoginIP  string     `gorm:"size:64;comment:最后登录IP" json:"last_login_ip"`
 	LoginCount   int        `gorm:"type:int;default:0;comment:登录次数" json:"login_count"`
-	DepartmentID uint       `gorm:"comment:部门ID" json:"department_id"`
-	DeptID       uint       `gorm:"comment:部门ID(别名)" json:"dept_id"`
+	DepartmentID uint       `gorm:"comment:主部门ID" json:"department_id"`
 	Gender       int        `gorm:"type:tinyint;default:0;comment:性别(0:未知,1:男,2:女)" json:"gender"`
 	Department   Department `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
 	Roles        []Role     `gorm:"many2many:user_roles;" json:"roles,omitempty"`
 	IsAdmin      bool       `gorm:"type:tinyint;default:0;comment:是否管理员" json:"is_admin"`
+	InvitationID uint       `gorm:"default:0;comment:注册使用的邀请码ID" json:"invitation_id,omitempty"`
+	DeletedKey   uint       `gorm:"not null;default:0;uniqueIndex:uk_users_username,priority:2;uniqueIndex:uk_users_email,priority:2;uniqueIndex:uk_users_phone,priority:2;comment:删除标记" json:"-"`
 }
 
 // TableName 设置表名
@@ -35,7 +51,7 @@ func (User) TableName() string {
 // BeforeCreate 创建前钩子
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.Status == 0 {
-		u.Status = 1
+		u.Status = UserStatusActive
 	}
 	return nil
 }
@@ -47,6 +63,8 @@ type Department struct {
 	ParentID uint         `gorm:"default:0;comment:上级部门ID" json:"parent_id"`
 	Sort     int          `gorm:"type:int;default:0;comment:排序" json:"sort"`
 	Status   int          `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:禁用)" json:"status"`
+	Path     string       `gorm:"size:255;index;comment:部门路径(/上级ID/.../本部门ID/)" json:"path"`
+	Level    int          `gorm:"type:int;default:0;comment:层级(顶级部门为1)" json:"level"`
 	Children []Department `gorm:"foreignKey:ParentID" json:"children,omitempty"`
 }
 
@@ -55,17 +73,37 @@ func (Department) TableName() string {
 	return "departments"
 }
 
+// AfterCreate 创建后根据上级部门生成路径
+func (d *Department) AfterCreate(tx *gorm.DB) error {
+	prefix := "/"
+	level := 1
+	if d.ParentID != 0 {
+		var parent Department
+		if err := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Select("path", "level").
+			First(&parent, d.ParentID).Error; err != nil {
+			return err
+		}
+		prefix = parent.Path
+		level = parent.Level + 1
+	}
+	d.Path = prefix + strconv.FormatUint(uint64(d.ID), 10) + "/"
+	d.Level = level
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&Department{}).Where("id = ?", d.ID).
+		UpdateColumns(map[string]interface{}{"path": d.Path, "level": d.Level}).Error
+}
+
 // Role 角色模型
 type Role struct {
 	BaseModel
-	Name        string       `gorm:"size:64;uniqueIndex;not null;comment:角色名称" json:"name" validate:"required"`
-	Key         string       `gorm:"size:64;uniqueIndex;not null;comment:角色标识" json:"key" validate:"required"`
+	Name        string       `gorm:"size:64;uniqueIndex:uk_roles_name,priority:1;not null;comment:角色名称" json:"name" validate:"required"`
+	Key         string       `gorm:"size:64;uniqueIndex:uk_roles_key,priority:1;not null;comment:角色标识" json:"key" validate:"required"`
 	Description string       `gorm:"size:255;comment:角色描述" json:"description"`
 	Status      int          `gorm:"type:tinyint;default:1;comment:状态(1:正常,2:禁用)" json:"status"`
 	Sort        int          `gorm:"type:int;default:0;comment:排序" json:"sort"`
 	Users       []User       `gorm:"many2many:user_roles;" json:"users,omitempty"`
 	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
 	Menus       []Menu       `gorm:"many2many:role_menus;" json:"menus,omitempty"`
+	DeletedKey  uint         `gorm:"not null;default:0;uniqueIndex:uk_roles_name,priority:2;uniqueIndex:uk_roles_key,priority:2;comment:删除标记" json:"-"`
 }
 
 // TableName 设置表名
@@ -77,9 +115,9 @@ func (Role) TableName() string {
 type Permission struct {
 	BaseModel
 	Name        string `gorm:"size:64;not null;comment:权限名称" json:"name" validate:"required"`
-	Key         string `gorm:"size:64;uniqueIndex;not null;comment:权限标识" json:"key" validate:"required"`
+	Key         string `gorm:"size:64;uniqueIndex:uk_permissions_key,priority:1;not null;comment:权限标识" json:"key" validate:"required"`
 	Description string `gorm:"size:255;comment:权限描述" json:"description"`
-	Type        string `gorm:"size:32;default:'api';comment:权限类型(api,menu,button)" json:"type"`
+	Type        string `gorm:"size:32;default:'api';comment:权限类型(api,button)" json:"type"`
 	Method      string `gorm:"size:16;comment:请求方法" json:"method"`
 	Path        string `gorm:"size:128;comment:请求路径" json:"path"`
 	Component   string `gorm:"size:128;comment:组件路径" json:"component"`
@@ -88,6 +126,7 @@ type Permission struct {
 	ParentID    uint   `gorm:"default:0;comment:上级权限ID" json:"parent_id"`
 	Sort        int    `gorm:"type:int;default:0;comment:排序" json:"sort"`
 	Roles       []Role `gorm:"many2many:role_permissions;" json:"roles,omitempty"`
+	DeletedKey  uint   `gorm:"not null;default:0;uniqueIndex:uk_permissions_key,priority:2;comment:删除标记" json:"-"`
 }
 
 // TableName 设置表名
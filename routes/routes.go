@@ -10,7 +10,12 @@ import (
 	"rbac_admin_server/api/user_api"
 	"rbac_admin_server/global"
 	"rbac_admin_server/middleware"
+	"rbac_admin_server/utils/audit"
 	"rbac_admin_server/utils/captcha"
+	"rbac_admin_server/utils/metrics"
+	"rbac_admin_server/utils/privacy"
+	"rbac_admin_server/utils/recycle"
+	"rbac_admin_server/utils/userstate"
 )
 
 // Run 运行路由和HTTP服务器
@@ -25,8 +30,9 @@ func Run() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// 创建默认路由
-	r := gin.Default()
+	// 创建路由，使用带请求ID的访问日志替代Gin默认日志
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.RequestID(), middleware.Logger())
 
 	// 设置跨域中间件
 	r.Use(middleware.Cors())
@@ -43,6 +49,25 @@ func Run() {
 	// 启动邮件验证码清理定时器
 	captcha.EmailStore.StartCleanupTimer()
 
+	// 启动审计检查点和日志保留策略定时器
+	audit.StartCheckpointTimer()
+	audit.StartRetentionTimer()
+
+	// 启动账号到期检查定时器
+	userstate.StartExpiryTimer()
+
+	// 启动回收站自动清理定时器
+	recycle.StartPurgeTimer()
+
+	// 启动到期注销申请处理定时器
+	privacy.StartDeletionTimer()
+
+	// 注册监控指标路由
+	if global.Config.Monitoring.Enabled && global.Config.Monitoring.MetricsPath != "" {
+		monitoring := global.Config.Monitoring
+		r.GET(monitoring.MetricsPath, metrics.Access(monitoring.MetricsToken, monitoring.MetricsAllowIPs), metrics.Handler())
+	}
+
 	// 创建API实例
 	userApi := user_api.NewUserApi()
 	captchaApi := &captcha_api.CaptchaApi{}
@@ -53,8 +78,12 @@ func Run() {
 	{
 		// 登录接口
 		public.POST("/login", userApi.Login)
+		// 邮箱验证码登录
+		public.POST("/login/email-code", userApi.SendLoginCode)
+		public.POST("/login/email", userApi.EmailCodeLogin)
 		// 注册接口
 		public.POST("/register", userApi.Register)
+		public.GET("/register/policy", userApi.GetRegisterPolicy)
 		// 验证码路由
 		captchaApi.RegisterRoutes(public)
 		// 邮箱路由
@@ -63,8 +92,8 @@ func Run() {
 
 	// 需要认证的路由组
 	admin := r.Group("/admin")
-	// 使用Auth中间件进行身份验证
-	admin.Use(middleware.Auth())
+	// 使用Auth中间件进行身份验证，Audit中间件记录操作日志
+	admin.Use(middleware.Auth(), middleware.Audit())
 	{
 		// 用户管理模块
 		api.App.UserApi.RegisterRoutes(admin)
@@ -78,6 +107,12 @@ func Run() {
 		// 部门管理模块
 		api.App.DeptApi.RegisterRoutes(admin)
 
+		// 岗位管理模块
+		api.App.PositionApi.RegisterRoutes(admin)
+
+		// 用户组管理模块
+		api.App.GroupApi.RegisterRoutes(admin)
+
 		// 菜单管理模块
 		api.App.MenuApi.RegisterRoutes(admin)
 
@@ -89,6 +124,18 @@ func Run() {
 
 		// 个人中心模块
 		api.App.ProfileApi.RegisterRoutes(admin)
+
+		// RBAC配置快照模块
+		api.App.RbacApi.RegisterRoutes(admin)
+
+		// 回收站模块
+		api.App.RecycleApi.RegisterRoutes(admin)
+
+		// 注册管理模块
+		api.App.RegistrationApi.RegisterRoutes(admin)
+
+		// 翻译管理模块
+		api.App.I18nApi.RegisterRoutes(admin)
 	}
 
 	// 启动HTTP服务器
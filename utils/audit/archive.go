@@ -0,0 +1,234 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm/clause"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// archiveBatchSize 归档和恢复时每批处理的记录数
+const archiveBatchSize = 500
+
+// maxArchiveLine 归档文件单行最大字节数
+const maxArchiveLine = 1 << 20
+
+// toArchive 将日志转换为归档记录
+func toArchive(l *models.Log) models.LogArchive {
+	return models.LogArchive{
+		LogID:       l.ID,
+		CreatedAt:   l.CreatedAt,
+		Type:        l.Type,
+		Seq:         l.Seq,
+		UserID:      l.UserID,
+		Username:    l.Username,
+		IP:          l.IP,
+		UserAgent:   l.UserAgent,
+		Method:      l.Method,
+		Path:        l.Path,
+		StatusCode:  l.StatusCode,
+		RequestBody: l.RequestBody,
+		Response:    l.Response,
+		Latency:     l.Latency,
+		Error:       l.Error,
+		Module:      l.Module,
+		Action:      l.Action,
+		Description: l.Description,
+		PrevHash:    l.PrevHash,
+		Hash:        l.Hash,
+		RequestID:   l.RequestID,
+		Redaction:   l.Redaction,
+		OnBehalfOf:  l.OnBehalfOf,
+	}
+}
+
+// fromArchive 将归档记录还原为日志，用于重新计算哈希
+func fromArchive(a *models.LogArchive) *models.Log {
+	l := &models.Log{
+		Type:        a.Type,
+		Seq:         a.Seq,
+		UserID:      a.UserID,
+		Username:    a.Username,
+		IP:          a.IP,
+		UserAgent:   a.UserAgent,
+		Method:      a.Method,
+		Path:        a.Path,
+		StatusCode:  a.StatusCode,
+		RequestBody: a.RequestBody,
+		Response:    a.Response,
+		Latency:     a.Latency,
+		Error:       a.Error,
+		Module:      a.Module,
+		Action:      a.Action,
+		Description: a.Description,
+		PrevHash:    a.PrevHash,
+		Hash:        a.Hash,
+		RequestID:   a.RequestID,
+		Redaction:   a.Redaction,
+		OnBehalfOf:  a.OnBehalfOf,
+	}
+	l.ID = a.LogID
+	l.CreatedAt = a.CreatedAt
+	return l
+}
+
+// Archive 将指定类型链上早于before的记录写入压缩归档文件后删除
+// 归档文件为gzip压缩的JSONL，每行一条记录，包含完整的哈希链字段
+func Archive(logType string, before time.Time) (int64, string, error) {
+	cutoff, err := retentionCutoff(logType, before)
+	if err != nil || cutoff == nil {
+		return 0, "", err
+	}
+
+	dir := filepath.Join(global.Config.Audit.ArchiveDir, logType)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, "", fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	name := fmt.Sprintf("%s_%d_%s.jsonl.gz", logType, cutoff.Seq, time.Now().Format("20060102150405"))
+	path := filepath.Join(dir, name)
+
+	n, err := writeArchive(path, logType, cutoff.Seq)
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return 0, "", err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return 0, "", fmt.Errorf("保存归档文件失败: %w", err)
+	}
+
+	deleted, err := purgeThrough(cutoff)
+	if err != nil {
+		return 0, path, err
+	}
+	if deleted != n {
+		global.Logger.Warnf("归档%s日志 %d 条，实际删除 %d 条", logType, n, deleted)
+	}
+	archivedRows.Add(logType, float64(n))
+	return n, path, nil
+}
+
+// writeArchive 分批读取链头到throughSeq的记录并写入临时归档文件
+func writeArchive(path, logType string, throughSeq uint64) (int64, error) {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return 0, fmt.Errorf("创建归档文件失败: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	encoder.SetEscapeHTML(false)
+
+	var count int64
+	var afterSeq uint64
+	for afterSeq < throughSeq {
+		var batch []models.Log
+		if err := global.DB.Where("type = ? AND seq > ? AND seq <= ?", logType, afterSeq, throughSeq).
+			Order("seq").Limit(archiveBatchSize).Find(&batch).Error; err != nil {
+			return count, fmt.Errorf("读取待归档日志失败: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for i := range batch {
+			if err := encoder.Encode(toArchive(&batch[i])); err != nil {
+				return count, fmt.Errorf("写入归档文件失败: %w", err)
+			}
+			count++
+		}
+		afterSeq = batch[len(batch)-1].Seq
+	}
+
+	if err := gz.Close(); err != nil {
+		return count, fmt.Errorf("写入归档文件失败: %w", err)
+	}
+	return count, file.Sync()
+}
+
+// RestoreResult 归档恢复结果
+type RestoreResult struct {
+	Rows     int64     `json:"rows"`
+	Problems []Problem `json:"problems"`
+}
+
+// Restore 将归档文件恢复到只读的归档日志表
+// 恢复时校验文件内的哈希链，已恢复过的记录会被跳过
+func Restore(path string) (*RestoreResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开归档文件失败: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取归档文件失败: %w", err)
+	}
+	defer gz.Close()
+
+	report := &Report{}
+	result := &RestoreResult{}
+	source := filepath.Base(path)
+	batch := make([]models.LogArchive, 0, archiveBatchSize)
+	var prev *models.Log
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		res := global.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&batch)
+		if res.Error != nil {
+			return fmt.Errorf("写入归档日志表失败: %w", res.Error)
+		}
+		result.Rows += res.RowsAffected
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), maxArchiveLine)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var row models.LogArchive
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return result, fmt.Errorf("归档文件第%d行格式错误: %w", lineNo, err)
+		}
+
+		l := fromArchive(&row)
+		if prev != nil {
+			if l.Seq != prev.Seq+1 {
+				report.add(l.Type, l.Seq, ProblemGap, "序号%d之后缺少%d条记录", prev.Seq, l.Seq-prev.Seq-1)
+			}
+			if l.PrevHash != prev.Hash {
+				report.add(l.Type, l.Seq, ProblemBrokenLink, "上一条记录哈希不匹配")
+			}
+		}
+		if !Intact(l) {
+			report.add(l.Type, l.Seq, ProblemModified, "记录内容与哈希不一致")
+		}
+		prev = l
+
+		row.ArchiveFile = source
+		batch = append(batch, row)
+		if len(batch) >= archiveBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("读取归档文件失败: %w", err)
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	result.Problems = report.Problems
+	return result, nil
+}
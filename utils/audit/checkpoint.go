@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"rbac_admin_server/global"
+)
+
+// 检查点产生原因
+const (
+	CheckpointPeriodic  = "periodic"  // 定时检查点
+	CheckpointRetention = "retention" // 保留策略清理前的锚点
+	CheckpointManual    = "manual"    // 命令行手动写入
+)
+
+// Checkpoint 签名检查点
+// 记录某条链在某一时刻的序号和哈希，用于发现整链重写或尾部截断
+type Checkpoint struct {
+	Type      string    `json:"type"`
+	Seq       uint64    `json:"seq"`
+	Hash      string    `json:"hash"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	Signature string    `json:"signature"`
+}
+
+// ErrNoSigningKey 未配置审计签名密钥
+var ErrNoSigningKey = errors.New("未配置审计签名密钥")
+
+// signingKey 返回检查点和匿名化的签名密钥
+// 密钥必须单独配置，不回退到JWT密钥，避免持有令牌密钥的人伪造审计签名
+func signingKey() ([]byte, error) {
+	secret := global.Config.Audit.SigningSecret
+	if secret == "" {
+		return nil, ErrNoSigningKey
+	}
+	return []byte(secret), nil
+}
+
+// sign 计算检查点签名
+func (cp *Checkpoint) sign() (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s|%d|%s|%s|%d", cp.Type, cp.Seq, cp.Hash, cp.Reason, cp.CreatedAt.Unix())
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Valid 校验检查点签名，未配置签名密钥时视为无效
+func (cp *Checkpoint) Valid() bool {
+	signature, err := cp.sign()
+	return err == nil && hmac.Equal([]byte(cp.Signature), []byte(signature))
+}
+
+// appendCheckpoint 签名并追加写入检查点文件
+func appendCheckpoint(cp Checkpoint) error {
+	path := global.Config.Audit.CheckpointFile
+	if path == "" {
+		return fmt.Errorf("未配置审计检查点文件")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建检查点目录失败: %w", err)
+	}
+
+	cp.CreatedAt = time.Now().Truncate(time.Second)
+	signature, err := cp.sign()
+	if err != nil {
+		return err
+	}
+	cp.Signature = signature
+	line, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开检查点文件失败: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入检查点失败: %w", err)
+	}
+	return file.Sync()
+}
+
+// ReadCheckpoints 读取检查点文件中的全部检查点
+// 文件不存在时返回空列表
+func ReadCheckpoints() ([]Checkpoint, error) {
+	file, err := os.Open(global.Config.Audit.CheckpointFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("打开检查点文件失败: %w", err)
+	}
+	defer file.Close()
+
+	var checkpoints []Checkpoint
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(scanner.Bytes(), &cp); err != nil {
+			return nil, fmt.Errorf("检查点文件第%d行格式错误: %w", lineNo, err)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, scanner.Err()
+}
+
+// WriteCheckpoints 为每条日志链写入当前链尾的检查点
+// 链尾自上次检查点以来没有变化时跳过
+func WriteCheckpoints(reason string) (int, error) {
+	types, err := Types()
+	if err != nil {
+		return 0, fmt.Errorf("查询日志类型失败: %w", err)
+	}
+	existing, err := ReadCheckpoints()
+	if err != nil {
+		return 0, err
+	}
+	lastSeq := make(map[string]uint64)
+	for _, cp := range existing {
+		if cp.Seq > lastSeq[cp.Type] {
+			lastSeq[cp.Type] = cp.Seq
+		}
+	}
+
+	written := 0
+	for _, logType := range types {
+		last, err := latest(logType)
+		if err != nil {
+			return written, fmt.Errorf("查询%s链尾失败: %w", logType, err)
+		}
+		if last == nil || last.Seq <= lastSeq[logType] {
+			continue
+		}
+		if err := appendCheckpoint(Checkpoint{Type: logType, Seq: last.Seq, Hash: last.Hash, Reason: reason}); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// StartCheckpointTimer 启动定时检查点任务
+func StartCheckpointTimer() {
+	interval := global.Config.Audit.CheckpointInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := WriteCheckpoints(CheckpointPeriodic); err != nil {
+				global.Logger.Errorf("写入审计检查点失败: %v", err)
+			} else if n > 0 {
+				global.Logger.Infof("写入了 %d 个审计检查点", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// recordMu 保证同一进程内链序号的分配是串行的
+// 多实例部署时由 (type, seq) 唯一索引兜底，冲突后重试
+var recordMu sync.Mutex
+
+// maxRecordRetries 链序号冲突时的最大重试次数
+const maxRecordRetries = 3
+
+// hashPayload 参与哈希计算的字段
+// 字段顺序固定，新增字段必须带omitempty以保证历史记录哈希不变
+type hashPayload struct {
+	Type        string `json:"type"`
+	Seq         uint64 `json:"seq"`
+	CreatedAt   int64  `json:"created_at"`
+	UserID      uint   `json:"user_id"`
+	Username    string `json:"username"`
+	IP          string `json:"ip"`
+	UserAgent   string `json:"user_agent"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	StatusCode  int    `json:"status_code"`
+	RequestBody string `json:"request_body"`
+	Response    string `json:"response"`
+	Latency     int64  `json:"latency"`
+	Error       string `json:"error"`
+	Module      string `json:"module"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	PrevHash    string `json:"prev_hash"`
+	RequestID   string `json:"request_id,omitempty"`
+	OnBehalfOf  uint   `json:"on_behalf_of,omitempty"`
+}
+
+// ComputeHash 计算日志记录的哈希值
+// 哈希覆盖记录内容和上一条记录的哈希，任何修改都会导致链断裂
+func ComputeHash(l *models.Log) string {
+	payload := hashPayload{
+		Type:        l.Type,
+		Seq:         l.Seq,
+		CreatedAt:   l.CreatedAt.Unix(),
+		UserID:      l.UserID,
+		Username:    l.Username,
+		IP:          l.IP,
+		UserAgent:   l.UserAgent,
+		Method:      l.Method,
+		Path:        l.Path,
+		StatusCode:  l.StatusCode,
+		RequestBody: l.RequestBody,
+		Response:    l.Response,
+		Latency:     l.Latency,
+		Error:       l.Error,
+		Module:      l.Module,
+		Action:      l.Action,
+		Description: l.Description,
+		PrevHash:    l.PrevHash,
+		RequestID:   l.RequestID,
+		OnBehalfOf:  l.OnBehalfOf,
+	}
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record 追加一条审计日志
+// 自动分配链内序号并计算哈希，调用方无需填写Seq/PrevHash/Hash
+func Record(entry *models.Log) error {
+	if global.DB == nil {
+		return fmt.Errorf("数据库连接未初始化")
+	}
+	if entry.Type == "" {
+		entry.Type = models.LogTypeOperation
+	}
+	normalize(entry)
+
+	recordMu.Lock()
+	defer recordMu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < maxRecordRetries; attempt++ {
+		err = global.DB.Transaction(func(tx *gorm.DB) error {
+			var last models.Log
+			if err := tx.Where("type = ?", entry.Type).Order("seq DESC").Limit(1).Find(&last).Error; err != nil {
+				return err
+			}
+			entry.ID = 0
+			entry.Seq = last.Seq + 1
+			entry.PrevHash = last.Hash
+			entry.Hash = ComputeHash(entry)
+			return tx.Create(entry).Error
+		})
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("写入审计日志失败: %w", err)
+}
+
+// normalize 规范化日志字段
+// 时间截断到秒、字符串截断到列宽，保证入库后重新计算的哈希一致
+func normalize(l *models.Log) {
+	now := time.Now().Truncate(time.Second)
+	l.CreatedAt = now
+	l.UpdatedAt = now
+	l.Username = truncate(l.Username, 64)
+	l.IP = truncate(l.IP, 64)
+	l.UserAgent = truncate(l.UserAgent, 255)
+	l.Method = truncate(l.Method, 16)
+	l.Path = truncate(l.Path, 128)
+	l.Module = truncate(l.Module, 64)
+	l.Action = truncate(l.Action, 64)
+	l.Description = truncate(l.Description, 255)
+	l.RequestID = truncate(l.RequestID, 64)
+	l.RequestBody = truncate(l.RequestBody, 16000)
+	l.Response = truncate(l.Response, 16000)
+	l.Error = truncate(l.Error, 16000)
+}
+
+// truncate 按字符数截断字符串，并去除非法UTF-8字节
+func truncate(s string, max int) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// Types 返回数据库中已有的日志类型
+func Types() ([]string, error) {
+	var types []string
+	if err := global.DB.Model(&models.Log{}).Distinct("type").Pluck("type", &types).Error; err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// latest 返回指定类型链上的最后一条记录
+func latest(logType string) (*models.Log, error) {
+	var last models.Log
+	result := global.DB.Where("type = ?", logType).Order("seq DESC").Limit(1).Find(&last)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &last, nil
+}
@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"rbac_admin_server/config"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+func init() {
+	global.Config = &config.Config{}
+	global.Config.JWT.Secret = "test-secret"
+	global.Config.Audit.SigningSecret = "test-signing-secret"
+	global.Logger = logrus.New()
+	global.Logger.SetOutput(io.Discard)
+}
+
+// chain 生成一条哈希链接好的日志链
+func chain(n int) []*models.Log {
+	created := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	logs := make([]*models.Log, n)
+	prev := ""
+	for i := range logs {
+		l := &models.Log{
+			Type:     "operation",
+			Seq:      uint64(i + 1),
+			UserID:   3,
+			Username: "eve01",
+			Method:   "POST",
+			Path:     "/admin/user/update",
+			PrevHash: prev,
+		}
+		l.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		l.Hash = ComputeHash(l)
+		prev = l.Hash
+		logs[i] = l
+	}
+	return logs
+}
+
+func TestComputeHashChain(t *testing.T) {
+	logs := chain(3)
+	for i, l := range logs {
+		if l.Hash != ComputeHash(l) {
+			t.Fatalf("第%d条哈希不稳定", i+1)
+		}
+		if i > 0 && l.PrevHash != logs[i-1].Hash {
+			t.Fatalf("第%d条未链接上一条哈希", i+1)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(l *models.Log)
+	}{
+		{"用户名", func(l *models.Log) { l.Username = "mallory" }},
+		{"序号", func(l *models.Log) { l.Seq++ }},
+		{"时间", func(l *models.Log) { l.CreatedAt = l.CreatedAt.Add(time.Second) }},
+		{"上一条哈希", func(l *models.Log) { l.PrevHash = "" }},
+		{"请求ID", func(l *models.Log) { l.RequestID = "req-1" }},
+		{"代操作用户", func(l *models.Log) { l.OnBehalfOf = 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := *logs[1]
+			tt.modify(&l)
+			if ComputeHash(&l) == l.Hash {
+				t.Fatalf("修改%s后哈希未变化", tt.name)
+			}
+		})
+	}
+}
+
+func TestComputeHashOmitEmpty(t *testing.T) {
+	// 新增字段为空值时不参与哈希，历史记录的哈希保持不变
+	a := chain(1)[0]
+	b := *a
+	b.RequestID, b.OnBehalfOf = "", 0
+	if ComputeHash(a) != ComputeHash(&b) {
+		t.Fatal("空的新增字段改变了哈希")
+	}
+}
+
+func TestCheckpointValid(t *testing.T) {
+	base := Checkpoint{
+		Type:      "operation",
+		Seq:       10,
+		Hash:      chain(1)[0].Hash,
+		Reason:    CheckpointManual,
+		CreatedAt: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+	}
+	signature, err := base.sign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.Signature = signature
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, cp *Checkpoint)
+		want   bool
+	}{
+		{"未修改", func(t *testing.T, cp *Checkpoint) {}, true},
+		{"序号", func(t *testing.T, cp *Checkpoint) { cp.Seq-- }, false},
+		{"哈希", func(t *testing.T, cp *Checkpoint) { cp.Hash = "" }, false},
+		{"原因", func(t *testing.T, cp *Checkpoint) { cp.Reason = CheckpointPeriodic }, false},
+		{"时间", func(t *testing.T, cp *Checkpoint) { cp.CreatedAt = cp.CreatedAt.Add(time.Second) }, false},
+		{"签名", func(t *testing.T, cp *Checkpoint) { cp.Signature = "" }, false},
+		{"未配置密钥", func(t *testing.T, cp *Checkpoint) { withSecret(t, "") }, false},
+		{"换密钥", func(t *testing.T, cp *Checkpoint) { withSecret(t, "other-secret") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := base
+			tt.modify(t, &cp)
+			if got := cp.Valid(); got != tt.want {
+				t.Fatalf("Valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// withSecret 在当前测试内替换审计签名密钥
+func withSecret(t *testing.T, secret string) {
+	old := global.Config.Audit.SigningSecret
+	global.Config.Audit.SigningSecret = secret
+	t.Cleanup(func() { global.Config.Audit.SigningSecret = old })
+}
+
+// mustRedact 匿名化并要求成功
+func mustRedact(t *testing.T, l *models.Log, alias string) {
+	ok, err := redact(l, alias)
+	if err != nil || !ok {
+		t.Fatalf("redact() = %v, %v", ok, err)
+	}
+}
+
+func TestIntact(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, l *models.Log)
+		want   bool
+	}{
+		{"未修改", func(t *testing.T, l *models.Log) {}, true},
+		{"篡改用户名", func(t *testing.T, l *models.Log) { l.Username = "mallory" }, false},
+		{"匿名化", func(t *testing.T, l *models.Log) { mustRedact(t, l, "deleted-3") }, true},
+		{"重复匿名化", func(t *testing.T, l *models.Log) {
+			mustRedact(t, l, "deleted-3")
+			mustRedact(t, l, "deleted-x")
+		}, true},
+		{"匿名化后改用户名", func(t *testing.T, l *models.Log) {
+			mustRedact(t, l, "deleted-3")
+			l.Username = "mallory"
+		}, false},
+		{"匿名化后改其他字段", func(t *testing.T, l *models.Log) {
+			mustRedact(t, l, "deleted-3")
+			l.Path = "/admin/user/delete"
+		}, false},
+		{"匿名化后改请求ID", func(t *testing.T, l *models.Log) {
+			mustRedact(t, l, "deleted-3")
+			l.RequestID = "req-1"
+		}, false},
+		{"伪造签名", func(t *testing.T, l *models.Log) {
+			l.Username = "deleted-3"
+			l.Redaction = "0000"
+		}, false},
+		{"复用其他记录的签名", func(t *testing.T, l *models.Log) {
+			other := chain(2)[1]
+			mustRedact(t, other, "deleted-3")
+			l.Username, l.Redaction = other.Username, other.Redaction
+		}, false},
+		{"换密钥签名", func(t *testing.T, l *models.Log) {
+			withSecret(t, "other-secret")
+			mustRedact(t, l, "deleted-3")
+			global.Config.Audit.SigningSecret = "test-signing-secret"
+		}, false},
+		{"不回退到JWT密钥", func(t *testing.T, l *models.Log) {
+			withSecret(t, global.Config.JWT.Secret)
+			mustRedact(t, l, "deleted-3")
+			global.Config.Audit.SigningSecret = "test-signing-secret"
+		}, false},
+		{"未配置密钥", func(t *testing.T, l *models.Log) {
+			mustRedact(t, l, "deleted-3")
+			withSecret(t, "")
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := chain(1)[0]
+			tt.modify(t, l)
+			if got := Intact(l); got != tt.want {
+				t.Fatalf("Intact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactTampered(t *testing.T) {
+	l := chain(1)[0]
+	l.Path = "/admin/user/delete"
+	if ok, err := redact(l, "deleted-3"); ok || err != nil {
+		t.Fatalf("篡改过的记录不应被匿名化: %v, %v", ok, err)
+	}
+	if l.Username != "eve01" || l.Redaction != "" {
+		t.Fatal("跳过匿名化的记录被修改")
+	}
+}
+
+func TestRedactWithoutSecret(t *testing.T) {
+	withSecret(t, "")
+	l := chain(1)[0]
+	if _, err := redact(l, "deleted-3"); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("err = %v, want ErrNoSigningKey", err)
+	}
+	if l.Username != "eve01" || l.Redaction != "" {
+		t.Fatal("签名失败的记录被修改")
+	}
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"gorm.io/gorm"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// redactedField 匿名化允许修改的唯一字段
+const redactedField = "username"
+
+// Intact 判断记录内容是否未被篡改
+// 内容与哈希一致，或经过签名的匿名化后只有用户名发生了变化
+func Intact(l *models.Log) bool {
+	if ComputeHash(l) == l.Hash {
+		return true
+	}
+	if l.Redaction == "" {
+		return false
+	}
+	signature, err := redactionSignature(l.Hash, l.Username, contentHash(l))
+	return err == nil && hmac.Equal([]byte(l.Redaction), []byte(signature))
+}
+
+// redactionSignature 匿名化签名
+// 绑定原哈希、被修改的字段名和新值，以及原记录除用户名外的内容哈希；
+// 链上的哈希保持不变，签名后修改其他字段或改成其他用户名都会使签名失效
+func redactionSignature(hash, username, content string) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s|%s|%s|%s", hash, redactedField, username, content)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// contentHash 计算除用户名外的内容哈希
+func contentHash(l *models.Log) string {
+	rest := *l
+	rest.Username = ""
+	return ComputeHash(&rest)
+}
+
+// Pseudonymize 将用户审计日志中的用户名替换为alias，返回修改的记录数
+// 包括已恢复的归档日志；已归档的压缩文件不做修改。内容已被篡改的记录保持原样并计入日志告警
+func Pseudonymize(db *gorm.DB, userID uint, alias string) (int64, error) {
+	alias = truncate(alias, 64)
+	var total int64
+
+	var afterID uint
+	for {
+		var batch []models.Log
+		if err := db.Where("user_id = ? AND username <> ? AND id > ?", userID, alias, afterID).
+			Order("id").Limit(verifyBatchSize).Find(&batch).Error; err != nil {
+			return total, fmt.Errorf("读取审计日志失败: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for i := range batch {
+			row := &batch[i]
+			ok, err := redact(row, alias)
+			if err != nil {
+				return total, fmt.Errorf("匿名化签名失败: %w", err)
+			}
+			if !ok {
+				continue
+			}
+			if err := db.Set(models.LogRedactionKey, true).Model(&models.Log{}).Where("id = ?", row.ID).
+				Updates(map[string]interface{}{"username": row.Username, "redaction": row.Redaction}).Error; err != nil {
+				return total, fmt.Errorf("匿名化审计日志失败: %w", err)
+			}
+			total++
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+
+	var afterArchiveID uint
+	for {
+		var batch []models.LogArchive
+		if err := db.Where("user_id = ? AND username <> ? AND id > ?", userID, alias, afterArchiveID).
+			Order("id").Limit(verifyBatchSize).Find(&batch).Error; err != nil {
+			return total, fmt.Errorf("读取归档日志失败: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for i := range batch {
+			row := fromArchive(&batch[i])
+			ok, err := redact(row, alias)
+			if err != nil {
+				return total, fmt.Errorf("匿名化签名失败: %w", err)
+			}
+			if !ok {
+				continue
+			}
+			if err := db.Set(models.LogRedactionKey, true).Model(&models.LogArchive{}).Where("id = ?", batch[i].ID).
+				Updates(map[string]interface{}{"username": row.Username, "redaction": row.Redaction}).Error; err != nil {
+				return total, fmt.Errorf("匿名化归档日志失败: %w", err)
+			}
+			total++
+		}
+		afterArchiveID = batch[len(batch)-1].ID
+	}
+	return total, nil
+}
+
+// redact 替换用户名并重新签名，记录已被篡改时返回false
+// 只对校验通过的记录签名，签名中的内容哈希取自原记录，保证除用户名外与原记录一致
+func redact(l *models.Log, alias string) (bool, error) {
+	if !Intact(l) {
+		global.Logger.Warnf("%s日志序号%d内容与哈希不一致，跳过匿名化", l.Type, l.Seq)
+		return false, nil
+	}
+	signature, err := redactionSignature(l.Hash, alias, contentHash(l))
+	if err != nil {
+		return false, err
+	}
+	l.Username, l.Redaction = alias, signature
+	return true, nil
+}
@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// sensitiveKeys 键名包含这些关键字的字段需要脱敏
+var sensitiveKeys = []string{"password", "token", "secret"}
+
+// credentialKeys 验证码等凭据字段，键名完全相同(不区分大小写)才脱敏
+// 不按code子串匹配，岗位编码、字典编码和status_code等字段保持原样
+var credentialKeys = []string{
+	"captchacode", "captcha_code", "emailcode", "email_code", "smscode", "sms_code",
+	"verifycode", "verify_code", "invitecode", "invite_code",
+}
+
+// maskFieldsKey 处理器声明的额外脱敏字段在gin上下文中的键
+const maskFieldsKey = "auditMaskFields"
+
+// MaskFields 声明当前请求体中额外需要脱敏的字段
+// 用于code等在多数接口中不敏感、只在个别接口中是验证码的通用字段名
+func MaskFields(c *gin.Context, keys ...string) {
+	c.Set(maskFieldsKey, append(c.GetStringSlice(maskFieldsKey), keys...))
+}
+
+// MaskSensitive 对JSON请求体中的敏感字段脱敏
+func MaskSensitive(body string) string {
+	return mask(body, sensitiveKeys, credentialKeys)
+}
+
+// MaskRequest 对请求体脱敏，包括处理器通过MaskFields声明的字段
+func MaskRequest(c *gin.Context, body string) string {
+	return mask(body, sensitiveKeys, append(c.GetStringSlice(maskFieldsKey), credentialKeys...))
+}
+
+// MaskResponse 对JSON响应中的敏感字段脱敏
+// 响应中的code是业务状态码，不做脱敏
+func MaskResponse(body string) string {
+	return mask(body, sensitiveKeys, nil)
+}
+
+// mask 对JSON中的敏感字段脱敏，contains按子串匹配，exact按完整键名匹配
+// 内容被截断无法解析时按正则替换字符串值，非JSON内容原样返回
+func mask(body string, contains, exact []string) string {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return maskPattern(contains, exact).ReplaceAllString(body, `$1"******"`)
+	}
+	masked, err := json.Marshal(maskValue(data, contains, exact))
+	if err != nil {
+		return body
+	}
+	return string(masked)
+}
+
+// maskPattern 匹配 "敏感键":"字符串值"
+func maskPattern(contains, exact []string) *regexp.Regexp {
+	key := `[^"]*(?i:` + quoteJoin(contains) + `)[^"]*`
+	if len(exact) > 0 {
+		key = `(?:` + key + `|(?i:` + quoteJoin(exact) + `))`
+	}
+	return regexp.MustCompile(`("` + key + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+}
+
+func quoteJoin(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	return strings.Join(quoted, "|")
+}
+
+func maskValue(v interface{}, contains, exact []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if isSensitive(k, contains, exact) {
+				val[k] = "******"
+			} else {
+				val[k] = maskValue(item, contains, exact)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = maskValue(item, contains, exact)
+		}
+	}
+	return v
+}
+
+func isSensitive(key string, contains, exact []string) bool {
+	key = strings.ToLower(key)
+	for _, s := range contains {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	for _, s := range exact {
+		if key == strings.ToLower(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordLogin 记录登录日志
+// success为false时reason记录失败原因
+func RecordLogin(c *gin.Context, userID uint, username string, success bool, statusCode int, reason string) {
+	entry := models.Log{
+		Type:        models.LogTypeLogin,
+		UserID:      userID,
+		Username:    username,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.Path,
+		StatusCode:  statusCode,
+		Module:      "auth",
+		RequestID:   c.GetString(reqlog.RequestIDKey),
+		Action:      "login",
+		Description: "登录成功",
+	}
+	if !success {
+		entry.Action = "login_failed"
+		entry.Description = "登录失败"
+		entry.Error = reason
+	}
+	if err := Record(&entry); err != nil {
+		global.Logger.Errorf("记录登录日志失败: %v", err)
+	}
+}
+
+// Actor 返回请求的实际操作人
+// 模拟登录时为发起模拟的管理员，onBehalfOf为被模拟的用户；普通请求onBehalfOf为0
+func Actor(c *gin.Context) (userID uint, username string, onBehalfOf uint) {
+	if id := c.GetUint("impersonatorID"); id != 0 {
+		return id, c.GetString("impersonatorName"), c.GetUint("userID")
+	}
+	return c.GetUint("userID"), c.GetString("username"), 0
+}
+
+// descriptionKey 处理器补充的操作说明在gin上下文中的键
+const descriptionKey = "auditDescription"
+
+// Describe 为审计中间件记录的本次写操作补充说明，err不为空时一并记录
+// 经过审计中间件的请求应使用Describe，而不是再调用RecordOperation写一条日志
+func Describe(c *gin.Context, description string, err error) {
+	c.Set(descriptionKey, description)
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// Description 返回处理器通过Describe补充的操作说明
+func Description(c *gin.Context) string {
+	return c.GetString(descriptionKey)
+}
+
+// RecordOperation 记录不经过审计中间件的操作，如导出等GET请求
+func RecordOperation(c *gin.Context, module, action, description string, opErr error) {
+	userID, username, onBehalfOf := Actor(c)
+	entry := models.Log{
+		Type:        models.LogTypeOperation,
+		UserID:      userID,
+		Username:    username,
+		OnBehalfOf:  onBehalfOf,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.Path,
+		StatusCode:  c.Writer.Status(),
+		RequestBody: c.Request.URL.RawQuery,
+		RequestID:   c.GetString(reqlog.RequestIDKey),
+		Module:      module,
+		Action:      action,
+		Description: description,
+	}
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+	if err := Record(&entry); err != nil {
+		global.Logger.Errorf("记录操作日志失败: %v", err)
+	}
+}
+
+// RecordChange 记录数据变更历史
+// before和after为变更前后的数据快照，序列化后写入请求体字段
+func RecordChange(c *gin.Context, module, action string, targetID uint, before, after interface{}) {
+	snapshot, _ := json.Marshal(gin.H{"before": before, "after": after})
+	userID, username, onBehalfOf := Actor(c)
+	entry := models.Log{
+		Type:        models.LogTypeChange,
+		UserID:      userID,
+		Username:    username,
+		OnBehalfOf:  onBehalfOf,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.Path,
+		StatusCode:  200,
+		RequestBody: MaskSensitive(string(snapshot)),
+		Module:      module,
+		Action:      action,
+		Description: fmt.Sprintf("ID=%d", targetID),
+		RequestID:   c.GetString(reqlog.RequestIDKey),
+	}
+	if err := Record(&entry); err != nil {
+		global.Logger.Errorf("记录变更历史失败: %v", err)
+	}
+}
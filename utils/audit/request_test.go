@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaskSensitive(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"密码", `{"username":"eve01","password":"Admin@123"}`, `{"password":"******","username":"eve01"}`},
+		{"键名包含关键字", `{"old_password":"a","refreshToken":"b","client_secret":"c"}`,
+			`{"client_secret":"******","old_password":"******","refreshToken":"******"}`},
+		{"验证码", `{"captchaCode":"1234","email_code":"5678","SMS_CODE":"0000","inviteCode":"x"}`,
+			`{"SMS_CODE":"******","captchaCode":"******","email_code":"******","inviteCode":"******"}`},
+		{"编码字段保持原样", `{"code":"dev","status_code":200,"dict_code":"gender"}`,
+			`{"code":"dev","dict_code":"gender","status_code":200}`},
+		{"嵌套", `{"before":{"code":"dev","password":"x"},"list":[{"token":"y"}]}`,
+			`{"before":{"code":"dev","password":"******"},"list":[{"token":"******"}]}`},
+		{"截断的JSON", `{"code":"dev","password":"Adm`, `{"code":"dev","password":"Adm`},
+		{"截断的JSON已完整的值", `{"password":"Admin@123","captchaCode":"1234","code":"dev","na`,
+			`{"password":"******","captchaCode":"******","code":"dev","na`},
+		{"非JSON", `page=1&code=dev`, `page=1&code=dev`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskSensitive(tt.body); got != tt.want {
+				t.Fatalf("MaskSensitive() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	body := `{"email":"a@example.com","code":"123456"}`
+	if got := MaskRequest(c, body); got != `{"code":"123456","email":"a@example.com"}` {
+		t.Fatalf("未声明时 = %s", got)
+	}
+	MaskFields(c, "code")
+	if got := MaskRequest(c, body); got != `{"code":"******","email":"a@example.com"}` {
+		t.Fatalf("声明后 = %s", got)
+	}
+	if got := MaskResponse(`{"code":200,"msg":"ok","data":{"token":"x"}}`); got != `{"code":200,"data":{"token":"******"},"msg":"ok"}` {
+		t.Fatalf("MaskResponse() = %s", got)
+	}
+}
@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/metrics"
+)
+
+// 保留策略相关指标
+var (
+	archivedRows = metrics.NewCounterVec("rbac_log_archived_rows_total", "按保留策略归档的日志行数", "type")
+	purgedRows   = metrics.NewCounterVec("rbac_log_purged_rows_total", "按保留策略删除的日志行数", "type")
+	lastRun      = metrics.NewGaugeVec("rbac_log_retention_last_run_timestamp", "最近一次执行保留策略的时间戳", "type")
+)
+
+// retentionCutoff 返回保留策略可删除的最后一条记录
+// 只允许删除链头的连续前缀，并且始终保留链尾记录
+func retentionCutoff(logType string, before time.Time) (*models.Log, error) {
+	last, err := latest(logType)
+	if err != nil || last == nil {
+		return nil, err
+	}
+
+	var cutoff models.Log
+	result := global.DB.Where("type = ? AND created_at < ? AND seq < ?", logType, before, last.Seq).
+		Order("seq DESC").Limit(1).Find(&cutoff)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &cutoff, nil
+}
+
+// purgeThrough 删除链头到cutoff(含)的记录
+// 删除前先写入保留锚点，保证剩余部分仍可校验
+func purgeThrough(cutoff *models.Log) (int64, error) {
+	if err := appendCheckpoint(Checkpoint{
+		Type:   cutoff.Type,
+		Seq:    cutoff.Seq,
+		Hash:   cutoff.Hash,
+		Reason: CheckpointRetention,
+	}); err != nil {
+		return 0, fmt.Errorf("写入保留锚点失败: %w", err)
+	}
+
+	result := global.DB.Set(models.LogRetentionKey, true).
+		Where("type = ? AND seq <= ?", cutoff.Type, cutoff.Seq).
+		Delete(&models.Log{})
+	if result.Error == nil {
+		purgedRows.Add(cutoff.Type, float64(result.RowsAffected))
+	}
+	return result.RowsAffected, result.Error
+}
+
+// Purge 不归档直接删除指定类型链上早于before的记录
+func Purge(logType string, before time.Time) (int64, error) {
+	cutoff, err := retentionCutoff(logType, before)
+	if err != nil || cutoff == nil {
+		return 0, err
+	}
+	return purgeThrough(cutoff)
+}
+
+// ApplyRetention 按配置的各类型保留天数归档并清理日志
+// 配置了归档目录时先写入压缩归档文件，归档成功后才删除
+func ApplyRetention() (int64, error) {
+	cfg := global.Config.Audit
+	types, err := Types()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, logType := range types {
+		days := cfg.RetentionDays(logType)
+		if days <= 0 {
+			continue
+		}
+		before := time.Now().AddDate(0, 0, -days)
+
+		var n int64
+		if cfg.ArchiveDir != "" {
+			var file string
+			n, file, err = Archive(logType, before)
+			if err == nil && n > 0 {
+				global.Logger.Infof("保留策略归档了 %d 条%s日志: %s", n, logType, file)
+			}
+		} else {
+			n, err = Purge(logType, before)
+			if err == nil && n > 0 {
+				global.Logger.Infof("保留策略删除了 %d 条%s日志", n, logType)
+			}
+		}
+		if err != nil {
+			return total, fmt.Errorf("清理%s日志失败: %w", logType, err)
+		}
+		lastRun.Set(logType, float64(time.Now().Unix()))
+		total += n
+	}
+	return total, nil
+}
+
+// StartRetentionTimer 启动保留策略定时任务
+func StartRetentionTimer() {
+	interval := global.Config.Audit.RetentionInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := ApplyRetention(); err != nil {
+				global.Logger.Errorf("执行日志保留策略失败: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,178 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// 校验问题类型
+const (
+	ProblemGap                = "gap"                 // 序号不连续
+	ProblemBrokenLink         = "broken_link"         // 上一条哈希不匹配
+	ProblemModified           = "modified"            // 记录内容被修改
+	ProblemCheckpointMismatch = "checkpoint_mismatch" // 与检查点哈希不一致
+	ProblemCheckpointInvalid  = "checkpoint_invalid"  // 检查点签名无效
+	ProblemTruncated          = "truncated"           // 链尾被截断
+)
+
+// verifyBatchSize 校验时每批读取的记录数
+const verifyBatchSize = 500
+
+// Problem 校验发现的问题
+type Problem struct {
+	Type    string `json:"type"`
+	Seq     uint64 `json:"seq"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// ChainReport 单条日志链的校验结果
+type ChainReport struct {
+	Type     string `json:"type"`
+	Count    int64  `json:"count"`
+	FirstSeq uint64 `json:"first_seq"`
+	LastSeq  uint64 `json:"last_seq"`
+}
+
+// Report 审计日志校验报告
+type Report struct {
+	Chains      []ChainReport `json:"chains"`
+	Checkpoints int           `json:"checkpoints"`
+	Problems    []Problem     `json:"problems"`
+}
+
+// OK 是否未发现任何问题
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) add(logType string, seq uint64, kind, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{
+		Type:    logType,
+		Seq:     seq,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Verify 校验全部日志链
+// 检查序号连续性、哈希链接、记录内容以及与签名检查点的一致性
+func Verify() (*Report, error) {
+	report := &Report{}
+
+	checkpoints, err := ReadCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	report.Checkpoints = len(checkpoints)
+
+	// 按类型和序号索引有效的检查点
+	byType := make(map[string]map[uint64]Checkpoint)
+	for _, cp := range checkpoints {
+		if !cp.Valid() {
+			report.add(cp.Type, cp.Seq, ProblemCheckpointInvalid, "检查点签名无效")
+			continue
+		}
+		if byType[cp.Type] == nil {
+			byType[cp.Type] = make(map[uint64]Checkpoint)
+		}
+		byType[cp.Type][cp.Seq] = cp
+	}
+
+	types, err := Types()
+	if err != nil {
+		return nil, fmt.Errorf("查询日志类型失败: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, t := range types {
+		seen[t] = true
+	}
+	for t := range byType {
+		if !seen[t] {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+
+	for _, logType := range types {
+		chain, err := verifyChain(logType, byType[logType], report)
+		if err != nil {
+			return nil, err
+		}
+		report.Chains = append(report.Chains, chain)
+	}
+	return report, nil
+}
+
+// verifyChain 校验单条日志链
+func verifyChain(logType string, checkpoints map[uint64]Checkpoint, report *Report) (ChainReport, error) {
+	chain := ChainReport{Type: logType}
+	var prev *models.Log
+	var afterSeq uint64
+
+	for {
+		var batch []models.Log
+		if err := global.DB.Where("type = ? AND seq > ?", logType, afterSeq).
+			Order("seq").Limit(verifyBatchSize).Find(&batch).Error; err != nil {
+			return chain, fmt.Errorf("读取%s日志失败: %w", logType, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			row := &batch[i]
+			chain.Count++
+			if prev == nil {
+				chain.FirstSeq = row.Seq
+				verifyHead(row, checkpoints, report)
+			} else {
+				if row.Seq != prev.Seq+1 {
+					report.add(logType, row.Seq, ProblemGap, "序号%d之后缺少%d条记录", prev.Seq, row.Seq-prev.Seq-1)
+				}
+				if row.PrevHash != prev.Hash {
+					report.add(logType, row.Seq, ProblemBrokenLink, "上一条记录哈希不匹配")
+				}
+			}
+			if !Intact(row) {
+				report.add(logType, row.Seq, ProblemModified, "记录内容与哈希不一致")
+			}
+			if cp, ok := checkpoints[row.Seq]; ok && cp.Hash != row.Hash {
+				report.add(logType, row.Seq, ProblemCheckpointMismatch, "与%s检查点(%s)的哈希不一致", cp.Reason, cp.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			prev = row
+		}
+		afterSeq = batch[len(batch)-1].Seq
+		chain.LastSeq = afterSeq
+	}
+
+	// 检查点记录过的序号大于当前链尾，说明链尾被删除
+	for seq := range checkpoints {
+		if seq > chain.LastSeq {
+			report.add(logType, seq, ProblemTruncated, "检查点记录的序号%d已不存在，当前链尾为%d", seq, chain.LastSeq)
+		}
+	}
+	return chain, nil
+}
+
+// verifyHead 校验链头
+// 链头序号不为1时，必须存在与之衔接的检查点(通常是保留锚点)
+func verifyHead(row *models.Log, checkpoints map[uint64]Checkpoint, report *Report) {
+	if row.Seq == 1 {
+		if row.PrevHash != "" {
+			report.add(row.Type, row.Seq, ProblemBrokenLink, "链头记录不应包含上一条哈希")
+		}
+		return
+	}
+	anchor, ok := checkpoints[row.Seq-1]
+	if !ok {
+		report.add(row.Type, row.Seq, ProblemGap, "链头之前的%d条记录缺失且没有保留锚点", row.Seq-1)
+		return
+	}
+	if anchor.Hash != row.PrevHash {
+		report.add(row.Type, row.Seq, ProblemBrokenLink, "链头与%s检查点的哈希不匹配", anchor.Reason)
+	}
+}
@@ -0,0 +1,132 @@
+package avatar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// Category 头像文件在files表中的分类
+const Category = "avatar"
+
+// Result 上传结果
+type Result struct {
+	Avatar string         `json:"avatar"` // 用户头像地址，即最大尺寸的图片
+	Sizes  map[int]string `json:"sizes"`  // 各尺寸图片地址
+	Files  []uint         `json:"files"`  // 文件记录ID
+}
+
+// Save 处理并保存用户头像，更新User.Avatar并删除旧头像文件
+func Save(db *gorm.DB, userID uint, data []byte) (*Result, error) {
+	cfg := global.Config.Avatar
+	sizes := append([]int(nil), cfg.Sizes...)
+	sort.Ints(sizes)
+	if len(sizes) == 0 || sizes[0] <= 0 {
+		return nil, fmt.Errorf("头像尺寸配置无效: %v", cfg.Sizes)
+	}
+	images, err := Render(data, sizes, cfg.MaxPixels*10000)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(cfg.SavePath, time.Now().Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	id := uuid.New().String()
+	files := make([]models.File, 0, len(sizes))
+	for _, size := range sizes {
+		name := fmt.Sprintf("%s_%d.png", id, size)
+		dst := filepath.ToSlash(filepath.Join(dir, name))
+		if err := os.WriteFile(dst, images[size], 0644); err != nil {
+			removePaths(files)
+			return nil, err
+		}
+		sum := sha256.Sum256(images[size])
+		files = append(files, models.File{
+			Name: name, Path: dst, Size: int64(len(images[size])),
+			Type: Category, Category: Category, MimeType: "image/png", Extension: ".png",
+			Hash: hex.EncodeToString(sum[:]), UploadedBy: userID,
+		})
+	}
+
+	result := &Result{Sizes: make(map[int]string, len(files))}
+	var old []models.File
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category = ? AND uploaded_by = ?", Category, userID).Find(&old).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&files).Error; err != nil {
+			return err
+		}
+		for i, f := range files {
+			result.Sizes[sizes[i]] = URL(f.Path)
+			result.Files = append(result.Files, f.ID)
+		}
+		result.Avatar = result.Sizes[sizes[len(sizes)-1]]
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("avatar", result.Avatar).Error; err != nil {
+			return err
+		}
+		if len(old) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(old))
+		for i, f := range old {
+			ids[i] = f.ID
+		}
+		return tx.Unscoped().Delete(&models.File{}, ids).Error
+	})
+	if err != nil {
+		removePaths(files)
+		return nil, err
+	}
+	removePaths(old)
+	return result, nil
+}
+
+// Remove 删除用户的全部头像文件并清空User.Avatar
+func Remove(db *gorm.DB, userID uint) error {
+	var old []models.File
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("category = ? AND uploaded_by = ?", Category, userID).Find(&old).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Update("avatar", "").Error; err != nil {
+			return err
+		}
+		if len(old) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Where("category = ? AND uploaded_by = ?", Category, userID).Delete(&models.File{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	removePaths(old)
+	return nil
+}
+
+// URL 文件路径对应的访问地址，uploads目录以/uploads提供静态访问
+func URL(filePath string) string {
+	return path.Clean("/" + filepath.ToSlash(filePath))
+}
+
+// removePaths 删除物理文件，失败时只记录日志
+func removePaths(files []models.File) {
+	for _, f := range files {
+		if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+			global.Logger.Warnf("删除头像文件 %s 失败: %v", f.Path, err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// ErrInvalidImage 图片格式不支持或内容损坏
+var ErrInvalidImage = errors.New("图片无效")
+
+// decoders 支持的图片类型，按文件头识别而不是信任扩展名和Content-Type
+var decoders = map[string]struct {
+	decode       func([]byte) (image.Image, error)
+	decodeConfig func([]byte) (image.Config, error)
+}{
+	"image/jpeg": {
+		func(b []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(b)) },
+		func(b []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(b)) },
+	},
+	"image/png": {
+		func(b []byte) (image.Image, error) { return png.Decode(bytes.NewReader(b)) },
+		func(b []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(b)) },
+	},
+	"image/gif": {
+		func(b []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(b)) },
+		func(b []byte) (image.Config, error) { return gif.DecodeConfig(bytes.NewReader(b)) },
+	},
+	"image/webp": {
+		func(b []byte) (image.Image, error) { return webp.Decode(bytes.NewReader(b)) },
+		func(b []byte) (image.Config, error) { return webp.DecodeConfig(bytes.NewReader(b)) },
+	},
+}
+
+// DetectType 根据文件头识别图片类型，不支持的类型返回ErrInvalidImage
+func DetectType(data []byte) (string, error) {
+	mime := http.DetectContentType(data)
+	if _, ok := decoders[mime]; !ok {
+		return "", fmt.Errorf("%w: 不支持的图片类型 %s", ErrInvalidImage, mime)
+	}
+	return mime, nil
+}
+
+// Render 将图片居中裁剪为正方形并缩放到各尺寸，输出PNG
+// 图片经过完整解码和重新编码，EXIF等元数据不会保留；JPEG会先按EXIF方向旋转
+func Render(data []byte, sizes []int, maxPixels int) (map[int][]byte, error) {
+	mime, err := DetectType(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := decoders[mime]
+	cfg, err := dec.decodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("%w: 图片尺寸为空", ErrInvalidImage)
+	}
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: 图片尺寸 %dx%d 过大", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+	src, err := dec.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	orientation := 1
+	if mime == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	square := squareRect(src.Bounds())
+	out := make(map[int][]byte, len(sizes))
+	for _, size := range sizes {
+		dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, square, draw.Src, nil)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, orient(dst, orientation)); err != nil {
+			return nil, err
+		}
+		out[size] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// squareRect 居中的最大正方形区域
+func squareRect(b image.Rectangle) image.Rectangle {
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// orient 按EXIF方向(1-8)变换正方形图片，使其正向显示
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	n := img.Bounds().Dx()
+	dst := image.NewNRGBA(img.Bounds())
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = n-1-x, y
+			case 3: // 旋转180度
+				dx, dy = n-1-x, n-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, n-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90度
+				dx, dy = n-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = n-1-y, n-1-x
+			case 8: // 逆时针旋转90度
+				dx, dy = y, n-1-x
+			}
+			dst.SetNRGBA(dx, dy, img.NRGBAAt(x, y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation 读取JPEG中EXIF的方向标记，没有或无法解析时返回1
+func jpegOrientation(data []byte) int {
+	pos := 2 // 跳过SOI
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 { // 图像数据开始或结束
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation 在TIFF结构的第0个IFD中查找方向标记(0x0112)
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
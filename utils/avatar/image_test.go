@@ -0,0 +1,220 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// halves 左半红色、右半蓝色的图片
+func halves(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.SetNRGBA(x, y, red)
+			} else {
+				img.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG 编码JPEG，orientation不为0时在SOI后插入带方向标记的EXIF段
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16, order binary.ByteOrder) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+	return append(append(append([]byte{}, data[:2]...), exifSegment(orientation, order)...), data[2:]...)
+}
+
+// exifSegment 只包含方向标记的APP1段
+func exifSegment(orientation uint16, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestDetectType(t *testing.T) {
+	img := halves(4, 4)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"PNG", encodePNG(t, img), "image/png", nil},
+		{"GIF", encodeGIF(t, img), "image/gif", nil},
+		{"JPEG", encodeJPEG(t, img, 0, nil), "image/jpeg", nil},
+		{"WebP", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "image/webp", nil},
+		{"SVG", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", ErrInvalidImage},
+		{"HTML", []byte("<html><script>alert(1)</script></html>"), "", ErrInvalidImage},
+		{"BMP", []byte("BM\x00\x00\x00\x00\x00\x00\x00\x00"), "", ErrInvalidImage},
+		{"空文件", nil, "", ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectType(tt.data)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Fatalf("DetectType() = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	src := encodePNG(t, halves(40, 20))
+	tests := []struct {
+		name      string
+		data      []byte
+		maxPixels int
+		err       error
+	}{
+		{"PNG", src, 0, nil},
+		{"GIF", encodeGIF(t, halves(20, 40)), 0, nil},
+		{"JPEG", encodeJPEG(t, halves(30, 30), 0, nil), 0, nil},
+		{"尺寸未超限", src, 800, nil},
+		{"尺寸超限", src, 799, ErrInvalidImage},
+		{"截断的PNG", src[:len(src)/2], 0, ErrInvalidImage},
+		{"只有文件头", src[:16], 0, ErrInvalidImage},
+		{"伪造的WebP", []byte("RIFF\x00\x00\x00\x00WEBPVP8 garbage"), 0, ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Render(tt.data, []int{16, 8}, tt.maxPixels)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			for _, size := range []int{16, 8} {
+				cfg, format, err := image.DecodeConfig(bytes.NewReader(out[size]))
+				if err != nil || format != "png" || cfg.Width != size || cfg.Height != size {
+					t.Fatalf("尺寸%d输出 = %s %dx%d, %v", size, format, cfg.Width, cfg.Height, err)
+				}
+			}
+		})
+	}
+}
+
+func TestJpegOrientation(t *testing.T) {
+	img := halves(8, 8)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"无EXIF", encodeJPEG(t, img, 0, nil), 1},
+		{"大端", encodeJPEG(t, img, 6, binary.BigEndian), 6},
+		{"小端", encodeJPEG(t, img, 3, binary.LittleEndian), 3},
+		{"超出范围", encodeJPEG(t, img, 9, binary.BigEndian), 1},
+		{"段长度越界", append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}, "Exif"...), 1},
+		{"截断", []byte{0xFF, 0xD8, 0xFF}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Fatalf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderOrientation(t *testing.T) {
+	// 左红右蓝的图片按EXIF方向旋转后，检查左上和右下角的颜色
+	tests := []struct {
+		orientation          uint16
+		topLeft, bottomRight color.NRGBA
+	}{
+		{1, red, blue},
+		{2, blue, red},
+		{3, blue, red},
+		{6, red, blue},
+		{8, blue, red},
+	}
+	for _, tt := range tests {
+		data := encodeJPEG(t, halves(64, 64), tt.orientation, binary.BigEndian)
+		out, err := Render(data, []int{32}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(out[32]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []struct {
+			x, y int
+			want color.NRGBA
+		}{{4, 4, tt.topLeft}, {27, 27, tt.bottomRight}} {
+			c := color.NRGBAModel.Convert(img.At(p.x, p.y)).(color.NRGBA)
+			if (c.R > c.B) != (p.want == red) {
+				t.Errorf("方向%d (%d,%d) = %v, want %v", tt.orientation, p.x, p.y, c, p.want)
+			}
+		}
+	}
+}
+
+func TestSquareRect(t *testing.T) {
+	tests := []struct {
+		in, want image.Rectangle
+	}{
+		{image.Rect(0, 0, 40, 20), image.Rect(10, 0, 30, 20)},
+		{image.Rect(0, 0, 20, 40), image.Rect(0, 10, 20, 30)},
+		{image.Rect(0, 0, 21, 21), image.Rect(0, 0, 21, 21)},
+		{image.Rect(5, 5, 15, 10), image.Rect(7, 5, 12, 10)},
+	}
+	for _, tt := range tests {
+		if got := squareRect(tt.in); got != tt.want {
+			t.Errorf("squareRect(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
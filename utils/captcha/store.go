@@ -75,6 +75,13 @@ func (s *EmailCodeStore) VerifyEmailCode(email, code string) bool {
 	return storedCode == code
 }
 
+// DeleteEmailCode 删除验证码，验证成功后调用使其不能重复使用
+func (s *EmailCodeStore) DeleteEmailCode(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.codes, email)
+}
+
 // CleanExpired 清理过期验证码
 // 定期调用以释放内存
 func (s *EmailCodeStore) CleanExpired() {
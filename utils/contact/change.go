@@ -0,0 +1,269 @@
+package contact
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/captcha"
+	"rbac_admin_server/utils/email"
+	"rbac_admin_server/utils/sms"
+)
+
+// 联系方式类型，同时也是users表中的列名
+const (
+	Email = "email"
+	Phone = "phone"
+)
+
+const (
+	codeTTL        = 10 * time.Minute // 验证码有效期
+	resendInterval = time.Minute      // 同一用户重新发送的最短间隔
+	maxAttempts    = 5                // 验证码最多尝试次数，超过后作废
+)
+
+var (
+	ErrInvalid       = errors.New("格式不正确")
+	ErrUnchanged     = errors.New("与当前值相同")
+	ErrTaken         = errors.New("已被其他用户使用")
+	ErrCode          = errors.New("验证码错误或已过期")
+	ErrTooFrequent   = errors.New("发送过于频繁")
+	ErrNotConfigured = errors.New("邮箱未配置")
+	ErrSendEmail     = errors.New("邮件发送失败")
+	ErrSendSMS       = errors.New("短信发送失败")
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+
+// pending 等待确认的变更，确认前用户原有的邮箱或手机号保持不变
+type pending struct {
+	target   string
+	sentAt   time.Time
+	attempts int
+}
+
+var (
+	mu       sync.Mutex
+	pendings = make(map[string]*pending)
+)
+
+// Normalize 校验并规范化邮箱或手机号
+func Normalize(kind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case Email:
+		value = strings.ToLower(value)
+		if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value || len(value) > 100 {
+			return "", fmt.Errorf("邮箱%w", ErrInvalid)
+		}
+	case Phone:
+		if !phonePattern.MatchString(value) {
+			return "", fmt.Errorf("手机号%w", ErrInvalid)
+		}
+	default:
+		return "", fmt.Errorf("不支持的联系方式 %s", kind)
+	}
+	return value, nil
+}
+
+// Request 申请变更邮箱或手机号，向新地址发送验证码并通知原地址
+func Request(db *gorm.DB, user *models.User, kind, target string) error {
+	target, err := Normalize(kind, target)
+	if err != nil {
+		return err
+	}
+	old := current(user, kind)
+	if strings.EqualFold(old, target) {
+		return ErrUnchanged
+	}
+	if err := checkTaken(db, user.ID, kind, target); err != nil {
+		return err
+	}
+	if kind == Email && !global.Config.Email.Verify() {
+		return ErrNotConfigured
+	}
+
+	key := pendingKey(kind, user.ID)
+	mu.Lock()
+	if p, ok := pendings[key]; ok && time.Since(p.sentAt) < resendInterval {
+		mu.Unlock()
+		return ErrTooFrequent
+	}
+	if p, ok := pendings[key]; ok {
+		captcha.EmailStore.DeleteEmailCode(codeKey(kind, user.ID, p.target))
+	}
+	pendings[key] = &pending{target: target, sentAt: time.Now()}
+	mu.Unlock()
+
+	code := captcha.EmailStore.GenerateAndStoreEmailCode(codeKey(kind, user.ID, target), codeTTL)
+	if err := send(kind, target, "验证码", fmt.Sprintf("您正在将账号 %s 的%s变更为 %s，验证码为 %s ，请在%d分钟内使用，过时无效！",
+		user.Username, label(kind), target, code, int(codeTTL.Minutes()))); err != nil {
+		discard(kind, user.ID)
+		if kind == Email {
+			return fmt.Errorf("%w: %v", ErrSendEmail, err)
+		}
+		return fmt.Errorf("%w: %v", ErrSendSMS, err)
+	}
+	if old != "" {
+		go notify(kind, old, fmt.Sprintf("账号 %s 正在申请将%s变更为 %s，如非本人操作请尽快修改密码。", user.Username, label(kind), mask(kind, target)))
+	}
+	return nil
+}
+
+// Confirm 校验验证码并完成变更，返回原来的值
+func Confirm(db *gorm.DB, userID uint, kind, target, code string) (string, error) {
+	target, err := Normalize(kind, target)
+	if err != nil {
+		return "", err
+	}
+	key := pendingKey(kind, userID)
+	mu.Lock()
+	p, ok := pendings[key]
+	if !ok || p.target != target {
+		mu.Unlock()
+		return "", ErrCode
+	}
+	if !captcha.EmailStore.VerifyEmailCode(codeKey(kind, userID, target), strings.TrimSpace(code)) {
+		p.attempts++
+		if p.attempts >= maxAttempts {
+			delete(pendings, key)
+			captcha.EmailStore.DeleteEmailCode(codeKey(kind, userID, target))
+		}
+		mu.Unlock()
+		return "", ErrCode
+	}
+	mu.Unlock()
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := checkTaken(tx, userID, kind, target); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update(kind, target).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	discard(kind, userID)
+
+	old := current(&user, kind)
+	if old != "" {
+		go notify(kind, old, fmt.Sprintf("账号 %s 的%s已变更为 %s，此地址将不再用于登录和找回密码。如非本人操作请立即联系管理员。",
+			user.Username, label(kind), mask(kind, target)))
+	}
+	return old, nil
+}
+
+// ErrorCode 错误对应的HTTP状态码和业务错误码，非本包错误返回500
+func ErrorCode(err error) (int, int) {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		return http.StatusBadRequest, utils.ERROR_INVALID_PARAM
+	case errors.Is(err, ErrUnchanged):
+		return http.StatusBadRequest, utils.ERROR_CONTACT_UNCHANGED
+	case errors.Is(err, ErrTaken):
+		return http.StatusConflict, utils.ERROR_CONTACT_USED
+	case errors.Is(err, ErrCode):
+		return http.StatusBadRequest, utils.ERROR_VERIFY_CODE
+	case errors.Is(err, ErrTooFrequent):
+		return http.StatusTooManyRequests, utils.ERROR_SEND_TOO_FREQUENT
+	case errors.Is(err, ErrNotConfigured):
+		return http.StatusBadRequest, utils.ERROR_EMAIL_CONFIG
+	case errors.Is(err, ErrSendEmail):
+		return http.StatusInternalServerError, utils.ERROR_EMAIL_SEND
+	case errors.Is(err, ErrSendSMS):
+		return http.StatusInternalServerError, utils.ERROR_SMS_SEND
+	}
+	return http.StatusInternalServerError, utils.ERROR_UPDATE_USER
+}
+
+// checkTaken 检查是否已被其他未删除的用户使用
+func checkTaken(db *gorm.DB, userID uint, kind, target string) error {
+	var count int64
+	if err := db.Model(&models.User{}).Where(kind+" = ? AND id <> ?", target, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTaken
+	}
+	return nil
+}
+
+// discard 作废用户待确认的变更
+func discard(kind string, userID uint) {
+	key := pendingKey(kind, userID)
+	mu.Lock()
+	defer mu.Unlock()
+	if p, ok := pendings[key]; ok {
+		captcha.EmailStore.DeleteEmailCode(codeKey(kind, userID, p.target))
+		delete(pendings, key)
+	}
+}
+
+func send(kind, to, subject, content string) error {
+	if kind == Email {
+		return email.SendEmail(to, label(kind)+subject, content)
+	}
+	return sms.Send(to, content)
+}
+
+// notify 向原地址发送通知，失败只记录日志
+func notify(kind, to, content string) {
+	if kind == Email && !global.Config.Email.Verify() {
+		return
+	}
+	if err := send(kind, to, "变更通知", content); err != nil {
+		global.Logger.Warnf("发送%s变更通知失败: %v", label(kind), err)
+	}
+}
+
+func current(user *models.User, kind string) string {
+	if kind == Email {
+		return string(user.Email)
+	}
+	return string(user.Phone)
+}
+
+func label(kind string) string {
+	if kind == Email {
+		return "邮箱"
+	}
+	return "手机号"
+}
+
+// mask 通知中隐藏新地址的部分内容
+func mask(kind, value string) string {
+	if kind == Email {
+		at := strings.Index(value, "@")
+		if at <= 1 {
+			return "*" + value[at:]
+		}
+		return value[:1] + "***" + value[at:]
+	}
+	if len(value) <= 7 {
+		return value[:len(value)-4] + "****"
+	}
+	return value[:3] + "****" + value[len(value)-4:]
+}
+
+func pendingKey(kind string, userID uint) string {
+	return fmt.Sprintf("%s:%d", kind, userID)
+}
+
+// codeKey 验证码在captcha.EmailStore中的键，包含新地址，确保验证码只能用于申请时的地址
+func codeKey(kind string, userID uint, target string) string {
+	return fmt.Sprintf("change:%s:%d:%s", kind, userID, target)
+}
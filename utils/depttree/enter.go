@@ -0,0 +1,269 @@
+package depttree
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/models"
+)
+
+var (
+	// ErrNotFound 部门不存在
+	ErrNotFound = errors.New("部门不存在")
+	// ErrParentNotFound 上级部门不存在
+	ErrParentNotFound = errors.New("上级部门不存在")
+	// ErrCycle 上级部门是自身或下级部门
+	ErrCycle = errors.New("不能移动到自身或下级部门下")
+	// ErrNotSibling 排序的部门不属于同一上级
+	ErrNotSibling = errors.New("排序的部门必须属于同一上级部门")
+	// ErrNoPath 部门路径尚未生成，需要先执行路径修复
+	ErrNoPath = errors.New("部门路径尚未生成，请先执行 -m db -t repair-dept")
+)
+
+// Join 拼接上级路径和部门ID
+func Join(parentPath string, id uint) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// IDs 解析路径中的部门ID，从顶级部门到本部门
+func IDs(path string) []uint {
+	var ids []uint
+	for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil && id != 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// Get 查询部门，路径未生成时返回ErrNoPath
+func Get(db *gorm.DB, id uint) (*models.Department, error) {
+	var dept models.Department
+	if err := db.First(&dept, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if dept.Path == "" {
+		return nil, ErrNoPath
+	}
+	return &dept, nil
+}
+
+// DescendantIDs 返回部门及其全部下级部门的ID
+func DescendantIDs(db *gorm.DB, id uint) ([]uint, error) {
+	dept, err := Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	if err := db.Model(&models.Department{}).Where("path LIKE ?", dept.Path+"%").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Descendants 返回全部下级部门，按层级和排序排列，不含部门本身
+func Descendants(db *gorm.DB, id uint) ([]models.Department, error) {
+	dept, err := Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+	var list []models.Department
+	if err := db.Where("path LIKE ? AND id <> ?", dept.Path+"%", id).
+		Order("level, sort, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Ancestors 返回全部上级部门，从顶级部门开始，不含部门本身
+func Ancestors(db *gorm.DB, id uint) ([]models.Department, error) {
+	dept, err := Get(db, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := IDs(dept.Path)
+	if len(ids) <= 1 {
+		return []models.Department{}, nil
+	}
+	var list []models.Department
+	if err := db.Where("id IN ?", ids[:len(ids)-1]).Order("level").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains 判断部门deptID是否为ancestorID本身或其下级部门，用于数据范围校验
+func Contains(db *gorm.DB, ancestorID, deptID uint) (bool, error) {
+	if ancestorID == 0 || deptID == 0 {
+		return false, nil
+	}
+	if ancestorID == deptID {
+		return true, nil
+	}
+	dept, err := Get(db, deptID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, id := range IDs(dept.Path) {
+		if id == ancestorID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Move 将部门及其下级部门移动到parentID下，parentID为0时移动为顶级部门
+// 回收站中的下级部门一并更新路径，恢复后仍在正确位置
+func Move(db *gorm.DB, id, parentID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		dept, err := Get(tx, id)
+		if err != nil {
+			return err
+		}
+		if dept.ParentID == parentID {
+			return nil
+		}
+
+		prefix, level := "/", 1
+		if parentID != 0 {
+			if parentID == id {
+				return ErrCycle
+			}
+			parent, err := Get(tx, parentID)
+			if errors.Is(err, ErrNotFound) {
+				return ErrParentNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(parent.Path, dept.Path) {
+				return ErrCycle
+			}
+			prefix, level = parent.Path, parent.Level+1
+		}
+
+		oldPath, newPath := dept.Path, Join(prefix, id)
+		delta := level - dept.Level
+		var subtree []models.Department
+		if err := tx.Unscoped().Select("id", "path", "level").
+			Where("path LIKE ?", oldPath+"%").Find(&subtree).Error; err != nil {
+			return err
+		}
+		for _, d := range subtree {
+			if err := tx.Unscoped().Model(&models.Department{}).Where("id = ?", d.ID).
+				UpdateColumns(map[string]interface{}{
+					"path":  newPath + strings.TrimPrefix(d.Path, oldPath),
+					"level": d.Level + delta,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Department{}).Where("id = ?", id).Update("parent_id", parentID).Error
+	})
+}
+
+// Reorder 按ids的顺序重新设置同级部门的排序值，从1开始
+func Reorder(db *gorm.DB, parentID uint, ids []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Department{}).Where("id IN ? AND parent_id = ?", ids, parentID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return ErrNotSibling
+		}
+		for i, id := range ids {
+			if err := tx.Model(&models.Department{}).Where("id = ?", id).Update("sort", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Report 路径修复结果
+type Report struct {
+	Total   int    `json:"total"`   // 部门总数，包括回收站中的部门
+	Updated int    `json:"updated"` // 修正了路径或层级的部门数
+	Orphans []uint `json:"orphans"` // 上级不存在或存在循环引用，已改为顶级部门
+}
+
+// Rebuild 按parent_id重新计算全部部门的路径和层级
+// 上级不存在或存在循环引用的部门改为顶级部门
+func Rebuild(db *gorm.DB) (*Report, error) {
+	report := &Report{Orphans: []uint{}}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var all []models.Department
+		if err := tx.Unscoped().Select("id", "parent_id", "path", "level").Order("id").Find(&all).Error; err != nil {
+			return err
+		}
+		report.Total = len(all)
+		byID := make(map[uint]*models.Department, len(all))
+		for i := range all {
+			byID[all[i].ID] = &all[i]
+		}
+
+		paths := make(map[uint]string, len(all))
+		var resolve func(d *models.Department, visiting map[uint]bool) string
+		resolve = func(d *models.Department, visiting map[uint]bool) string {
+			if p, ok := paths[d.ID]; ok {
+				return p
+			}
+			parent, ok := byID[d.ParentID]
+			if d.ParentID != 0 && (!ok || visiting[d.ID]) {
+				report.Orphans = append(report.Orphans, d.ID)
+				d.ParentID = 0
+			}
+			prefix := "/"
+			if d.ParentID != 0 {
+				visiting[d.ID] = true
+				prefix = resolve(parent, visiting)
+				delete(visiting, d.ID)
+				// 递归中发现循环时当前部门可能已被改为顶级部门
+				if p, ok := paths[d.ID]; ok {
+					return p
+				}
+			}
+			paths[d.ID] = Join(prefix, d.ID)
+			return paths[d.ID]
+		}
+
+		parents := make(map[uint]uint, len(all))
+		for _, d := range all {
+			parents[d.ID] = d.ParentID
+		}
+		for i := range all {
+			d := &all[i]
+			path := resolve(d, map[uint]bool{})
+			level := len(IDs(path))
+			if path == d.Path && level == d.Level && parents[d.ID] == d.ParentID {
+				continue
+			}
+			if err := tx.Unscoped().Model(&models.Department{}).Where("id = ?", d.ID).
+				UpdateColumns(map[string]interface{}{"parent_id": d.ParentID, "path": path, "level": level}).Error; err != nil {
+				return fmt.Errorf("更新部门%d路径失败: %w", d.ID, err)
+			}
+			report.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,212 @@
+package depttree
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"rbac_admin_server/models"
+)
+
+// openDB 创建内存数据库并建立以下部门
+//
+//	1
+//	├── 2
+//	│   └── 3
+//	│       └── 4
+//	└── 5
+//	6
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&models.Department{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, parent := range []uint{0, 1, 2, 3, 1, 0} {
+		if err := db.Create(&models.Department{Name: "dept", ParentID: parent}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// paths 返回全部部门的路径，包括回收站中的部门
+func paths(t *testing.T, db *gorm.DB) map[uint]string {
+	var list []models.Department
+	if err := db.Unscoped().Find(&list).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[uint]string, len(list))
+	for _, d := range list {
+		result[d.ID] = d.Path
+	}
+	return result
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		parent string
+		id     uint
+		want   string
+	}{
+		{"", 1, "/1/"},
+		{"/", 1, "/1/"},
+		{"/1/", 2, "/1/2/"},
+		{"/1/2/", 30, "/1/2/30/"},
+	}
+	for _, tt := range tests {
+		if got := Join(tt.parent, tt.id); got != tt.want {
+			t.Errorf("Join(%q, %d) = %q, want %q", tt.parent, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestIDs(t *testing.T) {
+	tests := []struct {
+		path string
+		want []uint
+	}{
+		{"", nil},
+		{"/", nil},
+		{"/1/", []uint{1}},
+		{"/1/2/30/", []uint{1, 2, 30}},
+		{"/1/x/0/3/", []uint{1, 3}},
+	}
+	for _, tt := range tests {
+		if got := IDs(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("IDs(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     uint
+		parent uint
+		err    error
+		want   map[uint]string
+	}{
+		{"移动到自身", 2, 2, ErrCycle, nil},
+		{"移动到下级", 2, 4, ErrCycle, nil},
+		{"移动到直接下级", 1, 2, ErrCycle, nil},
+		{"上级不存在", 2, 99, ErrParentNotFound, nil},
+		{"部门不存在", 99, 1, ErrNotFound, nil},
+		{"上级不变", 2, 1, nil, nil},
+		{"移动子树", 2, 6, nil, map[uint]string{2: "/6/2/", 3: "/6/2/3/", 4: "/6/2/3/4/", 5: "/1/5/"}},
+		{"移动为顶级部门", 3, 0, nil, map[uint]string{2: "/1/2/", 3: "/3/", 4: "/3/4/"}},
+		{"移动到同级部门下", 5, 2, nil, map[uint]string{5: "/1/2/5/", 3: "/1/2/3/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openDB(t)
+			before := paths(t, db)
+			err := Move(db, tt.id, tt.parent)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			after := paths(t, db)
+			if tt.err != nil || tt.want == nil {
+				if !reflect.DeepEqual(after, before) {
+					t.Fatalf("路径被修改: %v", after)
+				}
+				return
+			}
+			for id, want := range tt.want {
+				if after[id] != want {
+					t.Errorf("部门%d路径 = %q, want %q", id, after[id], want)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveDeleted(t *testing.T) {
+	// 回收站中的下级部门一并更新路径
+	db := openDB(t)
+	if err := db.Delete(&models.Department{}, 4).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Move(db, 2, 6); err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(t, db)[4]; got != "/6/2/3/4/" {
+		t.Fatalf("回收站中部门路径 = %q", got)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt map[uint]map[string]interface{}
+		updated int
+		orphans []uint
+		want    map[uint]string
+	}{
+		{"路径正确", nil, 0, []uint{}, nil},
+		{
+			"路径缺失",
+			map[uint]map[string]interface{}{2: {"path": ""}, 4: {"path": "", "level": 0}},
+			2, []uint{}, map[uint]string{2: "/1/2/", 4: "/1/2/3/4/"},
+		},
+		{
+			"上级不存在",
+			map[uint]map[string]interface{}{3: {"parent_id": 99}},
+			2, []uint{3}, map[uint]string{3: "/3/", 4: "/3/4/"},
+		},
+		{
+			"循环引用",
+			map[uint]map[string]interface{}{2: {"parent_id": 4}},
+			3, []uint{2}, map[uint]string{2: "/2/", 3: "/2/3/", 4: "/2/3/4/"},
+		},
+		{
+			"自身为上级",
+			map[uint]map[string]interface{}{5: {"parent_id": 5}},
+			1, []uint{5}, map[uint]string{5: "/5/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openDB(t)
+			for id, columns := range tt.corrupt {
+				if err := db.Model(&models.Department{}).Where("id = ?", id).UpdateColumns(columns).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			report, err := Rebuild(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Total != 6 || report.Updated != tt.updated || !reflect.DeepEqual(report.Orphans, tt.orphans) {
+				t.Fatalf("report = %+v", report)
+			}
+			after := paths(t, db)
+			for id, want := range tt.want {
+				if after[id] != want {
+					t.Errorf("部门%d路径 = %q, want %q", id, after[id], want)
+				}
+			}
+
+			// 修复后再次执行没有变化
+			again, err := Rebuild(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Updated != 0 || len(again.Orphans) != 0 {
+				t.Fatalf("再次修复 = %+v", again)
+			}
+		})
+	}
+}
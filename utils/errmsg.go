@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+
+	"rbac_admin_server/utils/i18n"
 )
 
 // 错误码常量定义
@@ -24,6 +28,22 @@ const (
 	ERROR_INVALID_PARAM   = 400
 	ERROR_UPDATE_USER     = 1011
 	ERROR_ENCRYPT_PASSWORD = 1012
+	ERROR_USER_PENDING    = 1013
+	ERROR_USER_LOCKED     = 1014
+	ERROR_USER_EXPIRED    = 1015
+	ERROR_USER_ARCHIVED   = 1016
+	ERROR_USER_STATUS_TRANSITION = 1017
+	ERROR_CONTACT_USED    = 1018
+	ERROR_CONTACT_UNCHANGED = 1019
+	ERROR_LOGIN_METHOD    = 1020
+	ERROR_REGISTER_CLOSED = 1021
+	ERROR_INVITATION      = 1022
+	ERROR_DELETION_PENDING = 1023
+	ERROR_DELETION_NONE   = 1024
+	ERROR_DELETION_ADMIN  = 1025
+	ERROR_IMPERSONATING   = 1026
+	ERROR_INVALID_CREDENTIALS = 1027
+	ERROR_IDENTIFIER_REQUIRED = 1028
 	// 文章模块错误
 	ERROR_ART_NOT_EXIST   = 2001
 	// 分类模块错误
@@ -38,6 +58,12 @@ const (
 	ERROR_EMAIL_CODE_WRONG = 5004
 	ERROR_EMAIL_CODE_EXPIRE = 5005
 	ERROR_EMAIL_CONFIG    = 5006
+	ERROR_SMS_SEND        = 5007
+	ERROR_VERIFY_CODE     = 5008
+	ERROR_SEND_TOO_FREQUENT = 5009
+	ERROR_CAPTCHA_REQUIRED = 5010
+	// 提示信息，响应码仍为SUCCESS
+	MSG_LOGIN_CODE_SENT = 5101
 )
 
 // GetValidationError 将validator错误转换为字符串
@@ -71,12 +97,33 @@ var codeMsg = map[int]string{
 	ERROR_INVALID_PARAM:   "参数无效",
 	ERROR_UPDATE_USER:     "更新用户信息失败",
 	ERROR_ENCRYPT_PASSWORD: "密码加密失败",
+	ERROR_USER_PENDING:    "账号未激活，请先确认邮箱或等待管理员审核",
+	ERROR_USER_LOCKED:     "账号已被锁定",
+	ERROR_USER_EXPIRED:    "账号已过期",
+	ERROR_USER_ARCHIVED:   "账号已归档",
+	ERROR_USER_STATUS_TRANSITION: "不允许的账号状态变更",
+	ERROR_CONTACT_USED:    "邮箱或手机号已被使用",
+	ERROR_CONTACT_UNCHANGED: "与当前邮箱或手机号相同",
+	ERROR_LOGIN_METHOD:    "不支持该登录方式",
+	ERROR_REGISTER_CLOSED: "暂不开放注册",
+	ERROR_INVITATION:      "邀请码无效或已过期",
+	ERROR_DELETION_PENDING: "已提交注销申请",
+	ERROR_DELETION_NONE:   "没有待处理的注销申请",
+	ERROR_DELETION_ADMIN:  "管理员账号不能自助注销",
+	ERROR_IMPERSONATING:   "模拟登录期间不允许该操作",
+	ERROR_INVALID_CREDENTIALS: "账号或密码错误",
+	ERROR_IDENTIFIER_REQUIRED: "请输入用户名、邮箱或手机号",
 	ERROR_CAPTCHA_WRONG:   "验证码错误",
 	ERROR_CAPTCHA_EXPIRE:  "验证码已过期",
 	ERROR_EMAIL_SEND:      "邮件发送失败",
 	ERROR_EMAIL_CODE_WRONG: "邮箱验证码错误",
 	ERROR_EMAIL_CODE_EXPIRE: "邮箱验证码已过期",
 	ERROR_EMAIL_CONFIG:    "邮箱配置错误",
+	ERROR_SMS_SEND:        "短信发送失败",
+	ERROR_VERIFY_CODE:     "验证码错误或已过期",
+	ERROR_SEND_TOO_FREQUENT: "发送过于频繁，请稍后再试",
+	ERROR_CAPTCHA_REQUIRED: "请输入验证码",
+	MSG_LOGIN_CODE_SENT:   "如果该邮箱已注册，验证码已发送",
 }
 
 // GetErrMsg 根据错误码获取错误信息
@@ -88,11 +135,25 @@ func GetErrMsg(code int) string {
 	return msg
 }
 
+// Message 根据错误码获取请求语言的错误信息
+func Message(c *gin.Context, code int) string {
+	return MessageIn(i18n.Locale(c), code)
+}
+
+// MessageIn 根据错误码获取指定语言的错误信息
+// 其他语言的翻译只从翻译表读取，没有翻译时返回中文源文本
+func MessageIn(locale string, code int) string {
+	if _, ok := codeMsg[code]; !ok {
+		code = ERROR
+	}
+	return i18n.T(locale, i18n.NamespaceError, strconv.Itoa(code), codeMsg[code])
+}
+
 // Error 响应错误信息
 func Error(c *gin.Context, status int, code int, data interface{}) {
 	c.JSON(status, gin.H{
 		"code": code,
-		"msg":  GetErrMsg(code),
+		"msg":  Message(c, code),
 		"data": data,
 	})
 }
@@ -101,7 +162,7 @@ func Error(c *gin.Context, status int, code int, data interface{}) {
 func Success(c *gin.Context, data interface{}) {
 	c.JSON(200, gin.H{
 		"code": SUCCESS,
-		"msg":  GetErrMsg(SUCCESS),
+		"msg":  Message(c, SUCCESS),
 		"data": data,
 	})
 }
\ No newline at end of file
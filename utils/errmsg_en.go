@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/i18n"
+)
+
+// seedMsgEN 错误信息的英文翻译，只用于写入translations表，运行时从翻译表读取
+var seedMsgEN = map[int]string{
+	SUCCESS:                      "OK",
+	ERROR:                        "FAIL",
+	ERROR_USERNAME_USED:          "Username already exists",
+	ERROR_PASSWORD_WRONG:         "Incorrect password",
+	ERROR_USER_NOT_EXIST:         "User does not exist",
+	ERROR_TOKEN_EXIST:            "Token does not exist",
+	ERROR_TOKEN_RUNTIME:          "Token has expired",
+	ERROR_TOKEN_WRONG:            "Invalid token",
+	ERROR_TOKEN_TYPE_WRONG:       "Malformed token",
+	ERROR_USER_NO_RIGHT:          "User has no permission",
+	ERROR_TOKEN_INVALID:          "Invalid token",
+	ERROR_ART_NOT_EXIST:          "Article does not exist",
+	ERROR_CATENAME_USED:          "Category already exists",
+	ERROR_CATE_NOT_EXIST:         "Category does not exist",
+	ERROR_PERMISSION_DENIED:      "Permission denied",
+	ERROR_UNAUTHORIZED:           "Unauthorized",
+	ERROR_GET_USER:               "Failed to get user information",
+	ERROR_INVALID_PARAM:          "Invalid parameters",
+	ERROR_UPDATE_USER:            "Failed to update user information",
+	ERROR_ENCRYPT_PASSWORD:       "Failed to encrypt password",
+	ERROR_USER_PENDING:           "Account is not activated, please confirm your email or wait for administrator approval",
+	ERROR_USER_LOCKED:            "Account is locked",
+	ERROR_USER_EXPIRED:           "Account has expired",
+	ERROR_USER_ARCHIVED:          "Account has been archived",
+	ERROR_USER_STATUS_TRANSITION: "Account status change is not allowed",
+	ERROR_CONTACT_USED:           "Email or phone number is already in use",
+	ERROR_CONTACT_UNCHANGED:      "Same as the current email or phone number",
+	ERROR_LOGIN_METHOD:           "Login method is not supported",
+	ERROR_REGISTER_CLOSED:        "Registration is closed",
+	ERROR_INVITATION:             "Invitation code is invalid or has expired",
+	ERROR_DELETION_PENDING:       "Account deletion has already been requested",
+	ERROR_DELETION_NONE:          "No pending account deletion request",
+	ERROR_DELETION_ADMIN:         "Administrator accounts cannot be self-deleted",
+	ERROR_IMPERSONATING:          "This operation is not allowed while impersonating",
+	ERROR_INVALID_CREDENTIALS:    "Incorrect account or password",
+	ERROR_IDENTIFIER_REQUIRED:    "Please enter your username, email or phone number",
+	ERROR_CAPTCHA_WRONG:          "Incorrect captcha",
+	ERROR_CAPTCHA_EXPIRE:         "Captcha has expired",
+	ERROR_EMAIL_SEND:             "Failed to send email",
+	ERROR_EMAIL_CODE_WRONG:       "Incorrect email verification code",
+	ERROR_EMAIL_CODE_EXPIRE:      "Email verification code has expired",
+	ERROR_EMAIL_CONFIG:           "Email configuration error",
+	ERROR_SMS_SEND:               "Failed to send SMS",
+	ERROR_VERIFY_CODE:            "Verification code is incorrect or has expired",
+	ERROR_SEND_TOO_FREQUENT:      "Sending too frequently, please try again later",
+	ERROR_CAPTCHA_REQUIRED:       "Please enter the captcha",
+	MSG_LOGIN_CODE_SENT:          "If the email is registered, a verification code has been sent",
+}
+
+// SeedTranslations 在translations表中写入缺少的错误信息英文翻译，已有的翻译不会被覆盖
+func SeedTranslations(db *gorm.DB) error {
+	rows := make([]models.Translation, 0, len(seedMsgEN))
+	for code, msg := range seedMsgEN {
+		rows = append(rows, models.Translation{
+			Locale: "en-US", Namespace: i18n.NamespaceError, Key: strconv.Itoa(code), Value: msg,
+		})
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return fmt.Errorf("写入错误信息翻译失败: %w", err)
+	}
+	return i18n.Reload(db)
+}
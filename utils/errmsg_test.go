@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/i18n"
+)
+
+func TestSeedTranslations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&models.Translation{}); err != nil {
+		t.Fatal(err)
+	}
+	global.Logger = logrus.New()
+	global.Logger.SetOutput(io.Discard)
+
+	// 管理员已修改的翻译不会被覆盖
+	custom := models.Translation{Locale: "en-US", Namespace: i18n.NamespaceError,
+		Key: strconv.Itoa(ERROR_USER_LOCKED), Value: "Your account is locked"}
+	if err := db.Create(&custom).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := SeedTranslations(db); err != nil {
+			t.Fatalf("第%d次写入失败: %v", i+1, err)
+		}
+	}
+
+	var count int64
+	db.Model(&models.Translation{}).Count(&count)
+	if count != int64(len(seedMsgEN)) {
+		t.Fatalf("翻译 %d 条, want %d", count, len(seedMsgEN))
+	}
+
+	tests := []struct {
+		locale string
+		code   int
+		want   string
+	}{
+		{"en-US", ERROR_INVALID_CREDENTIALS, "Incorrect account or password"},
+		{"en-US", ERROR_USER_LOCKED, "Your account is locked"},
+		{"en-US", MSG_LOGIN_CODE_SENT, "If the email is registered, a verification code has been sent"},
+		{"en-US", 99999, "FAIL"},
+		{"zh-CN", ERROR_IDENTIFIER_REQUIRED, "请输入用户名、邮箱或手机号"},
+		{"zh-CN", ERROR_CAPTCHA_REQUIRED, "请输入验证码"},
+	}
+	for _, tt := range tests {
+		if got := MessageIn(tt.locale, tt.code); got != tt.want {
+			t.Errorf("MessageIn(%s, %d) = %q, want %q", tt.locale, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestSeedCoversMessages(t *testing.T) {
+	// 每个错误码都应有英文翻译
+	for code := range codeMsg {
+		if seedMsgEN[code] == "" {
+			t.Errorf("错误码 %d 缺少英文翻译", code)
+		}
+	}
+}
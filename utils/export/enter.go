@@ -0,0 +1,214 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 支持的导出格式
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// Column 导出列定义
+// Key用于JSONL字段名，Title用于CSV和XLSX表头
+type Column struct {
+	Key   string
+	Title string
+}
+
+// Writer 逐行写入导出数据
+// 调用方按列定义的顺序传入每行的值，全部写完后必须调用Close
+type Writer interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// Valid 判断导出格式是否支持
+func Valid(format string) bool {
+	switch format {
+	case FormatCSV, FormatJSONL, FormatXLSX:
+		return true
+	}
+	return false
+}
+
+// ContentType 返回导出格式对应的MIME类型
+func ContentType(format string) string {
+	switch format {
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// NewWriter 创建指定格式的导出写入器
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSONL:
+		return &jsonlWriter{w: bufio.NewWriter(w), columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// formatValue 将单元格的值转换为文本
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// csvWriter CSV导出，写入UTF-8 BOM以便Excel正确识别中文
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+		return nil, err
+	}
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Title
+	}
+	return cw, cw.w.Write(header)
+}
+
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+		if _, ok := v.(string); ok {
+			record[i] = escapeFormula(record[i])
+		}
+	}
+	return cw.w.Write(record)
+}
+
+// escapeFormula 以公式字符开头的文本前加单引号，防止在Excel中打开时被当作公式执行
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonlWriter JSONL导出，按列定义的顺序输出字段
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []Column
+}
+
+func (jw *jsonlWriter) WriteRow(values []interface{}) error {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, col := range jw.columns {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		key, _ := json.Marshal(col.Key)
+		var v interface{}
+		if i < len(values) {
+			v = values[i]
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(time.RFC3339)
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		sb.Write(key)
+		sb.WriteByte(':')
+		sb.Write(val)
+	}
+	sb.WriteString("}\n")
+	_, err := jw.w.WriteString(sb.String())
+	return err
+}
+
+func (jw *jsonlWriter) Close() error {
+	return jw.w.Flush()
+}
+
+// xlsxWriter XLSX导出
+// 使用excelize的流式写入，超出内存阈值的数据会暂存到临时文件
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer, columns []Column) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	xw := &xlsxWriter{out: w, file: file, stream: stream}
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = col.Title
+	}
+	if err := xw.WriteRow(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (xw *xlsxWriter) WriteRow(values []interface{}) error {
+	xw.row++
+	cell, err := excelize.CoordinatesToCellName(1, xw.row)
+	if err != nil {
+		return err
+	}
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		if t, ok := v.(time.Time); ok {
+			row[i] = t.Format("2006-01-02 15:04:05")
+		} else {
+			row[i] = v
+		}
+	}
+	return xw.stream.SetRow(cell, row)
+}
+
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+	if err := xw.stream.Flush(); err != nil {
+		return err
+	}
+	return xw.file.Write(xw.out)
+}
@@ -0,0 +1,182 @@
+// Package i18n 翻译文本和请求语言
+// 源文本为中文，其他语言的内置翻译写在各自模块中，translations表中的翻译优先，管理员可以随时覆盖
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/usersettings"
+)
+
+// DefaultLocale 源文本的语言
+const DefaultLocale = "zh-CN"
+
+// 命名空间
+const (
+	NamespaceMenu       = "menu"       // 菜单标题，键为菜单名称
+	NamespaceDict       = "dict"       // 字典名称和字典项标签，键为字典标识或 字典标识.字典项值
+	NamespaceError      = "error"      // 错误码信息，键为错误码
+	NamespaceValidation = "validation" // 参数校验信息，键为校验规则，{field}和{param}替换为字段名和规则参数
+)
+
+// Namespaces 全部命名空间
+var Namespaces = []string{NamespaceMenu, NamespaceDict, NamespaceError, NamespaceValidation}
+
+// reloadInterval 翻译表重新加载的间隔，多实例部署时其他实例的修改最迟在此时间后生效
+const reloadInterval = 5 * time.Minute
+
+// localeKey 请求语言在gin上下文中的键
+const localeKey = "locale"
+
+var (
+	mu       sync.RWMutex
+	table    = map[string]string{}
+	loadedAt time.Time
+)
+
+func entryKey(locale, namespace, key string) string {
+	return locale + "\x00" + namespace + "\x00" + key
+}
+
+// Reload 从数据库重新加载翻译表，修改翻译后调用
+func Reload(db *gorm.DB) error {
+	var rows []models.Translation
+	if err := db.Find(&rows).Error; err != nil {
+		return err
+	}
+	t := make(map[string]string, len(rows))
+	for _, row := range rows {
+		t[entryKey(row.Locale, row.Namespace, row.Key)] = row.Value
+	}
+	mu.Lock()
+	table, loadedAt = t, time.Now()
+	mu.Unlock()
+	return nil
+}
+
+// ensureLoaded 翻译表过期时重新加载，加载失败时沿用旧的翻译表
+func ensureLoaded() {
+	mu.Lock()
+	stale := global.DB != nil && time.Since(loadedAt) > reloadInterval
+	if stale {
+		loadedAt = time.Now()
+	}
+	mu.Unlock()
+	if !stale {
+		return
+	}
+	if err := Reload(global.DB); err != nil {
+		global.Logger.Warnf("加载翻译失败: %v", err)
+	}
+}
+
+// T 返回指定语言的翻译，翻译表中没有时返回fallback
+func T(locale, namespace, key, fallback string) string {
+	ensureLoaded()
+	mu.RLock()
+	value := table[entryKey(locale, namespace, key)]
+	mu.RUnlock()
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// Menu 返回菜单标题的翻译
+func Menu(locale, name, title string) string {
+	return T(locale, NamespaceMenu, name, title)
+}
+
+// DictLabel 返回字典项标签的翻译
+func DictLabel(locale, dictKey, value, label string) string {
+	return T(locale, NamespaceDict, dictKey+"."+value, label)
+}
+
+// Supported 判断是否为支持的语言
+func Supported(locale string) bool {
+	for _, l := range usersettings.Languages {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Locale 返回请求使用的语言，结果缓存在上下文中
+// 已登录用户修改过语言时使用个人设置中的语言，否则按Accept-Language匹配，都没有时使用系统默认语言
+func Locale(c *gin.Context) string {
+	if v, ok := c.Get(localeKey); ok {
+		if locale, ok := v.(string); ok {
+			return locale
+		}
+	}
+	locale := resolve(c)
+	c.Set(localeKey, locale)
+	return locale
+}
+
+func resolve(c *gin.Context) string {
+	if global.DB == nil {
+		return DefaultLocale
+	}
+	db := global.DB.WithContext(c.Request.Context())
+	if userID := c.GetUint("userID"); userID != 0 {
+		if language, ok, err := usersettings.Language(db, userID); err == nil && ok && Supported(language) {
+			return language
+		}
+	}
+	if locale := Match(c.GetHeader("Accept-Language")); locale != "" {
+		return locale
+	}
+	if defaults, err := usersettings.Defaults(db); err == nil {
+		return defaults.Language
+	}
+	return DefaultLocale
+}
+
+// Match 按权重从Accept-Language中选出支持的语言，没有时返回空字符串
+// 只有主语言时匹配同一主语言的语言，如en匹配en-US
+func Match(header string) string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if name != "" && q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		primary, _, _ := strings.Cut(t.name, "-")
+		for _, l := range usersettings.Languages {
+			if strings.EqualFold(l, t.name) {
+				return l
+			}
+		}
+		for _, l := range usersettings.Languages {
+			if p, _, _ := strings.Cut(l, "-"); strings.EqualFold(p, primary) {
+				return l
+			}
+		}
+	}
+	return ""
+}
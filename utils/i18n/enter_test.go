@@ -0,0 +1,128 @@
+package i18n
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"zh-CN", "zh-CN"},
+		{"en-US,en;q=0.9", "en-US"},
+		{"EN-us", "en-US"},
+		{"en", "en-US"},
+		{"en-GB", "en-US"},
+		{"zh-TW,zh;q=0.9", "zh-CN"},
+		{"fr-FR,en;q=0.8,zh-CN;q=0.9", "zh-CN"},
+		{"fr-FR;q=1, en-US;q=0.5", "en-US"},
+		{"zh-CN;q=0, en-US;q=0.1", "en-US"},
+		{"zh-CN;q=0", ""},
+		{"en-US;q=abc,zh-CN;q=0.9", "en-US"},
+		{"fr-FR,de", ""},
+		{"*", ""},
+		{" , ;q=1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := Match(tt.header); got != tt.want {
+				t.Fatalf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   bool
+	}{
+		{"zh-CN", true},
+		{"en-US", true},
+		{"en", false},
+		{"zh-cn", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Supported(tt.locale); got != tt.want {
+			t.Errorf("Supported(%q) = %v, want %v", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&models.UserSettings{}, &models.Config{}, &models.Translation{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLocale(t *testing.T) {
+	db := openDB(t)
+	global.DB = db
+	global.Logger = logrus.New()
+	global.Logger.SetOutput(io.Discard)
+	t.Cleanup(func() { global.DB = nil })
+
+	english, dark := "en-US", "dark"
+	rows := []models.UserSettings{
+		{UserID: 1, Language: &english},
+		{UserID: 2, Theme: &dark}, // 只修改了主题，语言未修改
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID uint
+		header string
+		want   string
+	}{
+		{"用户设置的语言优先", 1, "zh-CN", "en-US"},
+		{"未修改语言时按请求头", 2, "en-US,en;q=0.9", "en-US"},
+		{"没有设置记录时按请求头", 3, "en", "en-US"},
+		{"未登录按请求头", 0, "en-GB", "en-US"},
+		{"请求头不支持时使用系统默认", 2, "fr-FR", DefaultLocale},
+		{"没有请求头时使用系统默认", 0, "", DefaultLocale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Accept-Language", tt.header)
+			}
+			if tt.userID != 0 {
+				c.Set("userID", tt.userID)
+			}
+			if got := Locale(c); got != tt.want {
+				t.Fatalf("Locale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
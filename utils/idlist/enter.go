@@ -0,0 +1,33 @@
+// Package idlist ID列表的去重和存在性校验，供批量绑定关系时使用
+package idlist
+
+import "gorm.io/gorm"
+
+// Unique 去重并去掉0，保持原顺序
+func Unique(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	list := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
+// Exists 检查ids对应的记录全部存在，有不存在的记录时返回notFound
+// ids应已去重
+func Exists(db *gorm.DB, model interface{}, ids []uint, notFound error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(model).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return notFound
+	}
+	return nil
+}
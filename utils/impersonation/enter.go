@@ -0,0 +1,117 @@
+package impersonation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/userstate"
+)
+
+// PermissionKey 模拟登录权限标识
+const PermissionKey = "user:impersonate"
+
+var (
+	// ErrSelf 不能模拟自己
+	ErrSelf = errors.New("不能模拟登录自己的账号")
+	// ErrNotFound 用户不存在
+	ErrNotFound = errors.New("用户不存在")
+	// ErrPrivileged 目标为管理员或同样拥有模拟登录权限
+	ErrPrivileged = errors.New("不能模拟登录管理员或拥有模拟登录权限的用户")
+	// ErrInactive 目标账号状态不可登录
+	ErrInactive = errors.New("目标账号当前不可登录")
+)
+
+// Session 模拟登录结果
+type Session struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *models.User `json:"user"`
+}
+
+// Start 以actor的身份签发模拟登录target的短期访问令牌
+// 令牌同时携带目标用户和实际操作的管理员，不签发刷新令牌
+func Start(db *gorm.DB, actorID uint, actorName string, targetID uint) (*Session, error) {
+	if actorID == targetID {
+		return nil, ErrSelf
+	}
+	var target models.User
+	if err := db.First(&target, targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if target.IsAdmin {
+		return nil, ErrPrivileged
+	}
+	if ok, err := global.HasPermission(db, target.ID, PermissionKey); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, ErrPrivileged
+	}
+	if code, err := userstate.Verify(db, target.ID); err != nil {
+		return nil, err
+	} else if code != utils.SUCCESS {
+		return nil, fmt.Errorf("%w: %s", ErrInactive, utils.GetErrMsg(code))
+	}
+
+	roleList, err := global.GetUserRoles(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(global.Config.Security.ImpersonateTTL) * time.Minute
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	expiresAt := time.Now().Add(ttl)
+	token, err := global.GenerateTokenWithTTL(global.ClaimsUserInfo{
+		UserID:           target.ID,
+		Username:         target.Username,
+		RoleList:         roleList,
+		ImpersonatorID:   actorID,
+		ImpersonatorName: actorName,
+	}, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Token: token, ExpiresAt: expiresAt, User: &target}, nil
+}
+
+// SeedPermission 创建模拟登录权限并授予超级管理员角色，已存在时跳过
+func SeedPermission(db *gorm.DB) error {
+	var perm models.Permission
+	err := db.Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: PermissionKey}).First(&perm).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		perm = models.Permission{
+			Name:        "模拟登录",
+			Key:         PermissionKey,
+			Description: "以其他用户身份登录查看其菜单和数据，操作记录归属实际的管理员",
+			Type:        "api",
+			Method:      "POST",
+			Path:        "/admin/user/impersonate/:id",
+			Status:      1,
+		}
+		if err := db.Create(&perm).Error; err != nil {
+			return fmt.Errorf("创建模拟登录权限失败: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("查询模拟登录权限失败: %w", err)
+	}
+
+	var role models.Role
+	if err := db.Where("name = ?", "超级管理员").First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RolePermission{RoleID: role.ID, PermissionID: perm.ID}).Error
+}
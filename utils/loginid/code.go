@@ -0,0 +1,114 @@
+package loginid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/captcha"
+	"rbac_admin_server/utils/email"
+)
+
+const (
+	resendInterval = time.Minute // 同一邮箱重新发送的最短间隔
+	maxAttempts    = 5           // 验证码最多尝试次数，超过后作废
+)
+
+var (
+	// ErrDisabled 未开启邮箱验证码登录
+	ErrDisabled = errors.New("未开启邮箱验证码登录")
+	// ErrTooFrequent 发送过于频繁
+	ErrTooFrequent = errors.New("发送过于频繁")
+	// ErrCode 验证码错误或已过期
+	ErrCode = errors.New("验证码错误或已过期")
+)
+
+// sent 已发送的登录验证码
+type sent struct {
+	at       time.Time
+	attempts int
+}
+
+var (
+	mu      sync.Mutex
+	sending = make(map[string]*sent)
+)
+
+// SendCode 向邮箱发送登录验证码
+// 邮箱未注册时不发送但同样返回nil，避免通过该接口探测已注册的邮箱
+func SendCode(db *gorm.DB, addr string) error {
+	if !global.Config.Login.EmailCode || !Allowed(Email) {
+		return ErrDisabled
+	}
+	addr = strings.ToLower(strings.TrimSpace(addr))
+
+	ttl := time.Duration(global.Config.Login.CodeTTL) * time.Minute
+	mu.Lock()
+	if s, ok := sending[addr]; ok && time.Since(s.at) < resendInterval {
+		mu.Unlock()
+		return ErrTooFrequent
+	}
+	for k, s := range sending {
+		if time.Since(s.at) > ttl+resendInterval {
+			delete(sending, k)
+		}
+	}
+	sending[addr] = &sent{at: time.Now()}
+	mu.Unlock()
+
+	user, err := findBy(db, Email, addr)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	code := captcha.EmailStore.GenerateAndStoreEmailCode(codeKey(addr), ttl)
+	content := fmt.Sprintf("您正在登录账号 %s，验证码为 %s ，请在%d分钟内使用，过时无效！如非本人操作请忽略。",
+		user.Username, code, global.Config.Login.CodeTTL)
+	if err := email.SendEmail(addr, "登录验证码", content); err != nil {
+		captcha.EmailStore.DeleteEmailCode(codeKey(addr))
+		mu.Lock()
+		delete(sending, addr)
+		mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// VerifyCode 校验登录验证码并返回对应用户，验证码只能使用一次
+func VerifyCode(db *gorm.DB, addr, code string) (*models.User, error) {
+	if !global.Config.Login.EmailCode || !Allowed(Email) {
+		return nil, ErrDisabled
+	}
+	addr = strings.ToLower(strings.TrimSpace(addr))
+
+	mu.Lock()
+	s, ok := sending[addr]
+	if !ok || !captcha.EmailStore.VerifyEmailCode(codeKey(addr), strings.TrimSpace(code)) {
+		if ok {
+			s.attempts++
+			if s.attempts >= maxAttempts {
+				captcha.EmailStore.DeleteEmailCode(codeKey(addr))
+			}
+		}
+		mu.Unlock()
+		return nil, ErrCode
+	}
+	captcha.EmailStore.DeleteEmailCode(codeKey(addr))
+	delete(sending, addr)
+	mu.Unlock()
+
+	return findBy(db, Email, addr)
+}
+
+func codeKey(addr string) string {
+	return "login:" + addr
+}
@@ -0,0 +1,85 @@
+package loginid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/core"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// 登录账号类型，同时也是users表中的列名
+const (
+	Username = "username"
+	Email    = "email"
+	Phone    = "phone"
+)
+
+var (
+	// ErrNotAllowed 当前部署不允许使用该类型的账号登录
+	ErrNotAllowed = errors.New("不支持该登录方式")
+	// ErrNotFound 用户不存在
+	ErrNotFound = errors.New("用户不存在")
+)
+
+// Detect 按格式判断登录账号的类型，手机号使用validatePhone规则
+// validatePhone对空值返回通过，空账号按用户名处理，避免匹配到未填手机号的用户
+func Detect(identifier string) string {
+	switch {
+	case identifier == "":
+		return Username
+	case strings.Contains(identifier, "@") && core.ValidateVar(identifier, "email") == nil:
+		return Email
+	case core.ValidateVar(identifier, "phone") == nil:
+		return Phone
+	}
+	return Username
+}
+
+// Allowed 判断是否允许使用该类型的账号登录
+func Allowed(kind string) bool {
+	for _, k := range global.Config.Login.Identifiers {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Find 按用户名、邮箱或手机号查找用户
+// 形如手机号但按手机号找不到时，再按用户名查找，兼容纯数字的用户名
+func Find(db *gorm.DB, identifier string) (*models.User, error) {
+	identifier = strings.TrimSpace(identifier)
+	kind := Detect(identifier)
+	if !Allowed(kind) {
+		if kind != Phone || !Allowed(Username) {
+			return nil, fmt.Errorf("%w: %s", ErrNotAllowed, kind)
+		}
+		kind = Username
+	}
+
+	user, err := findBy(db, kind, identifier)
+	if errors.Is(err, ErrNotFound) && kind == Phone && Allowed(Username) {
+		return findBy(db, Username, identifier)
+	}
+	return user, err
+}
+
+func findBy(db *gorm.DB, kind, value string) (*models.User, error) {
+	query := db.Where(kind+" = ?", value)
+	if kind == Email {
+		query = db.Where("LOWER(email) = ?", strings.ToLower(value))
+	}
+	var user models.User
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,63 @@
+package loginid
+
+import (
+	"testing"
+
+	"rbac_admin_server/config"
+	"rbac_admin_server/core"
+	"rbac_admin_server/global"
+)
+
+func init() {
+	if err := core.InitValidator(); err != nil {
+		panic(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       string
+	}{
+		{"admin", Username},
+		{"eve_01", Username},
+		{"admin@example.com", Email},
+		{"Eve.01+tag@mail.example.cn", Email},
+		{"admin@", Username},
+		{"@example.com", Username},
+		{"a@b@example.com", Username},
+		{"13800138000", Phone},
+		{"19912345678", Phone},
+		{"12800138000", Username},
+		{"1380013800", Username},
+		{"138001380001", Username},
+		{"+8613800138000", Username},
+		{"", Username},
+	}
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			if got := Detect(tt.identifier); got != tt.want {
+				t.Fatalf("Detect(%q) = %s, want %s", tt.identifier, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	global.Config = &config.Config{}
+	global.Config.Login.Identifiers = []string{Username, Email}
+	tests := []struct {
+		kind string
+		want bool
+	}{
+		{Username, true},
+		{Email, true},
+		{Phone, false},
+		{"id_card", false},
+	}
+	for _, tt := range tests {
+		if got := Allowed(tt.kind); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package logstat
+
+import "gorm.io/gorm"
+
+// 统计时间粒度
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// bucketExpr 返回按时间粒度分组的SQL表达式，结果统一为字符串
+// 各数据库的日期函数不同，按当前连接的方言生成，统一按服务器本地时间分组
+func bucketExpr(db *gorm.DB, column, interval string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		if interval == IntervalHour {
+			return "DATE_FORMAT(" + column + ", '%Y-%m-%d %H:00')"
+		}
+		return "DATE_FORMAT(" + column + ", '%Y-%m-%d')"
+	case "postgres":
+		if interval == IntervalHour {
+			return "to_char(" + column + ", 'YYYY-MM-DD HH24:00')"
+		}
+		return "to_char(" + column + ", 'YYYY-MM-DD')"
+	default:
+		if interval == IntervalHour {
+			return "strftime('%Y-%m-%d %H:00', " + column + ", 'localtime')"
+		}
+		return "strftime('%Y-%m-%d', " + column + ", 'localtime')"
+	}
+}
@@ -0,0 +1,186 @@
+package logstat
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// cacheTTL 统计结果缓存时间
+const cacheTTL = time.Minute
+
+// topLimit 排行榜返回的条数
+const topLimit = 10
+
+// Bucket 时间序列中的一个点
+type Bucket struct {
+	Time   string `json:"time" gorm:"column:bucket"`
+	Count  int64  `json:"count"`
+	Errors int64  `json:"errors"`
+}
+
+// TopItem 排行榜条目
+type TopItem struct {
+	Key   string `json:"key" gorm:"column:name"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// Dashboard 日志仪表盘统计结果
+type Dashboard struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Interval     string    `json:"interval"`
+	Total        int64     `json:"total"`
+	Errors       int64     `json:"errors"`
+	ErrorRate    float64   `json:"error_rate"`
+	LatencyP50   int64     `json:"latency_p50"`
+	LatencyP95   int64     `json:"latency_p95"`
+	Requests     []Bucket  `json:"requests"`
+	TopEndpoints []TopItem `json:"top_endpoints"`
+	TopUsers     []TopItem `json:"top_users"`
+	TopIPs       []TopItem `json:"top_ips"`
+	FailedLogins int64     `json:"failed_logins"`
+	LoginFails   []Bucket  `json:"login_fails"`
+	TopFailedIPs []TopItem `json:"top_failed_ips"`
+	GeneratedAt  time.Time `json:"generated_at"`
+}
+
+type cacheEntry struct {
+	data    *Dashboard
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cacheEntry)
+)
+
+// Get 统计最近window时间内的日志，结果缓存cacheTTL
+// interval为空时，窗口不超过两天按小时统计，否则按天统计
+func Get(window time.Duration, interval string) (*Dashboard, error) {
+	if interval != IntervalHour && interval != IntervalDay {
+		interval = IntervalDay
+		if window <= 48*time.Hour {
+			interval = IntervalHour
+		}
+	}
+	key := fmt.Sprintf("%d|%s", window, interval)
+
+	cacheMu.Lock()
+	if entry, ok := cache[key]; ok && time.Now().Before(entry.expires) {
+		cacheMu.Unlock()
+		return entry.data, nil
+	}
+	cacheMu.Unlock()
+
+	end := time.Now()
+	data, err := build(global.DB, end.Add(-window), end, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMu.Lock()
+	cache[key] = cacheEntry{data: data, expires: time.Now().Add(cacheTTL)}
+	cacheMu.Unlock()
+	return data, nil
+}
+
+// build 执行各项聚合查询
+func build(db *gorm.DB, start, end time.Time, interval string) (*Dashboard, error) {
+	d := &Dashboard{Start: start, End: end, Interval: interval, GeneratedAt: time.Now()}
+	bucket := bucketExpr(db, "created_at", interval)
+
+	ops := func() *gorm.DB {
+		return db.Model(&models.Log{}).
+			Where("type = ? AND created_at >= ? AND created_at < ?", models.LogTypeOperation, start, end)
+	}
+	logins := func() *gorm.DB {
+		return db.Model(&models.Log{}).
+			Where("type = ? AND action = ? AND created_at >= ? AND created_at < ?", models.LogTypeLogin, "login_failed", start, end)
+	}
+
+	if err := ops().Count(&d.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := ops().Where("status_code >= ?", 400).Count(&d.Errors).Error; err != nil {
+		return nil, err
+	}
+	if d.Total > 0 {
+		d.ErrorRate = float64(d.Errors) / float64(d.Total)
+	}
+
+	var err error
+	if d.LatencyP50, err = percentile(ops, d.Total, 0.5); err != nil {
+		return nil, err
+	}
+	if d.LatencyP95, err = percentile(ops, d.Total, 0.95); err != nil {
+		return nil, err
+	}
+
+	d.Requests = []Bucket{}
+	if err := ops().
+		Select(bucket + " AS bucket, count(*) AS count, sum(CASE WHEN status_code >= 400 THEN 1 ELSE 0 END) AS errors").
+		Group(bucket).Order("bucket").Scan(&d.Requests).Error; err != nil {
+		return nil, err
+	}
+
+	if d.TopEndpoints, err = top(ops(), "path", "method"); err != nil {
+		return nil, err
+	}
+	if d.TopUsers, err = top(ops().Where("user_id > 0"), "user_id", "username"); err != nil {
+		return nil, err
+	}
+	if d.TopIPs, err = top(ops(), "ip", ""); err != nil {
+		return nil, err
+	}
+
+	if err := logins().Count(&d.FailedLogins).Error; err != nil {
+		return nil, err
+	}
+	d.LoginFails = []Bucket{}
+	if err := logins().
+		Select(bucket + " AS bucket, count(*) AS count, count(*) AS errors").
+		Group(bucket).Order("bucket").Scan(&d.LoginFails).Error; err != nil {
+		return nil, err
+	}
+	if d.TopFailedIPs, err = top(logins(), "ip", ""); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// percentile 计算耗时的百分位数
+// 不依赖数据库的百分位函数，按排序后的偏移量取值以兼容各方言
+func percentile(query func() *gorm.DB, total int64, p float64) (int64, error) {
+	if total == 0 {
+		return 0, nil
+	}
+	// 最近秩法：取排序后第ceil(p*N)个值
+	offset := int(math.Ceil(p*float64(total))) - 1
+	var latency []int64
+	err := query().Order("latency").Offset(offset).Limit(1).Pluck("latency", &latency).Error
+	if err != nil || len(latency) == 0 {
+		return 0, err
+	}
+	return latency[0], nil
+}
+
+// top 按指定列分组统计次数最多的条目
+// label为附加的说明列，不参与分组，取分组内的最大值，为空时不输出
+func top(query *gorm.DB, key, label string) ([]TopItem, error) {
+	var rows []TopItem
+	sel := key + " AS name, count(*) AS count"
+	if label != "" {
+		sel += ", MAX(" + label + ") AS label"
+	}
+	if err := query.Select(sel).Group(key).Order("count DESC").Limit(topLimit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
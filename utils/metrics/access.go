@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access 指标接口的访问控制
+// 带有正确令牌的请求或来源IP在允许列表中的请求可以访问，其他请求返回403
+// 来源IP取TCP连接的对端地址，不使用可以伪造的X-Forwarded-For
+func Access(token string, allowIPs []string) gin.HandlerFunc {
+	var nets []*net.IPNet
+	for _, s := range allowIPs {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		if _, n, err := net.ParseCIDR(s); err == nil {
+			nets = append(nets, n)
+		}
+	}
+
+	return func(c *gin.Context) {
+		if token != "" {
+			bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		if ip := net.ParseIP(c.RemoteIP()); ip != nil {
+			for _, n := range nets {
+				if n.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(403, gin.H{"code": 403, "msg": "无权访问监控指标"})
+	}
+}
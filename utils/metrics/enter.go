@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metric 指标接口，按Prometheus文本格式输出
+type metric interface {
+	write(b *strings.Builder)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []metric
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// Vec 带单个标签的指标
+// kind为counter时只能增加，为gauge时可以直接设置
+type Vec struct {
+	name   string
+	help   string
+	kind   string
+	label  string
+	mu     sync.RWMutex
+	values map[string]float64
+}
+
+// NewCounterVec 创建计数器指标
+func NewCounterVec(name, help, label string) *Vec {
+	v := &Vec{name: name, help: help, kind: "counter", label: label, values: make(map[string]float64)}
+	register(v)
+	return v
+}
+
+// NewGaugeVec 创建仪表盘指标
+func NewGaugeVec(name, help, label string) *Vec {
+	v := &Vec{name: name, help: help, kind: "gauge", label: label, values: make(map[string]float64)}
+	register(v)
+	return v
+}
+
+// Add 增加指定标签的指标值
+func (v *Vec) Add(labelValue string, delta float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[labelValue] += delta
+}
+
+// Set 设置指定标签的指标值
+func (v *Vec) Set(labelValue string, value float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[labelValue] = value
+}
+
+func (v *Vec) write(b *strings.Builder) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", v.name, v.kind)
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s{%s=%q} %g\n", v.name, v.label, k, v.values[k])
+	}
+}
+
+// Handler 返回指标输出接口
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		registryMu.RLock()
+		defer registryMu.RUnlock()
+
+		var b strings.Builder
+		for _, m := range registry {
+			m.write(&b)
+		}
+		c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+	}
+}
@@ -0,0 +1,87 @@
+package org
+
+import (
+	"gorm.io/gorm"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/depttree"
+)
+
+// Node 组织架构图节点
+type Node struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	ParentID    uint     `json:"parent_id"`
+	Sort        int      `json:"sort"`
+	Level       int      `json:"level"`
+	Status      int      `json:"status"`
+	MemberCount int      `json:"member_count"` // 直属成员数
+	Headcount   int      `json:"headcount"`    // 包括下级部门的成员数，同一用户只计一次
+	Leaders     []Leader `json:"leaders"`
+	Children    []*Node  `json:"children"`
+}
+
+// Chart 构建组织架构图，成员数不包括已删除和已归档的用户
+func Chart(db *gorm.DB) ([]*Node, error) {
+	var departments []models.Department
+	if err := db.Order("level, sort, id").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	var members []models.UserDepartment
+	if err := db.Model(&models.UserDepartment{}).Select("user_departments.user_id, user_departments.department_id").
+		Joins("JOIN users ON users.id = user_departments.user_id AND users.deleted_at IS NULL").
+		Where("users.status <> ?", models.UserStatusArchived).
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	leaders, err := Leaders(db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := []*Node{}
+	nodes := make(map[uint]*Node, len(departments))
+	paths := make(map[uint]string, len(departments))
+	for _, d := range departments {
+		node := &Node{
+			ID: d.ID, Name: d.Name, ParentID: d.ParentID, Sort: d.Sort, Level: d.Level, Status: d.Status,
+			Leaders: leaders[d.ID], Children: []*Node{},
+		}
+		if node.Leaders == nil {
+			node.Leaders = []Leader{}
+		}
+		nodes[d.ID] = node
+		paths[d.ID] = d.Path
+		if parent, ok := nodes[d.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	// 成员计入所在部门及其全部上级部门
+	counted := make(map[uint]map[uint]bool, len(departments))
+	for _, m := range members {
+		node, ok := nodes[m.DepartmentID]
+		if !ok {
+			continue
+		}
+		node.MemberCount++
+		ancestors := depttree.IDs(paths[m.DepartmentID])
+		if len(ancestors) == 0 {
+			ancestors = []uint{m.DepartmentID}
+		}
+		for _, id := range ancestors {
+			if counted[id] == nil {
+				counted[id] = make(map[uint]bool)
+			}
+			counted[id][m.UserID] = true
+		}
+	}
+	for id, users := range counted {
+		if node, ok := nodes[id]; ok {
+			node.Headcount = len(users)
+		}
+	}
+	return roots, nil
+}
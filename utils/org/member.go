@@ -0,0 +1,219 @@
+package org
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/idlist"
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrDepartmentNotFound 部门不存在
+	ErrDepartmentNotFound = errors.New("部门不存在")
+	// ErrPositionNotFound 岗位不存在
+	ErrPositionNotFound = errors.New("岗位不存在")
+)
+
+// Membership 用户所属部门
+type Membership struct {
+	DepartmentID uint   `json:"department_id"`
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	IsPrimary    bool   `json:"is_primary"`
+	IsLeader     bool   `json:"is_leader"`
+}
+
+// Leader 部门负责人
+type Leader struct {
+	DepartmentID uint   `json:"-"`
+	ID           uint   `json:"id"`
+	Username     string `json:"username"`
+	Nickname     string `json:"nickname"`
+	Avatar       string `json:"avatar"`
+}
+
+// Memberships 返回用户所属部门，主部门在前
+func Memberships(db *gorm.DB, userID uint) ([]Membership, error) {
+	list := []Membership{}
+	err := db.Model(&models.UserDepartment{}).
+		Select("user_departments.department_id, departments.name, departments.path, user_departments.is_primary, user_departments.is_leader").
+		Joins("JOIN departments ON departments.id = user_departments.department_id AND departments.deleted_at IS NULL").
+		Where("user_departments.user_id = ?", userID).
+		Order("user_departments.is_primary DESC, departments.level, departments.sort, departments.id").
+		Scan(&list).Error
+	return list, err
+}
+
+// SetPrimary 设置用户的主部门，其他所属部门保持不变，deptID为0时清除主部门
+// 用于用户创建、更新和导入等只指定一个部门的场景
+func SetPrimary(db *gorm.DB, userID, deptID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserDepartment{}).Where("user_id = ? AND department_id <> ?", userID, deptID).
+			Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		if deptID != 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "department_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"is_primary": true}),
+			}).Create(&models.UserDepartment{UserID: userID, DepartmentID: deptID, IsPrimary: true}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("department_id", deptID).Error
+	})
+}
+
+// ReplacePrimary 将用户的主部门改为deptID，并删除原主部门的所属记录，用于只维护单个部门的编辑
+// 原主部门中的负责人记录保留为非主部门
+func ReplacePrimary(db *gorm.DB, userID, deptID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND department_id <> ? AND is_primary = ? AND is_leader = ?", userID, deptID, true, false).
+			Delete(&models.UserDepartment{}).Error; err != nil {
+			return err
+		}
+		return SetPrimary(tx, userID, deptID)
+	})
+}
+
+// SetDepartments 用deptIDs替换用户的所属部门，primaryID为主部门，不在deptIDs中时自动加入
+// 保留的部门中负责人标记不变
+func SetDepartments(db *gorm.DB, userID, primaryID uint, deptIDs []uint) error {
+	ids := idlist.Unique(append([]uint{primaryID}, deptIDs...))
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.User{}, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if err := idlist.Exists(tx, &models.Department{}, ids, ErrDepartmentNotFound); err != nil {
+			return err
+		}
+
+		stale := tx.Where("user_id = ?", userID)
+		if len(ids) > 0 {
+			stale = stale.Where("department_id NOT IN ?", ids)
+		}
+		if err := stale.Delete(&models.UserDepartment{}).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "department_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"is_primary": id == primaryID}),
+			}).Create(&models.UserDepartment{UserID: userID, DepartmentID: id, IsPrimary: id == primaryID}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("department_id", primaryID).Error
+	})
+}
+
+// SetLeaders 用userIDs替换部门负责人，负责人不在部门中时加入部门
+// 没有主部门的负责人以该部门为主部门
+func SetLeaders(db *gorm.DB, deptID uint, userIDs []uint) error {
+	userIDs = idlist.Unique(userIDs)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := idlist.Exists(tx, &models.Department{}, []uint{deptID}, ErrDepartmentNotFound); err != nil {
+			return err
+		}
+		if err := idlist.Exists(tx, &models.User{}, userIDs, ErrUserNotFound); err != nil {
+			return err
+		}
+
+		stale := tx.Model(&models.UserDepartment{}).Where("department_id = ? AND is_leader = ?", deptID, true)
+		if len(userIDs) > 0 {
+			stale = stale.Where("user_id NOT IN ?", userIDs)
+		}
+		if err := stale.Update("is_leader", false).Error; err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		for _, id := range userIDs {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "department_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"is_leader": true}),
+			}).Create(&models.UserDepartment{UserID: id, DepartmentID: deptID, IsLeader: true}).Error; err != nil {
+				return err
+			}
+		}
+
+		var orphans []uint
+		if err := tx.Model(&models.User{}).Where("id IN ? AND department_id = 0", userIDs).
+			Pluck("id", &orphans).Error; err != nil {
+			return err
+		}
+		for _, id := range orphans {
+			if err := SetPrimary(tx, id, deptID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Leaders 返回各部门的负责人，已删除和已归档的用户除外
+func Leaders(db *gorm.DB, deptIDs []uint) (map[uint][]Leader, error) {
+	var rows []Leader
+	query := db.Model(&models.UserDepartment{}).
+		Select("user_departments.department_id, users.id, users.username, users.nickname, users.avatar").
+		Joins("JOIN users ON users.id = user_departments.user_id AND users.deleted_at IS NULL").
+		Where("user_departments.is_leader = ? AND users.status <> ?", true, models.UserStatusArchived)
+	if deptIDs != nil {
+		query = query.Where("user_departments.department_id IN ?", deptIDs)
+	}
+	if err := query.Order("users.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	leaders := make(map[uint][]Leader)
+	for _, l := range rows {
+		leaders[l.DepartmentID] = append(leaders[l.DepartmentID], l)
+	}
+	return leaders, nil
+}
+
+// Positions 返回用户的岗位
+func Positions(db *gorm.DB, userID uint) ([]models.Position, error) {
+	list := []models.Position{}
+	err := db.Joins("JOIN user_positions ON user_positions.position_id = positions.id").
+		Where("user_positions.user_id = ?", userID).Order("positions.sort, positions.id").Find(&list).Error
+	return list, err
+}
+
+// SetPositions 用positionIDs替换用户的岗位
+func SetPositions(db *gorm.DB, userID uint, positionIDs []uint) error {
+	positionIDs = idlist.Unique(positionIDs)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := idlist.Exists(tx, &models.User{}, []uint{userID}, ErrUserNotFound); err != nil {
+			return err
+		}
+		if err := idlist.Exists(tx, &models.Position{}, positionIDs, ErrPositionNotFound); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserPosition{}).Error; err != nil {
+			return err
+		}
+		for _, id := range positionIDs {
+			if err := tx.Create(&models.UserPosition{UserID: userID, PositionID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Remove 删除用户的所属部门和岗位，用于彻底删除和注销账号
+func Remove(db *gorm.DB, userIDs []uint) error {
+	if err := db.Where("user_id IN ?", userIDs).Delete(&models.UserDepartment{}).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id IN ?", userIDs).Delete(&models.UserPosition{}).Error
+}
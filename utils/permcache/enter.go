@@ -0,0 +1,143 @@
+// Package permcache 用户权限标识的计算和缓存
+// 权限标识来自用户直接分配和通过用户组(含上级用户组)获得的启用角色，包括角色的权限和角色菜单中按钮的权限标识，
+// 是个人权限接口和用户菜单接口返回的按钮权限的唯一来源，供前端控制按钮显示
+package permcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// cacheTTL 权限标识缓存时间
+const cacheTTL = time.Hour
+
+// genKey 缓存代数，角色、权限或用户组变更时递增，使全部用户的缓存失效
+const genKey = "perm_keys:gen"
+
+// cacheKey 返回用户在当前代数下的缓存键
+func cacheKey(ctx context.Context, userID uint) string {
+	gen, err := global.Redis.Get(ctx, genKey).Result()
+	if err != nil {
+		gen = "0"
+	}
+	return fmt.Sprintf("perm_keys:%s:%d", gen, userID)
+}
+
+// Keys 返回用户拥有的启用权限标识和菜单按钮标识，去重后按标识排序；超级管理员返回全部启用的标识
+func Keys(db *gorm.DB, userID uint) ([]string, error) {
+	ctx := db.Statement.Context
+	if keys, ok := loadCache(ctx, userID); ok {
+		return keys, nil
+	}
+	keys, err := load(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	saveCache(ctx, userID, keys)
+	return keys, nil
+}
+
+// load 从数据库计算用户的权限标识
+func load(db *gorm.DB, userID uint) ([]string, error) {
+	var user models.User
+	if err := db.Select("id", "is_admin").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	permissions := db.Model(&models.Permission{}).Where("permissions.status = 1 AND permissions.key <> ''")
+	buttons := db.Model(&models.Menu{}).Where("menus.type = ? AND menus.status = 1 AND menus.permission <> ''", models.MenuTypeButton)
+	if !user.IsAdmin {
+		roleIDs, err := global.RoleIDs(db, userID)
+		if err != nil || len(roleIDs) == 0 {
+			return keys, err
+		}
+		permissions = permissions.
+			Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+			Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+			Where("role_permissions.role_id IN ?", roleIDs)
+		buttons = buttons.
+			Joins("JOIN role_menus ON role_menus.menu_id = menus.id").
+			Joins("JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+			Where("role_menus.role_id IN ?", roleIDs)
+	}
+	var permissionKeys, buttonKeys []string
+	if err := permissions.Distinct().Pluck("permissions.key", &permissionKeys).Error; err != nil {
+		return nil, err
+	}
+	if err := buttons.Distinct().Pluck("menus.permission", &buttonKeys).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(permissionKeys)+len(buttonKeys))
+	for _, key := range append(permissionKeys, buttonKeys...) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// loadCache 读取缓存，未启用Redis或未命中时返回false
+func loadCache(ctx context.Context, userID uint) ([]string, bool) {
+	if global.Redis == nil {
+		return nil, false
+	}
+	data, err := global.Redis.Get(ctx, cacheKey(ctx, userID)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var keys []string
+	if json.Unmarshal(data, &keys) != nil {
+		return nil, false
+	}
+	return keys, true
+}
+
+// saveCache 写入缓存，失败时忽略
+func saveCache(ctx context.Context, userID uint, keys []string) {
+	if global.Redis == nil {
+		return
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return
+	}
+	if err := global.Redis.Set(ctx, cacheKey(ctx, userID), data, cacheTTL).Err(); err != nil {
+		global.Logger.Warnf("缓存用户权限标识失败: %v", err)
+	}
+}
+
+// Invalidate 清除指定用户的权限标识缓存，用于用户角色和用户组成员变更
+func Invalidate(ctx context.Context, userIDs ...uint) {
+	if global.Redis == nil || len(userIDs) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, cacheKey(ctx, id))
+	}
+	if err := global.Redis.Del(ctx, keys...).Err(); err != nil {
+		global.Logger.Warnf("清除用户权限标识缓存失败: %v", err)
+	}
+}
+
+// InvalidateAll 清除全部用户的权限标识缓存，用于角色权限、角色菜单、角色、权限、菜单和用户组变更
+// 旧代数的缓存不再被读取，到期后由Redis删除
+func InvalidateAll(ctx context.Context) {
+	if global.Redis == nil {
+		return
+	}
+	if err := global.Redis.Incr(ctx, genKey).Err(); err != nil {
+		global.Logger.Warnf("清除权限标识缓存失败: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/avatar"
+	"rbac_admin_server/utils/email"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/usergroup"
+	"rbac_admin_server/utils/usersettings"
+	"rbac_admin_server/utils/userstate"
+)
+
+var (
+	// ErrPending 已有冷静期中的注销申请
+	ErrPending = errors.New("已提交注销申请，冷静期内可撤销")
+	// ErrNoRequest 没有冷静期中的注销申请
+	ErrNoRequest = errors.New("没有待处理的注销申请")
+	// ErrAdmin 管理员账号不能自助注销
+	ErrAdmin = errors.New("管理员账号不能自助注销，请先移交管理员权限")
+)
+
+// Alias 匿名化后的用户名
+func Alias(userID uint) string {
+	return fmt.Sprintf("deleted_%d", userID)
+}
+
+// Pending 返回用户冷静期中的注销申请，没有时返回nil
+func Pending(db *gorm.DB, userID uint) (*models.DeletionRequest, error) {
+	var req models.DeletionRequest
+	err := db.Where("user_id = ? AND status = ?", userID, models.DeletionStatusPending).First(&req).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Request 申请注销账号，冷静期结束后由定时任务匿名化
+func Request(db *gorm.DB, user *models.User, reason string) (*models.DeletionRequest, error) {
+	if user.IsAdmin {
+		return nil, ErrAdmin
+	}
+	if req, err := Pending(db, user.ID); err != nil {
+		return nil, err
+	} else if req != nil {
+		return nil, ErrPending
+	}
+
+	req := &models.DeletionRequest{
+		UserID:      user.ID,
+		Status:      models.DeletionStatusPending,
+		Reason:      reason,
+		ScheduledAt: time.Now().Add(time.Duration(global.Config.Privacy.DeletionDelay) * 24 * time.Hour),
+	}
+	if err := db.Create(req).Error; err != nil {
+		return nil, err
+	}
+	go notify(user, "账号注销申请", fmt.Sprintf("您的账号 %s 已提交注销申请，将于 %s 后注销并匿名化个人信息。如需保留账号，请在此之前登录并撤销申请。",
+		user.Username, req.ScheduledAt.Format("2006-01-02 15:04")))
+	return req, nil
+}
+
+// Cancel 撤销冷静期中的注销申请
+func Cancel(db *gorm.DB, userID uint) error {
+	res := db.Model(&models.DeletionRequest{}).
+		Where("user_id = ? AND status = ?", userID, models.DeletionStatusPending).
+		Updates(map[string]interface{}{"status": models.DeletionStatusCancelled, "processed_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNoRequest
+	}
+	return nil
+}
+
+// ProcessDue 匿名化冷静期已结束的账号，返回处理的数量
+func ProcessDue(db *gorm.DB) (int, error) {
+	var due []models.DeletionRequest
+	if err := db.Where("status = ? AND scheduled_at <= ?", models.DeletionStatusPending, time.Now()).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, req := range due {
+		// 用户已从回收站彻底删除时无需匿名化，直接结束申请
+		if err := Anonymize(db, req.UserID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			global.Logger.Errorf("注销账号 %d 失败: %v", req.UserID, err)
+			continue
+		}
+		if err := db.Model(&models.DeletionRequest{}).Where("id = ?", req.ID).
+			Updates(map[string]interface{}{"status": models.DeletionStatusDone, "processed_at": time.Now()}).Error; err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Anonymize 匿名化用户
+// 用户行保留以维持日志、文件等记录的引用，清除可识别个人身份的字段并归档；
+// 撤销角色、部门、岗位和个人设置，删除头像，其他上传文件解除归属，审计日志中的用户名替换为匿名名称
+func Anonymize(db *gorm.DB, userID uint) error {
+	var user models.User
+	if err := db.Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+	if err := avatar.Remove(db, userID); err != nil {
+		return fmt.Errorf("删除头像失败: %w", err)
+	}
+
+	alias := Alias(userID)
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"username":      alias,
+			"nickname":      "已注销用户",
+			"password":      utils.MakePassword(utils.RandomPassword()),
+			"email":         nil,
+			"phone":         nil,
+			"avatar":        "",
+			"gender":        0,
+			"last_login_ip": "",
+			"status":        models.UserStatusArchived,
+			"status_at":     now,
+			"expires_at":    nil,
+			"locked_until":  nil,
+			"lock_reason":   "账号已注销",
+			"department_id": 0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserGroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserSettings{}).Error; err != nil {
+			return err
+		}
+		if err := org.Remove(tx, []uint{userID}); err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.File{}).Where("uploaded_by = ?", userID).Update("uploaded_by", 0).Error
+	})
+	if err != nil {
+		return err
+	}
+	usersettings.Invalidate(db.Statement.Context, userID)
+	userstate.Invalidate(userID)
+	permcache.Invalidate(db.Statement.Context, userID)
+	usergroup.Resync(db)
+
+	n, err := audit.Pseudonymize(db, userID, alias)
+	if err != nil {
+		return err
+	}
+	global.Logger.Infof("账号 %s(%d) 已注销，匿名化审计日志 %d 条", user.Username, userID, n)
+	return nil
+}
+
+// StartDeletionTimer 启动注销申请到期检查定时任务
+func StartDeletionTimer() {
+	interval := global.Config.Privacy.DeleteInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := ProcessDue(global.DB)
+			if err != nil {
+				global.Logger.Errorf("处理到期注销申请失败: %v", err)
+				continue
+			}
+			if n > 0 {
+				global.Logger.Infof("已注销账号 %d 个", n)
+			}
+		}
+	}()
+}
+
+// notify 邮件通知用户，邮箱未配置时跳过
+func notify(user *models.User, subject, content string) {
+	if user.Email == "" || !global.Config.Email.Verify() {
+		return
+	}
+	if err := email.SendEmail(string(user.Email), subject, content); err != nil {
+		global.Logger.Warnf("发送%s通知失败: %v", subject, err)
+	}
+}
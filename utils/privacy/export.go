@@ -0,0 +1,186 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/usersettings"
+)
+
+// logEntry 导出的日志记录，不包含哈希链字段
+type logEntry struct {
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	Module      string    `json:"module,omitempty"`
+	Action      string    `json:"action,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Method      string    `json:"method,omitempty"`
+	Path        string    `json:"path,omitempty"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Request     string    `json:"request,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
+
+// session 可能仍有效的登录会话
+// 令牌不在服务端保存，按刷新令牌有效期内的成功登录记录推算
+type session struct {
+	LoginAt   time.Time `json:"login_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+}
+
+// manifest 导出说明
+type manifest struct {
+	UserID      uint           `json:"user_id"`
+	Username    string         `json:"username"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Files       map[string]int `json:"files"`     // 文件名和记录数
+	LogLimit    int            `json:"log_limit"` // 每类日志最多导出的条数
+}
+
+// Export 将用户的个人数据打包为ZIP写入w
+// 包括个人资料、角色、会话、登录历史、上传文件元数据和审计日志
+func Export(db *gorm.DB, userID uint, w io.Writer) error {
+	var user models.User
+	if err := db.Preload("Roles").Preload("Department").First(&user, userID).Error; err != nil {
+		return err
+	}
+	settings, err := usersettings.Get(db, userID)
+	if err != nil {
+		return err
+	}
+	limit := global.Config.Privacy.ExportMaxLogs
+
+	logins, err := findLogs(db, limit, "user_id = ? AND type = ?", userID, models.LogTypeLogin)
+	if err != nil {
+		return err
+	}
+	operations, err := findLogs(db, limit, "user_id = ? AND type IN ?", userID, []string{models.LogTypeOperation, models.LogTypeChange})
+	if err != nil {
+		return err
+	}
+	// 其他用户对本账号的修改记录
+	changes, err := findLogs(db, limit, "type = ? AND module = ? AND description = ? AND user_id <> ?",
+		models.LogTypeChange, "user", fmt.Sprintf("ID=%d", userID), userID)
+	if err != nil {
+		return err
+	}
+	var files []models.File
+	if err := db.Where("uploaded_by = ?", userID).Order("id").Find(&files).Error; err != nil {
+		return err
+	}
+
+	roles := make([]role, len(user.Roles))
+	for i, r := range user.Roles {
+		roles[i] = role{ID: r.ID, Name: r.Name, Key: r.Key, Description: r.Description}
+	}
+	user.Roles = nil
+
+	active := sessions(logins)
+
+	zw := zip.NewWriter(w)
+	m := manifest{UserID: user.ID, Username: user.Username, GeneratedAt: time.Now(), Files: map[string]int{}, LogLimit: limit}
+	entries := []struct {
+		name  string
+		data  interface{}
+		count int
+	}{
+		{"profile.json", map[string]interface{}{"user": user, "settings": settings}, 1},
+		{"roles.json", roles, len(roles)},
+		{"sessions.json", active, len(active)},
+		{"login_history.json", logins, len(logins)},
+		{"files.json", files, len(files)},
+		{"audit_log.json", operations, len(operations)},
+		{"account_changes.json", changes, len(changes)},
+	}
+	for _, e := range entries {
+		m.Files[e.name] = e.count
+		if err := writeJSON(zw, e.name, e.data); err != nil {
+			return err
+		}
+	}
+	if err := writeJSON(zw, "manifest.json", m); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// role 导出的角色信息
+type role struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	Description string `json:"description"`
+}
+
+// findLogs 按时间倒序查询日志，包括已恢复的归档日志
+func findLogs(db *gorm.DB, limit int, query string, args ...interface{}) ([]logEntry, error) {
+	var rows []models.Log
+	if err := db.Where(query, args...).Order("id DESC").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]logEntry, 0, len(rows))
+	for i := range rows {
+		entries = append(entries, toEntry(&rows[i]))
+	}
+	if len(entries) >= limit {
+		return entries, nil
+	}
+	var archived []models.LogArchive
+	if err := db.Where(query, args...).Order("created_at DESC").Limit(limit - len(entries)).Find(&archived).Error; err != nil {
+		return nil, err
+	}
+	for i := range archived {
+		a := &archived[i]
+		entries = append(entries, toEntry(&models.Log{
+			BaseModelNoDelete: models.BaseModelNoDelete{CreatedAt: a.CreatedAt},
+			Type:              a.Type, Module: a.Module, Action: a.Action, Description: a.Description,
+			Method: a.Method, Path: a.Path, StatusCode: a.StatusCode, IP: a.IP, UserAgent: a.UserAgent,
+			RequestBody: a.RequestBody, Error: a.Error, RequestID: a.RequestID,
+		}))
+	}
+	return entries, nil
+}
+
+func toEntry(l *models.Log) logEntry {
+	return logEntry{
+		Time: l.CreatedAt, Type: l.Type, Module: l.Module, Action: l.Action, Description: l.Description,
+		Method: l.Method, Path: l.Path, StatusCode: l.StatusCode, IP: l.IP, UserAgent: l.UserAgent,
+		Request: l.RequestBody, Error: l.Error, RequestID: l.RequestID,
+	}
+}
+
+// sessions 从登录历史中推算仍在刷新令牌有效期内的会话
+func sessions(logins []logEntry) []session {
+	ttl := time.Duration(global.Config.JWT.RefreshExpireHours) * time.Hour
+	now := time.Now()
+	list := []session{}
+	for _, l := range logins {
+		if l.Action != "login" || now.Sub(l.Time) > ttl {
+			continue
+		}
+		list = append(list, session{LoginAt: l.Time, ExpiresAt: l.Time.Add(ttl), IP: l.IP, UserAgent: l.UserAgent})
+	}
+	return list
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
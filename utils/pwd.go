@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"math/big"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -34,4 +37,29 @@ func ComparePassword(hashedPassword, password string) bool {
 // 输入明文密码，返回加密后的密码
 func MakePassword(password string) string {
 	return HashedPassword(password)
-}
\ No newline at end of file
+}
+
+// RandomPassword 生成满足密码规则的随机初始密码
+func RandomPassword() string {
+	const (
+		upper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lower   = "abcdefghijkmnpqrstuvwxyz"
+		digits  = "23456789"
+		special = "!@#$%*"
+	)
+	pick := func(set string) byte {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		return set[n.Int64()]
+	}
+	all := upper + lower + digits + special
+	buf := []byte{pick(upper), pick(lower), pick(digits), pick(special)}
+	for len(buf) < 12 {
+		buf = append(buf, pick(all))
+	}
+	// 打乱顺序
+	for i := len(buf) - 1; i > 0; i-- {
+		j, _ := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		buf[i], buf[j.Int64()] = buf[j.Int64()], buf[i]
+	}
+	return string(buf)
+}
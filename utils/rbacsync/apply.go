@@ -0,0 +1,378 @@
+package rbacsync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/usergroup"
+	"rbac_admin_server/utils/userstate"
+)
+
+// Result 导入结果
+type Result struct {
+	Plan *Plan `json:"plan"`
+	// Passwords 新建用户的随机初始密码，快照中不包含密码
+	Passwords map[string]string `json:"passwords,omitempty"`
+}
+
+// Apply 在一个事务中按计划导入快照，任一步失败时全部回滚
+// 重复执行同一快照不会产生变更，数据库最终与快照一致
+func Apply(db *gorm.DB, snap *Snapshot, opts Options) (*Result, error) {
+	result := &Result{Passwords: make(map[string]string)}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		st, err := load(tx)
+		if err != nil {
+			return err
+		}
+		plan, err := st.diff(snap, opts)
+		if err != nil {
+			return err
+		}
+		result.Plan = plan
+		if plan.Empty() {
+			return nil
+		}
+		a := &applier{tx: tx, st: st, plan: plan, result: result}
+		for _, step := range []func(*Snapshot) error{
+			a.permissions, a.menus, a.roles, a.departments, a.dicts, a.users, a.bindings, a.prune,
+		} {
+			if err := step(snap); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 快照直接写入用户状态和角色，清除认证中间件的状态缓存和权限标识缓存，并同步Casbin继承关系
+	userstate.Reset()
+	permcache.InvalidateAll(db.Statement.Context)
+	usergroup.Resync(db)
+	return result, nil
+}
+
+// applier 导入过程的上下文
+type applier struct {
+	tx     *gorm.DB
+	st     *state
+	plan   *Plan
+	result *Result
+}
+
+// save 按标识新建或更新记录，已软删除的记录会被恢复
+// model需为模型指针，base为其内嵌的BaseModel；omit为更新时保留原值的字段
+func (a *applier) save(kind, key string, model interface{}, base *models.BaseModel, omit ...string) error {
+	if r, ok := a.st.ref(kind, key); ok {
+		base.ID = r.ID
+		omit = append(omit, "id", "created_at", clause.Associations)
+		if err := a.tx.Unscoped().Model(model).Select("*").Omit(omit...).Updates(model).Error; err != nil {
+			return fmt.Errorf("更新%s %s 失败: %w", kindNames[kind], key, err)
+		}
+	} else if err := a.tx.Omit(clause.Associations).Create(model).Error; err != nil {
+		return fmt.Errorf("创建%s %s 失败: %w", kindNames[kind], key, err)
+	}
+	a.st.setRef(kind, key, base.ID)
+	return nil
+}
+
+// id 返回标识对应的记录ID，标识为空时返回0
+func (a *applier) id(kind, key string) uint {
+	if key == "" {
+		return 0
+	}
+	r, _ := a.st.ref(kind, key)
+	return r.ID
+}
+
+// relink 用ids替换关联表中owner的全部关联
+func (a *applier) relink(table, ownerColumn, targetColumn string, ownerID uint, ids []uint) error {
+	if err := a.tx.Exec("DELETE FROM "+table+" WHERE "+ownerColumn+" = ?", ownerID).Error; err != nil {
+		return fmt.Errorf("清除%s失败: %w", table, err)
+	}
+	for _, id := range ids {
+		row := map[string]interface{}{ownerColumn: ownerID, targetColumn: id}
+		if err := a.tx.Table(table).Create(row).Error; err != nil {
+			return fmt.Errorf("写入%s失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// permissions 导入权限，上级权限在全部写入后再关联
+func (a *applier) permissions(snap *Snapshot) error {
+	var changed []Permission
+	for _, p := range snap.Permissions {
+		if !a.plan.changed(KindPermission, p.Key) {
+			continue
+		}
+		perm := models.Permission{
+			Name: p.Name, Key: p.Key, Description: p.Description, Type: p.Type, Method: p.Method,
+			Path: p.Path, Component: p.Component, Icon: p.Icon, Status: p.Status, Sort: p.Sort,
+			ParentID: a.id(KindPermission, p.Parent),
+		}
+		if err := a.save(KindPermission, p.Key, &perm, &perm.BaseModel); err != nil {
+			return err
+		}
+		changed = append(changed, p)
+	}
+	for _, p := range changed {
+		err := a.tx.Model(&models.Permission{}).Where("id = ?", a.id(KindPermission, p.Key)).
+			Update("parent_id", a.id(KindPermission, p.Parent)).Error
+		if err != nil {
+			return fmt.Errorf("设置权限 %s 的上级失败: %w", p.Key, err)
+		}
+	}
+	return nil
+}
+
+// menus 按层级导入菜单，先写入上级
+func (a *applier) menus(snap *Snapshot) error {
+	for _, m := range byDepth(snap.Menus, func(m Menu) string { return m.Key }) {
+		if !a.plan.changed(KindMenu, m.Key) {
+			continue
+		}
+		parent, _ := parentPath(m.Key)
+		menu := m.toModel(a.id(KindMenu, parent))
+		if err := a.save(KindMenu, m.Key, &menu, &menu.BaseModel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roles 导入角色及其权限和菜单
+func (a *applier) roles(snap *Snapshot) error {
+	for _, r := range snap.Roles {
+		if !a.plan.changed(KindRole, r.Key) {
+			continue
+		}
+		role := models.Role{Name: r.Name, Key: r.Key, Description: r.Description, Status: r.Status, Sort: r.Sort}
+		if err := a.save(KindRole, r.Key, &role, &role.BaseModel); err != nil {
+			return err
+		}
+		if a.plan.changed(KindRole, r.Key, "permissions") {
+			if err := a.relink("role_permissions", "role_id", "permission_id", role.ID, a.ids(KindPermission, r.Permissions)); err != nil {
+				return err
+			}
+		}
+		if a.plan.changed(KindRole, r.Key, "menus") {
+			if err := a.relink("role_menus", "role_id", "menu_id", role.ID, a.ids(KindMenu, r.Menus)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// departments 按层级导入部门，先写入上级
+func (a *applier) departments(snap *Snapshot) error {
+	for _, d := range byDepth(snap.Departments, func(d Department) string { return d.Key }) {
+		if !a.plan.changed(KindDepartment, d.Key) {
+			continue
+		}
+		parent, name := parentPath(d.Key)
+		dept := models.Department{Name: name, ParentID: a.id(KindDepartment, parent), Sort: d.Sort, Status: d.Status}
+		// 标识包含上级路径，已有部门的上级不变，路径和层级保持原值
+		if err := a.save(KindDepartment, d.Key, &dept, &dept.BaseModel, "path", "level"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dicts 导入字典和字典项，快照中未列出的字典项会被删除
+func (a *applier) dicts(snap *Snapshot) error {
+	for _, d := range snap.Dicts {
+		if a.plan.changed(KindDict, d.Key) {
+			dict := models.Dict{Name: d.Name, Key: d.Key, Description: d.Description, Status: d.Status, Sort: d.Sort}
+			if err := a.save(KindDict, d.Key, &dict, &dict.BaseModel); err != nil {
+				return err
+			}
+		}
+		dictID := a.id(KindDict, d.Key)
+		for _, item := range d.Items {
+			key := d.Key + "/" + item.Value
+			if !a.plan.changed(KindDictItem, key) {
+				continue
+			}
+			model := models.DictItem{
+				DictID: dictID, Label: item.Label, Value: item.Value, Description: item.Description,
+				Status: item.Status, Sort: item.Sort,
+			}
+			if err := a.save(KindDictItem, key, &model, &model.BaseModel); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range a.plan.Changes {
+		if c.Kind != KindDictItem || c.Action != ActionDelete {
+			continue
+		}
+		if err := a.tx.Delete(&models.DictItem{}, a.id(KindDictItem, c.Key)).Error; err != nil {
+			return fmt.Errorf("删除字典项 %s 失败: %w", c.Key, err)
+		}
+	}
+	return nil
+}
+
+// users 导入用户及其角色，新用户使用随机初始密码
+func (a *applier) users(snap *Snapshot) error {
+	for _, u := range snap.Users {
+		if !a.plan.changed(KindUser, u.Username) {
+			continue
+		}
+		deptID := a.id(KindDepartment, u.Department)
+		user := models.User{
+			Username: u.Username, Nickname: u.Nickname, Email: models.NullString(u.Email), Phone: models.NullString(u.Phone), Avatar: u.Avatar,
+			Status: u.Status, Gender: u.Gender, IsAdmin: u.IsAdmin, DepartmentID: deptID,
+		}
+		// 登录信息不在快照中，更新时保留原值；新建和恢复的用户使用随机密码
+		omit := []string{"last_login_at", "last_login_ip", "login_count"}
+		if r, ok := a.st.ref(KindUser, u.Username); ok && !r.Deleted {
+			omit = append(omit, "password")
+		} else {
+			password := utils.RandomPassword()
+			user.Password = utils.MakePassword(password)
+			a.result.Passwords[u.Username] = password
+		}
+		if err := a.save(KindUser, u.Username, &user, &user.BaseModel, omit...); err != nil {
+			return err
+		}
+		if a.plan.changed(KindUser, u.Username, "department") {
+			if err := org.ReplacePrimary(a.tx, user.ID, deptID); err != nil {
+				return fmt.Errorf("设置用户 %s 的部门失败: %w", u.Username, err)
+			}
+		}
+		if a.plan.changed(KindUser, u.Username, "roles") {
+			if err := a.relink("user_roles", "user_id", "role_id", user.ID, a.ids(KindRole, u.Roles)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindings 导入用户角色绑定
+func (a *applier) bindings(snap *Snapshot) error {
+	for _, b := range snap.Bindings {
+		if !a.plan.changed(KindBinding, b.Username) {
+			continue
+		}
+		if err := a.relink("user_roles", "user_id", "role_id", a.id(KindUser, b.Username), a.ids(KindRole, b.Roles)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune 删除快照中未列出的实体及其关联，先删除引用方
+func (a *applier) prune(*Snapshot) error {
+	deleted := make(map[string][]uint)
+	for _, c := range a.plan.Changes {
+		if c.Action != ActionDelete || c.Kind == KindDictItem {
+			continue
+		}
+		kind := c.Kind
+		if kind == KindBinding {
+			kind = KindUser
+		}
+		r, _ := a.st.ref(kind, c.Key)
+		deleted[c.Kind] = append(deleted[c.Kind], r.ID)
+	}
+
+	if ids := append(deleted[KindBinding], deleted[KindUser]...); len(ids) > 0 {
+		if err := a.unlink(ids, "user_roles.user_id"); err != nil {
+			return err
+		}
+	}
+	if ids := deleted[KindUser]; len(ids) > 0 {
+		if err := a.tx.Delete(&models.User{}, ids).Error; err != nil {
+			return fmt.Errorf("删除用户失败: %w", err)
+		}
+	}
+	if ids := deleted[KindDict]; len(ids) > 0 {
+		if err := a.tx.Where("dict_id IN ?", ids).Delete(&models.DictItem{}).Error; err != nil {
+			return fmt.Errorf("删除字典项失败: %w", err)
+		}
+		if err := a.tx.Delete(&models.Dict{}, ids).Error; err != nil {
+			return fmt.Errorf("删除字典失败: %w", err)
+		}
+	}
+	if ids := deleted[KindDepartment]; len(ids) > 0 {
+		var count int64
+		if err := a.tx.Model(&models.User{}).Where("department_id IN ? OR id IN (?)", ids,
+			a.tx.Model(&models.UserDepartment{}).Select("user_id").Where("department_id IN ?", ids)).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询部门用户失败: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("待删除的部门下仍有%d个用户，请先在快照中调整这些用户的部门", count)
+		}
+		if err := a.tx.Delete(&models.Department{}, ids).Error; err != nil {
+			return fmt.Errorf("删除部门失败: %w", err)
+		}
+	}
+	if ids := deleted[KindRole]; len(ids) > 0 {
+		if err := a.unlink(ids, "user_roles.role_id", "role_permissions.role_id", "role_menus.role_id"); err != nil {
+			return err
+		}
+		if err := a.tx.Delete(&models.Role{}, ids).Error; err != nil {
+			return fmt.Errorf("删除角色失败: %w", err)
+		}
+	}
+	if ids := deleted[KindMenu]; len(ids) > 0 {
+		if err := a.unlink(ids, "role_menus.menu_id"); err != nil {
+			return err
+		}
+		if err := a.tx.Delete(&models.Menu{}, ids).Error; err != nil {
+			return fmt.Errorf("删除菜单失败: %w", err)
+		}
+	}
+	if ids := deleted[KindPermission]; len(ids) > 0 {
+		if err := a.unlink(ids, "role_permissions.permission_id"); err != nil {
+			return err
+		}
+		if err := a.tx.Delete(&models.Permission{}, ids).Error; err != nil {
+			return fmt.Errorf("删除权限失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// unlink 从关联表中删除引用了ids的记录，columns形如 "user_roles.user_id"
+func (a *applier) unlink(ids []uint, columns ...string) error {
+	for _, column := range columns {
+		table, col, _ := strings.Cut(column, ".")
+		if err := a.tx.Exec("DELETE FROM "+table+" WHERE "+col+" IN ?", ids).Error; err != nil {
+			return fmt.Errorf("清除%s失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// ids 将标识列表转换为记录ID
+func (a *applier) ids(kind string, keys []string) []uint {
+	ids := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		if id := a.id(kind, key); id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// byDepth 按路径层级排序，保证上级先于下级写入
+func byDepth[T any](items []T, key func(T) string) []T {
+	sorted := append([]T(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.Count(key(sorted[i]), pathSep) < strings.Count(key(sorted[j]), pathSep)
+	})
+	return sorted
+}
@@ -0,0 +1,452 @@
+package rbacsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// 实体类型
+const (
+	KindPermission = "permission"
+	KindMenu       = "menu"
+	KindRole       = "role"
+	KindDepartment = "department"
+	KindDict       = "dict"
+	KindDictItem   = "dict_item"
+	KindUser       = "user"
+	KindBinding    = "binding"
+)
+
+var kindNames = map[string]string{
+	KindPermission: "权限",
+	KindMenu:       "菜单",
+	KindRole:       "角色",
+	KindDepartment: "部门",
+	KindDict:       "字典",
+	KindDictItem:   "字典项",
+	KindUser:       "用户",
+	KindBinding:    "角色绑定",
+}
+
+// 变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change 单条变更
+type Change struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"`
+	Key    string   `json:"key"`
+	Fields []string `json:"fields,omitempty"` // 更新时变化的字段
+}
+
+// Plan 导入计划
+type Plan struct {
+	Changes []Change `json:"changes"`
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Deleted int      `json:"deleted"`
+
+	index map[string]int
+}
+
+// Empty 计划中是否没有变更
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String 返回可读的计划摘要
+func (p *Plan) String() string {
+	return fmt.Sprintf("新增%d项, 更新%d项, 删除%d项", p.Created, p.Updated, p.Deleted)
+}
+
+func (p *Plan) add(c Change) {
+	if p.index == nil {
+		p.index = make(map[string]int)
+	}
+	p.index[refKey(c.Kind, c.Key)] = len(p.Changes)
+	p.Changes = append(p.Changes, c)
+	switch c.Action {
+	case ActionCreate:
+		p.Created++
+	case ActionUpdate:
+		p.Updated++
+	case ActionDelete:
+		p.Deleted++
+	}
+}
+
+// change 返回实体的变更，没有变更时返回nil
+func (p *Plan) change(kind, key string) *Change {
+	if i, ok := p.index[refKey(kind, key)]; ok {
+		return &p.Changes[i]
+	}
+	return nil
+}
+
+// changed 实体是否新建，或者更新了fields中的任一字段；fields为空时表示任意字段
+func (p *Plan) changed(kind, key string, fields ...string) bool {
+	c := p.change(kind, key)
+	if c == nil || c.Action == ActionDelete {
+		return false
+	}
+	if c.Action == ActionCreate || len(fields) == 0 {
+		return true
+	}
+	for _, f := range c.Fields {
+		for _, want := range fields {
+			if f == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Options 导入选项
+type Options struct {
+	// Prune 删除数据库中存在但快照中未列出的实体，只作用于快照中出现的分组
+	// 例如快照没有users分组时不会删除任何用户，而 "users: []" 会删除全部用户
+	Prune bool
+}
+
+// Diff 比较快照和数据库，返回导入计划，不修改数据
+func Diff(db *gorm.DB, snap *Snapshot, opts Options) (*Plan, error) {
+	st, err := load(db)
+	if err != nil {
+		return nil, err
+	}
+	return st.diff(snap, opts)
+}
+
+// diff 生成导入计划
+// 未启用Prune时快照中未列出的实体保持不变；已列出角色和用户的关联、已列出字典的字典项总是以快照为准
+func (st *state) diff(snap *Snapshot, opts Options) (*Plan, error) {
+	if err := st.checkRefs(snap, opts); err != nil {
+		return nil, err
+	}
+	plan := &Plan{Changes: []Change{}}
+
+	current := make(map[string]interface{})
+	for _, p := range st.snap.Permissions {
+		current[refKey(KindPermission, p.Key)] = p
+	}
+	for _, m := range st.snap.Menus {
+		current[refKey(KindMenu, m.Key)] = m
+	}
+	for _, r := range st.snap.Roles {
+		current[refKey(KindRole, r.Key)] = r
+	}
+	for _, d := range st.snap.Departments {
+		current[refKey(KindDepartment, d.Key)] = d
+	}
+	currentItems := make(map[string]DictItem)
+	for _, d := range st.snap.Dicts {
+		current[refKey(KindDict, d.Key)] = d
+		for _, item := range d.Items {
+			currentItems[d.Key+"/"+item.Value] = item
+		}
+	}
+	for _, u := range st.snap.Users {
+		current[refKey(KindUser, u.Username)] = u
+	}
+
+	compare := func(kind, key string, want interface{}, skip ...string) error {
+		have, ok := current[refKey(kind, key)]
+		if !ok {
+			plan.add(Change{Action: ActionCreate, Kind: kind, Key: key})
+			return nil
+		}
+		fields, err := diffFields(have, want, skip...)
+		if err != nil {
+			return err
+		}
+		if len(fields) > 0 {
+			plan.add(Change{Action: ActionUpdate, Kind: kind, Key: key, Fields: fields})
+		}
+		return nil
+	}
+
+	for _, p := range snap.Permissions {
+		if err := compare(KindPermission, p.Key, p); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range snap.Menus {
+		if err := compare(KindMenu, m.Key, m); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range snap.Roles {
+		if err := compare(KindRole, r.Key, r); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range snap.Departments {
+		if err := compare(KindDepartment, d.Key, d); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range snap.Dicts {
+		if err := compare(KindDict, d.Key, d, "items"); err != nil {
+			return nil, err
+		}
+		listed := make(map[string]bool)
+		for _, item := range d.Items {
+			key := d.Key + "/" + item.Value
+			listed[key] = true
+			have, ok := currentItems[key]
+			if !ok {
+				plan.add(Change{Action: ActionCreate, Kind: KindDictItem, Key: key})
+				continue
+			}
+			fields, err := diffFields(have, item)
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) > 0 {
+				plan.add(Change{Action: ActionUpdate, Kind: KindDictItem, Key: key, Fields: fields})
+			}
+		}
+		if have, ok := current[refKey(KindDict, d.Key)]; ok {
+			for _, item := range have.(Dict).Items {
+				if key := d.Key + "/" + item.Value; !listed[key] {
+					plan.add(Change{Action: ActionDelete, Kind: KindDictItem, Key: key})
+				}
+			}
+		}
+	}
+	for _, u := range snap.Users {
+		if err := compare(KindUser, u.Username, u); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range snap.Bindings {
+		have := current[refKey(KindUser, b.Username)].(User)
+		if !reflect.DeepEqual(have.Roles, b.Roles) {
+			plan.add(Change{Action: ActionUpdate, Kind: KindBinding, Key: b.Username, Fields: []string{"roles"}})
+		}
+	}
+
+	if opts.Prune {
+		st.prune(snap, plan)
+	}
+	return plan, nil
+}
+
+// prune 为快照中出现的分组生成删除未列出实体的变更
+func (st *state) prune(snap *Snapshot, plan *Plan) {
+	listed := make(map[string]bool)
+	mark := func(kind, key string) { listed[refKey(kind, key)] = true }
+	remove := func(kind, key string) {
+		if !listed[refKey(kind, key)] {
+			plan.add(Change{Action: ActionDelete, Kind: kind, Key: key})
+		}
+	}
+
+	if snap.Users != nil {
+		for _, u := range snap.Users {
+			mark(KindUser, u.Username)
+		}
+		for _, u := range st.snap.Users {
+			remove(KindUser, u.Username)
+		}
+	}
+	if snap.Bindings != nil {
+		for _, u := range snap.Users {
+			mark(KindBinding, u.Username)
+		}
+		for _, b := range snap.Bindings {
+			mark(KindBinding, b.Username)
+		}
+		// 未列出的用户解除全部角色，将被删除的用户不再单独处理
+		for _, u := range st.snap.Users {
+			if len(u.Roles) > 0 && plan.change(KindUser, u.Username) == nil {
+				remove(KindBinding, u.Username)
+			}
+		}
+	}
+	if snap.Dicts != nil {
+		for _, d := range snap.Dicts {
+			mark(KindDict, d.Key)
+		}
+		for _, d := range st.snap.Dicts {
+			remove(KindDict, d.Key)
+		}
+	}
+	if snap.Departments != nil {
+		for _, d := range snap.Departments {
+			mark(KindDepartment, d.Key)
+		}
+		for _, d := range st.snap.Departments {
+			remove(KindDepartment, d.Key)
+		}
+	}
+	if snap.Roles != nil {
+		for _, r := range snap.Roles {
+			mark(KindRole, r.Key)
+		}
+		for _, r := range st.snap.Roles {
+			remove(KindRole, r.Key)
+		}
+	}
+	if snap.Menus != nil {
+		for _, m := range snap.Menus {
+			mark(KindMenu, m.Key)
+		}
+		for _, m := range st.snap.Menus {
+			remove(KindMenu, m.Key)
+		}
+	}
+	if snap.Permissions != nil {
+		for _, p := range snap.Permissions {
+			mark(KindPermission, p.Key)
+		}
+		for _, p := range st.snap.Permissions {
+			remove(KindPermission, p.Key)
+		}
+	}
+}
+
+// checkRefs 检查快照中引用的标识存在于快照或数据库中
+// 启用Prune时，快照中出现的分组只以快照为准，数据库中未列出的实体将被删除，不能被引用
+func (st *state) checkRefs(snap *Snapshot, opts Options) error {
+	exists := make(map[string]bool)
+	keep := func(listed bool) bool { return !opts.Prune || !listed }
+	if keep(snap.Permissions != nil) {
+		for _, p := range st.snap.Permissions {
+			exists[refKey(KindPermission, p.Key)] = true
+		}
+	}
+	for _, p := range snap.Permissions {
+		exists[refKey(KindPermission, p.Key)] = true
+	}
+	if keep(snap.Menus != nil) {
+		for _, m := range st.snap.Menus {
+			exists[refKey(KindMenu, m.Key)] = true
+		}
+	}
+	for _, m := range snap.Menus {
+		exists[refKey(KindMenu, m.Key)] = true
+	}
+	if keep(snap.Roles != nil) {
+		for _, r := range st.snap.Roles {
+			exists[refKey(KindRole, r.Key)] = true
+		}
+	}
+	for _, r := range snap.Roles {
+		exists[refKey(KindRole, r.Key)] = true
+	}
+	if keep(snap.Departments != nil) {
+		for _, d := range st.snap.Departments {
+			exists[refKey(KindDepartment, d.Key)] = true
+		}
+	}
+	for _, d := range snap.Departments {
+		exists[refKey(KindDepartment, d.Key)] = true
+	}
+	// 绑定只维护已有用户的角色
+	if keep(snap.Users != nil) {
+		for _, u := range st.snap.Users {
+			exists[refKey(KindUser, u.Username)] = true
+		}
+	}
+
+	need := func(kind, key, owner string) error {
+		if key != "" && !exists[refKey(kind, key)] {
+			return fmt.Errorf("%s引用的%s不存在: %s", owner, kindNames[kind], key)
+		}
+		return nil
+	}
+	for _, p := range snap.Permissions {
+		if err := need(KindPermission, p.Parent, "权限 "+p.Key); err != nil {
+			return err
+		}
+	}
+	for _, m := range snap.Menus {
+		parent, _ := parentPath(m.Key)
+		if err := need(KindMenu, parent, "菜单 "+m.Key); err != nil {
+			return err
+		}
+	}
+	for _, r := range snap.Roles {
+		for _, key := range r.Permissions {
+			if err := need(KindPermission, key, "角色 "+r.Key); err != nil {
+				return err
+			}
+		}
+		for _, key := range r.Menus {
+			if err := need(KindMenu, key, "角色 "+r.Key); err != nil {
+				return err
+			}
+		}
+	}
+	for _, d := range snap.Departments {
+		parent, _ := parentPath(d.Key)
+		if err := need(KindDepartment, parent, "部门 "+d.Key); err != nil {
+			return err
+		}
+	}
+	for _, u := range snap.Users {
+		if err := need(KindDepartment, u.Department, "用户 "+u.Username); err != nil {
+			return err
+		}
+		for _, key := range u.Roles {
+			if err := need(KindRole, key, "用户 "+u.Username); err != nil {
+				return err
+			}
+		}
+	}
+	for _, b := range snap.Bindings {
+		if err := need(KindUser, b.Username, "角色绑定"); err != nil {
+			return err
+		}
+		for _, key := range b.Roles {
+			if err := need(KindRole, key, "用户 "+b.Username+" 的角色绑定"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffFields 按JSON字段比较两个实体，返回不同的字段名
+func diffFields(have, want interface{}, skip ...string) ([]string, error) {
+	a, err := toMap(have)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toMap(want)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range skip {
+		delete(a, f)
+		delete(b, f)
+	}
+	var fields []string
+	for f, v := range b {
+		if !reflect.DeepEqual(a[f], v) {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
@@ -0,0 +1,409 @@
+package rbacsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/userstate"
+)
+
+// Version 当前快照格式版本
+const Version = 1
+
+// 快照文件格式
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// pathSep 菜单和部门路径分隔符
+// 菜单和部门没有唯一标识，按从根节点开始的名称路径匹配，如 "系统管理/用户管理"
+const pathSep = "/"
+
+// Snapshot RBAC配置快照
+// 各实体按标识匹配而不是按ID，可在不同环境之间迁移；用户不包含密码
+type Snapshot struct {
+	Version     int          `json:"version" yaml:"version"`
+	ExportedAt  time.Time    `json:"exported_at" yaml:"exported_at"`
+	Permissions []Permission `json:"permissions" yaml:"permissions"`
+	Menus       []Menu       `json:"menus" yaml:"menus"`
+	Roles       []Role       `json:"roles" yaml:"roles"`
+	Departments []Department `json:"departments" yaml:"departments"`
+	Dicts       []Dict       `json:"dicts" yaml:"dicts"`
+	Users       []User       `json:"users" yaml:"users"`
+	Bindings    []Binding    `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// Permission 权限，按Key匹配
+type Permission struct {
+	Key         string `json:"key" yaml:"key"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Type        string `json:"type" yaml:"type"`
+	Method      string `json:"method" yaml:"method"`
+	Path        string `json:"path" yaml:"path"`
+	Component   string `json:"component" yaml:"component"`
+	Icon        string `json:"icon" yaml:"icon"`
+	Status      int    `json:"status" yaml:"status"`
+	Sort        int    `json:"sort" yaml:"sort"`
+	Parent      string `json:"parent,omitempty" yaml:"parent,omitempty"` // 上级权限Key
+}
+
+// Menu 菜单，按名称路径匹配，上级菜单由路径决定
+type Menu struct {
+	Key        string   `json:"key" yaml:"key"`
+	Path       string   `json:"path" yaml:"path"`
+	Component  string   `json:"component" yaml:"component"`
+	Redirect   string   `json:"redirect" yaml:"redirect"`
+	Icon       string   `json:"icon" yaml:"icon"`
+	Type       string   `json:"type" yaml:"type"`
+	Permission string   `json:"permission" yaml:"permission"`
+	Sort       int      `json:"sort" yaml:"sort"`
+	Status     int      `json:"status" yaml:"status"`
+	Hidden     int      `json:"hidden" yaml:"hidden"`
+	KeepAlive  int      `json:"keep_alive" yaml:"keep_alive"`
+	AlwaysShow int      `json:"always_show" yaml:"always_show"`
+	Breadcrumb int      `json:"breadcrumb" yaml:"breadcrumb"`
+	Affix      int      `json:"affix" yaml:"affix"`
+	NoCache    int      `json:"no_cache" yaml:"no_cache"`
+	Meta       MenuMeta `json:"meta" yaml:"meta"`
+}
+
+// MenuMeta 菜单元数据
+type MenuMeta struct {
+	Title      string `json:"title" yaml:"title"`
+	Icon       string `json:"icon" yaml:"icon"`
+	NoCache    bool   `json:"no_cache" yaml:"no_cache"`
+	Breadcrumb bool   `json:"breadcrumb" yaml:"breadcrumb"`
+	Affix      bool   `json:"affix" yaml:"affix"`
+	ActiveMenu string `json:"active_menu" yaml:"active_menu"`
+	Roles      string `json:"roles" yaml:"roles"`
+}
+
+// Role 角色，按Key匹配，权限和菜单以标识列出
+type Role struct {
+	Key         string   `json:"key" yaml:"key"`
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Status      int      `json:"status" yaml:"status"`
+	Sort        int      `json:"sort" yaml:"sort"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+	Menus       []string `json:"menus" yaml:"menus"`
+}
+
+// Department 部门，按名称路径匹配，上级部门由路径决定
+type Department struct {
+	Key    string `json:"key" yaml:"key"`
+	Sort   int    `json:"sort" yaml:"sort"`
+	Status int    `json:"status" yaml:"status"`
+}
+
+// Dict 字典，按Key匹配，字典项按Value匹配
+type Dict struct {
+	Key         string     `json:"key" yaml:"key"`
+	Name        string     `json:"name" yaml:"name"`
+	Description string     `json:"description" yaml:"description"`
+	Status      int        `json:"status" yaml:"status"`
+	Sort        int        `json:"sort" yaml:"sort"`
+	Items       []DictItem `json:"items" yaml:"items"`
+}
+
+// DictItem 字典项
+type DictItem struct {
+	Value       string `json:"value" yaml:"value"`
+	Label       string `json:"label" yaml:"label"`
+	Description string `json:"description" yaml:"description"`
+	Status      int    `json:"status" yaml:"status"`
+	Sort        int    `json:"sort" yaml:"sort"`
+}
+
+// User 用户，按用户名匹配，不包含密码
+type User struct {
+	Username   string   `json:"username" yaml:"username"`
+	Nickname   string   `json:"nickname" yaml:"nickname"`
+	Email      string   `json:"email" yaml:"email"`
+	Phone      string   `json:"phone" yaml:"phone"`
+	Avatar     string   `json:"avatar" yaml:"avatar"`
+	Status     int      `json:"status" yaml:"status"`
+	Gender     int      `json:"gender" yaml:"gender"`
+	IsAdmin    bool     `json:"is_admin" yaml:"is_admin"`
+	Department string   `json:"department" yaml:"department"` // 部门路径
+	Roles      []string `json:"roles" yaml:"roles"`           // 角色Key
+}
+
+// Binding 用户角色绑定，只维护已有用户的角色，不修改用户资料
+// 同一用户不能同时出现在users和bindings中
+type Binding struct {
+	Username string   `json:"username" yaml:"username"`
+	Roles    []string `json:"roles" yaml:"roles"` // 角色Key
+}
+
+// FormatOf 根据文件名判断快照格式，默认为YAML
+func FormatOf(name string) string {
+	if strings.ToLower(filepath.Ext(name)) == ".json" {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Encode 将快照编码为YAML或JSON
+func Encode(snap *Snapshot, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(snap, "", "  ")
+	case FormatYAML, "":
+		return yaml.Marshal(snap)
+	default:
+		return nil, fmt.Errorf("不支持的快照格式: %s", format)
+	}
+}
+
+// Decode 解析快照并检查版本和标识唯一性
+func Decode(data []byte, format string) (*Snapshot, error) {
+	var snap Snapshot
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &snap)
+	case FormatYAML, "":
+		err = yaml.Unmarshal(data, &snap)
+	default:
+		return nil, fmt.Errorf("不支持的快照格式: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("快照解析失败: %w", err)
+	}
+	if snap.Version == 0 {
+		return nil, fmt.Errorf("快照缺少版本号")
+	}
+	if snap.Version > Version {
+		return nil, fmt.Errorf("不支持的快照版本: %d，当前最高支持%d", snap.Version, Version)
+	}
+	snap.normalize()
+	if err := snap.validate(); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// normalize 去除标识两端空白、对引用列表排序，并为省略的字段填充与模型一致的默认值
+// 手写的声明式文件可以只写关心的字段
+func (s *Snapshot) normalize() {
+	for i := range s.Permissions {
+		p := &s.Permissions[i]
+		p.Key = strings.TrimSpace(p.Key)
+		p.Parent = strings.TrimSpace(p.Parent)
+		p.Type = orDefault(p.Type, "api")
+		p.Status = statusOrDefault(p.Status)
+	}
+	for i := range s.Menus {
+		m := &s.Menus[i]
+		m.Key = cleanPath(m.Key)
+		m.Type = orDefault(m.Type, "menu")
+		m.Status = statusOrDefault(m.Status)
+		m.Hidden = flagOrDefault(m.Hidden, 2)
+		m.KeepAlive = flagOrDefault(m.KeepAlive, 1)
+		m.AlwaysShow = flagOrDefault(m.AlwaysShow, 2)
+		m.Breadcrumb = flagOrDefault(m.Breadcrumb, 1)
+		m.Affix = flagOrDefault(m.Affix, 2)
+		m.NoCache = flagOrDefault(m.NoCache, 2)
+	}
+	for i := range s.Roles {
+		r := &s.Roles[i]
+		r.Key = strings.TrimSpace(r.Key)
+		r.Status = statusOrDefault(r.Status)
+		r.Permissions = sortedKeys(r.Permissions)
+		r.Menus = sortedKeys(r.Menus, cleanPath)
+	}
+	for i := range s.Departments {
+		s.Departments[i].Key = cleanPath(s.Departments[i].Key)
+		s.Departments[i].Status = statusOrDefault(s.Departments[i].Status)
+	}
+	for i := range s.Dicts {
+		d := &s.Dicts[i]
+		d.Key = strings.TrimSpace(d.Key)
+		d.Status = statusOrDefault(d.Status)
+		if d.Items == nil {
+			d.Items = []DictItem{}
+		}
+		for j := range d.Items {
+			d.Items[j].Status = statusOrDefault(d.Items[j].Status)
+		}
+	}
+	for i := range s.Users {
+		u := &s.Users[i]
+		u.Username = strings.TrimSpace(u.Username)
+		u.Status = statusOrDefault(u.Status)
+		u.Department = cleanPath(u.Department)
+		u.Roles = sortedKeys(u.Roles)
+	}
+	for i := range s.Bindings {
+		s.Bindings[i].Username = strings.TrimSpace(s.Bindings[i].Username)
+		s.Bindings[i].Roles = sortedKeys(s.Bindings[i].Roles)
+	}
+}
+
+// statusOrDefault 状态省略时默认为正常
+func statusOrDefault(status int) int {
+	return flagOrDefault(status, 1)
+}
+
+// flagOrDefault 取值为1/2的开关字段省略时使用默认值
+func flagOrDefault(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// validate 检查必填标识和重复项
+func (s *Snapshot) validate() error {
+	seen := make(map[string]bool)
+	check := func(kind, key string) error {
+		if key == "" {
+			return fmt.Errorf("%s缺少标识", kindNames[kind])
+		}
+		if seen[refKey(kind, key)] {
+			return fmt.Errorf("%s标识重复: %s", kindNames[kind], key)
+		}
+		seen[refKey(kind, key)] = true
+		return nil
+	}
+	for _, p := range s.Permissions {
+		if err := check(KindPermission, p.Key); err != nil {
+			return err
+		}
+	}
+	for _, m := range s.Menus {
+		if err := check(KindMenu, m.Key); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.Roles {
+		if err := check(KindRole, r.Key); err != nil {
+			return err
+		}
+	}
+	for _, d := range s.Departments {
+		if err := check(KindDepartment, d.Key); err != nil {
+			return err
+		}
+	}
+	for _, d := range s.Dicts {
+		if err := check(KindDict, d.Key); err != nil {
+			return err
+		}
+		for _, item := range d.Items {
+			if err := check(KindDictItem, d.Key+"/"+item.Value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, u := range s.Users {
+		if err := check(KindUser, u.Username); err != nil {
+			return err
+		}
+		if !userstate.Valid(u.Status) {
+			return fmt.Errorf("用户 %s 状态无效: %d", u.Username, u.Status)
+		}
+	}
+	for _, b := range s.Bindings {
+		if seen[refKey(KindUser, b.Username)] {
+			return fmt.Errorf("用户 %s 同时出现在users和bindings中", b.Username)
+		}
+		if err := check(KindBinding, b.Username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export 导出当前数据库中的RBAC配置
+func Export(db *gorm.DB) (*Snapshot, error) {
+	st, err := load(db)
+	if err != nil {
+		return nil, err
+	}
+	snap := st.snap
+	snap.ExportedAt = time.Now()
+	return snap, nil
+}
+
+// cleanPath 规范化名称路径，去除各段两端空白和空段
+func cleanPath(path string) string {
+	var parts []string
+	for _, part := range strings.Split(path, pathSep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, pathSep)
+}
+
+// parentPath 返回名称路径的上级路径和末段名称
+func parentPath(path string) (string, string) {
+	i := strings.LastIndex(path, pathSep)
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+// sortedKeys 去重并排序标识列表，空列表返回非nil切片
+func sortedKeys(keys []string, clean ...func(string) string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if len(clean) > 0 {
+			key = clean[0](key)
+		}
+		if key != "" && !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// menuFromModel 数据库菜单转为快照菜单
+func menuFromModel(key string, m *models.Menu) Menu {
+	return Menu{
+		Key: key, Path: m.Path, Component: m.Component, Redirect: m.Redirect, Icon: m.Icon,
+		Type: m.Type, Permission: m.Permission, Sort: m.Sort, Status: m.Status, Hidden: m.Hidden,
+		KeepAlive: m.KeepAlive, AlwaysShow: m.AlwaysShow, Breadcrumb: m.Breadcrumb, Affix: m.Affix, NoCache: m.NoCache,
+		Meta: MenuMeta{
+			Title: m.Meta.Title, Icon: m.Meta.Icon, NoCache: m.Meta.NoCache, Breadcrumb: m.Meta.Breadcrumb,
+			Affix: m.Meta.Affix, ActiveMenu: m.Meta.ActiveMenu, Roles: m.Meta.Roles,
+		},
+	}
+}
+
+// toModel 快照菜单转为数据库菜单
+func (m Menu) toModel(parentID uint) models.Menu {
+	_, name := parentPath(m.Key)
+	return models.Menu{
+		Name: name, Path: m.Path, Component: m.Component, Redirect: m.Redirect, Icon: m.Icon,
+		Type: m.Type, Permission: m.Permission, Sort: m.Sort, ParentID: parentID, Status: m.Status, Hidden: m.Hidden,
+		KeepAlive: m.KeepAlive, AlwaysShow: m.AlwaysShow, Breadcrumb: m.Breadcrumb, Affix: m.Affix, NoCache: m.NoCache,
+		Meta: models.MenuMeta{
+			Title: m.Meta.Title, Icon: m.Meta.Icon, NoCache: m.Meta.NoCache, Breadcrumb: m.Meta.Breadcrumb,
+			Affix: m.Meta.Affix, ActiveMenu: m.Meta.ActiveMenu, Roles: m.Meta.Roles,
+		},
+	}
+}
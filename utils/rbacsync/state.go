@@ -0,0 +1,305 @@
+package rbacsync
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"rbac_admin_server/models"
+)
+
+// ref 标识对应的数据库记录
+type ref struct {
+	ID      uint
+	Deleted bool // 已软删除，导入时恢复
+}
+
+// state 数据库当前状态
+// snap只包含未删除的记录，refs还包含带唯一索引的已删除记录，导入同名记录时恢复已删除的记录
+type state struct {
+	snap *Snapshot
+	refs map[string]ref
+}
+
+func refKey(kind, key string) string {
+	return kind + "\x00" + key
+}
+
+func (st *state) ref(kind, key string) (ref, bool) {
+	r, ok := st.refs[refKey(kind, key)]
+	return r, ok
+}
+
+func (st *state) setRef(kind, key string, id uint) {
+	st.refs[refKey(kind, key)] = ref{ID: id}
+}
+
+// addRef 记录可能已删除的记录，同一标识已有未删除记录时优先保留未删除的
+func (st *state) addRef(kind, key string, id uint, deleted gorm.DeletedAt) {
+	if r, ok := st.ref(kind, key); ok && !r.Deleted && deleted.Valid {
+		return
+	}
+	st.refs[refKey(kind, key)] = ref{ID: id, Deleted: deleted.Valid}
+}
+
+// load 读取数据库中的RBAC配置
+func load(db *gorm.DB) (*state, error) {
+	st := &state{snap: &Snapshot{Version: Version}, refs: make(map[string]ref)}
+
+	permKeys, err := st.loadPermissions(db)
+	if err != nil {
+		return nil, err
+	}
+	menuKeys, err := st.loadMenus(db)
+	if err != nil {
+		return nil, err
+	}
+	roleKeys, err := st.loadRoles(db, permKeys, menuKeys)
+	if err != nil {
+		return nil, err
+	}
+	deptKeys, err := st.loadDepartments(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.loadDicts(db); err != nil {
+		return nil, err
+	}
+	if err := st.loadUsers(db, roleKeys, deptKeys); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// loadPermissions 读取权限，返回未删除权限的ID到Key映射
+func (st *state) loadPermissions(db *gorm.DB) (map[uint]string, error) {
+	var permissions []models.Permission
+	if err := db.Unscoped().Order("id").Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("查询权限失败: %w", err)
+	}
+	keys := make(map[uint]string)
+	for _, p := range permissions {
+		st.addRef(KindPermission, p.Key, p.ID, p.DeletedAt)
+		if !p.DeletedAt.Valid {
+			keys[p.ID] = p.Key
+		}
+	}
+	st.snap.Permissions = []Permission{}
+	for _, p := range permissions {
+		if p.DeletedAt.Valid {
+			continue
+		}
+		st.snap.Permissions = append(st.snap.Permissions, Permission{
+			Key: p.Key, Name: p.Name, Description: p.Description, Type: p.Type, Method: p.Method,
+			Path: p.Path, Component: p.Component, Icon: p.Icon, Status: p.Status, Sort: p.Sort,
+			Parent: keys[p.ParentID],
+		})
+	}
+	sort.Slice(st.snap.Permissions, func(i, j int) bool { return st.snap.Permissions[i].Key < st.snap.Permissions[j].Key })
+	return keys, nil
+}
+
+// loadMenus 读取菜单，返回ID到名称路径映射
+func (st *state) loadMenus(db *gorm.DB) (map[uint]string, error) {
+	var menus []models.Menu
+	if err := db.Order("id").Find(&menus).Error; err != nil {
+		return nil, fmt.Errorf("查询菜单失败: %w", err)
+	}
+	nodes := make([]node, len(menus))
+	for i, m := range menus {
+		nodes[i] = node{ID: m.ID, ParentID: m.ParentID, Name: m.Name}
+	}
+	keys, err := buildPaths(KindMenu, nodes)
+	if err != nil {
+		return nil, err
+	}
+	st.snap.Menus = []Menu{}
+	for i := range menus {
+		key := keys[menus[i].ID]
+		st.setRef(KindMenu, key, menus[i].ID)
+		st.snap.Menus = append(st.snap.Menus, menuFromModel(key, &menus[i]))
+	}
+	sort.Slice(st.snap.Menus, func(i, j int) bool { return st.snap.Menus[i].Key < st.snap.Menus[j].Key })
+	return keys, nil
+}
+
+// loadRoles 读取角色及其权限和菜单，返回未删除角色的ID到Key映射
+func (st *state) loadRoles(db *gorm.DB, permKeys, menuKeys map[uint]string) (map[uint]string, error) {
+	var roles []models.Role
+	if err := db.Unscoped().Order("id").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("查询角色失败: %w", err)
+	}
+	var rolePermissions []models.RolePermission
+	if err := db.Find(&rolePermissions).Error; err != nil {
+		return nil, fmt.Errorf("查询角色权限失败: %w", err)
+	}
+	var roleMenus []models.RoleMenu
+	if err := db.Find(&roleMenus).Error; err != nil {
+		return nil, fmt.Errorf("查询角色菜单失败: %w", err)
+	}
+	perms := make(map[uint][]string)
+	for _, rp := range rolePermissions {
+		if key, ok := permKeys[rp.PermissionID]; ok {
+			perms[rp.RoleID] = append(perms[rp.RoleID], key)
+		}
+	}
+	menus := make(map[uint][]string)
+	for _, rm := range roleMenus {
+		if key, ok := menuKeys[rm.MenuID]; ok {
+			menus[rm.RoleID] = append(menus[rm.RoleID], key)
+		}
+	}
+
+	keys := make(map[uint]string)
+	st.snap.Roles = []Role{}
+	for _, r := range roles {
+		st.addRef(KindRole, r.Key, r.ID, r.DeletedAt)
+		if r.DeletedAt.Valid {
+			continue
+		}
+		keys[r.ID] = r.Key
+		st.snap.Roles = append(st.snap.Roles, Role{
+			Key: r.Key, Name: r.Name, Description: r.Description, Status: r.Status, Sort: r.Sort,
+			Permissions: sortedKeys(perms[r.ID]), Menus: sortedKeys(menus[r.ID]),
+		})
+	}
+	sort.Slice(st.snap.Roles, func(i, j int) bool { return st.snap.Roles[i].Key < st.snap.Roles[j].Key })
+	return keys, nil
+}
+
+// loadDepartments 读取部门，返回ID到名称路径映射
+func (st *state) loadDepartments(db *gorm.DB) (map[uint]string, error) {
+	var departments []models.Department
+	if err := db.Order("id").Find(&departments).Error; err != nil {
+		return nil, fmt.Errorf("查询部门失败: %w", err)
+	}
+	nodes := make([]node, len(departments))
+	for i, d := range departments {
+		nodes[i] = node{ID: d.ID, ParentID: d.ParentID, Name: d.Name}
+	}
+	keys, err := buildPaths(KindDepartment, nodes)
+	if err != nil {
+		return nil, err
+	}
+	st.snap.Departments = []Department{}
+	for _, d := range departments {
+		key := keys[d.ID]
+		st.setRef(KindDepartment, key, d.ID)
+		st.snap.Departments = append(st.snap.Departments, Department{Key: key, Sort: d.Sort, Status: d.Status})
+	}
+	sort.Slice(st.snap.Departments, func(i, j int) bool { return st.snap.Departments[i].Key < st.snap.Departments[j].Key })
+	return keys, nil
+}
+
+// loadDicts 读取字典和字典项
+func (st *state) loadDicts(db *gorm.DB) error {
+	var dicts []models.Dict
+	if err := db.Unscoped().Order("id").Find(&dicts).Error; err != nil {
+		return fmt.Errorf("查询字典失败: %w", err)
+	}
+	var items []models.DictItem
+	if err := db.Order("sort, id").Find(&items).Error; err != nil {
+		return fmt.Errorf("查询字典项失败: %w", err)
+	}
+	byDict := make(map[uint][]models.DictItem)
+	for _, item := range items {
+		byDict[item.DictID] = append(byDict[item.DictID], item)
+	}
+
+	st.snap.Dicts = []Dict{}
+	for _, d := range dicts {
+		st.addRef(KindDict, d.Key, d.ID, d.DeletedAt)
+		if d.DeletedAt.Valid {
+			continue
+		}
+		dict := Dict{Key: d.Key, Name: d.Name, Description: d.Description, Status: d.Status, Sort: d.Sort, Items: []DictItem{}}
+		for _, item := range byDict[d.ID] {
+			// 同一字典下Value重复时只保留第一项
+			if _, ok := st.ref(KindDictItem, d.Key+"/"+item.Value); ok {
+				continue
+			}
+			st.setRef(KindDictItem, d.Key+"/"+item.Value, item.ID)
+			dict.Items = append(dict.Items, DictItem{
+				Value: item.Value, Label: item.Label, Description: item.Description, Status: item.Status, Sort: item.Sort,
+			})
+		}
+		st.snap.Dicts = append(st.snap.Dicts, dict)
+	}
+	sort.Slice(st.snap.Dicts, func(i, j int) bool { return st.snap.Dicts[i].Key < st.snap.Dicts[j].Key })
+	return nil
+}
+
+// loadUsers 读取用户及其角色和部门
+func (st *state) loadUsers(db *gorm.DB, roleKeys, deptKeys map[uint]string) error {
+	var users []models.User
+	if err := db.Unscoped().Omit("Password").Order("id").Find(&users).Error; err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+	var userRoles []models.UserRole
+	if err := db.Find(&userRoles).Error; err != nil {
+		return fmt.Errorf("查询用户角色失败: %w", err)
+	}
+	roles := make(map[uint][]string)
+	for _, ur := range userRoles {
+		if key, ok := roleKeys[ur.RoleID]; ok {
+			roles[ur.UserID] = append(roles[ur.UserID], key)
+		}
+	}
+
+	st.snap.Users = []User{}
+	for _, u := range users {
+		st.addRef(KindUser, u.Username, u.ID, u.DeletedAt)
+		if u.DeletedAt.Valid {
+			continue
+		}
+		st.snap.Users = append(st.snap.Users, User{
+			Username: u.Username, Nickname: u.Nickname, Email: string(u.Email), Phone: string(u.Phone), Avatar: u.Avatar,
+			Status: u.Status, Gender: u.Gender, IsAdmin: u.IsAdmin, Department: deptKeys[u.DepartmentID],
+			Roles: sortedKeys(roles[u.ID]),
+		})
+	}
+	sort.Slice(st.snap.Users, func(i, j int) bool { return st.snap.Users[i].Username < st.snap.Users[j].Username })
+	return nil
+}
+
+// node 树节点
+type node struct {
+	ID       uint
+	ParentID uint
+	Name     string
+}
+
+// buildPaths 计算树节点的名称路径
+// 上级不存在的节点视为根节点；同级重名会导致路径无法区分，返回错误
+func buildPaths(kind string, nodes []node) (map[uint]string, error) {
+	byID := make(map[uint]node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	paths := make(map[uint]string, len(nodes))
+	var resolve func(id uint, depth int) string
+	resolve = func(id uint, depth int) string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		n := byID[id]
+		name := cleanPath(n.Name)
+		path := name
+		// 深度超过节点数说明存在循环引用，截断为根节点
+		if parent, ok := byID[n.ParentID]; ok && n.ParentID != id && depth < len(nodes) {
+			path = resolve(parent.ID, depth+1) + pathSep + name
+		}
+		paths[id] = path
+		return path
+	}
+
+	owners := make(map[string]uint, len(nodes))
+	for _, n := range nodes {
+		path := resolve(n.ID, 0)
+		if other, ok := owners[path]; ok {
+			return nil, fmt.Errorf("%s路径重复: %s (ID %d 和 %d)", kindNames[kind], path, other, n.ID)
+		}
+		owners[path] = n.ID
+	}
+	return paths, nil
+}
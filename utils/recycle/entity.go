@@ -0,0 +1,176 @@
+package recycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/usersettings"
+	"rbac_admin_server/utils/userstate"
+)
+
+// Entity 回收站实体
+type Entity struct {
+	Name   string             `json:"name"`  // 实体标识，用于接口路径
+	Label  string             `json:"label"` // 显示名称
+	Model  func() interface{} `json:"-"`     // 返回模型指针
+	Title  string             `json:"-"`     // 关键字搜索的列
+	Unique []Field            `json:"-"`     // 带唯一索引的列，恢复前检查是否已被占用
+	Parent string             `json:"-"`     // 上级ID列，恢复时上级必须存在
+	Links  []Link             `json:"-"`     // 删除时解除、恢复时重建的关联
+	// Cleanup 彻底删除前的清理，如删除物理文件
+	Cleanup func(db *gorm.DB, ids []uint) error `json:"-"`
+	// Changed 删除、恢复或彻底删除后调用，用于清除缓存
+	Changed func(id uint) `json:"-"`
+	// Grants 删除和恢复会解除或重建用户的角色、用户组关联，完成后同步Casbin继承关系
+	Grants bool `json:"-"`
+}
+
+// Field 唯一列
+type Field struct {
+	Column string
+	Label  string
+}
+
+// Link 多对多关联
+type Link struct {
+	Table      string // 关联表
+	Column     string // 关联表中本实体的列
+	Other      string // 关联表中对方的列
+	OtherTable string // 对方的表，恢复时只重建对方仍存在的关联
+}
+
+// entities 支持回收站的实体，按显示顺序排列
+var entities = []*Entity{
+	{
+		Name: "user", Label: "用户", Model: func() interface{} { return &models.User{} }, Title: "username",
+		Unique: []Field{{"username", "用户名"}, {"email", "邮箱"}, {"phone", "手机号"}},
+		Links: []Link{
+			{Table: "user_roles", Column: "user_id", Other: "role_id", OtherTable: "roles"},
+			{Table: "user_positions", Column: "user_id", Other: "position_id", OtherTable: "positions"},
+			{Table: "user_group_members", Column: "user_id", Other: "group_id", OtherTable: "user_groups"},
+		},
+		Cleanup: removeUserData,
+		Changed: userChanged,
+		Grants:  true,
+	},
+	{
+		Name: "role", Label: "角色", Model: func() interface{} { return &models.Role{} }, Title: "name",
+		Unique: []Field{{"name", "角色名称"}, {"key", "角色标识"}},
+		Links: []Link{
+			{Table: "role_permissions", Column: "role_id", Other: "permission_id", OtherTable: "permissions"},
+			{Table: "role_menus", Column: "role_id", Other: "menu_id", OtherTable: "menus"},
+			{Table: "user_roles", Column: "role_id", Other: "user_id", OtherTable: "users"},
+			{Table: "user_group_roles", Column: "role_id", Other: "group_id", OtherTable: "user_groups"},
+		},
+		Changed: grantsChanged,
+		Grants:  true,
+	},
+	{
+		Name: "permission", Label: "权限", Model: func() interface{} { return &models.Permission{} }, Title: "name",
+		Unique: []Field{{"key", "权限标识"}}, Parent: "parent_id",
+		Links:   []Link{{Table: "role_permissions", Column: "permission_id", Other: "role_id", OtherTable: "roles"}},
+		Changed: grantsChanged,
+	},
+	{
+		Name: "menu", Label: "菜单", Model: func() interface{} { return &models.Menu{} }, Title: "name",
+		Parent:  "parent_id",
+		Links:   []Link{{Table: "role_menus", Column: "menu_id", Other: "role_id", OtherTable: "roles"}},
+		Changed: grantsChanged,
+	},
+	{
+		Name: "department", Label: "部门", Model: func() interface{} { return &models.Department{} }, Title: "name",
+		Parent: "parent_id",
+	},
+	{
+		Name: "position", Label: "岗位", Model: func() interface{} { return &models.Position{} }, Title: "name",
+		Unique: []Field{{"code", "岗位编码"}},
+		Links:  []Link{{Table: "user_positions", Column: "position_id", Other: "user_id", OtherTable: "users"}},
+	},
+	{
+		Name: "user_group", Label: "用户组", Model: func() interface{} { return &models.UserGroup{} }, Title: "name",
+		Unique: []Field{{"code", "用户组编码"}}, Parent: "parent_id",
+		Links: []Link{
+			{Table: "user_group_members", Column: "group_id", Other: "user_id", OtherTable: "users"},
+			{Table: "user_group_roles", Column: "group_id", Other: "role_id", OtherTable: "roles"},
+		},
+		Changed: grantsChanged,
+		Grants:  true,
+	},
+	{
+		Name: "dict", Label: "字典", Model: func() interface{} { return &models.Dict{} }, Title: "name",
+		Unique: []Field{{"key", "字典标识"}},
+	},
+	{
+		Name: "file", Label: "文件", Model: func() interface{} { return &models.File{} }, Title: "name",
+		Cleanup: removeFiles,
+	},
+}
+
+// Entities 返回支持回收站的实体
+func Entities() []*Entity {
+	return entities
+}
+
+// Lookup 按标识查找实体
+func Lookup(name string) (*Entity, bool) {
+	for _, e := range entities {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Delete 按实体标识软删除记录，见Entity.Delete
+func Delete(db *gorm.DB, name string, id uint) error {
+	e, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("实体 %s 不支持回收站", name)
+	}
+	return e.Delete(db, id)
+}
+
+// userChanged 清除用户的状态和权限标识缓存
+func userChanged(id uint) {
+	userstate.Invalidate(id)
+	permcache.Invalidate(context.Background(), id)
+}
+
+// grantsChanged 角色、权限、菜单或用户组变更后清除全部用户的权限标识缓存
+func grantsChanged(uint) {
+	permcache.InvalidateAll(context.Background())
+}
+
+// removeUserData 删除用户的个人设置和所属部门
+// 所属部门在回收站期间保留，恢复后仍在原部门
+func removeUserData(db *gorm.DB, ids []uint) error {
+	if err := db.Where("user_id IN ?", ids).Delete(&models.UserSettings{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id IN ?", ids).Delete(&models.UserDepartment{}).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		usersettings.Invalidate(db.Statement.Context, id)
+	}
+	return nil
+}
+
+// removeFiles 删除文件记录对应的物理文件，文件已不存在时忽略
+func removeFiles(db *gorm.DB, ids []uint) error {
+	var paths []string
+	if err := db.Unscoped().Model(&models.File{}).Where("id IN ?", ids).Pluck("path", &paths).Error; err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package recycle
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+)
+
+// purgeBatchSize 自动清理每批删除的记录数
+const purgeBatchSize = 500
+
+// PurgeExpired 彻底删除超过保留天数的记录，返回各实体删除的数量
+func PurgeExpired(db *gorm.DB, days int) (map[string]int64, error) {
+	purged := make(map[string]int64)
+	if days <= 0 {
+		return purged, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	for _, e := range entities {
+		for {
+			var ids []uint
+			if err := db.Unscoped().Model(e.Model()).Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Limit(purgeBatchSize).Pluck("id", &ids).Error; err != nil {
+				return purged, err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			n, err := e.Purge(db, ids)
+			if err != nil {
+				return purged, err
+			}
+			purged[e.Name] += n
+			if len(ids) < purgeBatchSize {
+				break
+			}
+		}
+	}
+	return purged, nil
+}
+
+// StartPurgeTimer 启动回收站自动清理定时任务
+func StartPurgeTimer() {
+	cfg := global.Config.Recycle
+	if cfg.RetentionDays <= 0 || cfg.PurgeInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.PurgeInterval) * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := PurgeExpired(global.DB, cfg.RetentionDays)
+			if err != nil {
+				global.Logger.Errorf("清理回收站失败: %v", err)
+			}
+			for name, n := range purged {
+				if n > 0 {
+					global.Logger.Infof("回收站自动清理 %s %d 条", name, n)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,247 @@
+package recycle
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/search"
+	"rbac_admin_server/utils/usergroup"
+)
+
+// ErrNotDeleted 记录不存在或未被删除
+var ErrNotDeleted = errors.New("回收站中没有该记录")
+
+// ConflictError 恢复冲突
+type ConflictError struct {
+	Reasons []string
+}
+
+func (e *ConflictError) Error() string {
+	return "恢复冲突: " + strings.Join(e.Reasons, "；")
+}
+
+// Restored 恢复结果
+type Restored struct {
+	ID       uint `json:"id"`
+	Relinked int  `json:"relinked"` // 重建的关联数
+	Skipped  int  `json:"skipped"`  // 对方已删除而未重建的关联数
+}
+
+// Spec 回收站列表查询规则
+func (e *Entity) Spec() search.Spec {
+	return search.Spec{
+		Filters: map[string]search.Filter{
+			"keyword":    {Column: e.Title, Op: search.OpLike},
+			"deleted_at": {Column: "deleted_at", Op: search.OpDateRange},
+		},
+		Sorts:       map[string]string{"id": "id", "deleted_at": "deleted_at"},
+		DefaultSort: "deleted_at DESC",
+	}
+}
+
+// List 分页查询已删除的记录
+func (e *Entity) List(db *gorm.DB, q *search.Query) (*search.Page, error) {
+	list := reflect.New(reflect.SliceOf(reflect.TypeOf(e.Model()).Elem())).Interface()
+	return q.Find(db.Unscoped().Model(e.Model()).Where("deleted_at IS NOT NULL"), list)
+}
+
+// Delete 软删除记录，先解除多对多关联并保存到回收站，恢复时据此重建
+func (e *Entity) Delete(db *gorm.DB, id uint) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(e.Model(), id).Error; err != nil {
+			return err
+		}
+		for _, l := range e.Links {
+			var others []uint
+			if err := tx.Table(l.Table).Where(l.Column+" = ?", id).Pluck(l.Other, &others).Error; err != nil {
+				return fmt.Errorf("查询%s失败: %w", l.Table, err)
+			}
+			if len(others) == 0 {
+				continue
+			}
+			links := make([]models.RecycleLink, len(others))
+			for i, other := range others {
+				links[i] = models.RecycleLink{Entity: e.Name, RecordID: id, LinkTable: l.Table, OtherID: other}
+			}
+			if err := tx.Create(&links).Error; err != nil {
+				return fmt.Errorf("保存%s失败: %w", l.Table, err)
+			}
+			if err := tx.Exec("DELETE FROM "+l.Table+" WHERE "+l.Column+" = ?", id).Error; err != nil {
+				return fmt.Errorf("清除%s失败: %w", l.Table, err)
+			}
+		}
+		return tx.Delete(e.Model(), id).Error
+	})
+	if err == nil {
+		e.changed(db, id)
+	}
+	return err
+}
+
+// Restore 恢复已删除的记录并重建关联
+// 唯一值已被占用或上级已删除时返回ConflictError，不做任何修改
+func (e *Entity) Restore(db *gorm.DB, id uint) (*Restored, error) {
+	result := &Restored{ID: id}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		model := e.Model()
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(model, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotDeleted
+			}
+			return err
+		}
+		if err := e.checkConflicts(tx, model); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"deleted_at": nil}
+		if hasField(tx, model, "DeletedKey") {
+			updates["deleted_key"] = 0
+		}
+		if err := tx.Unscoped().Model(e.Model()).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		return e.relink(tx, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.changed(db, id)
+	return result, nil
+}
+
+// Purge 彻底删除回收站中的记录，返回删除的数量
+func (e *Entity) Purge(db *gorm.DB, ids []uint) (int64, error) {
+	var purged int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var deleted []uint
+		if err := tx.Unscoped().Model(e.Model()).Where("id IN ? AND deleted_at IS NOT NULL", ids).
+			Pluck("id", &deleted).Error; err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return nil
+		}
+		if e.Cleanup != nil {
+			if err := e.Cleanup(tx, deleted); err != nil {
+				return fmt.Errorf("清理%s失败: %w", e.Label, err)
+			}
+		}
+		if err := tx.Where("entity = ? AND record_id IN ?", e.Name, deleted).Delete(&models.RecycleLink{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Delete(e.Model(), deleted)
+		purged = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if e.Changed != nil {
+			e.Changed(id)
+		}
+	}
+	return purged, nil
+}
+
+// checkConflicts 检查唯一值和上级记录
+func (e *Entity) checkConflicts(tx *gorm.DB, model interface{}) error {
+	var reasons []string
+	for _, f := range e.Unique {
+		value := fieldValue(tx, model, f.Column)
+		if value == nil || fmt.Sprint(value) == "" {
+			continue
+		}
+		var owner struct{ ID uint }
+		err := tx.Model(e.Model()).Select("id").Where(clause.Eq{Column: clause.Column{Name: f.Column}, Value: value}).Take(&owner).Error
+		if err == nil {
+			reasons = append(reasons, fmt.Sprintf("%s %v 已被ID %d 使用", f.Label, value, owner.ID))
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if e.Parent != "" {
+		if parent, _ := fieldValue(tx, model, e.Parent).(uint); parent != 0 {
+			var count int64
+			if err := tx.Model(e.Model()).Where("id = ?", parent).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				reasons = append(reasons, fmt.Sprintf("上级%s(ID %d)已删除，请先恢复上级", e.Label, parent))
+			}
+		}
+	}
+	if len(reasons) > 0 {
+		return &ConflictError{Reasons: reasons}
+	}
+	return nil
+}
+
+// relink 重建删除时解除的关联，对方已删除的关联丢弃
+func (e *Entity) relink(tx *gorm.DB, result *Restored) error {
+	var saved []models.RecycleLink
+	if err := tx.Where("entity = ? AND record_id = ?", e.Name, result.ID).Find(&saved).Error; err != nil {
+		return err
+	}
+	for _, l := range e.Links {
+		var others []uint
+		for _, s := range saved {
+			if s.LinkTable == l.Table {
+				others = append(others, s.OtherID)
+			}
+		}
+		if len(others) == 0 {
+			continue
+		}
+		var alive []uint
+		if err := tx.Table(l.OtherTable).Where("id IN ? AND deleted_at IS NULL", others).Pluck("id", &alive).Error; err != nil {
+			return fmt.Errorf("查询%s失败: %w", l.OtherTable, err)
+		}
+		for _, other := range alive {
+			row := map[string]interface{}{l.Column: result.ID, l.Other: other}
+			if err := tx.Table(l.Table).Clauses(clause.OnConflict{DoNothing: true}).Create(row).Error; err != nil {
+				return fmt.Errorf("写入%s失败: %w", l.Table, err)
+			}
+		}
+		result.Relinked += len(alive)
+		result.Skipped += len(others) - len(alive)
+	}
+	return tx.Where("entity = ? AND record_id = ?", e.Name, result.ID).Delete(&models.RecycleLink{}).Error
+}
+
+// changed 删除或恢复后清除缓存，关联了用户角色或用户组的实体同步Casbin
+func (e *Entity) changed(db *gorm.DB, id uint) {
+	if e.Changed != nil {
+		e.Changed(id)
+	}
+	if e.Grants {
+		usergroup.Resync(db)
+	}
+}
+
+// fieldValue 按列名读取模型字段值
+func fieldValue(db *gorm.DB, model interface{}, column string) interface{} {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil
+	}
+	field := stmt.Schema.LookUpField(column)
+	if field == nil {
+		return nil
+	}
+	value, _ := field.ValueOf(db.Statement.Context, reflect.ValueOf(model).Elem())
+	return value
+}
+
+// hasField 判断模型是否包含指定字段
+func hasField(db *gorm.DB, model interface{}, name string) bool {
+	stmt := &gorm.Statement{DB: db}
+	return stmt.Parse(model) == nil && stmt.Schema.LookUpField(name) != nil
+}
@@ -0,0 +1,188 @@
+package registration
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/org"
+)
+
+// 注册模式
+const (
+	ModeDisabled = "disabled" // 关闭自助注册
+	ModeOpen     = "open"     // 开放注册
+	ModeDomain   = "domain"   // 只允许指定域名的邮箱注册
+	ModeInvite   = "invite"   // 凭邀请码注册
+	ModeApproval = "approval" // 注册后需管理员审核
+)
+
+var (
+	// ErrClosed 当前不开放注册
+	ErrClosed = errors.New("暂不开放注册")
+	// ErrDomain 邮箱域名不在允许范围内
+	ErrDomain = errors.New("该邮箱域名不允许注册")
+	// ErrInvitation 邀请码无效、已作废、已过期或已用完
+	ErrInvitation = errors.New("邀请码无效或已过期")
+)
+
+// Policy 公开的注册策略，供前端决定是否显示注册入口和邀请码输入框
+type Policy struct {
+	Mode           string   `json:"mode"`
+	InviteRequired bool     `json:"invite_required"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	NeedApproval   bool     `json:"need_approval"`
+}
+
+// Plan 按注册策略决定的新用户状态、角色和部门
+type Plan struct {
+	Status       int
+	RoleID       uint
+	DepartmentID uint
+	Invitation   *models.Invitation
+}
+
+// Mode 当前注册模式，未配置时为open
+func Mode() string {
+	if mode := global.Config.Register.Mode; mode != "" {
+		return mode
+	}
+	return ModeOpen
+}
+
+// Current 返回当前注册策略
+func Current() Policy {
+	mode := Mode()
+	p := Policy{Mode: mode, InviteRequired: mode == ModeInvite, NeedApproval: mode == ModeApproval}
+	if mode == ModeDomain {
+		p.AllowedDomains = global.Config.Register.AllowedDomains
+	}
+	return p
+}
+
+// Check 校验能否以该邮箱和邀请码注册
+// 邀请码在invite模式下必填，其他模式下填写时同样校验并使用其预设的角色和部门
+func Check(db *gorm.DB, email, code string) (*Plan, error) {
+	mode := Mode()
+	plan := &Plan{Status: models.UserStatusActive}
+	switch mode {
+	case ModeOpen, ModeInvite:
+	case ModeDomain:
+		if !domainAllowed(email) {
+			return nil, ErrDomain
+		}
+	case ModeApproval:
+		plan.Status = models.UserStatusPending
+	default:
+		return nil, ErrClosed
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" && mode == ModeInvite {
+		return nil, ErrInvitation
+	}
+	if code != "" {
+		var inv models.Invitation
+		if err := db.Where("code = ?", code).First(&inv).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrInvitation
+			}
+			return nil, err
+		}
+		if !usable(&inv, time.Now()) {
+			return nil, ErrInvitation
+		}
+		plan.Invitation = &inv
+		plan.RoleID = inv.RoleID
+		plan.DepartmentID = inv.DepartmentID
+	}
+
+	if plan.RoleID == 0 && global.Config.Register.DefaultRole != "" {
+		var role models.Role
+		err := db.Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: global.Config.Register.DefaultRole}).Where("status = 1").First(&role).Error
+		if err == nil {
+			plan.RoleID = role.ID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		} else {
+			global.Logger.Warnf("注册默认角色 %s 不存在或已禁用", global.Config.Register.DefaultRole)
+		}
+	}
+	return plan, nil
+}
+
+// Apply 在创建用户的事务中占用邀请码并分配角色，user须已创建
+// 邀请码在并发注册中被用完时返回ErrInvitation，事务应回滚
+func Apply(tx *gorm.DB, user *models.User, plan *Plan) error {
+	if inv := plan.Invitation; inv != nil {
+		res := tx.Model(&models.Invitation{}).
+			Where("id = ? AND status = ? AND (max_uses = 0 OR used_count < max_uses) AND (expires_at IS NULL OR expires_at > ?)",
+				inv.ID, models.InvitationStatusActive, time.Now()).
+			UpdateColumn("used_count", gorm.Expr("used_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvitation
+		}
+	}
+	if plan.RoleID != 0 {
+		if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: plan.RoleID}).Error; err != nil {
+			return fmt.Errorf("分配角色失败: %w", err)
+		}
+	}
+	if plan.DepartmentID != 0 {
+		if err := org.SetPrimary(tx, user.ID, plan.DepartmentID); err != nil {
+			return fmt.Errorf("分配部门失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// NewCode 生成随机邀请码，去掉了容易混淆的字符
+func NewCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 12)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// usable 判断邀请码当前是否可用
+func usable(inv *models.Invitation, now time.Time) bool {
+	if inv.Status != models.InvitationStatusActive {
+		return false
+	}
+	if inv.ExpiresAt != nil && !now.Before(*inv.ExpiresAt) {
+		return false
+	}
+	return inv.MaxUses == 0 || inv.UsedCount < inv.MaxUses
+}
+
+// domainAllowed 判断邮箱域名是否在允许范围内，大小写不敏感
+func domainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range global.Config.Register.AllowedDomains {
+		if strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@")) == domain {
+			return true
+		}
+	}
+	return false
+}
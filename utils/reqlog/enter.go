@@ -0,0 +1,71 @@
+package reqlog
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"rbac_admin_server/global"
+)
+
+// Header 请求ID的HTTP头
+const Header = "X-Request-ID"
+
+// Gin上下文中的键
+const (
+	RequestIDKey = "requestID"
+	loggerKey    = "logger"
+)
+
+// ctxKey 标准库上下文中请求ID的键
+type ctxKey struct{}
+
+// NewID 生成新的请求ID
+func NewID() string {
+	return uuid.New().String()
+}
+
+// WithRequestID 将请求ID写入标准库上下文，供GORM等下游组件读取
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// RequestID 从上下文中读取请求ID
+// 同时支持标准库上下文和gin.Context
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return c.GetString(RequestIDKey)
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Attach 将请求ID和带请求信息的日志实例绑定到当前请求
+func Attach(c *gin.Context, id string) {
+	c.Set(RequestIDKey, id)
+	c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+	c.Set(loggerKey, global.Logger.WithFields(logrus.Fields{
+		"request_id": id,
+		"route":      c.FullPath(),
+	}))
+}
+
+// WithField 为当前请求的日志实例追加字段，如认证后的用户ID
+func WithField(c *gin.Context, key string, value interface{}) {
+	c.Set(loggerKey, Entry(c).WithField(key, value))
+}
+
+// Entry 返回当前请求的日志实例
+// 未经过请求ID中间件时返回不带请求信息的全局日志实例
+func Entry(c *gin.Context) *logrus.Entry {
+	if v, ok := c.Get(loggerKey); ok {
+		if entry, ok := v.(*logrus.Entry); ok {
+			return entry
+		}
+	}
+	return logrus.NewEntry(global.Logger)
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 分页默认值
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+	maxInValues     = 100
+)
+
+// Op 筛选方式
+type Op int
+
+const (
+	OpEq        Op = iota // 等于: ?status=1
+	OpLike                // 模糊匹配: ?username=adm
+	OpIn                  // 多值: ?status=1,2
+	OpRange               // 数值区间: ?login_count=10,20，任一端可省略
+	OpDateRange           // 日期区间: ?created_at=2024-01-01,2024-01-31，结束日期包含当天
+	OpGte                 // 大于等于: ?start_time=2024-01-01 08:00:00
+	OpLte                 // 小于等于: ?end_time=2024-01-31 18:00:00
+)
+
+// Filter 允许的筛选条件
+type Filter struct {
+	Column string
+	Op     Op
+}
+
+// Spec 列表查询规则
+// 只有在Filters和Sorts中声明的参数和字段才会进入SQL，列名不来自请求
+// 筛选和排序的列名按数据库方言加引号，key等保留字可以直接作为列名
+type Spec struct {
+	Filters     map[string]Filter // 查询参数名 -> 筛选条件
+	Sorts       map[string]string // 排序参数名 -> 列名
+	DefaultSort string            // 未指定sort时的排序，如 "id DESC"
+}
+
+// Query 解析后的列表查询
+type Query struct {
+	Page     int
+	PageSize int
+	filters  []func(*gorm.DB) *gorm.DB
+	order    interface{} // DefaultSort字符串或解析后的clause.OrderBy
+}
+
+// Page 标准分页响应
+type Page struct {
+	List     interface{} `json:"list"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
+
+// Parse 解析请求中的分页、排序和筛选参数
+// 参数不合法时返回错误，调用方应返回400
+func Parse(c *gin.Context, spec Spec) (*Query, error) {
+	q := &Query{Page: 1, PageSize: DefaultPageSize, order: spec.DefaultSort}
+
+	if v := c.Query("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("page参数错误")
+		}
+		q.Page = page
+	}
+	if v := c.Query("page_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("page_size参数错误")
+		}
+		if size > MaxPageSize {
+			size = MaxPageSize
+		}
+		q.PageSize = size
+	}
+
+	if v := c.Query("sort"); v != "" {
+		order, err := parseSort(v, spec.Sorts)
+		if err != nil {
+			return nil, err
+		}
+		q.order = order
+	}
+
+	for param, filter := range spec.Filters {
+		value := strings.TrimSpace(c.Query(param))
+		if value == "" {
+			continue
+		}
+		scope, err := filter.scope(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s参数错误: %w", param, err)
+		}
+		if scope != nil {
+			q.filters = append(q.filters, scope)
+		}
+	}
+	return q, nil
+}
+
+// parseSort 解析排序参数，如 "-created_at,username"，前缀-表示降序
+func parseSort(value string, sorts map[string]string) (clause.OrderBy, error) {
+	var order clause.OrderBy
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		column, ok := sorts[field]
+		if !ok {
+			return order, fmt.Errorf("不支持按%s排序", field)
+		}
+		order.Columns = append(order.Columns, clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc})
+	}
+	return order, nil
+}
+
+// scope 将单个筛选条件转换为GORM作用域
+func (f Filter) scope(value string) (func(*gorm.DB) *gorm.DB, error) {
+	column := clause.Column{Name: f.Column}
+	switch f.Op {
+	case OpLike:
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where(clause.Like{Column: column, Value: "%" + value + "%"})
+		}, nil
+	case OpIn:
+		values := splitValues(value)
+		if len(values) > maxInValues {
+			return nil, fmt.Errorf("最多支持%d个值", maxInValues)
+		}
+		in := clause.IN{Column: column}
+		for _, v := range values {
+			in.Values = append(in.Values, v)
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where(in)
+		}, nil
+	case OpRange:
+		min, max := splitRange(value)
+		for _, v := range []string{min, max} {
+			if v == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("区间值必须为数字")
+			}
+		}
+		return between(column, min, max), nil
+	case OpDateRange:
+		start, end := splitRange(value)
+		var from, to interface{}
+		if start != "" {
+			t, _, err := parseTime(start)
+			if err != nil {
+				return nil, err
+			}
+			from = t
+		}
+		if end != "" {
+			t, dateOnly, err := parseTime(end)
+			if err != nil {
+				return nil, err
+			}
+			if dateOnly {
+				// 只有日期时包含结束当天
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			to = t
+		}
+		return between(column, from, to), nil
+	case OpGte, OpLte:
+		t, _, err := parseTime(value)
+		if err != nil {
+			return nil, err
+		}
+		var cond clause.Expression = clause.Gte{Column: column, Value: t}
+		if f.Op == OpLte {
+			cond = clause.Lte{Column: column, Value: t}
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where(cond)
+		}, nil
+	default:
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where(clause.Eq{Column: column, Value: value})
+		}, nil
+	}
+}
+
+// between 生成区间条件，空值的一端不限制
+func between(column clause.Column, min, max interface{}) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if min != nil && min != "" {
+			db = db.Where(clause.Gte{Column: column, Value: min})
+		}
+		if max != nil && max != "" {
+			db = db.Where(clause.Lte{Column: column, Value: max})
+		}
+		return db
+	}
+}
+
+// splitValues 按逗号拆分多值参数并去除空项
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// splitRange 拆分 "min,max" 形式的区间参数
+func splitRange(value string) (string, string) {
+	min, max, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(min), strings.TrimSpace(max)
+}
+
+// timeLayouts 支持的时间格式
+var timeLayouts = []string{"2006-01-02 15:04:05", time.RFC3339, "2006-01-02"}
+
+// parseTime 解析时间参数，dateOnly表示只包含日期
+func parseTime(value string) (time.Time, bool, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, layout == "2006-01-02", nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("时间格式错误")
+}
+
+// Scope 返回全部筛选条件组成的作用域，可用于导出等不分页的查询
+func (q *Query) Scope(db *gorm.DB) *gorm.DB {
+	for _, filter := range q.filters {
+		db = filter(db)
+	}
+	return db
+}
+
+// Find 按筛选条件统计总数，再按排序和分页查询到dest
+// db应已通过Model或Table指定查询的表
+func (q *Query) Find(db *gorm.DB, dest interface{}) (*Page, error) {
+	db = db.Scopes(q.Scope)
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	find := db.Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize)
+	if q.order != nil && q.order != "" {
+		find = find.Order(q.order)
+	}
+	if err := find.Find(dest).Error; err != nil {
+		return nil, err
+	}
+	return &Page{List: dest, Total: total, Page: q.Page, PageSize: q.PageSize}, nil
+}
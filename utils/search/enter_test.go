@@ -0,0 +1,142 @@
+package search
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testSpec = Spec{
+	Filters: map[string]Filter{
+		"key":         {Column: "roles.key", Op: OpLike},
+		"status":      {Column: "status", Op: OpIn},
+		"login_count": {Column: "login_count", Op: OpRange},
+		"created_at":  {Column: "created_at", Op: OpDateRange},
+		"start_time":  {Column: "start_time", Op: OpGte},
+		"name":        {Column: "name", Op: OpEq},
+	},
+	Sorts: map[string]string{
+		"id":  "id",
+		"key": "roles.key",
+	},
+	DefaultSort: "id DESC",
+}
+
+// context 创建带查询参数的请求上下文
+func context(query string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+	return c
+}
+
+// dryRun 只生成SQL不连接数据库
+func dryRun(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query    string
+		page     int
+		pageSize int
+		filters  int
+		err      string
+	}{
+		{"", 1, DefaultPageSize, 0, ""},
+		{"page=3&page_size=20", 3, 20, 0, ""},
+		{"page_size=1000", 1, MaxPageSize, 0, ""},
+		{"page=0", 0, 0, 0, "page参数错误"},
+		{"page=abc", 0, 0, 0, "page参数错误"},
+		{"page_size=-1", 0, 0, 0, "page_size参数错误"},
+		{"sort=-key,id", 1, DefaultPageSize, 0, ""},
+		{"sort=password", 0, 0, 0, "不支持按password排序"},
+		{"sort=id%3Bdrop", 0, 0, 0, "不支持按id;drop排序"},
+		{"key=adm&status=1,2&name=%20", 1, DefaultPageSize, 2, ""},
+		{"unknown=1", 1, DefaultPageSize, 0, ""},
+		{"login_count=10,20", 1, DefaultPageSize, 1, ""},
+		{"login_count=,20", 1, DefaultPageSize, 1, ""},
+		{"login_count=a,20", 0, 0, 0, "login_count参数错误: 区间值必须为数字"},
+		{"created_at=2024-01-01,2024-01-31", 1, DefaultPageSize, 1, ""},
+		{"created_at=2024-01-01,31/01/2024", 0, 0, 0, "created_at参数错误: 时间格式错误"},
+		{"start_time=2024-01-01%2008:00:00", 1, DefaultPageSize, 1, ""},
+		{"start_time=yesterday", 0, 0, 0, "start_time参数错误: 时间格式错误"},
+		{"status=" + strings.Repeat("1,", maxInValues+1), 0, 0, 0, "status参数错误: 最多支持100个值"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(context(tt.query), testSpec)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if q.Page != tt.page || q.PageSize != tt.pageSize || len(q.filters) != tt.filters {
+				t.Fatalf("got page=%d size=%d filters=%d", q.Page, q.PageSize, len(q.filters))
+			}
+		})
+	}
+}
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"key=adm", []string{"`roles`.`key` LIKE ?"}},
+		{"status=1,2", []string{"`status` IN (?,?)"}},
+		{"login_count=10,", []string{"`login_count` >= ?"}},
+		{"created_at=2024-01-01,2024-01-31", []string{"`created_at` >= ?", "`created_at` <= ?"}},
+		{"name=x", []string{"`name` = ?"}},
+		{"sort=-key,id", []string{"ORDER BY `roles`.`key` DESC,`id`"}},
+		{"", []string{"ORDER BY id DESC"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(context(tt.query), testSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows []map[string]interface{}
+			stmt := dryRun(t).Table("roles").Scopes(q.Scope).Order(q.order).Find(&rows).Statement
+			sql := stmt.SQL.String()
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Fatalf("SQL %q 不包含 %q", sql, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDateRangeIncludesEndDay(t *testing.T) {
+	q, err := Parse(context("created_at=2024-01-01,2024-01-31"), testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]interface{}
+	stmt := dryRun(t).Table("roles").Scopes(q.Scope).Find(&rows).Statement
+	end, ok := stmt.Vars[len(stmt.Vars)-1].(interface{ Format(string) string })
+	if !ok {
+		t.Fatalf("结束时间类型错误: %T", stmt.Vars[len(stmt.Vars)-1])
+	}
+	if got := end.Format("2006-01-02 15:04:05"); got != "2024-01-31 23:59:59" {
+		t.Fatalf("结束时间 = %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package sms
+
+import (
+	"fmt"
+	"sync"
+
+	"rbac_admin_server/global"
+)
+
+// Sender 短信发送接口，接入短信服务商时实现该接口并通过Register注册
+type Sender interface {
+	Send(phone, content string) error
+}
+
+var (
+	mu      sync.RWMutex
+	senders = map[string]Sender{"log": LogSender{}}
+)
+
+// Register 注册短信服务商，同名会覆盖
+func Register(name string, s Sender) {
+	mu.Lock()
+	defer mu.Unlock()
+	senders[name] = s
+}
+
+// Send 使用配置的服务商发送短信
+func Send(phone, content string) error {
+	name := global.Config.SMS.Provider
+	mu.RLock()
+	s, ok := senders[name]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("短信服务商 %s 未注册", name)
+	}
+	if err := s.Send(phone, content); err != nil {
+		global.Logger.Errorf("发送短信失败: 手机号=%s, %v", phone, err)
+		return err
+	}
+	return nil
+}
+
+// LogSender 只写日志的短信发送器，用于开发环境或尚未接入服务商时
+type LogSender struct{}
+
+// Send 将短信内容写入日志
+func (LogSender) Send(phone, content string) error {
+	global.Logger.Infof("[短信] 手机号=%s, 内容=%s", phone, content)
+	return nil
+}
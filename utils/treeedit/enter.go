@@ -0,0 +1,224 @@
+// Package treeedit 菜单、权限等树形表的批量移动、排序、启用禁用和删除
+// 前端拖拽后提交整棵树或移动操作列表，校验通过后在一个事务中写入
+package treeedit
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/utils/recycle"
+)
+
+var (
+	// ErrNotFound 节点不存在
+	ErrNotFound = errors.New("节点不存在")
+	// ErrDuplicate 同一节点出现多次
+	ErrDuplicate = errors.New("节点重复")
+	// ErrParentNotFound 上级节点不存在
+	ErrParentNotFound = errors.New("上级节点不存在")
+	// ErrLeafParent 上级节点不能有下级，如按钮
+	ErrLeafParent = errors.New("上级节点不能是按钮")
+	// ErrNeedParent 节点必须有上级，如按钮必须属于菜单
+	ErrNeedParent = errors.New("按钮必须属于菜单")
+	// ErrParentType 上级节点的类型不允许，如按钮不能直接属于目录
+	ErrParentType = errors.New("按钮只能属于菜单，不能属于目录")
+	// ErrCycle 上级是自身或其下级
+	ErrCycle = errors.New("上级不能是自身或其下级")
+)
+
+// Node 节点的当前位置和类型约束
+type Node struct {
+	ID          uint
+	ParentID    uint
+	Type        string
+	Leaf        bool     // 不能有下级
+	NeedParent  bool     // 不能作为顶级节点
+	ParentTypes []string // 允许的上级类型，为空表示不限
+}
+
+// allowParent 判断parent的类型是否可以作为节点的上级
+func (n Node) allowParent(parent Node) bool {
+	if len(n.ParentTypes) == 0 {
+		return true
+	}
+	for _, t := range n.ParentTypes {
+		if t == parent.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Move 移动操作，将节点移动到新的上级下并设置排序值
+type Move struct {
+	ID       uint `json:"id"`
+	ParentID uint `json:"parent_id"`
+	Sort     int  `json:"sort"`
+}
+
+// TreeNode 拖拽后的树节点，只需要ID和下级的顺序
+type TreeNode struct {
+	ID       uint       `json:"id"`
+	Children []TreeNode `json:"children"`
+}
+
+// Flatten 将树转为移动操作，上级取树中的位置，排序值为同级中的序号，从1开始
+func Flatten(parentID uint, tree []TreeNode) []Move {
+	var moves []Move
+	for i, node := range tree {
+		moves = append(moves, Move{ID: node.ID, ParentID: parentID, Sort: i + 1})
+		moves = append(moves, Flatten(node.ID, node.Children)...)
+	}
+	return moves
+}
+
+// Check 校验移动操作：节点和上级存在、上级不是叶子节点且类型允许、必须有上级的节点不移到顶级，
+// 全部移动完成后不存在循环
+func Check(nodes []Node, moves []Move) error {
+	index := make(map[uint]Node, len(nodes))
+	parents := make(map[uint]uint, len(nodes))
+	for _, n := range nodes {
+		index[n.ID] = n
+		parents[n.ID] = n.ParentID
+	}
+
+	seen := make(map[uint]bool, len(moves))
+	for _, m := range moves {
+		node, ok := index[m.ID]
+		if !ok {
+			return fmt.Errorf("%w: ID=%d", ErrNotFound, m.ID)
+		}
+		if seen[m.ID] {
+			return fmt.Errorf("%w: ID=%d", ErrDuplicate, m.ID)
+		}
+		seen[m.ID] = true
+		if m.ParentID == 0 {
+			if node.NeedParent {
+				return fmt.Errorf("%w: ID=%d", ErrNeedParent, m.ID)
+			}
+		} else {
+			parent, ok := index[m.ParentID]
+			if !ok {
+				return fmt.Errorf("%w: ID=%d", ErrParentNotFound, m.ParentID)
+			}
+			if parent.Leaf {
+				return fmt.Errorf("%w: ID=%d", ErrLeafParent, m.ParentID)
+			}
+			if !node.allowParent(parent) {
+				return fmt.Errorf("%w: ID=%d", ErrParentType, m.ParentID)
+			}
+		}
+		parents[m.ID] = m.ParentID
+	}
+
+	// 新的循环必然经过被移动的节点，步数上限用于跳出已有的错误数据
+	for _, m := range moves {
+		steps := 0
+		for id := parents[m.ID]; id != 0; id = parents[id] {
+			if id == m.ID || steps > len(nodes) {
+				return fmt.Errorf("%w: ID=%d", ErrCycle, m.ID)
+			}
+			steps++
+		}
+	}
+	return nil
+}
+
+// Subtree 返回ids及其全部下级节点的ID，上级在前
+func Subtree(nodes []Node, ids []uint) ([]uint, error) {
+	exists := make(map[uint]bool, len(nodes))
+	children := make(map[uint][]uint)
+	for _, n := range nodes {
+		exists[n.ID] = true
+		children[n.ParentID] = append(children[n.ParentID], n.ID)
+	}
+
+	result := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool)
+	for _, id := range ids {
+		if !exists[id] {
+			return nil, fmt.Errorf("%w: ID=%d", ErrNotFound, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	for i := 0; i < len(result); i++ {
+		for _, child := range children[result[i]] {
+			if !seen[child] {
+				seen[child] = true
+				result = append(result, child)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Tree 可批量调整的树形表
+type Tree struct {
+	Label  string                            // 节点名称，用于提示信息和日志，如菜单、权限
+	Entity string                            // 回收站实体标识，批量删除时移入回收站
+	Model  func() interface{}                // 返回模型指针
+	Load   func(db *gorm.DB) ([]Node, error) // 查询全部未删除的节点
+}
+
+// Sort 校验并应用移动操作，任一操作不合法时不做任何修改
+func (t *Tree) Sort(db *gorm.DB, moves []Move) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		nodes, err := t.Load(tx)
+		if err != nil {
+			return err
+		}
+		if err := Check(nodes, moves); err != nil {
+			return err
+		}
+		for _, m := range moves {
+			if err := tx.Model(t.Model()).Where("id = ?", m.ID).
+				Updates(map[string]interface{}{"parent_id": m.ParentID, "sort": m.Sort}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetStatus 设置节点及其全部下级的状态，返回修改的节点ID
+func (t *Tree) SetStatus(db *gorm.DB, ids []uint, status int) ([]uint, error) {
+	var changed []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		nodes, err := t.Load(tx)
+		if err != nil {
+			return err
+		}
+		if changed, err = Subtree(nodes, ids); err != nil {
+			return err
+		}
+		return tx.Model(t.Model()).Where("id IN ?", changed).Update("status", status).Error
+	})
+	return changed, err
+}
+
+// Delete 将节点及其全部下级移入回收站，返回删除的节点ID
+// 下级先于上级删除，从回收站恢复时需要先恢复上级
+func (t *Tree) Delete(db *gorm.DB, ids []uint) ([]uint, error) {
+	var deleted []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		nodes, err := t.Load(tx)
+		if err != nil {
+			return err
+		}
+		if deleted, err = Subtree(nodes, ids); err != nil {
+			return err
+		}
+		for i := len(deleted) - 1; i >= 0; i-- {
+			if err := recycle.Delete(tx, t.Entity, deleted[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
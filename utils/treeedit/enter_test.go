@@ -0,0 +1,121 @@
+package treeedit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// nodes 测试用的菜单树，1和6为目录，7和8为按钮，按钮只能属于菜单
+//
+//	1 (目录)
+//	├── 2
+//	│   ├── 3
+//	│   └── 7 (按钮)
+//	└── 4
+//	5
+//	└── 8 (按钮)
+//	6 (目录)
+var nodes = []Node{
+	{ID: 1, Type: "dir"},
+	{ID: 2, ParentID: 1, Type: "menu"},
+	{ID: 3, ParentID: 2, Type: "menu"},
+	{ID: 4, ParentID: 1, Type: "menu"},
+	{ID: 5, Type: "menu"},
+	{ID: 6, Type: "dir"},
+	button(7, 2),
+	button(8, 5),
+}
+
+func button(id, parentID uint) Node {
+	return Node{ID: id, ParentID: parentID, Type: "button", Leaf: true, NeedParent: true, ParentTypes: []string{"menu"}}
+}
+
+func TestFlatten(t *testing.T) {
+	tree := []TreeNode{
+		{ID: 5, Children: []TreeNode{{ID: 1, Children: []TreeNode{{ID: 4}, {ID: 2}}}}},
+		{ID: 6},
+	}
+	want := []Move{
+		{ID: 5, ParentID: 0, Sort: 1},
+		{ID: 1, ParentID: 5, Sort: 1},
+		{ID: 4, ParentID: 1, Sort: 1},
+		{ID: 2, ParentID: 1, Sort: 2},
+		{ID: 6, ParentID: 0, Sort: 2},
+	}
+	if got := Flatten(0, tree); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Flatten() = %+v, want %+v", got, want)
+	}
+	if got := Flatten(0, nil); got != nil {
+		t.Fatalf("Flatten(nil) = %+v", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []Move
+		err   error
+	}{
+		{"空操作", nil, nil},
+		{"排序", []Move{{ID: 2, ParentID: 1, Sort: 2}, {ID: 4, ParentID: 1, Sort: 1}}, nil},
+		{"移动到其他上级", []Move{{ID: 2, ParentID: 5}}, nil},
+		{"移动为顶级", []Move{{ID: 2}}, nil},
+		{"按钮移动到菜单", []Move{{ID: 7, ParentID: 4}}, nil},
+		{"节点不存在", []Move{{ID: 99, ParentID: 1}}, ErrNotFound},
+		{"节点重复", []Move{{ID: 2, ParentID: 1}, {ID: 2, ParentID: 5}}, ErrDuplicate},
+		{"上级不存在", []Move{{ID: 2, ParentID: 99}}, ErrParentNotFound},
+		{"上级是按钮", []Move{{ID: 3, ParentID: 7}}, ErrLeafParent},
+		{"按钮移为顶级", []Move{{ID: 8}}, ErrNeedParent},
+		{"按钮移到目录", []Move{{ID: 7, ParentID: 1}}, ErrParentType},
+		{"整棵树中按钮位于目录", Flatten(0, []TreeNode{{ID: 6, Children: []TreeNode{{ID: 8}}}}), ErrParentType},
+		{"菜单移到目录", []Move{{ID: 5, ParentID: 6}}, nil},
+		{"上级是自身", []Move{{ID: 2, ParentID: 2}}, ErrCycle},
+		{"上级是下级", []Move{{ID: 1, ParentID: 3}}, ErrCycle},
+		{"两个节点互为上级", []Move{{ID: 5, ParentID: 6}, {ID: 6, ParentID: 5}}, ErrCycle},
+		{"先移出下级再移入", []Move{{ID: 3}, {ID: 1, ParentID: 3}}, nil},
+		{"整棵树", Flatten(0, []TreeNode{{ID: 6, Children: []TreeNode{{ID: 1}, {ID: 5}}}}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(nodes, tt.moves); !errors.Is(err, tt.err) {
+				t.Fatalf("Check() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckExistingCycle(t *testing.T) {
+	// 已有的错误数据中存在循环时不会死循环
+	broken := []Node{{ID: 1, ParentID: 2}, {ID: 2, ParentID: 1}, {ID: 3}}
+	if err := Check(broken, []Move{{ID: 3, ParentID: 1}}); !errors.Is(err, ErrCycle) {
+		t.Fatalf("Check() = %v, want ErrCycle", err)
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []uint
+		want []uint
+		err  error
+	}{
+		{"叶子节点", []uint{3}, []uint{3}, nil},
+		{"整棵子树", []uint{1}, []uint{1, 2, 4, 3, 7}, nil},
+		{"重复和包含关系", []uint{2, 1, 2}, []uint{2, 1, 3, 7, 4}, nil},
+		{"多个顶级", []uint{5, 6}, []uint{5, 6, 8}, nil},
+		{"节点不存在", []uint{1, 99}, nil, ErrNotFound},
+		{"空列表", nil, []uint{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Subtree(nodes, tt.ids)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Subtree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package treeedit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/utils/audit"
+	"rbac_admin_server/utils/permcache"
+	"rbac_admin_server/utils/reqlog"
+)
+
+// MaxMoves 一次最多调整的节点数
+const MaxMoves = 2000
+
+// SortRequest 批量调整位置请求参数，tree和moves二选一
+type SortRequest struct {
+	// tree的上级节点ID，0表示提交的是整棵树
+	ParentID uint `json:"parent_id"`
+	// 拖拽后的树，按位置设置上级和排序
+	Tree []TreeNode `json:"tree"`
+	// 移动操作列表
+	Moves []Move `json:"moves" binding:"max=2000"`
+}
+
+// StatusRequest 批量启用禁用请求参数
+type StatusRequest struct {
+	// 节点ID，全部下级一并修改
+	IDs []uint `json:"ids" binding:"required,min=1,max=500"`
+	// 状态(1:正常,2:禁用)
+	Status int `json:"status" binding:"required,oneof=1 2"`
+}
+
+// DeleteRequest 批量删除请求参数
+type DeleteRequest struct {
+	// 节点ID，全部下级一并删除
+	IDs []uint `json:"ids" binding:"required,min=1,max=500"`
+}
+
+// HandleSort 批量调整位置接口
+func (t *Tree) HandleSort(c *gin.Context) {
+	var req SortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("调整" + t.Label + "位置参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+	moves := req.Moves
+	if len(req.Tree) > 0 {
+		moves = Flatten(req.ParentID, req.Tree)
+	}
+	if len(moves) == 0 || (len(req.Tree) > 0 && len(req.Moves) > 0) {
+		c.JSON(400, gin.H{"code": 400, "msg": t.Label + "树和移动操作必须提交其中一项"})
+		return
+	}
+	if len(moves) > MaxMoves {
+		c.JSON(400, gin.H{"code": 400, "msg": fmt.Sprintf("一次最多调整%d个%s", MaxMoves, t.Label)})
+		return
+	}
+
+	err := t.Sort(global.DB.WithContext(c.Request.Context()), moves)
+	audit.Describe(c, fmt.Sprintf("调整%s位置 %d 个", t.Label, len(moves)), err)
+	if err != nil {
+		respondError(c, "调整"+t.Label+"位置", err)
+		return
+	}
+
+	reqlog.Entry(c).Infof("管理员调整%s位置成功: 共%d个", t.Label, len(moves))
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "调整成功",
+	})
+}
+
+// HandleStatus 批量启用禁用接口，成功后用户的权限缓存全部失效
+func (t *Tree) HandleStatus(c *gin.Context) {
+	var req StatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("批量修改" + t.Label + "状态参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	ids, err := t.SetStatus(global.DB.WithContext(c.Request.Context()), req.IDs, req.Status)
+	audit.Describe(c, fmt.Sprintf("修改%s状态为 %d，共 %d 个: %v", t.Label, req.Status, len(ids), ids), err)
+	if err != nil {
+		respondError(c, "批量修改"+t.Label+"状态", err)
+		return
+	}
+	permcache.InvalidateAll(c.Request.Context())
+
+	reqlog.Entry(c).Infof("管理员批量修改%s状态成功: 状态=%d, 共%d个", t.Label, req.Status, len(ids))
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "修改成功",
+		"data": ids,
+	})
+}
+
+// HandleDelete 批量删除接口，节点及其全部下级移入回收站
+func (t *Tree) HandleDelete(c *gin.Context) {
+	var req DeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		reqlog.Entry(c).Error("批量删除" + t.Label + "参数错误: " + err.Error())
+		c.JSON(400, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	ids, err := t.Delete(global.DB.WithContext(c.Request.Context()), req.IDs)
+	audit.Describe(c, fmt.Sprintf("删除%s %d 个: %v", t.Label, len(ids), ids), err)
+	if err != nil {
+		respondError(c, "批量删除"+t.Label, err)
+		return
+	}
+
+	reqlog.Entry(c).Infof("管理员批量删除%s成功，已移入回收站: 共%d个", t.Label, len(ids))
+	c.JSON(200, gin.H{
+		"code": 200,
+		"msg":  "删除成功",
+		"data": ids,
+	})
+}
+
+// respondError 按树操作的错误类型返回响应，校验错误返回400
+func respondError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrDuplicate),
+		errors.Is(err, ErrParentNotFound), errors.Is(err, ErrLeafParent),
+		errors.Is(err, ErrNeedParent), errors.Is(err, ErrParentType), errors.Is(err, ErrCycle):
+		c.JSON(400, gin.H{"code": 400, "msg": err.Error()})
+	default:
+		reqlog.Entry(c).Error(action + "失败: " + err.Error())
+		c.JSON(500, gin.H{"code": 500, "msg": action + "失败"})
+	}
+}
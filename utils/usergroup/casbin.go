@@ -0,0 +1,122 @@
+package usergroup
+
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// Casbin中的主体前缀，g策略形如 user:1 → group:2 → group:3 → role:4
+const (
+	userPrefix  = "user:"
+	groupPrefix = "group:"
+	rolePrefix  = "role:"
+)
+
+// SyncCasbin 将用户、用户组和角色的继承关系同步为Casbin的g策略
+// 只增删有差异的策略，不改动其他前缀的策略；禁用的用户组不参与继承，与global.RoleIDs一致
+// Casbin未初始化时直接返回
+func SyncCasbin(db *gorm.DB) error {
+	enforcer := global.Casbin
+	if enforcer == nil {
+		return nil
+	}
+
+	var groups []models.UserGroup
+	if err := db.Select("id", "parent_id", "status").Find(&groups).Error; err != nil {
+		return err
+	}
+	enabled := make(map[uint]bool, len(groups))
+	for _, g := range groups {
+		enabled[g.ID] = g.Status == 1
+	}
+
+	want := make(map[string][]string)
+	add := func(sub, parent string) {
+		want[sub+"\x00"+parent] = []string{sub, parent}
+	}
+	var userRoles []models.UserRole
+	if err := db.Find(&userRoles).Error; err != nil {
+		return err
+	}
+	for _, ur := range userRoles {
+		add(subject(userPrefix, ur.UserID), subject(rolePrefix, ur.RoleID))
+	}
+	var members []models.UserGroupMember
+	if err := db.Find(&members).Error; err != nil {
+		return err
+	}
+	for _, m := range members {
+		if enabled[m.GroupID] {
+			add(subject(userPrefix, m.UserID), subject(groupPrefix, m.GroupID))
+		}
+	}
+	for _, g := range groups {
+		if enabled[g.ID] && enabled[g.ParentID] {
+			add(subject(groupPrefix, g.ID), subject(groupPrefix, g.ParentID))
+		}
+	}
+	var groupRoles []models.UserGroupRole
+	if err := db.Find(&groupRoles).Error; err != nil {
+		return err
+	}
+	for _, gr := range groupRoles {
+		if enabled[gr.GroupID] {
+			add(subject(groupPrefix, gr.GroupID), subject(rolePrefix, gr.RoleID))
+		}
+	}
+
+	current, err := enforcer.GetGroupingPolicy()
+	if err != nil {
+		return err
+	}
+	var stale [][]string
+	for _, rule := range current {
+		if len(rule) < 2 || !managed(rule[0]) {
+			continue
+		}
+		key := rule[0] + "\x00" + rule[1]
+		if _, ok := want[key]; ok {
+			delete(want, key)
+		} else {
+			stale = append(stale, rule)
+		}
+	}
+	if len(stale) > 0 {
+		if _, err := enforcer.RemoveGroupingPolicies(stale); err != nil {
+			return err
+		}
+	}
+	if len(want) > 0 {
+		rules := make([][]string, 0, len(want))
+		for _, rule := range want {
+			rules = append(rules, rule)
+		}
+		if _, err := enforcer.AddGroupingPolicies(rules); err != nil {
+			return err
+		}
+	}
+	return enforcer.InvalidateCache()
+}
+
+// Resync 同步Casbin继承关系，失败时只记录日志
+// 供批量导入、回收站等修改了用户角色或用户组成员、不便返回同步错误的写入路径在提交后调用
+func Resync(db *gorm.DB) {
+	if err := SyncCasbin(db); err != nil {
+		global.Logger.Warnf("同步Casbin用户组继承关系失败: %v", err)
+	}
+}
+
+// subject 生成Casbin主体
+func subject(prefix string, id uint) string {
+	return prefix + strconv.FormatUint(uint64(id), 10)
+}
+
+// managed 判断主体是否由本包维护
+func managed(sub string) bool {
+	return strings.HasPrefix(sub, userPrefix) || strings.HasPrefix(sub, groupPrefix)
+}
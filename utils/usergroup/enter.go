@@ -0,0 +1,178 @@
+// Package usergroup 用户组的嵌套、成员和角色绑定
+// 成员获得所在用户组及其全部上级用户组绑定的角色，计算见global.RoleIDs
+package usergroup
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils/idlist"
+)
+
+var (
+	// ErrNotFound 用户组不存在
+	ErrNotFound = errors.New("用户组不存在")
+	// ErrParentNotFound 上级用户组不存在
+	ErrParentNotFound = errors.New("上级用户组不存在")
+	// ErrCycle 上级用户组是自身或其下级用户组
+	ErrCycle = errors.New("不能将用户组设为自身或其下级用户组的下级")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+)
+
+// Grant 用户的有效角色及其来源
+type Grant struct {
+	ID     uint     `json:"id"`
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Status int      `json:"status"`
+	Direct bool     `json:"direct"` // 是否直接分配给用户
+	Groups []string `json:"groups"` // 通过哪些用户组获得
+}
+
+// CheckParent 检查parentID能否作为用户组id的上级，id为0表示新建的用户组
+func CheckParent(db *gorm.DB, id, parentID uint) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == id {
+		return ErrCycle
+	}
+	var groups []models.UserGroup
+	if err := db.Select("id", "parent_id").Find(&groups).Error; err != nil {
+		return err
+	}
+	parents := make(map[uint]uint, len(groups))
+	for _, g := range groups {
+		parents[g.ID] = g.ParentID
+	}
+	if _, ok := parents[parentID]; !ok {
+		return ErrParentNotFound
+	}
+
+	// 沿上级链向上查找，遇到自身说明形成环
+	seen := make(map[uint]bool)
+	for cur := parentID; cur != 0 && !seen[cur]; cur = parents[cur] {
+		if cur == id {
+			return ErrCycle
+		}
+		seen[cur] = true
+	}
+	return nil
+}
+
+// AddMembers 将用户加入用户组，已是成员的忽略
+func AddMembers(db *gorm.DB, groupID uint, userIDs []uint) error {
+	userIDs = idlist.Unique(userIDs)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := idlist.Exists(tx, &models.UserGroup{}, []uint{groupID}, ErrNotFound); err != nil {
+			return err
+		}
+		if err := idlist.Exists(tx, &models.User{}, userIDs, ErrUserNotFound); err != nil {
+			return err
+		}
+		for _, id := range userIDs {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.UserGroupMember{GroupID: groupID, UserID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveMembers 将用户移出用户组
+func RemoveMembers(db *gorm.DB, groupID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return db.Where("group_id = ? AND user_id IN ?", groupID, userIDs).Delete(&models.UserGroupMember{}).Error
+}
+
+// Roles 返回用户组直接绑定的角色
+func Roles(db *gorm.DB, groupID uint) ([]models.Role, error) {
+	list := []models.Role{}
+	err := db.Joins("JOIN user_group_roles ON user_group_roles.role_id = roles.id").
+		Where("user_group_roles.group_id = ?", groupID).Order("roles.id").Find(&list).Error
+	return list, err
+}
+
+// SetRoles 用roleIDs替换用户组绑定的角色
+func SetRoles(db *gorm.DB, groupID uint, roleIDs []uint) error {
+	roleIDs = idlist.Unique(roleIDs)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := idlist.Exists(tx, &models.UserGroup{}, []uint{groupID}, ErrNotFound); err != nil {
+			return err
+		}
+		if err := idlist.Exists(tx, &models.Role{}, roleIDs, ErrRoleNotFound); err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.UserGroupRole{}).Error; err != nil {
+			return err
+		}
+		for _, id := range roleIDs {
+			if err := tx.Create(&models.UserGroupRole{GroupID: groupID, RoleID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Effective 返回用户的有效角色，标明直接分配还是来自哪些用户组
+func Effective(db *gorm.DB, userID uint) ([]Grant, error) {
+	var direct []uint
+	if err := db.Model(&models.UserRole{}).Where("user_id = ?", userID).Pluck("role_id", &direct).Error; err != nil {
+		return nil, err
+	}
+	groupIDs, err := global.GroupIDs(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	var bindings []struct {
+		RoleID uint
+		Name   string
+	}
+	if len(groupIDs) > 0 {
+		if err := db.Model(&models.UserGroupRole{}).
+			Select("user_group_roles.role_id, user_groups.name").
+			Joins("JOIN user_groups ON user_groups.id = user_group_roles.group_id").
+			Where("user_group_roles.group_id IN ?", groupIDs).
+			Order("user_groups.id").Scan(&bindings).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	ids := append([]uint{}, direct...)
+	groups := make(map[uint][]string)
+	for _, b := range bindings {
+		ids = append(ids, b.RoleID)
+		groups[b.RoleID] = append(groups[b.RoleID], b.Name)
+	}
+	list := []Grant{}
+	if len(ids) == 0 {
+		return list, nil
+	}
+	var roles []models.Role
+	if err := db.Where("id IN ?", idlist.Unique(ids)).Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	isDirect := make(map[uint]bool, len(direct))
+	for _, id := range direct {
+		isDirect[id] = true
+	}
+	for _, r := range roles {
+		g := groups[r.ID]
+		if g == nil {
+			g = []string{}
+		}
+		list = append(list, Grant{ID: r.ID, Name: r.Name, Key: r.Key, Status: r.Status, Direct: isDirect[r.ID], Groups: g})
+	}
+	return list, nil
+}
@@ -0,0 +1,472 @@
+package userimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"rbac_admin_server/core"
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+	"rbac_admin_server/utils/org"
+	"rbac_admin_server/utils/usergroup"
+)
+
+// MaxRows 单次导入允许的最大行数
+const MaxRows = 10000
+
+// headerAliases 表头别名，支持英文和中文列名
+var headerAliases = map[string]string{
+	"username":   "username",
+	"用户名":        "username",
+	"nickname":   "nickname",
+	"昵称":         "nickname",
+	"email":      "email",
+	"邮箱":         "email",
+	"phone":      "phone",
+	"手机号":        "phone",
+	"password":   "password",
+	"密码":         "password",
+	"department": "department",
+	"部门":         "department",
+	"roles":      "roles",
+	"角色":         "roles",
+}
+
+// Row 导入文件中的一行
+type Row struct {
+	Line       int    `json:"line"`
+	Username   string `json:"username"`
+	Nickname   string `json:"nickname"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	Password   string `json:"-"`
+	Department string `json:"department"` // 部门路径，如 总部/研发部
+	Roles      string `json:"roles"`      // 角色标识，多个用逗号分隔
+}
+
+// normalize 去除首尾空白，只有空白的邮箱和手机号视为未填写，写入时存为NULL
+func (r *Row) normalize() {
+	r.Username = strings.TrimSpace(r.Username)
+	r.Nickname = strings.TrimSpace(r.Nickname)
+	r.Email = strings.TrimSpace(r.Email)
+	r.Phone = strings.TrimSpace(r.Phone)
+}
+
+// RowResult 单行导入结果
+type RowResult struct {
+	Line     int      `json:"line"`
+	Username string   `json:"username"`
+	Errors   []string `json:"errors,omitempty"`
+	Created  bool     `json:"created"`
+	Password string   `json:"password,omitempty"` // 未提供密码时生成的初始密码
+}
+
+// Result 导入结果
+type Result struct {
+	DryRun  bool        `json:"dry_run"`
+	Total   int         `json:"total"`
+	Valid   int         `json:"valid"`
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+	Rows    []RowResult `json:"rows"`
+}
+
+// ReadFile 按扩展名解析CSV或XLSX文件
+func ReadFile(name string, r io.Reader) ([]Row, error) {
+	var records [][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		records, err = readCSV(r)
+	case ".xlsx":
+		records, err = readXLSX(r)
+	default:
+		return nil, fmt.Errorf("只支持CSV和XLSX文件")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseRecords(records)
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析CSV失败: %w", err)
+		}
+		records = append(records, record)
+		if len(records) > MaxRows+1 {
+			return nil, fmt.Errorf("单次最多导入%d行", MaxRows)
+		}
+	}
+	return records, nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("解析XLSX失败: %w", err)
+	}
+	defer file.Close()
+
+	rows, err := file.Rows(file.GetSheetName(0))
+	if err != nil {
+		return nil, fmt.Errorf("解析XLSX失败: %w", err)
+	}
+	defer rows.Close()
+
+	var records [][]string
+	for rows.Next() {
+		record, err := rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("解析XLSX失败: %w", err)
+		}
+		records = append(records, record)
+		if len(records) > MaxRows+1 {
+			return nil, fmt.Errorf("单次最多导入%d行", MaxRows)
+		}
+	}
+	return records, nil
+}
+
+// parseRecords 按表头将记录转换为导入行，跳过空行
+func parseRecords(records [][]string) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("文件为空")
+	}
+	columns := make(map[string]int)
+	for i, title := range records[0] {
+		title = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(title, "\xEF\xBB\xBF")))
+		if key, ok := headerAliases[title]; ok {
+			columns[key] = i
+		}
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, fmt.Errorf("缺少username列")
+	}
+
+	var rows []Row
+	for i, record := range records[1:] {
+		get := func(key string) string {
+			if idx, ok := columns[key]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+		row := Row{
+			Line:       i + 2,
+			Username:   get("username"),
+			Nickname:   get("nickname"),
+			Email:      get("email"),
+			Phone:      get("phone"),
+			Password:   get("password"),
+			Department: get("department"),
+			Roles:      get("roles"),
+		}
+		if row == (Row{Line: row.Line}) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// resolver 部门和角色查找表
+type resolver struct {
+	deptByParent map[uint]map[string]uint
+	deptByName   map[string][]uint
+	roles        map[string]uint
+}
+
+func newResolver(db *gorm.DB) (*resolver, error) {
+	var departments []models.Department
+	if err := db.Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	var roles []models.Role
+	if err := db.Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	r := &resolver{
+		deptByParent: make(map[uint]map[string]uint),
+		deptByName:   make(map[string][]uint),
+		roles:        make(map[string]uint),
+	}
+	for _, d := range departments {
+		if r.deptByParent[d.ParentID] == nil {
+			r.deptByParent[d.ParentID] = make(map[string]uint)
+		}
+		r.deptByParent[d.ParentID][d.Name] = d.ID
+		r.deptByName[d.Name] = append(r.deptByName[d.Name], d.ID)
+	}
+	// 角色名称和标识都可以匹配，标识优先
+	for _, role := range roles {
+		if _, ok := r.roles[role.Name]; !ok {
+			r.roles[role.Name] = role.ID
+		}
+	}
+	for _, role := range roles {
+		r.roles[role.Key] = role.ID
+	}
+	return r, nil
+}
+
+// department 按路径查找部门
+// 第一段优先从顶级部门匹配，不存在时按名称全局唯一匹配
+func (r *resolver) department(path string) (uint, error) {
+	parts := strings.FieldsFunc(path, func(c rune) bool { return c == '/' || c == '>' })
+	var id uint
+	for i, name := range parts {
+		name = strings.TrimSpace(name)
+		next, ok := r.deptByParent[id][name]
+		if !ok && i == 0 && len(r.deptByName[name]) == 1 {
+			next, ok = r.deptByName[name][0], true
+		}
+		if !ok {
+			return 0, fmt.Errorf("部门不存在: %s", path)
+		}
+		id = next
+	}
+	return id, nil
+}
+
+// roleIDs 查找角色
+func (r *resolver) roleIDs(value string) ([]uint, error) {
+	var ids []uint
+	for _, key := range strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ';' || c == '|' }) {
+		key = strings.TrimSpace(key)
+		id, ok := r.roles[key]
+		if !ok {
+			return nil, fmt.Errorf("角色不存在: %s", key)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// prepared 校验通过、待创建的用户
+type prepared struct {
+	index   int
+	user    models.User
+	roleIDs []uint
+}
+
+// Import 校验并导入用户
+// 每行独立校验和创建，失败的行不影响其他行；dryRun为true时只校验不写入
+// locale为校验信息的语言，progress在每处理完一行后调用，可为nil
+func Import(db *gorm.DB, rows []Row, dryRun bool, locale string, progress func(done, total int)) (*Result, error) {
+	if len(rows) > MaxRows {
+		return nil, fmt.Errorf("单次最多导入%d行", MaxRows)
+	}
+	if core.Validate == nil {
+		return nil, fmt.Errorf("验证器未初始化")
+	}
+	for i := range rows {
+		rows[i].normalize()
+	}
+	res, err := newResolver(db)
+	if err != nil {
+		return nil, fmt.Errorf("加载部门和角色失败: %w", err)
+	}
+	existing, err := existingKeys(db, rows)
+	if err != nil {
+		return nil, fmt.Errorf("查询已有用户失败: %w", err)
+	}
+
+	result := &Result{DryRun: dryRun, Total: len(rows), Rows: make([]RowResult, len(rows))}
+	seen := make(map[string]int)
+	var valid []prepared
+
+	for i, row := range rows {
+		rr := &result.Rows[i]
+		rr.Line, rr.Username = row.Line, row.Username
+
+		password := row.Password
+		if password == "" {
+			password = utils.RandomPassword()
+		}
+		user := models.User{
+			Username: row.Username,
+			Password: password,
+			Nickname: row.Nickname,
+			Email:    models.NullString(row.Email),
+			Phone:    models.NullString(row.Phone),
+			Status:   models.UserStatusActive,
+		}
+		// 只校验用户自身字段，关联的部门和角色另行解析
+		if err := core.Validate.StructExcept(&user, "Department", "Roles"); err != nil {
+			messages := core.FormatValidationError(err, locale)
+			if len(messages) == 0 {
+				rr.Errors = append(rr.Errors, err.Error())
+			}
+			for _, msg := range sortedValues(messages) {
+				rr.Errors = append(rr.Errors, msg)
+			}
+		}
+
+		// 文件内和数据库中的唯一性检查
+		for _, key := range uniqueKeys(row) {
+			if line, ok := seen[key]; ok {
+				rr.Errors = append(rr.Errors, fmt.Sprintf("%s与第%d行重复", keyLabel(key), line))
+			} else {
+				seen[key] = row.Line
+			}
+			if existing[key] {
+				rr.Errors = append(rr.Errors, keyLabel(key)+"已存在")
+			}
+		}
+
+		if row.Department != "" {
+			deptID, err := res.department(row.Department)
+			if err != nil {
+				rr.Errors = append(rr.Errors, err.Error())
+			}
+			user.DepartmentID = deptID
+		}
+		roleIDs, err := res.roleIDs(row.Roles)
+		if err != nil {
+			rr.Errors = append(rr.Errors, err.Error())
+		}
+
+		if len(rr.Errors) > 0 {
+			result.Failed++
+			continue
+		}
+		result.Valid++
+		if row.Password == "" && !dryRun {
+			rr.Password = password
+		}
+		user.Password = utils.MakePassword(password)
+		valid = append(valid, prepared{index: i, user: user, roleIDs: roleIDs})
+	}
+
+	if dryRun {
+		if progress != nil {
+			progress(len(rows), len(rows))
+		}
+		return result, nil
+	}
+
+	done := len(rows) - len(valid)
+	for _, p := range valid {
+		rr := &result.Rows[p.index]
+		if err := createUser(db, &p.user, p.roleIDs); err != nil {
+			rr.Errors = append(rr.Errors, "创建失败: "+err.Error())
+			rr.Password = ""
+			result.Failed++
+		} else {
+			rr.Created = true
+			result.Created++
+		}
+		done++
+		if progress != nil {
+			progress(done, len(rows))
+		}
+	}
+	if result.Created > 0 {
+		usergroup.Resync(db)
+	}
+	return result, nil
+}
+
+// createUser 在事务中创建用户并分配部门和角色
+func createUser(db *gorm.DB, user *models.User, roleIDs []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Department", "Roles").Create(user).Error; err != nil {
+			return err
+		}
+		if err := org.SetPrimary(tx, user.ID, user.DepartmentID); err != nil {
+			return err
+		}
+		for _, roleID := range roleIDs {
+			if err := tx.Create(&models.UserRole{UserID: user.ID, RoleID: roleID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// uniqueKeys 返回需要唯一的字段值
+func uniqueKeys(row Row) []string {
+	keys := []string{"username:" + row.Username}
+	if row.Email != "" {
+		keys = append(keys, "email:"+row.Email)
+	}
+	if row.Phone != "" {
+		keys = append(keys, "phone:"+row.Phone)
+	}
+	return keys
+}
+
+func keyLabel(key string) string {
+	switch {
+	case strings.HasPrefix(key, "email:"):
+		return "邮箱"
+	case strings.HasPrefix(key, "phone:"):
+		return "手机号"
+	default:
+		return "用户名"
+	}
+}
+
+// existingKeys 查询数据库中已占用的用户名、邮箱和手机号，回收站中的用户不占用，与注册和创建用户一致
+func existingKeys(db *gorm.DB, rows []Row) (map[string]bool, error) {
+	var usernames, emails, phones []string
+	for _, row := range rows {
+		usernames = append(usernames, row.Username)
+		if row.Email != "" {
+			emails = append(emails, row.Email)
+		}
+		if row.Phone != "" {
+			phones = append(phones, row.Phone)
+		}
+	}
+
+	existing := make(map[string]bool)
+	for _, field := range []struct {
+		column string
+		values []string
+	}{{"username", usernames}, {"email", emails}, {"phone", phones}} {
+		for start := 0; start < len(field.values); start += 500 {
+			end := start + 500
+			if end > len(field.values) {
+				end = len(field.values)
+			}
+			var found []string
+			if err := db.Model(&models.User{}).
+				Where(field.column+" IN ?", field.values[start:end]).
+				Pluck(field.column, &found).Error; err != nil {
+				return nil, err
+			}
+			for _, v := range found {
+				existing[field.column+":"+v] = true
+			}
+		}
+	}
+	return existing, nil
+}
+
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,84 @@
+package userimport
+
+import (
+	"io"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"rbac_admin_server/config"
+	"rbac_admin_server/core"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Department{}, &models.Role{},
+		&models.UserRole{}, &models.UserDepartment{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func setup(t *testing.T) {
+	global.Config = &config.Config{}
+	global.Logger = logrus.New()
+	global.Logger.SetOutput(io.Discard)
+	if err := core.InitValidator(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportWithoutContacts(t *testing.T) {
+	setup(t)
+	db := openDB(t)
+
+	rows := []Row{
+		{Line: 2, Username: "import01"},
+		{Line: 3, Username: "import02", Email: "  ", Phone: " "},
+		{Line: 4, Username: "import03", Nickname: "三号"},
+		{Line: 5, Username: "import04", Email: " d@example.com "},
+	}
+	result, err := Import(db, rows, false, "zh-CN", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != len(rows) || result.Failed != 0 {
+		t.Fatalf("Created = %d, Failed = %d, rows = %+v", result.Created, result.Failed, result.Rows)
+	}
+
+	var blank int64
+	db.Model(&models.User{}).Where("email IS NULL AND phone IS NULL").Count(&blank)
+	if blank != 3 {
+		t.Fatalf("未填写的邮箱和手机号应存为NULL，实际 %d 条", blank)
+	}
+	var user models.User
+	if err := db.Where("username = ?", "import04").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "d@example.com" {
+		t.Fatalf("Email = %q, want trimmed", user.Email)
+	}
+
+	// 再次导入未填写联系方式的用户，不应与已有的空值冲突
+	result, err = Import(db, []Row{{Line: 2, Username: "import05"}}, false, "zh-CN", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 1 {
+		t.Fatalf("再次导入失败: %+v", result.Rows)
+	}
+}
@@ -0,0 +1,100 @@
+package userimport
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"rbac_admin_server/global"
+)
+
+// 导入任务状态
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// jobTTL 已结束任务的保留时间
+const jobTTL = time.Hour
+
+// Job 后台导入任务
+type Job struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	DryRun     bool       `json:"dry_run"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Result     *Result    `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedBy  uint       `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+var (
+	jobsMu sync.Mutex
+	jobs   = make(map[string]*Job)
+)
+
+// StartJob 在后台执行导入，返回可轮询进度的任务，locale为校验信息的语言
+func StartJob(db *gorm.DB, rows []Row, dryRun bool, locale string, createdBy uint) *Job {
+	job := &Job{
+		ID:        uuid.New().String(),
+		Status:    JobRunning,
+		DryRun:    dryRun,
+		Total:     len(rows),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	jobsMu.Lock()
+	cleanupJobs()
+	jobs[job.ID] = job
+	jobsMu.Unlock()
+
+	go func() {
+		result, err := Import(db, rows, dryRun, locale, func(done, total int) {
+			jobsMu.Lock()
+			job.Processed = done
+			jobsMu.Unlock()
+		})
+
+		jobsMu.Lock()
+		defer jobsMu.Unlock()
+		now := time.Now()
+		job.FinishedAt = &now
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			global.Logger.Errorf("用户导入任务 %s 失败: %v", job.ID, err)
+			return
+		}
+		job.Status = JobDone
+		job.Processed = job.Total
+		job.Result = result
+		global.Logger.Infof("用户导入任务 %s 完成: 创建%d, 失败%d", job.ID, result.Created, result.Failed)
+	}()
+	return job
+}
+
+// GetJob 查询任务的当前状态，返回副本
+func GetJob(id string) (Job, bool) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	job, ok := jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// cleanupJobs 清理已过期的任务，调用方需持有jobsMu
+func cleanupJobs() {
+	for id, job := range jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > jobTTL {
+			delete(jobs, id)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package usersettings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"rbac_admin_server/global"
+)
+
+// cacheTTL 用户设置缓存时间，系统默认值修改后最迟在此时间后生效
+const cacheTTL = time.Hour
+
+func cacheKey(userID uint) string {
+	return fmt.Sprintf("user_settings:%d", userID)
+}
+
+// loadCache 读取缓存，未启用Redis或未命中时返回false
+func loadCache(ctx context.Context, userID uint) (*Settings, bool) {
+	if global.Redis == nil {
+		return nil, false
+	}
+	data, err := global.Redis.Get(ctx, cacheKey(userID)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var settings Settings
+	if json.Unmarshal(data, &settings) != nil {
+		return nil, false
+	}
+	return &settings, true
+}
+
+// saveCache 写入缓存，失败时忽略
+func saveCache(ctx context.Context, userID uint, settings *Settings) {
+	if global.Redis == nil {
+		return
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return
+	}
+	if err := global.Redis.Set(ctx, cacheKey(userID), data, cacheTTL).Err(); err != nil {
+		global.Logger.Warnf("缓存用户设置失败: %v", err)
+	}
+}
+
+// Invalidate 清除用户设置缓存
+func Invalidate(ctx context.Context, userID uint) {
+	if global.Redis == nil {
+		return
+	}
+	if err := global.Redis.Del(ctx, cacheKey(userID)).Err(); err != nil {
+		global.Logger.Warnf("清除用户设置缓存失败: %v", err)
+	}
+}
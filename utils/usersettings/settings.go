@@ -0,0 +1,297 @@
+package usersettings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"rbac_admin_server/models"
+)
+
+// ErrInvalid 设置内容不合法
+var ErrInvalid = errors.New("设置内容不合法")
+
+// configPrefix 系统默认设置在configs表中的键前缀，如 user.settings.theme
+const configPrefix = "user.settings."
+
+// 可选值
+var (
+	Themes    = []string{"light", "dark", "auto"}
+	Languages = []string{"zh-CN", "en-US"}
+	Channels  = []string{"email", "sms", "push", "system"}
+)
+
+// maxLayoutLen 布局名称最大长度
+const maxLayoutLen = 32
+
+// Settings 生效的用户设置，由系统默认值和用户修改合并而成
+type Settings struct {
+	Theme            string          `json:"theme"`             // 主题: light, dark, auto
+	Language         string          `json:"language"`          // 语言: zh-CN, en-US
+	Layout           string          `json:"layout"`            // 布局
+	SidebarCollapsed bool            `json:"sidebar_collapsed"` // 侧边栏是否折叠
+	Notifications    map[string]bool `json:"notifications"`     // 各类通知是否开启
+}
+
+// builtin 内置默认设置，configs表中没有配置时使用
+func builtin() Settings {
+	return Settings{
+		Theme:            "light",
+		Language:         "zh-CN",
+		Layout:           "default",
+		SidebarCollapsed: false,
+		Notifications:    map[string]bool{"email": true, "sms": false, "push": true, "system": true},
+	}
+}
+
+// Defaults 读取系统默认设置，configs表中的无效值会被忽略
+func Defaults(db *gorm.DB) (Settings, error) {
+	defaults := builtin()
+	var configs []models.Config
+	if err := db.Where(clause.Like{Column: clause.Column{Name: "key"}, Value: configPrefix + "%"}).Where("status = 1").Find(&configs).Error; err != nil {
+		return defaults, err
+	}
+	for _, cfg := range configs {
+		value := strings.TrimSpace(cfg.Value)
+		switch strings.TrimPrefix(cfg.Key, configPrefix) {
+		case "theme":
+			if contains(Themes, value) {
+				defaults.Theme = value
+			}
+		case "language":
+			if contains(Languages, value) {
+				defaults.Language = value
+			}
+		case "layout":
+			if value != "" && len(value) <= maxLayoutLen {
+				defaults.Layout = value
+			}
+		case "sidebar_collapsed":
+			if b, err := strconv.ParseBool(value); err == nil {
+				defaults.SidebarCollapsed = b
+			}
+		case "notifications":
+			var notifications map[string]bool
+			if json.Unmarshal([]byte(value), &notifications) == nil {
+				for channel, on := range notifications {
+					if contains(Channels, channel) {
+						defaults.Notifications[channel] = on
+					}
+				}
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// SeedDefaults 在configs表中写入缺少的系统默认设置，已有的配置不会被覆盖
+func SeedDefaults(db *gorm.DB) error {
+	defaults := builtin()
+	notifications, _ := json.Marshal(defaults.Notifications)
+	items := []models.Config{
+		{Key: configPrefix + "theme", Value: defaults.Theme, Type: "string", Description: "用户默认主题(light,dark,auto)"},
+		{Key: configPrefix + "language", Value: defaults.Language, Type: "string", Description: "用户默认语言"},
+		{Key: configPrefix + "layout", Value: defaults.Layout, Type: "string", Description: "用户默认布局"},
+		{Key: configPrefix + "sidebar_collapsed", Value: strconv.FormatBool(defaults.SidebarCollapsed), Type: "bool", Description: "侧边栏默认是否折叠"},
+		{Key: configPrefix + "notifications", Value: string(notifications), Type: "json", Description: "默认通知偏好"},
+	}
+	for _, item := range items {
+		item.IsSystem = 1
+		item.Status = 1
+		item.Group = "user_settings"
+		if err := db.Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: item.Key}).FirstOrCreate(&item).Error; err != nil {
+			return fmt.Errorf("写入默认设置 %s 失败: %w", item.Key, err)
+		}
+	}
+	return nil
+}
+
+// Get 返回用户生效的设置，优先读取缓存
+func Get(db *gorm.DB, userID uint) (*Settings, error) {
+	ctx := db.Statement.Context
+	if settings, ok := loadCache(ctx, userID); ok {
+		return settings, nil
+	}
+
+	settings, err := Defaults(db)
+	if err != nil {
+		return nil, err
+	}
+	var row models.UserSettings
+	err = db.Where("user_id = ?", userID).Take(&row).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		merge(&settings, &row)
+	}
+	saveCache(ctx, userID, &settings)
+	return &settings, nil
+}
+
+// Language 返回用户自己修改过的语言，没有修改时返回false
+// 与Get不同，不合并系统默认值，用于判断是否应按请求头选择语言
+func Language(db *gorm.DB, userID uint) (string, bool, error) {
+	var row models.UserSettings
+	err := db.Select("language").Where("user_id = ?", userID).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil || row.Language == nil {
+		return "", false, err
+	}
+	return *row.Language, true, nil
+}
+
+// Update 以JSON Merge Patch方式修改用户设置
+// 未出现的项保持不变，值为null的项恢复系统默认值，通知偏好按类型逐项合并
+func Update(db *gorm.DB, userID uint, patch []byte) (*Settings, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var row models.UserSettings
+		if err := tx.Where(models.UserSettings{UserID: userID}).FirstOrInit(&row).Error; err != nil {
+			return err
+		}
+		if err := apply(&row, patch); err != nil {
+			return err
+		}
+		return tx.Save(&row).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	Invalidate(db.Statement.Context, userID)
+	return Get(db, userID)
+}
+
+// Reset 删除用户的全部修改，恢复系统默认设置
+func Reset(db *gorm.DB, userID uint) (*Settings, error) {
+	if err := db.Where("user_id = ?", userID).Delete(&models.UserSettings{}).Error; err != nil {
+		return nil, err
+	}
+	Invalidate(db.Statement.Context, userID)
+	return Get(db, userID)
+}
+
+// merge 将用户修改合并到默认设置
+func merge(settings *Settings, row *models.UserSettings) {
+	if row.Theme != nil {
+		settings.Theme = *row.Theme
+	}
+	if row.Language != nil {
+		settings.Language = *row.Language
+	}
+	if row.Layout != nil {
+		settings.Layout = *row.Layout
+	}
+	if row.SidebarCollapsed != nil {
+		settings.SidebarCollapsed = *row.SidebarCollapsed
+	}
+	for channel, on := range overrides(row) {
+		settings.Notifications[channel] = on
+	}
+}
+
+// apply 校验修改内容并写入记录，任一项不合法时返回ErrInvalid
+func apply(row *models.UserSettings, patch []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil || fields == nil {
+		return fmt.Errorf("%w: 请求体必须是JSON对象", ErrInvalid)
+	}
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "theme":
+			row.Theme, err = parseString(raw, key, func(v string) bool { return contains(Themes, v) })
+		case "language":
+			row.Language, err = parseString(raw, key, func(v string) bool { return contains(Languages, v) })
+		case "layout":
+			row.Layout, err = parseString(raw, key, func(v string) bool { return v != "" && len(v) <= maxLayoutLen })
+		case "sidebar_collapsed":
+			row.SidebarCollapsed = nil
+			if !isNull(raw) {
+				var b bool
+				if json.Unmarshal(raw, &b) != nil {
+					err = fmt.Errorf("%w: %s 必须是布尔值", ErrInvalid, key)
+				}
+				row.SidebarCollapsed = &b
+			}
+		case "notifications":
+			err = applyNotifications(row, raw)
+		default:
+			err = fmt.Errorf("%w: 未知的设置项 %s", ErrInvalid, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyNotifications 按通知类型合并，值为null的类型恢复默认值
+func applyNotifications(row *models.UserSettings, raw json.RawMessage) error {
+	if isNull(raw) {
+		row.Notifications = ""
+		return nil
+	}
+	var changes map[string]*bool
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		return fmt.Errorf("%w: notifications 必须是通知类型到布尔值的对象", ErrInvalid)
+	}
+	current := overrides(row)
+	for channel, on := range changes {
+		if !contains(Channels, channel) {
+			return fmt.Errorf("%w: 未知的通知类型 %s", ErrInvalid, channel)
+		}
+		if on == nil {
+			delete(current, channel)
+		} else {
+			current[channel] = *on
+		}
+	}
+	row.Notifications = ""
+	if len(current) > 0 {
+		data, _ := json.Marshal(current)
+		row.Notifications = string(data)
+	}
+	return nil
+}
+
+// overrides 解析记录中用户修改过的通知偏好
+func overrides(row *models.UserSettings) map[string]bool {
+	current := make(map[string]bool)
+	if row.Notifications != "" {
+		_ = json.Unmarshal([]byte(row.Notifications), &current)
+	}
+	return current
+}
+
+// parseString 解析字符串设置项，null表示恢复默认值
+func parseString(raw json.RawMessage, key string, valid func(string) bool) (*string, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+	var v string
+	if err := json.Unmarshal(raw, &v); err != nil || !valid(v) {
+		return nil, fmt.Errorf("%w: %s 的值无效", ErrInvalid, key)
+	}
+	return &v, nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package userstate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// Sweep 批量解除到期的锁定并将到期账号标记为过期，返回变更的用户数
+func Sweep(db *gorm.DB) (int64, error) {
+	now := time.Now()
+	unlocked := db.Model(&models.User{}).
+		Where("status = ? AND locked_until IS NOT NULL AND locked_until <= ?", models.UserStatusLocked, now).
+		Updates(map[string]interface{}{
+			"status": models.UserStatusActive, "status_at": now,
+			"lock_reason": "", "locked_until": nil, "failed_logins": 0,
+		})
+	if unlocked.Error != nil {
+		return 0, unlocked.Error
+	}
+
+	statuses := []int{models.UserStatusActive, models.UserStatusLocked, models.UserStatusPending}
+	expired := db.Model(&models.User{}).
+		Where("status IN ? AND expires_at IS NOT NULL AND expires_at <= ?", statuses, now).
+		Updates(map[string]interface{}{
+			"status": models.UserStatusExpired, "status_at": now,
+			"lock_reason": "", "locked_until": nil,
+		})
+	if expired.Error != nil {
+		return unlocked.RowsAffected, expired.Error
+	}
+
+	n := unlocked.RowsAffected + expired.RowsAffected
+	if n > 0 {
+		Reset()
+	}
+	return n, nil
+}
+
+// StartExpiryTimer 启动账号到期检查定时任务
+func StartExpiryTimer() {
+	interval := global.Config.Security.ExpiryInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := Sweep(global.DB)
+			if err != nil {
+				global.Logger.Errorf("检查账号到期失败: %v", err)
+				continue
+			}
+			if n > 0 {
+				global.Logger.Infof("账号状态自动变更 %d 个", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,95 @@
+package userstate
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+// cacheTTL 认证中间件状态缓存时间
+// 本进程内的状态变更会主动清除缓存，其他实例上的变更最多延迟该时间生效
+const cacheTTL = 30 * time.Second
+
+type cached struct {
+	user models.User
+	at   time.Time
+}
+
+var cache = struct {
+	sync.RWMutex
+	users map[uint]cached
+}{users: make(map[uint]cached)}
+
+// LoginFailed 记录一次登录失败，正常状态的账号连续失败次数达到上限时锁定，返回是否本次锁定
+func LoginFailed(db *gorm.DB, u *models.User) (bool, error) {
+	if err := db.Model(&models.User{}).Where("id = ?", u.ID).
+		UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error; err != nil {
+		return false, err
+	}
+	u.FailedLogins++
+
+	// 不可登录的账号只累计次数，不改变状态，手动锁定不会被改为自动解锁
+	if u.Status != models.UserStatusActive {
+		return false, nil
+	}
+	cfg := global.Config.Security
+	if cfg.MaxLoginFailures <= 0 || u.FailedLogins < cfg.MaxLoginFailures {
+		return false, nil
+	}
+	var until *time.Time
+	if cfg.LockoutMinutes > 0 {
+		t := time.Now().Add(time.Duration(cfg.LockoutMinutes) * time.Minute)
+		until = &t
+	}
+	reason := fmt.Sprintf("连续登录失败 %d 次", u.FailedLogins)
+	return true, setStatus(db, u, models.UserStatusLocked, reason, until)
+}
+
+// LoginSucceeded 登录成功后清零连续失败次数
+func LoginSucceeded(db *gorm.DB, u *models.User) error {
+	if u.FailedLogins == 0 {
+		return nil
+	}
+	u.FailedLogins = 0
+	return db.Model(&models.User{}).Where("id = ?", u.ID).UpdateColumn("failed_logins", 0).Error
+}
+
+// Verify 返回用户当前状态对应的错误码，供认证中间件和刷新令牌使用
+// 用户不存在或已删除时返回gorm.ErrRecordNotFound
+func Verify(db *gorm.DB, userID uint) (int, error) {
+	now := time.Now()
+	cache.RLock()
+	entry, ok := cache.users[userID]
+	cache.RUnlock()
+
+	if !ok || now.Sub(entry.at) > cacheTTL {
+		var user models.User
+		if err := db.Select("id", "status", "expires_at", "locked_until").First(&user, userID).Error; err != nil {
+			return 0, err
+		}
+		entry = cached{user: user, at: now}
+		cache.Lock()
+		cache.users[userID] = entry
+		cache.Unlock()
+	}
+	return ErrorCode(Effective(&entry.user, now)), nil
+}
+
+// Invalidate 清除用户的状态缓存
+func Invalidate(userID uint) {
+	cache.Lock()
+	delete(cache.users, userID)
+	cache.Unlock()
+}
+
+// Reset 清除全部状态缓存，批量修改用户状态后调用
+func Reset() {
+	cache.Lock()
+	cache.users = make(map[uint]cached)
+	cache.Unlock()
+}
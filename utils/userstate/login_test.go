@@ -0,0 +1,77 @@
+package userstate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"rbac_admin_server/config"
+	"rbac_admin_server/global"
+	"rbac_admin_server/models"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoginFailed(t *testing.T) {
+	global.Config = &config.Config{}
+	global.Config.Security.MaxLoginFailures = 3
+	global.Config.Security.LockoutMinutes = 15
+
+	tests := []struct {
+		name       string
+		status     int
+		failed     int
+		locked     bool
+		wantStatus int
+		timedLock  bool
+	}{
+		{"未达到上限", statusActive, 0, false, statusActive, false},
+		{"达到上限锁定", statusActive, 2, true, statusLocked, true},
+		{"手动锁定只累计次数", statusLocked, 5, false, statusLocked, false},
+		{"待激活只累计次数", statusPending, 5, false, statusPending, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openDB(t)
+			user := models.User{Username: "eve01", Password: "x", Status: tt.status, FailedLogins: tt.failed}
+			if err := db.Create(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			locked, err := LoginFailed(db, &user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored models.User
+			if err := db.First(&stored, user.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if locked != tt.locked || stored.Status != tt.wantStatus || stored.FailedLogins != tt.failed+1 {
+				t.Fatalf("locked=%v status=%s failed=%d", locked, Name(stored.Status), stored.FailedLogins)
+			}
+			if (stored.LockedUntil != nil) != tt.timedLock {
+				t.Fatalf("locked_until = %v", stored.LockedUntil)
+			}
+			if tt.timedLock && stored.LockedUntil.Before(time.Now().Add(14*time.Minute)) {
+				t.Fatalf("locked_until = %v", stored.LockedUntil)
+			}
+		})
+	}
+}
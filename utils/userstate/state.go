@@ -0,0 +1,193 @@
+package userstate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+)
+
+// ErrTransition 不允许的状态变更
+var ErrTransition = errors.New("不允许的账号状态变更")
+
+// names 状态名称
+var names = map[int]string{
+	models.UserStatusActive:   "正常",
+	models.UserStatusLocked:   "锁定",
+	models.UserStatusPending:  "待激活",
+	models.UserStatusExpired:  "已过期",
+	models.UserStatusArchived: "已归档",
+}
+
+// transitions 允许的状态变更，归档账号只能重新启用
+var transitions = map[int][]int{
+	models.UserStatusPending:  {models.UserStatusActive, models.UserStatusExpired, models.UserStatusArchived},
+	models.UserStatusActive:   {models.UserStatusLocked, models.UserStatusExpired, models.UserStatusArchived},
+	models.UserStatusLocked:   {models.UserStatusActive, models.UserStatusExpired, models.UserStatusArchived},
+	models.UserStatusExpired:  {models.UserStatusActive, models.UserStatusArchived},
+	models.UserStatusArchived: {models.UserStatusActive},
+}
+
+// codes 不可登录状态对应的错误码
+var codes = map[int]int{
+	models.UserStatusLocked:   utils.ERROR_USER_LOCKED,
+	models.UserStatusPending:  utils.ERROR_USER_PENDING,
+	models.UserStatusExpired:  utils.ERROR_USER_EXPIRED,
+	models.UserStatusArchived: utils.ERROR_USER_ARCHIVED,
+}
+
+// Valid 判断状态值是否有效
+func Valid(status int) bool {
+	_, ok := names[status]
+	return ok
+}
+
+// Name 返回状态名称
+func Name(status int) string {
+	if name, ok := names[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知(%d)", status)
+}
+
+// CanTransition 判断是否允许从from变更为to
+func CanTransition(from, to int) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Effective 返回用户在指定时间的实际状态
+// 锁定截止时间已过视为正常，账号到期时间已过视为过期，定时任务写回数据库前也能生效
+func Effective(u *models.User, now time.Time) int {
+	status := u.Status
+	if status == models.UserStatusLocked && u.LockedUntil != nil && !now.Before(*u.LockedUntil) {
+		status = models.UserStatusActive
+	}
+	if expired(u, now) && status != models.UserStatusArchived {
+		status = models.UserStatusExpired
+	}
+	return status
+}
+
+// ErrorCode 返回状态对应的错误码，可以登录时返回utils.SUCCESS
+func ErrorCode(status int) int {
+	if status == models.UserStatusActive {
+		return utils.SUCCESS
+	}
+	if code, ok := codes[status]; ok {
+		return code
+	}
+	return utils.ERROR_USER_LOCKED
+}
+
+// Message 返回指定语言的用户不可登录提示信息，锁定有截止时间时提示解锁时间
+func Message(locale string, u *models.User, status int) string {
+	msg := utils.MessageIn(locale, ErrorCode(status))
+	if status == models.UserStatusLocked && u.LockedUntil != nil {
+		until := u.LockedUntil.Format("2006-01-02 15:04:05")
+		if locale == "en-US" {
+			msg += ", please try again after " + until
+		} else {
+			msg += "，请于 " + until + " 后重试"
+		}
+	}
+	return msg
+}
+
+// Check 校验用户能否变更为指定状态
+func Check(u *models.User, to int) error {
+	if !Valid(to) {
+		return fmt.Errorf("%w: 无效的状态 %d", ErrTransition, to)
+	}
+	now := time.Now()
+	from := Effective(u, now)
+	if from != to && !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s → %s", ErrTransition, Name(from), Name(to))
+	}
+	if to == models.UserStatusActive && expired(u, now) {
+		return fmt.Errorf("%w: 账号已到期，请先调整到期时间", ErrTransition)
+	}
+	return nil
+}
+
+// Transition 校验并变更用户状态
+// reason和lockedUntil只在锁定时使用，lockedUntil为空表示需手动解锁
+func Transition(db *gorm.DB, u *models.User, to int, reason string, lockedUntil *time.Time) error {
+	if err := Check(u, to); err != nil {
+		return err
+	}
+	if to == u.Status && to != models.UserStatusLocked {
+		return nil
+	}
+	return setStatus(db, u, to, reason, lockedUntil)
+}
+
+// Refresh 将自动解锁和账号到期写回数据库，返回用户当前状态
+func Refresh(db *gorm.DB, u *models.User) (int, error) {
+	status := Effective(u, time.Now())
+	if status == u.Status {
+		return status, nil
+	}
+	return status, setStatus(db, u, status, "", nil)
+}
+
+// SetExpiry 设置账号到期时间，expiresAt为空表示永久有效
+// 已过期账号延期后恢复正常，到期时间早于当前时间的账号立即过期
+func SetExpiry(db *gorm.DB, u *models.User, expiresAt *time.Time) error {
+	if err := db.Model(u).Update("expires_at", expiresAt).Error; err != nil {
+		return err
+	}
+	u.ExpiresAt = expiresAt
+	Invalidate(u.ID)
+
+	if u.Status == models.UserStatusExpired && !expired(u, time.Now()) {
+		return setStatus(db, u, models.UserStatusActive, "", nil)
+	}
+	_, err := Refresh(db, u)
+	return err
+}
+
+// setStatus 写入状态，离开锁定状态时清除锁定信息和登录失败次数
+func setStatus(db *gorm.DB, u *models.User, to int, reason string, lockedUntil *time.Time) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":        to,
+		"status_at":     now,
+		"lock_reason":   "",
+		"locked_until":  nil,
+		"failed_logins": 0,
+	}
+	if to == models.UserStatusLocked {
+		updates["lock_reason"] = reason
+		updates["locked_until"] = lockedUntil
+		delete(updates, "failed_logins")
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", u.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	Invalidate(u.ID)
+
+	u.Status = to
+	u.StatusAt = &now
+	u.LockReason, _ = updates["lock_reason"].(string)
+	u.LockedUntil = nil
+	if to == models.UserStatusLocked {
+		u.LockedUntil = lockedUntil
+	} else {
+		u.FailedLogins = 0
+	}
+	return nil
+}
+
+// expired 判断账号是否已到期
+func expired(u *models.User, now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
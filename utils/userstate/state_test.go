@@ -0,0 +1,147 @@
+package userstate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"rbac_admin_server/models"
+	"rbac_admin_server/utils"
+)
+
+const (
+	statusActive   = models.UserStatusActive
+	statusLocked   = models.UserStatusLocked
+	statusPending  = models.UserStatusPending
+	statusExpired  = models.UserStatusExpired
+	statusArchived = models.UserStatusArchived
+)
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func TestCanTransition(t *testing.T) {
+	all := []int{statusActive, statusLocked, statusPending, statusExpired, statusArchived}
+	allowed := map[[2]int]bool{
+		{statusPending, statusActive}: true, {statusPending, statusExpired}: true, {statusPending, statusArchived}: true,
+		{statusActive, statusLocked}: true, {statusActive, statusExpired}: true, {statusActive, statusArchived}: true,
+		{statusLocked, statusActive}: true, {statusLocked, statusExpired}: true, {statusLocked, statusArchived}: true,
+		{statusExpired, statusActive}: true, {statusExpired, statusArchived}: true,
+		{statusArchived, statusActive}: true,
+	}
+	for _, from := range all {
+		for _, to := range all {
+			want := allowed[[2]int{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", Name(from), Name(to), got, want)
+			}
+		}
+	}
+	if CanTransition(statusActive, 99) || CanTransition(99, statusActive) {
+		t.Error("未知状态不应允许变更")
+	}
+}
+
+func TestEffective(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	past, future := timePtr(now.Add(-time.Hour)), timePtr(now.Add(time.Hour))
+	tests := []struct {
+		name string
+		user models.User
+		want int
+	}{
+		{"正常", models.User{Status: statusActive}, statusActive},
+		{"手动锁定", models.User{Status: statusLocked}, statusLocked},
+		{"锁定未到期", models.User{Status: statusLocked, LockedUntil: future}, statusLocked},
+		{"锁定已到期", models.User{Status: statusLocked, LockedUntil: past}, statusActive},
+		{"锁定恰好到期", models.User{Status: statusLocked, LockedUntil: timePtr(now)}, statusActive},
+		{"账号已到期", models.User{Status: statusActive, ExpiresAt: past}, statusExpired},
+		{"账号未到期", models.User{Status: statusActive, ExpiresAt: future}, statusActive},
+		{"锁定且账号已到期", models.User{Status: statusLocked, LockedUntil: future, ExpiresAt: past}, statusExpired},
+		{"待激活且账号已到期", models.User{Status: statusPending, ExpiresAt: past}, statusExpired},
+		{"归档不受到期影响", models.User{Status: statusArchived, ExpiresAt: past}, statusArchived},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Effective(&tt.user, now); got != tt.want {
+				t.Fatalf("Effective() = %s, want %s", Name(got), Name(tt.want))
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	past, future := timePtr(time.Now().Add(-time.Hour)), timePtr(time.Now().Add(time.Hour))
+	tests := []struct {
+		name string
+		user models.User
+		to   int
+		ok   bool
+	}{
+		{"激活", models.User{Status: statusPending}, statusActive, true},
+		{"锁定", models.User{Status: statusActive}, statusLocked, true},
+		{"保持不变", models.User{Status: statusLocked}, statusLocked, true},
+		{"归档后锁定", models.User{Status: statusArchived}, statusLocked, false},
+		{"归档后启用", models.User{Status: statusArchived}, statusActive, true},
+		{"待激活直接锁定", models.User{Status: statusPending}, statusLocked, false},
+		{"过期后改回待激活", models.User{Status: statusExpired}, statusPending, false},
+		{"无效状态", models.User{Status: statusActive}, 0, false},
+		{"已到期不能启用", models.User{Status: statusActive, ExpiresAt: past}, statusActive, false},
+		{"未到期可以启用", models.User{Status: statusExpired, ExpiresAt: future}, statusActive, true},
+		{"锁定到期后按正常判断", models.User{Status: statusLocked, LockedUntil: past}, statusArchived, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(&tt.user, tt.to)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrTransition) {
+				t.Fatalf("err = %v, want ErrTransition", err)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   int
+	}{
+		{statusActive, utils.SUCCESS},
+		{statusLocked, utils.ERROR_USER_LOCKED},
+		{statusPending, utils.ERROR_USER_PENDING},
+		{statusExpired, utils.ERROR_USER_EXPIRED},
+		{statusArchived, utils.ERROR_USER_ARCHIVED},
+		{99, utils.ERROR_USER_LOCKED},
+	}
+	for _, tt := range tests {
+		if got := ErrorCode(tt.status); got != tt.want {
+			t.Errorf("ErrorCode(%d) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	until := time.Date(2024, 6, 1, 12, 30, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		locale string
+		user   models.User
+		status int
+		want   string
+	}{
+		{"锁定带截止时间", "zh-CN", models.User{LockedUntil: &until}, statusLocked, "请于 2024-06-01 12:30:00 后重试"},
+		{"英文锁定带截止时间", "en-US", models.User{LockedUntil: &until}, statusLocked, "please try again after 2024-06-01 12:30:00"},
+		{"手动锁定", "zh-CN", models.User{}, statusLocked, utils.MessageIn("zh-CN", utils.ERROR_USER_LOCKED)},
+		{"已过期", "en-US", models.User{LockedUntil: &until}, statusExpired, utils.MessageIn("en-US", utils.ERROR_USER_EXPIRED)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Message(tt.locale, &tt.user, tt.status)
+			if !strings.HasSuffix(got, tt.want) {
+				t.Fatalf("Message() = %q, want suffix %q", got, tt.want)
+			}
+		})
+	}
+}